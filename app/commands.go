@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/samvibes/vexop/auth-service/internal/breach"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+)
+
+// RunCommand runs a one-off administrative command instead of the server.
+func RunCommand(args []string) error {
+	command := strings.Join(args, " ")
+
+	switch {
+	case command == "keys rotate":
+		container := InitApp()
+		rotator, ok := container.KeyService.(services.KeyRotator)
+		if !ok {
+			return errors.New("key rotation requires JWT_KEY_STORE=postgres")
+		}
+		if err := rotator.RotateKeys(); err != nil {
+			return err
+		}
+		log.Println("signing keys rotated")
+		return nil
+	case len(args) >= 2 && args[0] == "breached" && args[1] == "build":
+		return buildBreachedFilter(args[2:])
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// buildBreachedFilter builds the filter BREACHED_PASSWORDS_FILE points at
+// from Have I Been Pwned hashes:
+//
+//	breached build [-fp-rate 0.001] <hashes file or range directory> <output>
+func buildBreachedFilter(args []string) error {
+	flags := flag.NewFlagSet("breached build", flag.ContinueOnError)
+	fpRate := flags.Float64("fp-rate", 0.001, "false positive rate of the filter")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: breached build [-fp-rate 0.001] <hashes file or range directory> <output>")
+	}
+
+	filter, err := breach.Build(flags.Arg(0), *fpRate)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	if _, err := filter.WriteTo(out); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("breached password filter with %d hashes written to %s", filter.Len(), flags.Arg(1))
+	return nil
+}
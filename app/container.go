@@ -1,22 +1,59 @@
 package app
 
 import (
+	"log"
+	"time"
+
 	"github.com/samvibes/vexop/auth-service/config"
+	"github.com/samvibes/vexop/auth-service/internal/breach"
+	"github.com/samvibes/vexop/auth-service/internal/extauthz"
 	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/lockout"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
 	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/passhash"
+	"github.com/samvibes/vexop/auth-service/internal/ratelimit"
 	"github.com/samvibes/vexop/auth-service/internal/repository"
 	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
 	"github.com/samvibes/vexop/auth-service/seed"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
 
 type AppContainer struct {
-	DB            *gorm.DB
-	AuthHandler   handlers.AuthHandler
-	TenantHandler handlers.TenantHandler
-	InviteHandler handlers.InviteHandler
-	UserHandler   handlers.UserHandler
-	RoleHandler   handlers.RoleHandler
+	DB                         *gorm.DB
+	AuthService                services.AuthService
+	KeyService                 services.KeyService
+	SessionService             services.SessionService
+	PersonalAccessTokenService services.PersonalAccessTokenService
+	ServiceAccountService      services.ServiceAccountService
+	ImpersonationService       services.ImpersonationService
+	AuthHandler                handlers.AuthHandler
+	TenantHandler              handlers.TenantHandler
+	InviteHandler              handlers.InviteHandler
+	UserHandler                handlers.UserHandler
+	RoleHandler                handlers.RoleHandler
+	WellKnownHandler           handlers.WellKnownHandler
+	TokenClaimHandler          handlers.TokenClaimHandler
+	OAuthHandler               handlers.OAuthHandler
+	OAuthClientHandler         handlers.OAuthClientHandler
+	ForwardAuthHandler         handlers.ForwardAuthHandler
+	ExtAuthzServer             *extauthz.Server
+	MFAHandler                 handlers.MFAHandler
+	WebAuthnHandler            handlers.WebAuthnHandler
+	PasswordlessHandler        handlers.PasswordlessHandler
+	EmailTemplateHandler       handlers.EmailTemplateHandler
+	EmailVerificationHandler   handlers.EmailVerificationHandler
+	AuditHandler               handlers.AuditHandler
+	PasswordPolicyHandler      handlers.PasswordPolicyHandler
+	SessionHandler             handlers.SessionHandler
+	PersonalAccessTokenHandler handlers.PersonalAccessTokenHandler
+	ServiceAccountHandler      handlers.ServiceAccountHandler
+	ImpersonationHandler       handlers.ImpersonationHandler
+	RateLimitStore             ratelimit.Store
+	RateLimitRules             middleware.RateLimitRules
 }
 
 func InitApp() *AppContainer {
@@ -27,39 +64,209 @@ func InitApp() *AppContainer {
 		&models.Role{},
 		&models.Permission{},
 		&models.Invitation{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.UserTokenRevocation{},
+		&models.SigningKey{},
+		&models.TokenClaimConfig{},
+		&models.OAuthClient{},
+		&models.AuthorizationCode{},
+		&models.TOTPCredential{},
+		&models.RecoveryCode{},
+		&models.MFAChallenge{},
+		&models.MFAPolicy{},
+		&models.WebAuthnCredential{},
+		&models.WebAuthnSession{},
+		&models.LoginToken{},
+		&models.LoginMethodPolicy{},
+		&models.OutboxEmail{},
+		&models.EmailTemplate{},
+		&models.EmailVerification{},
+		&models.EmailVerificationPolicy{},
+		&models.LoginThrottle{},
+		&models.AuditEvent{},
+		&models.RateLimitBucket{},
+		&models.PasswordPolicy{},
+		&models.PasswordHistory{},
+		&models.Session{},
+		&models.PersonalAccessToken{},
 	)
 
-	seed.SeedSuperAdmin(db)
-	seed.SeedRoles(db)
-
 	roleRepo := repository.NewRoleRepository(db)
 	roleService := services.NewRoleService(roleRepo)
 	roleHandler := handlers.NewRoleHandler(roleService)
 
 	permissionRepo := repository.NewPermissionRepository(db)
 
-	authService := services.NewAuthService()
+	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	revocationRepo := repository.NewRevocationRepository(db)
+	revocationService := services.NewRevocationService(revocationRepo)
+	keyService, err := initKeyService(db)
+	if err != nil {
+		log.Fatalf("failed to load signing key: %v", err)
+	}
+	claimConfigRepo := repository.NewTokenClaimConfigRepository(db)
+	claimsService := services.NewClaimsService(claimConfigRepo, roleRepo)
+	tokenClaimHandler := handlers.NewTokenClaimHandler(claimsService)
+	passwords, err := passhash.New()
+	if err != nil {
+		log.Fatalf("failed to configure password hashing: %v", err)
+	}
+	authService := services.NewAuthService(refreshTokenRepo, sessionRepo, userRepo, revocationService, keyService, claimsService, passwords)
+	wellKnownHandler := handlers.NewWellKnownHandler(keyService)
+
+	seed.SeedSuperAdmin(db, authService)
+	seed.SeedRoles(db)
+	if err := seed.GrantAdminPermissions(db); err != nil {
+		log.Printf("failed to grant admin permissions: %v", err)
+	}
+
+	transactor := repository.NewTransactor(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	mailService := services.NewMailService(outboxRepo, repository.NewEmailTemplateRepository(db))
+	emailTemplateHandler := handlers.NewEmailTemplateHandler(mailService)
+	mailDriver, err := mailer.New()
+	if err != nil {
+		log.Fatalf("failed to configure mailer: %v", err)
+	}
+	outboxService := services.NewOutboxService(outboxRepo, mailDriver)
+	outboxService.StartDispatcher(utils.GetDuration("MAIL_OUTBOX_INTERVAL", 5*time.Second))
 
 	tenantRepo := repository.NewTenantRepo(db)
 	tenantService := services.NewTenantSvc(tenantRepo)
 	tenantHandler := handlers.NewTenantHandler(tenantService)
 
-	userRepo := repository.NewUserRepository(db)
-	userService := services.NewUserService(userRepo, roleRepo, permissionRepo, authService)
+	auditService := services.NewAuditService(repository.NewAuditRepository(db))
+	auditHandler := handlers.NewAuditHandler(auditService)
+	lockoutStore, err := lockout.New(db)
+	if err != nil {
+		log.Fatalf("failed to configure lockout store: %v", err)
+	}
+	lockoutService := services.NewLockoutService(lockoutStore, userRepo, auditService)
+
+	mfaRepo := repository.NewMFARepository(db)
+	webauthnRepo := repository.NewWebAuthnRepository(db)
+	mfaService := services.NewMFAService(mfaRepo, webauthnRepo, userRepo, roleRepo, authService, lockoutService)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+	webauthnService, err := services.NewWebAuthnService(webauthnRepo, userRepo, mfaService, authService)
+	if err != nil {
+		log.Fatalf("failed to configure webauthn: %v", err)
+	}
+	webauthnHandler := handlers.NewWebAuthnHandler(webauthnService)
+	passwordlessRepo := repository.NewPasswordlessRepository(db)
+	passwordlessService := services.NewPasswordlessService(passwordlessRepo, userRepo, mfaService, authService, mailService, lockoutService, transactor)
+	passwordlessHandler := handlers.NewPasswordlessHandler(passwordlessService)
+
+	emailVerificationService := services.NewEmailVerificationService(repository.NewEmailVerificationRepository(db), userRepo, roleRepo, authService, mailService, transactor)
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(emailVerificationService)
+
+	breachedPasswords, err := breach.New()
+	if err != nil {
+		log.Fatalf("failed to configure breached password check: %v", err)
+	}
+	passwordPolicyService := services.NewPasswordPolicyService(repository.NewPasswordPolicyRepository(db), authService, breachedPasswords)
+	passwordPolicyHandler := handlers.NewPasswordPolicyHandler(passwordPolicyService)
+
+	sessionService := services.NewSessionService(sessionRepo, userRepo, revocationService, auditService)
+	sessionService.StartFlusher(utils.GetDuration("SESSION_ACTIVITY_INTERVAL", 30*time.Second))
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	tokenService := services.NewPersonalAccessTokenService(repository.NewPersonalAccessTokenRepository(db), userRepo, roleRepo, auditService)
+	tokenHandler := handlers.NewPersonalAccessTokenHandler(tokenService)
+	serviceAccountService := services.NewServiceAccountService(userRepo, roleRepo, auditService)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(serviceAccountService, tokenService)
+	impersonationService := services.NewImpersonationService(userRepo, authService, auditService)
+	impersonationHandler := handlers.NewImpersonationHandler(impersonationService)
+
+	userService := services.NewUserService(userRepo, roleRepo, permissionRepo, authService, mfaService, mailService, transactor, emailVerificationService, lockoutService, passwordPolicyService)
 	authHandler := handlers.NewAuthHandler(authService, userService, tenantService, db)
 
 	inviteRepo := repository.NewInviteRepository(db)
-	inviteService := services.NewInviteService(inviteRepo, userRepo, roleRepo)
+	inviteService := services.NewInviteService(inviteRepo, userRepo, roleRepo, mailService, transactor, passwordPolicyService, authService)
 	inviteHandler := handlers.NewInviteHandler(inviteService, db)
 
-	userHandler := handlers.NewUserHandler(userService, db)
+	userHandler := handlers.NewUserHandler(userService, lockoutService, db)
+
+	if viper.GetString("JWT_ISSUER") == "" {
+		log.Println("JWT_ISSUER is not set; OpenID Connect discovery and ID tokens need it")
+	}
+	oauthClientRepo := repository.NewOAuthClientRepository(db)
+	authorizationCodeRepo := repository.NewAuthorizationCodeRepository(db)
+	oidcService := services.NewOIDCService(oauthClientRepo, authorizationCodeRepo, userRepo, authService, keyService)
+	oauthHandler := handlers.NewOAuthHandler(oidcService, authService, userService, mfaService)
+	oauthClientService := services.NewOAuthClientService(oauthClientRepo, roleRepo)
+	oauthClientHandler := handlers.NewOAuthClientHandler(oauthClientService)
+
+	resourceRules, err := middleware.LoadResourceRules()
+	if err != nil {
+		log.Fatalf("failed to load forward auth rules: %v", err)
+	}
+	requestAuthorizer := middleware.NewRequestAuthorizer(middleware.NewAuthenticator(db, authService, tokenService), resourceRules, db)
+	forwardAuthHandler := handlers.NewForwardAuthHandler(requestAuthorizer)
+	extAuthzServer := extauthz.NewServer(requestAuthorizer)
+
+	rateLimitStore, err := ratelimit.New(db)
+	if err != nil {
+		log.Fatalf("failed to configure rate limit store: %v", err)
+	}
+	rateLimitRules, err := middleware.LoadRateLimitRules()
+	if err != nil {
+		log.Fatalf("failed to load rate limits: %v", err)
+	}
 
 	return &AppContainer{
-		DB:            db,
-		AuthHandler:   authHandler,
-		TenantHandler: tenantHandler,
-		InviteHandler: inviteHandler,
-		UserHandler:   userHandler,
-		RoleHandler:   roleHandler,
+		DB:                         db,
+		AuthService:                authService,
+		KeyService:                 keyService,
+		SessionService:             sessionService,
+		PersonalAccessTokenService: tokenService,
+		ServiceAccountService:      serviceAccountService,
+		ImpersonationService:       impersonationService,
+		AuthHandler:                authHandler,
+		TenantHandler:              tenantHandler,
+		InviteHandler:              inviteHandler,
+		UserHandler:                userHandler,
+		RoleHandler:                roleHandler,
+		WellKnownHandler:           wellKnownHandler,
+		TokenClaimHandler:          tokenClaimHandler,
+		OAuthHandler:               oauthHandler,
+		OAuthClientHandler:         oauthClientHandler,
+		ForwardAuthHandler:         forwardAuthHandler,
+		ExtAuthzServer:             extAuthzServer,
+		MFAHandler:                 mfaHandler,
+		WebAuthnHandler:            webauthnHandler,
+		PasswordlessHandler:        passwordlessHandler,
+		EmailTemplateHandler:       emailTemplateHandler,
+		EmailVerificationHandler:   emailVerificationHandler,
+		AuditHandler:               auditHandler,
+		PasswordPolicyHandler:      passwordPolicyHandler,
+		SessionHandler:             sessionHandler,
+		PersonalAccessTokenHandler: tokenHandler,
+		ServiceAccountHandler:      serviceAccountHandler,
+		ImpersonationHandler:       impersonationHandler,
+		RateLimitStore:             rateLimitStore,
+		RateLimitRules:             rateLimitRules,
 	}
 }
+
+// initKeyService uses the signing key from config unless JWT_KEY_STORE is
+// "postgres", in which case keys are generated, stored and rotated in the DB.
+func initKeyService(db *gorm.DB) (services.KeyService, error) {
+	if viper.GetString("JWT_KEY_STORE") != "postgres" {
+		return services.NewKeyService()
+	}
+
+	keyStore, err := services.NewKeyStoreService(repository.NewSigningKeyRepository(db))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := keyStore.EnsureKeys(); err != nil {
+		return nil, err
+	}
+
+	keyStore.StartRotation(utils.GetDuration("JWT_KEY_ROTATION_CHECK_INTERVAL", time.Hour))
+
+	return keyStore, nil
+}
@@ -0,0 +1,42 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// Checker tells whether a password is known to have been in a breach.
+type Checker interface {
+	Contains(password string) bool
+}
+
+// New loads the filter at BREACHED_PASSWORDS_FILE, built with the
+// "breached build" command. Without one no password is reported as
+// breached.
+func New() (Checker, error) {
+	path := viper.GetString("BREACHED_PASSWORDS_FILE")
+	if path == "" {
+		return disabled{}, nil
+	}
+
+	filter, err := Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load breached passwords from %s: %w", path, err)
+	}
+
+	return filter, nil
+}
+
+// Hash returns the SHA-1 digest of password, the form breach corpora such
+// as Have I Been Pwned publish passwords in.
+func Hash(password string) [sha1.Size]byte {
+	return sha1.Sum([]byte(password))
+}
+
+type disabled struct{}
+
+func (disabled) Contains(string) bool {
+	return false
+}
@@ -0,0 +1,97 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rangePrefixLen is the length of the hash prefix that names a Have I Been
+// Pwned range file.
+const rangePrefixLen = 5
+
+// Build reads Have I Been Pwned style SHA-1 hashes from path and returns a
+// filter holding all of them, sized for fpRate. path is either a file of
+// "HASH:COUNT" lines, like the downloadable corpus, or a directory of range
+// files named after their five character prefix, holding "SUFFIX:COUNT"
+// lines like the range API returns.
+func Build(path string, fpRate float64) (*Filter, error) {
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1, got %v", fpRate)
+	}
+
+	// the first pass only counts, so the filter can be sized up front
+	var n uint64
+	if err := eachHash(path, func([sha1.Size]byte) { n++ }); err != nil {
+		return nil, err
+	}
+
+	filter := NewFilter(n, fpRate)
+	if err := eachHash(path, filter.Add); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func eachHash(path string, fn func([sha1.Size]byte)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return eachHashInFile(path, "", fn)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		prefix := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if len(prefix) != rangePrefixLen {
+			return fmt.Errorf("%s: range files must be named after a %d character hash prefix", entry.Name(), rangePrefixLen)
+		}
+		if err := eachHashInFile(filepath.Join(path, entry.Name()), prefix, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func eachHashInFile(path, prefix string, fn func([sha1.Size]byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		suffix, _, _ := strings.Cut(text, ":")
+		hash := prefix + suffix
+
+		var digest [sha1.Size]byte
+		if len(hash) != hex.EncodedLen(sha1.Size) {
+			return fmt.Errorf("%s:%d: not a SHA-1 hash", path, line)
+		}
+		if _, err := hex.Decode(digest[:], []byte(hash)); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		fn(digest)
+	}
+
+	return scanner.Err()
+}
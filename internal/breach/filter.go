@@ -0,0 +1,148 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// filterMagic starts every filter file, followed by a format version.
+var filterMagic = [4]byte{'V', 'X', 'B', 'F'}
+
+const filterVersion = 1
+
+// Filter is a bloom filter of SHA-1 password hashes. It never misses a
+// hash that was added, and reports one that was not with roughly the false
+// positive rate it was sized for.
+type Filter struct {
+	bits   []uint64
+	m      uint64
+	k      uint32
+	hashes uint64
+}
+
+// NewFilter returns an empty filter sized to hold n hashes with the given
+// false positive rate.
+func NewFilter(n uint64, fpRate float64) *Filter {
+	if n == 0 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &Filter{bits: make([]uint64, m/64), m: m, k: k}
+}
+
+// Add puts a SHA-1 password hash in the filter.
+func (f *Filter) Add(digest [sha1.Size]byte) {
+	h1, h2 := split(digest)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.hashes++
+}
+
+// ContainsHash reports whether a SHA-1 password hash is probably in the
+// filter.
+func (f *Filter) ContainsHash(digest [sha1.Size]byte) bool {
+	h1, h2 := split(digest)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Filter) Contains(password string) bool {
+	return f.ContainsHash(Hash(password))
+}
+
+// Len returns how many hashes were added to the filter.
+func (f *Filter) Len() uint64 {
+	return f.hashes
+}
+
+// split derives the two hashes for double hashing from the digest, which
+// SHA-1 already spreads evenly. h2 is odd so it never repeats a bit early.
+func split(digest [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(digest[0:8]), binary.BigEndian.Uint64(digest[8:16]) | 1
+}
+
+// WriteTo writes the filter in the format Load reads.
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	header := struct {
+		Magic   [4]byte
+		Version uint32
+		M       uint64
+		K       uint32
+		Hashes  uint64
+	}{filterMagic, filterVersion, f.m, f.k, f.hashes}
+
+	if err := binary.Write(bw, binary.LittleEndian, header); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, f.bits); err != nil {
+		return 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+
+	return int64(binary.Size(header) + binary.Size(f.bits)), nil
+}
+
+// ReadFilter reads a filter written by WriteTo.
+func ReadFilter(r io.Reader) (*Filter, error) {
+	br := bufio.NewReader(r)
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		M       uint64
+		K       uint32
+		Hashes  uint64
+	}
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("invalid filter header: %w", err)
+	}
+	if header.Magic != filterMagic {
+		return nil, errors.New("not a breached password filter")
+	}
+	if header.Version != filterVersion {
+		return nil, fmt.Errorf("unsupported filter version %d", header.Version)
+	}
+	if header.M == 0 || header.M%64 != 0 || header.K == 0 {
+		return nil, errors.New("invalid filter size")
+	}
+
+	f := &Filter{bits: make([]uint64, header.M/64), m: header.M, k: header.K, hashes: header.Hashes}
+	if err := binary.Read(br, binary.LittleEndian, f.bits); err != nil {
+		return nil, fmt.Errorf("truncated filter: %w", err)
+	}
+
+	return f, nil
+}
+
+// Load reads a filter file written by WriteTo.
+func Load(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadFilter(file)
+}
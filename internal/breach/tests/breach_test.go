@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samvibes/vexop/auth-service/internal/breach"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var breachedPasswords = []string{"password", "123456", "qwerty", "letmein", "dragon"}
+
+func sha1Hex(password string) string {
+	digest := breach.Hash(password)
+	return strings.ToUpper(hex.EncodeToString(digest[:]))
+}
+
+func TestBuild_FromHashesFile(t *testing.T) {
+	var lines []string
+	for i, password := range breachedPasswords {
+		lines = append(lines, fmt.Sprintf("%s:%d", sha1Hex(password), i+1))
+	}
+	path := filepath.Join(t.TempDir(), "pwned-passwords.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600))
+
+	filter, err := breach.Build(path, 0.001)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, len(breachedPasswords), filter.Len())
+	for _, password := range breachedPasswords {
+		assert.True(t, filter.Contains(password), password)
+	}
+	assert.False(t, filter.Contains("correct horse battery staple"))
+}
+
+func TestBuild_FromRangeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for _, password := range breachedPasswords {
+		hash := sha1Hex(password)
+		file, err := os.OpenFile(filepath.Join(dir, hash[:5]+".txt"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = fmt.Fprintf(file, "%s:1\n", hash[5:])
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+
+	filter, err := breach.Build(dir, 0.001)
+	require.NoError(t, err)
+
+	for _, password := range breachedPasswords {
+		assert.True(t, filter.Contains(password), password)
+	}
+}
+
+func TestBuild_RejectsMalformedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	require.NoError(t, os.WriteFile(path, []byte(sha1Hex("password")+":1\nnot-a-hash:2\n"), 0o600))
+
+	_, err := breach.Build(path, 0.001)
+
+	assert.ErrorContains(t, err, "hashes.txt:2")
+}
+
+func TestFilter_FalsePositiveRate(t *testing.T) {
+	filter := breach.NewFilter(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		filter.Add(breach.Hash(fmt.Sprintf("breached-%d", i)))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.Contains(fmt.Sprintf("fresh-%d", i)) {
+			falsePositives++
+		}
+	}
+
+	assert.Less(t, falsePositives, 200)
+}
+
+func TestFilter_WriteAndLoad(t *testing.T) {
+	filter := breach.NewFilter(uint64(len(breachedPasswords)), 0.001)
+	for _, password := range breachedPasswords {
+		filter.Add(breach.Hash(password))
+	}
+
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	var buf bytes.Buffer
+	_, err := filter.WriteTo(&buf)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	loaded, err := breach.Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, filter.Len(), loaded.Len())
+	for _, password := range breachedPasswords {
+		assert.True(t, loaded.Contains(password), password)
+	}
+}
+
+func TestReadFilter_RejectsOtherFiles(t *testing.T) {
+	_, err := breach.ReadFilter(strings.NewReader("definitely not a filter"))
+
+	assert.Error(t, err)
+}
+
+func TestNew_DisabledWithoutFile(t *testing.T) {
+	viper.Set("BREACHED_PASSWORDS_FILE", "")
+
+	checker, err := breach.New()
+	require.NoError(t, err)
+
+	assert.False(t, checker.Contains("password"))
+}
+
+func TestNew_MissingFile(t *testing.T) {
+	viper.Set("BREACHED_PASSWORDS_FILE", filepath.Join(t.TempDir(), "missing.bloom"))
+	t.Cleanup(func() { viper.Set("BREACHED_PASSWORDS_FILE", "") })
+
+	_, err := breach.New()
+
+	assert.Error(t, err)
+}
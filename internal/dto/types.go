@@ -1,14 +1,16 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
 )
 
 type SignupRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"`
 	TenantID string `json:"tenant_id" binding:"omitempty,uuid"`
 }
 
@@ -18,7 +20,122 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	// only set when a login completed TOTP enrollment
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// the session started or continued; the client finds it in the sid claim
+	SessionID string `json:"-"`
+}
+
+// MFAChallengeResponse is returned by login instead of tokens when a second
+// factor is needed. EnrollmentRequired means the user has no authenticator
+// yet but their tenant requires one.
+type MFAChallengeResponse struct {
+	MFARequired        bool     `json:"mfa_required"`
+	MFAToken           string   `json:"mfa_token"`
+	Methods            []string `json:"methods"`
+	EnrollmentRequired bool     `json:"enrollment_required"`
+	ExpiresIn          int64    `json:"expires_in"`
+}
+
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type MFATokenRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TOTPEnrollment carries the secret to load into an authenticator app.
+// OTPAuthURI is what the QR code should encode.
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAPolicyRequest struct {
+	Required      bool     `json:"required"`
+	RequiredRoles []string `json:"required_roles"`
+}
+
+// WebAuthnOptions starts a WebAuthn ceremony. Options is passed to
+// navigator.credentials.create or get, and SessionID is sent back with the
+// authenticator's response.
+type WebAuthnOptions struct {
+	SessionID string `json:"session_id"`
+	Options   any    `json:"options"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// WebAuthnFinishRequest carries the PublicKeyCredential returned by the
+// browser. Name labels a newly registered credential.
+type WebAuthnFinishRequest struct {
+	SessionID  string          `json:"session_id" binding:"required"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+type WebAuthnMFARequest struct {
+	MFAToken   string          `json:"mfa_token" binding:"required"`
+	SessionID  string          `json:"session_id" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+type PasswordlessRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailOTPLoginRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required"`
+}
+
+type LoginMethodPolicyRequest struct {
+	MagicLinkEnabled bool `json:"magic_link_enabled"`
+	EmailOTPEnabled  bool `json:"email_otp_enabled"`
+}
+
+type EmailTemplateRequest struct {
+	Subject string `json:"subject" binding:"required"`
+	HTML    string `json:"html" binding:"required"`
+	Text    string `json:"text"`
+}
+
+// EmailTemplateResponse is the template a tenant's emails use. Custom is
+// false while the built-in one is in effect.
+type EmailTemplateResponse struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+	Custom  bool   `json:"custom"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type CreateInviteRequest struct {
@@ -42,6 +159,24 @@ type UpdateUserRoleRequest struct {
 	RoleName string `json:"role_name" binding:"required"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type EmailVerificationPolicyRequest struct {
+	Mode           string `json:"mode" binding:"required,oneof=off block restrict"`
+	RestrictedRole string `json:"restricted_role"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
 type ResetPasswordRequest struct {
 	Token    string `json:"token" binding:"required"`
 	Password string `json:"password" binding:"required"`
@@ -80,3 +215,175 @@ type RoleResponse struct {
 	Permissions []PermissionInfo `json:"permissions"`
 	IsDefault   bool             `json:"is_default"`
 }
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type TokenClaimConfigRequest struct {
+	TenantID           string `json:"tenant_id" binding:"omitempty,uuid"`
+	Audience           string `json:"audience"`
+	IncludeTenant      bool   `json:"include_tenant"`
+	IncludeRole        bool   `json:"include_role"`
+	IncludePermissions bool   `json:"include_permissions"`
+	IncludeEmail       bool   `json:"include_email"`
+}
+
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+type AuthorizeLoginRequest struct {
+	AuthorizeRequest
+	Email    string `form:"email"`
+	Password string `form:"password"`
+	MFACode  string `form:"mfa_code"`
+}
+
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// TokenActionRequest is the body of the introspection and revocation
+// endpoints.
+type TokenActionRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+type IntrospectionResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	TenantID  string   `json:"tenant_id,omitempty"`
+	Role      string   `json:"role,omitempty"`
+}
+
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type OAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	TenantID     string   `json:"tenant_id" binding:"omitempty,uuid"`
+	Public       bool     `json:"public"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	GrantTypes   []string `json:"grant_types"`
+}
+
+type OAuthClientResponse struct {
+	Client       *models.OAuthClient `json:"client"`
+	ClientSecret string              `json:"client_secret,omitempty"`
+}
+
+type PasswordPolicyRequest struct {
+	MinLength     int  `json:"min_length" binding:"required,min=1,max=72"`
+	MaxLength     int  `json:"max_length" binding:"required,min=1,max=72"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	DisallowEmail bool `json:"disallow_email"`
+	HistorySize   int  `json:"history_size" binding:"min=0,max=24"`
+	MaxAgeDays    int  `json:"max_age_days" binding:"min=0,max=3650"`
+	CheckBreached bool `json:"check_breached"`
+}
+
+// ImportUsersRequest carries users exported from another system, with their
+// password hashes in PHC string format or bcrypt's own format.
+type ImportUsersRequest struct {
+	Users []ImportUserRequest `json:"users" binding:"required,min=1,max=1000,dive"`
+}
+
+type ImportUserRequest struct {
+	Email         string `json:"email" binding:"required,email"`
+	PasswordHash  string `json:"password_hash" binding:"required"`
+	Role          string `json:"role" binding:"required"`
+	EmailVerified bool   `json:"email_verified"`
+	// PasswordChangedAt is when the password was last set in the other
+	// system. Passwords without it count as set at import.
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+}
+
+type ChangePasswordRequest struct {
+	Email           string `json:"email" binding:"required,email"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// PersonalAccessTokenRequest creates a personal access token with a subset
+// of the owner's permission codes. It never expires without ExpiresAt.
+type PersonalAccessTokenRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PersonalAccessTokenResponse holds the token itself, which is only ever
+// shown here.
+type PersonalAccessTokenResponse struct {
+	Token       *models.PersonalAccessToken `json:"token"`
+	AccessToken string                      `json:"access_token"`
+}
+
+// ServiceAccountRequest creates a service account acting with one of the
+// tenant's roles. Name is lowercase letters, digits and dashes.
+type ServiceAccountRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+	Role string `json:"role" binding:"required"`
+}
+
+type ImpersonationRequest struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+}
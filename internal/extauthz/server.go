@@ -0,0 +1,133 @@
+package extauthz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	HeaderUserID   = "x-user-id"
+	HeaderTenantID = "x-tenant-id"
+	HeaderRole     = "x-role"
+	// HeaderImpersonatorID names who is acting as the user on requests made
+	// with an impersonation token.
+	HeaderImpersonatorID = "x-impersonator-id"
+)
+
+// Server implements Envoy's envoy.service.auth.v3.Authorization API with the
+// same decisions as the forward auth endpoint.
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+	authorizer *middleware.RequestAuthorizer
+}
+
+func NewServer(authorizer *middleware.RequestAuthorizer) *Server {
+	return &Server{authorizer: authorizer}
+}
+
+// Register adds the Authorization service to a gRPC server.
+func (s *Server) Register(grpcServer *grpc.Server) {
+	authv3.RegisterAuthorizationServer(grpcServer, s)
+}
+
+// ListenAndServe serves the Authorization service on addr until it fails.
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	grpcServer := grpc.NewServer()
+	s.Register(grpcServer)
+
+	return grpcServer.Serve(listener)
+}
+
+// Check authorizes the HTTP request Envoy describes. Allowed requests get the
+// caller's identity headers set upstream, replacing any the client sent.
+func (s *Server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+	if httpReq == nil {
+		return denied(codes.InvalidArgument, typev3.StatusCode_BadRequest, "missing http request attributes"), nil
+	}
+
+	requestPath := ""
+	if parsed, err := url.Parse(httpReq.GetPath()); err == nil {
+		requestPath = parsed.Path
+	}
+
+	// envoy lowercases header names
+	user, claims, err := s.authorizer.Authorize(httpReq.GetHeaders()["authorization"], httpReq.GetMethod(), requestPath)
+	if err != nil {
+		var appErr *utils.AppError
+		if !errors.As(err, &appErr) {
+			return nil, err
+		}
+		if appErr.Code == http.StatusUnauthorized {
+			return denied(codes.Unauthenticated, typev3.StatusCode_Unauthorized, appErr.Message), nil
+		}
+		return denied(codes.PermissionDenied, typev3.StatusCode_Forbidden, appErr.Message), nil
+	}
+
+	ok := &authv3.OkHttpResponse{
+		Headers: []*corev3.HeaderValueOption{
+			overwriteHeader(HeaderUserID, user.ID.String()),
+			overwriteHeader(HeaderRole, user.Role.Name),
+		},
+	}
+	if user.TenantID != nil {
+		ok.Headers = append(ok.Headers, overwriteHeader(HeaderTenantID, user.TenantID.String()))
+	} else {
+		ok.HeadersToRemove = append(ok.HeadersToRemove, HeaderTenantID)
+	}
+	if claims.IsImpersonation() {
+		ok.Headers = append(ok.Headers, overwriteHeader(HeaderImpersonatorID, claims.Act.Subject))
+	} else {
+		ok.HeadersToRemove = append(ok.HeadersToRemove, HeaderImpersonatorID)
+	}
+
+	return &authv3.CheckResponse{
+		Status:       &status.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: ok},
+	}, nil
+}
+
+func denied(code codes.Code, httpStatus typev3.StatusCode, message string) *authv3.CheckResponse {
+	body, _ := json.Marshal(map[string]string{"error": message})
+
+	response := &authv3.DeniedHttpResponse{
+		Status: &typev3.HttpStatus{Code: httpStatus},
+		Headers: []*corev3.HeaderValueOption{
+			overwriteHeader("content-type", "application/json"),
+		},
+		Body: string(body),
+	}
+	if httpStatus == typev3.StatusCode_Unauthorized {
+		response.Headers = append(response.Headers, overwriteHeader("www-authenticate", "Bearer"))
+	}
+
+	return &authv3.CheckResponse{
+		Status:       &status.Status{Code: int32(code), Message: message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: response},
+	}
+}
+
+func overwriteHeader(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header:       &corev3.HeaderValue{Key: key, Value: value},
+		AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	}
+}
@@ -0,0 +1,165 @@
+package extauthz
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/extauthz"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	serviceMock "github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var rules = middleware.ResourceRules{
+	{PathPrefix: "/billing/invoices", Resource: "invoice"},
+}
+
+// newClient serves the Authorization service over an in-memory listener. It
+// runs stateless so no DB is needed.
+func newClient(t *testing.T, mockAuthService *serviceMock.MockAuthService) authv3.AuthorizationClient {
+	viper.Set("JWT_STATELESS", true)
+	t.Cleanup(func() { viper.Set("JWT_STATELESS", false) })
+
+	authorizer := middleware.NewRequestAuthorizer(middleware.NewAuthenticator(nil, mockAuthService, nil), rules, nil)
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	extauthz.NewServer(authorizer).Register(grpcServer)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return authv3.NewAuthorizationClient(conn)
+}
+
+func checkRequest(method, path, authorization string) *authv3.CheckRequest {
+	headers := map[string]string{}
+	if authorization != "" {
+		headers["authorization"] = authorization
+	}
+
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{Method: method, Path: path, Headers: headers},
+			},
+		},
+	}
+}
+
+func TestCheck_AllowsAndInjectsIdentity(t *testing.T) {
+	tenantID := uuid.New()
+	claims := &utils.Claims{
+		UserID:      uuid.NewString(),
+		TenantID:    tenantID.String(),
+		Role:        "member",
+		Permissions: []string{"invoice:read"},
+	}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+	client := newClient(t, mockAuthService)
+
+	response, err := client.Check(context.Background(), checkRequest(http.MethodGet, "/billing/invoices/42?expand=lines", "Bearer token"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(codes.OK), response.GetStatus().GetCode())
+	headers := map[string]string{}
+	for _, option := range response.GetOkResponse().GetHeaders() {
+		headers[option.GetHeader().GetKey()] = option.GetHeader().GetValue()
+	}
+	assert.Equal(t, claims.UserID, headers[extauthz.HeaderUserID])
+	assert.Equal(t, tenantID.String(), headers[extauthz.HeaderTenantID])
+	assert.Equal(t, "member", headers[extauthz.HeaderRole])
+	// a client must not pose as an impersonator
+	assert.Contains(t, response.GetOkResponse().GetHeadersToRemove(), extauthz.HeaderImpersonatorID)
+}
+
+func TestCheck_InjectsImpersonator(t *testing.T) {
+	claims := &utils.Claims{
+		UserID:      uuid.NewString(),
+		Role:        "member",
+		Permissions: []string{"invoice:read"},
+		Act:         &utils.ActorClaim{Subject: uuid.NewString()},
+	}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+	client := newClient(t, mockAuthService)
+
+	response, err := client.Check(context.Background(), checkRequest(http.MethodGet, "/billing/invoices", "Bearer token"))
+	require.NoError(t, err)
+
+	headers := map[string]string{}
+	for _, option := range response.GetOkResponse().GetHeaders() {
+		headers[option.GetHeader().GetKey()] = option.GetHeader().GetValue()
+	}
+	assert.Equal(t, claims.UserID, headers[extauthz.HeaderUserID])
+	assert.Equal(t, claims.Act.Subject, headers[extauthz.HeaderImpersonatorID])
+}
+
+func TestCheck_DeniesMissingPermission(t *testing.T) {
+	claims := &utils.Claims{UserID: uuid.NewString(), Role: "member", Permissions: []string{"invoice:read"}}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+	client := newClient(t, mockAuthService)
+
+	response, err := client.Check(context.Background(), checkRequest(http.MethodDelete, "/billing/invoices/42", "Bearer token"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(codes.PermissionDenied), response.GetStatus().GetCode())
+	assert.Equal(t, typev3.StatusCode_Forbidden, response.GetDeniedResponse().GetStatus().GetCode())
+}
+
+func TestCheck_DeniesUnmappedPath(t *testing.T) {
+	claims := &utils.Claims{UserID: uuid.NewString(), Role: "member", Permissions: []string{"invoice:read"}}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+	client := newClient(t, mockAuthService)
+
+	response, err := client.Check(context.Background(), checkRequest(http.MethodGet, "/admin", "Bearer token"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(codes.PermissionDenied), response.GetStatus().GetCode())
+}
+
+func TestCheck_RevokedToken_Unauthenticated(t *testing.T) {
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(nil, services.ErrTokenRevoked)
+	client := newClient(t, mockAuthService)
+
+	response, err := client.Check(context.Background(), checkRequest(http.MethodGet, "/billing/invoices", "Bearer token"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(codes.Unauthenticated), response.GetStatus().GetCode())
+	assert.Equal(t, typev3.StatusCode_Unauthorized, response.GetDeniedResponse().GetStatus().GetCode())
+	assert.Contains(t, response.GetDeniedResponse().GetBody(), "token revoked")
+}
+
+func TestCheck_MissingToken_Unauthenticated(t *testing.T) {
+	client := newClient(t, new(serviceMock.MockAuthService))
+
+	response, err := client.Check(context.Background(), checkRequest(http.MethodGet, "/billing/invoices", ""))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(codes.Unauthenticated), response.GetStatus().GetCode())
+}
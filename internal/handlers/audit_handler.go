@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type AuditHandler interface {
+	GetEvents(*gin.Context)
+}
+
+type AuditHandlerImpl struct {
+	auditService services.AuditService
+}
+
+func NewAuditHandler(auditService services.AuditService) AuditHandler {
+	return &AuditHandlerImpl{auditService: auditService}
+}
+
+func (h *AuditHandlerImpl) GetEvents(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+	page, limit := utils.GetPageAndLimit(c)
+
+	events, err := h.auditService.GetEvents(requestor, page, limit)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get audit events")
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/samvibes/vexop/auth-service/internal/dto"
 	"github.com/samvibes/vexop/auth-service/internal/models"
 	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -15,6 +21,12 @@ type AuthHandler interface {
 	Health(*gin.Context)
 	SignUp(*gin.Context)
 	Login(*gin.Context)
+	Refresh(*gin.Context)
+	Logout(*gin.Context)
+	LogoutAll(*gin.Context)
+	ForgotPassword(*gin.Context)
+	ResetPassword(*gin.Context)
+	ChangePassword(*gin.Context)
 }
 
 type AuthHandlerImpl struct {
@@ -40,6 +52,12 @@ func (h *AuthHandlerImpl) SignUp(c *gin.Context) {
 		return
 	}
 
+	// a new tenant has no password policy yet, so the defaults apply
+	if err := h.userService.ValidatePassword(&models.User{Email: req.Email}, req.Password); err != nil {
+		passwordPolicyResponse(c, err, "failed to create user")
+		return
+	}
+
 	hashedPassword, err := h.authService.HashPassword(req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -75,11 +93,161 @@ func (h *AuthHandlerImpl) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.userService.Login(req.Email, req.Password)
+	tokens, challenge, err := h.userService.Login(req.Email, req.Password, c.ClientIP())
 	if err != nil {
+		if lockedResponse(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrEmailNotVerified) || errors.Is(err, services.ErrPasswordExpired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	// a second factor is needed before tokens are issued
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	tokensResponse(c, tokens)
+}
+
+func (h *AuthHandlerImpl) Refresh(c *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.authService.RefreshTokens(req.RefreshToken, "")
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		return
+	}
+
+	tokensResponse(c, tokens)
+}
+
+func (h *AuthHandlerImpl) Logout(c *gin.Context) {
+	var req dto.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims := utils.GetCurrentClaims(c)
+	if err := h.authService.Logout(claims, req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+func (h *AuthHandlerImpl) LogoutAll(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	if err := h.authService.RevokeUserTokens(user.ID.String()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out all sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out of all sessions"})
+}
+
+// ForgotPassword answers the same whether or not the address has an
+// account, so it cannot be used to find out which ones do.
+func (h *AuthHandlerImpl) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.InitResetPassword(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed while initiating reset password"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the address has an account, a reset link has been sent"})
+}
+
+func (h *AuthHandlerImpl) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.ResetPassword(req.Token, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		passwordPolicyResponse(c, err, "failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password updated successfully"})
+}
+
+// ChangePassword takes the current password rather than an access token, so
+// users whose password has expired can still change it.
+func (h *AuthHandlerImpl) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.ChangePassword(req.Email, req.CurrentPassword, req.NewPassword, c.ClientIP()); err != nil {
+		if lockedResponse(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		passwordPolicyResponse(c, err, "failed to change password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
+}
+
+// passwordPolicyResponse answers 422 with every broken rule when err is a
+// password policy violation, and 500 with msg otherwise.
+func passwordPolicyResponse(c *gin.Context, err error, msg string) {
+	var policyErr *services.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": policyErr.Error(), "violations": policyErr.Violations})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
+
+// lockedResponse answers 429 with a Retry-After header when err is a login
+// lockout and reports whether it did.
+func lockedResponse(c *gin.Context, err error) bool {
+	var locked *services.LoginLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	setRetryAfter(c, locked.RetryAfter)
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": locked.Error()})
+	return true
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounded up.
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
 }
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type EmailTemplateHandler interface {
+	GetTemplates(*gin.Context)
+	GetTemplate(*gin.Context)
+	SaveTemplate(*gin.Context)
+	DeleteTemplate(*gin.Context)
+}
+
+type EmailTemplateHandlerImpl struct {
+	mailService services.MailService
+}
+
+func NewEmailTemplateHandler(mailService services.MailService) EmailTemplateHandler {
+	return &EmailTemplateHandlerImpl{mailService: mailService}
+}
+
+func (h *EmailTemplateHandlerImpl) GetTemplates(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	templates, err := h.mailService.GetTemplates(requestor)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get email templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *EmailTemplateHandlerImpl) GetTemplate(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	template, err := h.mailService.GetTemplate(requestor, c.Param("name"))
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get email template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *EmailTemplateHandlerImpl) SaveTemplate(c *gin.Context) {
+	var req dto.EmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	template, err := h.mailService.SaveTemplate(requestor, c.Param("name"), &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not save email template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *EmailTemplateHandlerImpl) DeleteTemplate(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	if err := h.mailService.DeleteTemplate(requestor, c.Param("name")); err != nil {
+		serviceErrorResponse(c, err, "could not delete email template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email template reset to default"})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type EmailVerificationHandler interface {
+	VerifyEmail(*gin.Context)
+	ResendVerification(*gin.Context)
+	ChangeEmail(*gin.Context)
+	GetPolicy(*gin.Context)
+	SavePolicy(*gin.Context)
+}
+
+type EmailVerificationHandlerImpl struct {
+	emailVerificationService services.EmailVerificationService
+}
+
+func NewEmailVerificationHandler(emailVerificationService services.EmailVerificationService) EmailVerificationHandler {
+	return &EmailVerificationHandlerImpl{emailVerificationService: emailVerificationService}
+}
+
+func (h *EmailVerificationHandlerImpl) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.emailVerificationService.VerifyEmail(req.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		serviceErrorResponse(c, err, "could not verify email address")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email address verified"})
+}
+
+// ResendVerification answers the same whether or not a link was sent, so it
+// does not reveal which addresses have accounts.
+func (h *EmailVerificationHandlerImpl) ResendVerification(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.emailVerificationService.ResendVerification(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not send verification link"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the address needs verifying, a link has been sent"})
+}
+
+func (h *EmailVerificationHandlerImpl) ChangeEmail(c *gin.Context) {
+	var req dto.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := utils.GetCurrentUser(c)
+
+	if err := h.emailVerificationService.RequestEmailChange(user, req.Email, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		serviceErrorResponse(c, err, "could not change email address")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "a verification link has been sent to the new address"})
+}
+
+func (h *EmailVerificationHandlerImpl) GetPolicy(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	policy, err := h.emailVerificationService.GetPolicy(requestor)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get email verification policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *EmailVerificationHandlerImpl) SavePolicy(c *gin.Context) {
+	var req dto.EmailVerificationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	policy, err := h.emailVerificationService.SavePolicy(requestor, &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not save email verification policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+// ForwardAuthHandler lets a reverse proxy (nginx auth_request, Traefik
+// ForwardAuth, ...) ask whether a request for another service may go through.
+type ForwardAuthHandler interface {
+	Verify(*gin.Context)
+}
+
+type ForwardAuthHandlerImpl struct {
+	authorizer *middleware.RequestAuthorizer
+}
+
+func NewForwardAuthHandler(authorizer *middleware.RequestAuthorizer) ForwardAuthHandler {
+	return &ForwardAuthHandlerImpl{authorizer: authorizer}
+}
+
+// Verify authorizes the original request. On success the caller's identity is
+// returned in X-User-Id, X-Tenant-Id and X-Role, and in X-Impersonator-Id who
+// is acting as them when the token is an impersonation token.
+func (h *ForwardAuthHandlerImpl) Verify(c *gin.Context) {
+	method, path := originalRequest(c)
+	if method == "" || path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing original method or uri"})
+		return
+	}
+
+	user, claims, err := h.authorizer.Authorize(c.GetHeader("Authorization"), method, path)
+	if err != nil {
+		var appErr *utils.AppError
+		if !errors.As(err, &appErr) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if appErr.Code == http.StatusUnauthorized {
+			c.Header("WWW-Authenticate", "Bearer")
+		}
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+		return
+	}
+
+	c.Header("X-User-Id", user.ID.String())
+	if user.TenantID != nil {
+		c.Header("X-Tenant-Id", user.TenantID.String())
+	}
+	c.Header("X-Role", user.Role.Name)
+	if claims.IsImpersonation() {
+		c.Header("X-Impersonator-Id", claims.Act.Subject)
+	}
+	c.Status(http.StatusOK)
+}
+
+// originalRequest reads the proxied request's method and path from the
+// headers nginx (X-Original-*) or Traefik and others (X-Forwarded-*) send.
+func originalRequest(c *gin.Context) (string, string) {
+	method := c.GetHeader("X-Original-Method")
+	if method == "" {
+		method = c.GetHeader("X-Forwarded-Method")
+	}
+
+	uri := c.GetHeader("X-Original-URI")
+	if uri == "" {
+		uri = c.GetHeader("X-Forwarded-Uri")
+	}
+	if uri == "" {
+		uri = c.GetHeader("X-Original-URL")
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return method, ""
+	}
+
+	return method, parsed.Path
+}
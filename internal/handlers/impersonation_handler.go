@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type ImpersonationHandler interface {
+	StartImpersonation(c *gin.Context)
+	StopImpersonation(c *gin.Context)
+}
+
+type ImpersonationHandlerImpl struct {
+	impersonationService services.ImpersonationService
+}
+
+func NewImpersonationHandler(impersonationService services.ImpersonationService) ImpersonationHandler {
+	return &ImpersonationHandlerImpl{impersonationService: impersonationService}
+}
+
+// StartImpersonation issues the caller a token to act as another user.
+func (h *ImpersonationHandlerImpl) StartImpersonation(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	var req dto.ImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.impersonationService.StartImpersonation(requestor, req.UserID)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not start impersonation")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, tokens)
+}
+
+// StopImpersonation ends the impersonation the caller's token is from.
+func (h *ImpersonationHandlerImpl) StopImpersonation(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	if err := h.impersonationService.StopImpersonation(user, utils.GetCurrentClaims(c)); err != nil {
+		serviceErrorResponse(c, err, "could not stop impersonation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "impersonation stopped"})
+}
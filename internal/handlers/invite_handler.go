@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -37,7 +38,7 @@ func (i *InviteHandlerImpl) CreateInvite(c *gin.Context) {
 	}
 
 	requestor := utils.GetCurrentUser(c)
-	token, inviteId, err := i.inviteService.CreateInvite(requestor, createInviteReq.Email, createInviteReq.Role)
+	_, err := i.inviteService.CreateInvite(requestor, createInviteReq.Email, createInviteReq.Role)
 	if err != nil {
 		if appError, ok := err.(*utils.AppError); ok {
 			c.JSON(appError.Code, gin.H{"error": appError.Message})
@@ -47,9 +48,6 @@ func (i *InviteHandlerImpl) CreateInvite(c *gin.Context) {
 		return
 	}
 
-	// TODO: send token via email
-	fmt.Println("To send email with token and id ", token, inviteId)
-
 	c.JSON(http.StatusCreated, gin.H{"message": "invite sent"})
 }
 
@@ -94,6 +92,10 @@ func (i *InviteHandlerImpl) AcceptInvite(c *gin.Context) {
 
 	err := i.inviteService.AcceptInvite(acceptInviteReq, i.db)
 	if err != nil {
+		if errors.Is(err, services.ErrWeakPassword) {
+			passwordPolicyResponse(c, err, "failed to accept invite")
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -104,9 +106,14 @@ func (i *InviteHandlerImpl) AcceptInvite(c *gin.Context) {
 func (i *InviteHandlerImpl) ResendInvitation(c *gin.Context) {
 	inviteId := c.Query("invite_id")
 
-	invitation, err := i.inviteService.GetInviteById(inviteId)
+	requestor := utils.GetCurrentUser(c)
+	invitation, err := i.inviteService.ResendInvite(requestor, inviteId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, "could not fetch invitation")
+		if appError, ok := err.(*utils.AppError); ok {
+			c.JSON(appError.Code, gin.H{"error": appError.Message})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not resend invitation"})
 		return
 	}
 
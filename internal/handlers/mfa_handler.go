@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type MFAHandler interface {
+	EnrollTOTP(*gin.Context)
+	ActivateTOTP(*gin.Context)
+	DisableTOTP(*gin.Context)
+	RegenerateRecoveryCodes(*gin.Context)
+	LoginEnrollTOTP(*gin.Context)
+	LoginVerify(*gin.Context)
+	GetPolicy(*gin.Context)
+	SavePolicy(*gin.Context)
+}
+
+type MFAHandlerImpl struct {
+	mfaService services.MFAService
+}
+
+func NewMFAHandler(mfaService services.MFAService) MFAHandler {
+	return &MFAHandlerImpl{mfaService: mfaService}
+}
+
+func (h *MFAHandlerImpl) EnrollTOTP(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	enrollment, err := h.mfaService.EnrollTOTP(user)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not start totp enrollment")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *MFAHandlerImpl) ActivateTOTP(c *gin.Context) {
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := utils.GetCurrentUser(c)
+
+	codes, err := h.mfaService.ActivateTOTP(user, req.Code)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not enable totp")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *MFAHandlerImpl) DisableTOTP(c *gin.Context) {
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := utils.GetCurrentUser(c)
+
+	if err := h.mfaService.DisableTOTP(user, req.Code); err != nil {
+		serviceErrorResponse(c, err, "could not disable totp")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "totp disabled"})
+}
+
+func (h *MFAHandlerImpl) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := utils.GetCurrentUser(c)
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(user, req.Code)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not create recovery codes")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// LoginEnrollTOTP sets up an authenticator for a user signing in whose tenant
+// requires MFA but who has none yet.
+func (h *MFAHandlerImpl) LoginEnrollTOTP(c *gin.Context) {
+	var req dto.MFATokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.mfaService.EnrollChallenge(req.MFAToken)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not start totp enrollment")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// LoginVerify is the second step of login: it exchanges the mfa token from
+// the first step plus a TOTP or recovery code for tokens.
+func (h *MFAHandlerImpl) LoginVerify(c *gin.Context) {
+	var req dto.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.mfaService.CompleteLogin(&req, c.ClientIP())
+	if err != nil {
+		serviceErrorResponse(c, err, "could not complete login")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	tokensResponse(c, tokens)
+}
+
+func (h *MFAHandlerImpl) GetPolicy(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	policy, err := h.mfaService.GetPolicy(requestor)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get mfa policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *MFAHandlerImpl) SavePolicy(c *gin.Context) {
+	var req dto.MFAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	policy, err := h.mfaService.SavePolicy(requestor, &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not save mfa policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type OAuthClientHandler interface {
+	GetClients(*gin.Context)
+	CreateClient(*gin.Context)
+	UpdateClient(*gin.Context)
+	DeleteClient(*gin.Context)
+	RotateClientSecret(*gin.Context)
+}
+
+type OAuthClientHandlerImpl struct {
+	service services.OAuthClientService
+}
+
+func NewOAuthClientHandler(service services.OAuthClientService) OAuthClientHandler {
+	return &OAuthClientHandlerImpl{service: service}
+}
+
+func (h *OAuthClientHandlerImpl) GetClients(c *gin.Context) {
+	page, limit := utils.GetPageAndLimit(c)
+
+	requestor := utils.GetCurrentUser(c)
+
+	clients, err := h.service.GetClients(requestor, page, limit)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get clients")
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+func (h *OAuthClientHandlerImpl) CreateClient(c *gin.Context) {
+	var req dto.OAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	client, err := h.service.CreateClient(requestor, &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not create client")
+		return
+	}
+
+	c.JSON(http.StatusCreated, client)
+}
+
+func (h *OAuthClientHandlerImpl) UpdateClient(c *gin.Context) {
+	var req dto.OAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	client, err := h.service.UpdateClient(requestor, c.Param("client_id"), &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not update client")
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+func (h *OAuthClientHandlerImpl) DeleteClient(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	if err := h.service.DeleteClient(requestor, c.Param("client_id")); err != nil {
+		serviceErrorResponse(c, err, "could not delete client")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "client deleted successfully"})
+}
+
+func (h *OAuthClientHandlerImpl) RotateClientSecret(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	client, err := h.service.RotateClientSecret(requestor, c.Param("client_id"))
+	if err != nil {
+		serviceErrorResponse(c, err, "could not rotate client secret")
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
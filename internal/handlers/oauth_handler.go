@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+var authorizeTemplate = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sign in</title></head>
+<body>
+<h1>Sign in to {{.ClientName}}</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth/authorize">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<label>Email <input type="email" name="email" value="{{.Email}}" required autofocus></label>
+<label>Password <input type="password" name="password" required></label>
+{{if .AskMFACode}}<label>Authentication code <input type="text" name="mfa_code" inputmode="numeric" autocomplete="one-time-code" required></label>{{end}}
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+type authorizePage struct {
+	ClientName string
+	Request    *dto.AuthorizeRequest
+	Error      string
+	Email      string
+	AskMFACode bool
+}
+
+type OAuthHandler interface {
+	Discovery(*gin.Context)
+	Authorize(*gin.Context)
+	AuthorizeLogin(*gin.Context)
+	Token(*gin.Context)
+	UserInfo(*gin.Context)
+	Introspect(*gin.Context)
+	Revoke(*gin.Context)
+}
+
+type OAuthHandlerImpl struct {
+	oidcService services.OIDCService
+	authService services.AuthService
+	userService services.UserService
+	mfaService  services.MFAService
+}
+
+func NewOAuthHandler(oidcService services.OIDCService, authService services.AuthService, userService services.UserService, mfaService services.MFAService) OAuthHandler {
+	return &OAuthHandlerImpl{oidcService: oidcService, authService: authService, userService: userService, mfaService: mfaService}
+}
+
+func (h *OAuthHandlerImpl) Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.oidcService.Discovery())
+}
+
+// Authorize validates the authorization request and shows the sign-in form.
+func (h *OAuthHandlerImpl) Authorize(c *gin.Context) {
+	var req dto.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.String(http.StatusBadRequest, "invalid authorization request")
+		return
+	}
+
+	client, err := h.oidcService.ValidateAuthorizeRequest(&req)
+	if err != nil {
+		h.authorizeError(c, client != nil, &req, err)
+		return
+	}
+
+	renderAuthorizePage(c, http.StatusOK, authorizePage{ClientName: client.Name, Request: &req})
+}
+
+// AuthorizeLogin signs the user in and redirects back to the client with an
+// authorization code.
+func (h *OAuthHandlerImpl) AuthorizeLogin(c *gin.Context) {
+	var req dto.AuthorizeLoginRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.String(http.StatusBadRequest, "invalid authorization request")
+		return
+	}
+
+	client, err := h.oidcService.ValidateAuthorizeRequest(&req.AuthorizeRequest)
+	if err != nil {
+		h.authorizeError(c, client != nil, &req.AuthorizeRequest, err)
+		return
+	}
+
+	user, err := h.userService.Authenticate(req.Email, req.Password, c.ClientIP())
+	if err != nil {
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			setRetryAfter(c, locked.RetryAfter)
+			renderAuthorizePage(c, http.StatusTooManyRequests, authorizePage{
+				ClientName: client.Name,
+				Request:    &req.AuthorizeRequest,
+				Error:      err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			renderAuthorizePage(c, http.StatusUnauthorized, authorizePage{
+				ClientName: client.Name,
+				Request:    &req.AuthorizeRequest,
+				Error:      err.Error(),
+			})
+			return
+		}
+		h.authorizeError(c, true, &req.AuthorizeRequest, err)
+		return
+	}
+
+	if err := h.userService.CheckPasswordExpiry(user); err != nil {
+		if errors.Is(err, services.ErrPasswordExpired) {
+			renderAuthorizePage(c, http.StatusForbidden, authorizePage{
+				ClientName: client.Name,
+				Request:    &req.AuthorizeRequest,
+				Error:      err.Error(),
+			})
+			return
+		}
+		h.authorizeError(c, true, &req.AuthorizeRequest, err)
+		return
+	}
+
+	if err := h.userService.CheckEmailVerification(user); err != nil {
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			renderAuthorizePage(c, http.StatusForbidden, authorizePage{
+				ClientName: client.Name,
+				Request:    &req.AuthorizeRequest,
+				Error:      err.Error(),
+			})
+			return
+		}
+		h.authorizeError(c, true, &req.AuthorizeRequest, err)
+		return
+	}
+
+	// the form asks for the second factor along with the password
+	if err := h.mfaService.VerifyLoginCode(user, req.MFACode, c.ClientIP()); err != nil {
+		page := authorizePage{
+			ClientName: client.Name,
+			Request:    &req.AuthorizeRequest,
+			Email:      req.Email,
+			AskMFACode: true,
+		}
+		var appErr *utils.AppError
+		switch {
+		case errors.Is(err, services.ErrMFARequired):
+			page.Error = "enter the code from your authenticator app or a recovery code"
+		case errors.Is(err, services.ErrInvalidMFACode):
+			page.Error = err.Error()
+		case errors.As(err, &appErr):
+			page.AskMFACode = false
+			page.Error = appErr.Message
+		default:
+			h.authorizeError(c, true, &req.AuthorizeRequest, err)
+			return
+		}
+		renderAuthorizePage(c, http.StatusUnauthorized, page)
+		return
+	}
+
+	code, err := h.oidcService.Authorize(&req.AuthorizeRequest, user)
+	if err != nil {
+		h.authorizeError(c, true, &req.AuthorizeRequest, err)
+		return
+	}
+
+	redirectToClient(c, req.RedirectURI, url.Values{"code": {code}, "state": {req.State}})
+}
+
+// Token is the OAuth token endpoint. Clients authenticate with HTTP basic auth
+// or client_id and client_secret form fields.
+func (h *OAuthHandlerImpl) Token(c *gin.Context) {
+	var req dto.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	readClientCredentials(c, &req.ClientID, &req.ClientSecret)
+
+	tokens, err := h.oidcService.Token(&req)
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *OAuthHandlerImpl) UserInfo(c *gin.Context) {
+	tokenStr, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		c.Header("WWW-Authenticate", "Bearer")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	claims, err := h.authService.ValidateAccessToken(tokenStr)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	info, err := h.oidcService.UserInfo(claims)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+			return
+		}
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// Introspect is the RFC 7662 introspection endpoint.
+func (h *OAuthHandlerImpl) Introspect(c *gin.Context) {
+	var req dto.TokenActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	readClientCredentials(c, &req.ClientID, &req.ClientSecret)
+
+	response, err := h.oidcService.Introspect(&req)
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke is the RFC 7009 revocation endpoint.
+func (h *OAuthHandlerImpl) Revoke(c *gin.Context) {
+	var req dto.TokenActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	readClientCredentials(c, &req.ClientID, &req.ClientSecret)
+
+	if err := h.oidcService.Revoke(&req); err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// readClientCredentials takes the client credentials from HTTP basic auth when
+// present, falling back to the client_id and client_secret form fields.
+func readClientCredentials(c *gin.Context, clientID, clientSecret *string) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		*clientID, _ = url.QueryUnescape(id)
+		*clientSecret, _ = url.QueryUnescape(secret)
+	}
+}
+
+// authorizeError reports a failed authorization request. Errors are only sent
+// to the redirect URI once it is known to belong to the client.
+func (h *OAuthHandlerImpl) authorizeError(c *gin.Context, canRedirect bool, req *dto.AuthorizeRequest, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &services.OAuthError{Code: "server_error", Description: "the request could not be processed"}
+	}
+
+	if !canRedirect {
+		c.String(http.StatusBadRequest, oauthErr.Description)
+		return
+	}
+
+	redirectToClient(c, req.RedirectURI, url.Values{
+		"error":             {oauthErr.Code},
+		"error_description": {oauthErr.Description},
+		"state":             {req.State},
+	})
+}
+
+func redirectToClient(c *gin.Context, redirectURI string, params url.Values) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid redirect_uri")
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+	target.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusFound, target.String())
+}
+
+func renderAuthorizePage(c *gin.Context, status int, page authorizePage) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := authorizeTemplate.Execute(c.Writer, page); err != nil {
+		c.Error(err)
+	}
+}
+
+func oauthErrorResponse(c *gin.Context, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch oauthErr.Code {
+	case "invalid_client":
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", "Basic")
+	case "insufficient_scope":
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type PasswordPolicyHandler interface {
+	GetPolicy(*gin.Context)
+	SavePolicy(*gin.Context)
+}
+
+type PasswordPolicyHandlerImpl struct {
+	passwordPolicyService services.PasswordPolicyService
+}
+
+func NewPasswordPolicyHandler(passwordPolicyService services.PasswordPolicyService) PasswordPolicyHandler {
+	return &PasswordPolicyHandlerImpl{passwordPolicyService: passwordPolicyService}
+}
+
+func (h *PasswordPolicyHandlerImpl) GetPolicy(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	policy, err := h.passwordPolicyService.GetPolicy(requestor)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get password policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *PasswordPolicyHandlerImpl) SavePolicy(c *gin.Context) {
+	var req dto.PasswordPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	policy, err := h.passwordPolicyService.SavePolicy(requestor, &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not save password policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type PasswordlessHandler interface {
+	RequestMagicLink(*gin.Context)
+	MagicLinkLogin(*gin.Context)
+	RequestEmailOTP(*gin.Context)
+	EmailOTPLogin(*gin.Context)
+	GetPolicy(*gin.Context)
+	SavePolicy(*gin.Context)
+}
+
+type PasswordlessHandlerImpl struct {
+	passwordlessService services.PasswordlessService
+}
+
+func NewPasswordlessHandler(passwordlessService services.PasswordlessService) PasswordlessHandler {
+	return &PasswordlessHandlerImpl{passwordlessService: passwordlessService}
+}
+
+// RequestMagicLink answers the same whether or not a link was sent, so it
+// does not reveal which addresses have accounts.
+func (h *PasswordlessHandlerImpl) RequestMagicLink(c *gin.Context) {
+	var req dto.PasswordlessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordlessService.RequestMagicLink(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not send login link"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the address can sign in with a link, one has been sent"})
+}
+
+func (h *PasswordlessHandlerImpl) MagicLinkLogin(c *gin.Context) {
+	var req dto.MagicLinkLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, challenge, err := h.passwordlessService.LoginWithMagicLink(req.Token)
+	passwordlessLoginResponse(c, tokens, challenge, err)
+}
+
+// RequestEmailOTP answers the same whether or not a code was sent.
+func (h *PasswordlessHandlerImpl) RequestEmailOTP(c *gin.Context) {
+	var req dto.PasswordlessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordlessService.RequestEmailOTP(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not send login code"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the address can sign in with a code, one has been sent"})
+}
+
+func (h *PasswordlessHandlerImpl) EmailOTPLogin(c *gin.Context) {
+	var req dto.EmailOTPLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, challenge, err := h.passwordlessService.LoginWithEmailOTP(req.Email, req.Code, c.ClientIP())
+	passwordlessLoginResponse(c, tokens, challenge, err)
+}
+
+func (h *PasswordlessHandlerImpl) GetPolicy(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	policy, err := h.passwordlessService.GetPolicy(requestor)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get login method policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *PasswordlessHandlerImpl) SavePolicy(c *gin.Context) {
+	var req dto.LoginMethodPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	policy, err := h.passwordlessService.SavePolicy(requestor, &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not save login method policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func passwordlessLoginResponse(c *gin.Context, tokens *dto.LoginResponse, challenge *dto.MFAChallengeResponse, err error) {
+	if err != nil {
+		if lockedResponse(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidLoginToken) || errors.Is(err, services.ErrInvalidLoginCode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not complete login"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	// a second factor is needed before tokens are issued
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	tokensResponse(c, tokens)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type PersonalAccessTokenHandler interface {
+	GetTokens(c *gin.Context)
+	CreateToken(c *gin.Context)
+	RevokeToken(c *gin.Context)
+	GetUserTokens(c *gin.Context)
+	RevokeUserToken(c *gin.Context)
+}
+
+type PersonalAccessTokenHandlerImpl struct {
+	tokenService services.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler(tokenService services.PersonalAccessTokenService) PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandlerImpl{tokenService: tokenService}
+}
+
+// GetTokens lists the caller's own personal access tokens.
+func (h *PersonalAccessTokenHandlerImpl) GetTokens(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	tokens, err := h.tokenService.GetTokens(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken issues a personal access token to the caller. The response is
+// the only time the token is shown.
+func (h *PersonalAccessTokenHandlerImpl) CreateToken(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	var req dto.PersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.tokenService.CreateToken(user, &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not create token")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, token)
+}
+
+// RevokeToken revokes one of the caller's own tokens.
+func (h *PersonalAccessTokenHandlerImpl) RevokeToken(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	if err := h.tokenService.RevokeToken(user, c.Param("id")); err != nil {
+		serviceErrorResponse(c, err, "could not revoke token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "token revoked"})
+}
+
+// GetUserTokens lists the tokens of a member of the caller's tenant, given
+// by the user_id query parameter.
+func (h *PersonalAccessTokenHandlerImpl) GetUserTokens(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	tokens, err := h.tokenService.GetUserTokens(requestor, userID)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get tokens")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokeUserToken revokes a token of a member of the caller's tenant.
+func (h *PersonalAccessTokenHandlerImpl) RevokeUserToken(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	if err := h.tokenService.RevokeUserToken(requestor, c.Param("id")); err != nil {
+		serviceErrorResponse(c, err, "could not revoke token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "token revoked"})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+// serviceErrorResponse answers with the status a service error calls for. An
+// *utils.AppError carries its own; errors the handler does not recognize are
+// reported as message with a 500.
+func serviceErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFAToken), errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrUnauthorized):
+		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		return
+	}
+	if appError, ok := err.(*utils.AppError); ok {
+		c.JSON(appError.Code, gin.H{"error": appError.Message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// tokensResponse sends tokens from a login or refresh. Their session is put
+// in the context for middleware.TrackSessions to record the device it was
+// started or continued from.
+func tokensResponse(c *gin.Context, tokens *dto.LoginResponse) {
+	if tokens.SessionID != "" {
+		c.Set(utils.SessionContextKey, tokens.SessionID)
+	}
+	c.JSON(http.StatusOK, tokens)
+}
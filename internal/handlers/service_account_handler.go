@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type ServiceAccountHandler interface {
+	GetServiceAccounts(c *gin.Context)
+	CreateServiceAccount(c *gin.Context)
+	DeleteServiceAccount(c *gin.Context)
+	CreateKey(c *gin.Context)
+}
+
+type ServiceAccountHandlerImpl struct {
+	serviceAccountService services.ServiceAccountService
+	tokenService          services.PersonalAccessTokenService
+}
+
+func NewServiceAccountHandler(serviceAccountService services.ServiceAccountService, tokenService services.PersonalAccessTokenService) ServiceAccountHandler {
+	return &ServiceAccountHandlerImpl{serviceAccountService: serviceAccountService, tokenService: tokenService}
+}
+
+// GetServiceAccounts lists the service accounts of the caller's tenant.
+func (h *ServiceAccountHandlerImpl) GetServiceAccounts(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	accounts, err := h.serviceAccountService.GetServiceAccounts(requestor)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get service accounts")
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+func (h *ServiceAccountHandlerImpl) CreateServiceAccount(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	var req dto.ServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.serviceAccountService.CreateServiceAccount(requestor, utils.GetCurrentClaims(c), &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not create service account")
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+func (h *ServiceAccountHandlerImpl) DeleteServiceAccount(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	if err := h.serviceAccountService.DeleteServiceAccount(requestor, c.Param("id")); err != nil {
+		serviceErrorResponse(c, err, "could not delete service account")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "service account deleted"})
+}
+
+// CreateKey issues an API key for a service account. The response is the
+// only time the key is shown. Keys are listed and revoked like any personal
+// access token.
+func (h *ServiceAccountHandlerImpl) CreateKey(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	var req dto.PersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.tokenService.CreateServiceAccountToken(requestor, utils.GetCurrentClaims(c), c.Param("id"), &req)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not create key")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, key)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type SessionHandler interface {
+	GetSessions(c *gin.Context)
+	RevokeSession(c *gin.Context)
+	GetUserSessions(c *gin.Context)
+	RevokeUserSession(c *gin.Context)
+}
+
+type SessionHandlerImpl struct {
+	sessionService services.SessionService
+}
+
+func NewSessionHandler(sessionService services.SessionService) SessionHandler {
+	return &SessionHandlerImpl{sessionService: sessionService}
+}
+
+// GetSessions lists the caller's own sessions.
+func (h *SessionHandlerImpl) GetSessions(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	sessions, err := h.sessionService.GetSessions(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession logs the caller out of one of their sessions.
+func (h *SessionHandlerImpl) RevokeSession(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	if err := h.sessionService.RevokeSession(user, c.Param("id")); err != nil {
+		serviceErrorResponse(c, err, "could not revoke session")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// GetUserSessions lists the sessions of a member of the caller's tenant,
+// given by the user_id query parameter.
+func (h *SessionHandlerImpl) GetUserSessions(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	sessions, err := h.sessionService.GetUserSessions(requestor, userID)
+	if err != nil {
+		serviceErrorResponse(c, err, "could not get sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeUserSession logs a member of the caller's tenant out of a session.
+func (h *SessionHandlerImpl) RevokeUserSession(c *gin.Context) {
+	requestor := utils.GetCurrentUser(c)
+
+	if err := h.sessionService.RevokeUserSession(requestor, c.Param("id")); err != nil {
+		serviceErrorResponse(c, err, "could not revoke session")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
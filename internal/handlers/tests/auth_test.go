@@ -6,12 +6,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
 	"github.com/samvibes/vexop/auth-service/internal/handlers"
-	repoMock "github.com/samvibes/vexop/auth-service/internal/repository/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
 	serviceMock "github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -19,16 +23,16 @@ import (
 func TestSignup_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	mockRepo := new(repoMock.MockUserRepository)
+	mockAuthService := new(serviceMock.MockAuthService)
 	mockUserService := new(serviceMock.MockUserService)
-	handler := handlers.NewAuthHandler(mockUserService)
+	mockTenantService := new(serviceMock.MockTenantService)
+	handler := handlers.NewAuthHandler(mockAuthService, mockUserService, mockTenantService, nil)
 
-	tenantID := uuid.New().String()
+	tenantID := uuid.New()
 
-	signupReq := SignupRequest{
+	signupReq := dto.SignupRequest{
 		Email:    "test@example.com",
 		Password: "test_password",
-		TenantID: tenantID,
 	}
 
 	body, _ := json.Marshal(signupReq)
@@ -40,8 +44,11 @@ func TestSignup_Success(t *testing.T) {
 	router.POST("/signup", handler.SignUp)
 
 	// setup expectation on the mock
-	mockRepo.
-		On("CreateUser", mock.AnythingOfType("*models.User")).
+	mockUserService.On("ValidatePassword", mock.AnythingOfType("*models.User"), signupReq.Password).Return(nil)
+	mockAuthService.On("HashPassword", signupReq.Password).Return("hashed", nil)
+	mockTenantService.On("CreateTenant", (*models.User)(nil), signupReq.Email).Return(&models.Tenant{ID: &tenantID}, nil)
+	mockUserService.
+		On("CreateUser", mock.AnythingOfType("*models.User"), mock.Anything).
 		Return(nil)
 
 	// Perform request
@@ -52,5 +59,146 @@ func TestSignup_Success(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "user created successfully")
 
 	// Verify that CreateUser was called
-	mockRepo.AssertExpectations(t)
+	mockUserService.AssertExpectations(t)
+}
+
+func TestSignup_WeakPassword_UnprocessableEntity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockUserService := new(serviceMock.MockUserService)
+	mockTenantService := new(serviceMock.MockTenantService)
+	handler := handlers.NewAuthHandler(mockAuthService, mockUserService, mockTenantService, nil)
+
+	policyErr := &services.PasswordPolicyError{Violations: []services.PasswordViolation{
+		{Rule: utils.PasswordRuleMinLength, Message: "must be at least 8 characters long"},
+	}}
+	mockUserService.On("ValidatePassword", mock.AnythingOfType("*models.User"), "short").Return(policyErr)
+
+	body, _ := json.Marshal(dto.SignupRequest{Email: "test@example.com", Password: "short"})
+	req, _ := http.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	router.POST("/signup", handler.SignUp)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"rule":"min_length"`)
+	mockTenantService.AssertNotCalled(t, "CreateTenant", mock.Anything, mock.Anything)
+}
+
+func TestRefresh_ReusedToken_Unauthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockAuthService := new(serviceMock.MockAuthService)
+	handler := handlers.NewAuthHandler(mockAuthService, new(serviceMock.MockUserService), new(serviceMock.MockTenantService), nil)
+
+	body, _ := json.Marshal(dto.RefreshTokenRequest{RefreshToken: "stolen"})
+	req, _ := http.NewRequest(http.MethodPost, "/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	router.POST("/refresh", handler.Refresh)
+
+	mockAuthService.On("RefreshTokens", "stolen", "").Return(nil, services.ErrRefreshTokenReused)
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockAuthService.AssertExpectations(t)
+}
+
+func TestForgotPassword_SameResponseForUnknownEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(serviceMock.MockUserService)
+	handler := handlers.NewAuthHandler(new(serviceMock.MockAuthService), mockUserService, new(serviceMock.MockTenantService), nil)
+
+	router := gin.Default()
+	router.POST("/password/forgot", handler.ForgotPassword)
+
+	// the service reports nothing for unknown addresses
+	mockUserService.On("InitResetPassword", mock.Anything).Return(nil)
+
+	var responses []string
+	for _, email := range []string{"known@example.com", "unknown@example.com"} {
+		body, _ := json.Marshal(dto.ForgotPasswordRequest{Email: email})
+		req, _ := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		responses = append(responses, rr.Body.String())
+	}
+	assert.Equal(t, responses[0], responses[1])
+}
+
+func TestResetPassword_InvalidToken_BadRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(serviceMock.MockUserService)
+	handler := handlers.NewAuthHandler(new(serviceMock.MockAuthService), mockUserService, new(serviceMock.MockTenantService), nil)
+
+	body, _ := json.Marshal(dto.ResetPasswordRequest{Token: "expired", Password: "new-password"})
+	req, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	router.POST("/password/reset", handler.ResetPassword)
+
+	mockUserService.On("ResetPassword", "expired", "new-password").Return(services.ErrInvalidResetToken)
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), services.ErrInvalidResetToken.Error())
+}
+
+func TestLogin_EmailNotVerified_Forbidden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(serviceMock.MockUserService)
+	handler := handlers.NewAuthHandler(new(serviceMock.MockAuthService), mockUserService, new(serviceMock.MockTenantService), nil)
+
+	router := gin.Default()
+	router.POST("/login", handler.Login)
+
+	mockUserService.On("Login", "user@example.com", "password", mock.Anything).Return(nil, nil, services.ErrEmailNotVerified)
+
+	body, _ := json.Marshal(dto.LoginRequest{Email: "user@example.com", Password: "password"})
+	req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestLogin_Locked_TooManyRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(serviceMock.MockUserService)
+	handler := handlers.NewAuthHandler(new(serviceMock.MockAuthService), mockUserService, new(serviceMock.MockTenantService), nil)
+
+	router := gin.Default()
+	router.POST("/login", handler.Login)
+
+	mockUserService.On("Login", "user@example.com", "password", mock.Anything).Return(nil, nil, &services.LoginLockedError{RetryAfter: 1500 * time.Millisecond})
+
+	body, _ := json.Marshal(dto.LoginRequest{Email: "user@example.com", Password: "password"})
+	req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
 }
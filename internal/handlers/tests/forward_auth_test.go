@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+	serviceMock "github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var forwardAuthRules = middleware.ResourceRules{
+	{PathPrefix: "/billing/invoices", Resource: "invoice"},
+	{PathPrefix: "/billing/invoices/export", Resource: "invoice", Actions: map[string]string{http.MethodPost: "read"}},
+	{PathPrefix: "/status"},
+}
+
+// newForwardAuthRouter runs stateless so the user comes from the token claims
+// and no DB is needed.
+func newForwardAuthRouter(t *testing.T, claims *utils.Claims) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_STATELESS", true)
+	t.Cleanup(func() { viper.Set("JWT_STATELESS", false) })
+
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+
+	handler := handlers.NewForwardAuthHandler(middleware.NewRequestAuthorizer(middleware.NewAuthenticator(nil, mockAuthService, nil), forwardAuthRules, nil))
+	router := gin.New()
+	router.Any("/verify", handler.Verify)
+	return router
+}
+
+func forwardAuthRequest(method, uri string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/verify", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Forwarded-Method", method)
+	req.Header.Set("X-Forwarded-Uri", uri)
+	return req
+}
+
+func TestForwardAuth_AllowsPermittedRequest(t *testing.T) {
+	tenantID := uuid.New()
+	claims := &utils.Claims{
+		UserID:      uuid.NewString(),
+		TenantID:    tenantID.String(),
+		Role:        "member",
+		Permissions: []string{"invoice:read"},
+	}
+	router := newForwardAuthRouter(t, claims)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, forwardAuthRequest(http.MethodGet, "/billing/invoices/42?expand=lines"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, claims.UserID, w.Header().Get("X-User-Id"))
+	assert.Equal(t, tenantID.String(), w.Header().Get("X-Tenant-Id"))
+	assert.Equal(t, "member", w.Header().Get("X-Role"))
+}
+
+func TestForwardAuth_ActionOverrideAndNginxHeaders(t *testing.T) {
+	claims := &utils.Claims{UserID: uuid.NewString(), Role: "member", Permissions: []string{"invoice:read"}}
+	router := newForwardAuthRouter(t, claims)
+
+	req := httptest.NewRequest(http.MethodGet, "/verify", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Original-Method", http.MethodPost)
+	req.Header.Set("X-Original-URI", "/billing/invoices/export")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestForwardAuth_DeniesMissingPermission(t *testing.T) {
+	claims := &utils.Claims{UserID: uuid.NewString(), Role: "member", Permissions: []string{"invoice:read"}}
+	router := newForwardAuthRouter(t, claims)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, forwardAuthRequest(http.MethodDelete, "/billing/invoices/42"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("X-User-Id"))
+}
+
+func TestForwardAuth_DeniesUnmappedAndDotSegmentPaths(t *testing.T) {
+	claims := &utils.Claims{UserID: uuid.NewString(), Role: "member", Permissions: []string{"invoice:read"}}
+	router := newForwardAuthRouter(t, claims)
+
+	for _, uri := range []string{"/admin", "/status/../admin", "/billing/invoicesX"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, forwardAuthRequest(http.MethodGet, uri))
+		assert.Equal(t, http.StatusForbidden, w.Code, uri)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, forwardAuthRequest(http.MethodGet, "/status"))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestForwardAuth_MissingToken_Unauthorized(t *testing.T) {
+	router := newForwardAuthRouter(t, &utils.Claims{})
+
+	req := forwardAuthRequest(http.MethodGet, "/billing/invoices")
+	req.Header.Del("Authorization")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+}
+
+func TestForwardAuth_ClientScope(t *testing.T) {
+	claims := &utils.Claims{ClientID: uuid.NewString(), TenantID: uuid.NewString(), Scope: "invoice:create"}
+	router := newForwardAuthRouter(t, claims)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, forwardAuthRequest(http.MethodPost, "/billing/invoices"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, utils.RoleClient, w.Header().Get("X-Role"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, forwardAuthRequest(http.MethodGet, "/billing/invoices"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestForwardAuth_ClientWithoutTenant_Forbidden(t *testing.T) {
+	claims := &utils.Claims{ClientID: uuid.NewString(), Scope: "invoice:create"}
+	router := newForwardAuthRouter(t, claims)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, forwardAuthRequest(http.MethodPost, "/billing/invoices"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("X-User-Id"))
+}
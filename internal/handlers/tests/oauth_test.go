@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	serviceMock "github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizeLogin_RedirectsWithCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockOIDCService := new(serviceMock.MockOIDCService)
+	mockUserService := new(serviceMock.MockUserService)
+	mockMFAService := new(serviceMock.MockMFAService)
+	handler := handlers.NewOAuthHandler(mockOIDCService, new(serviceMock.MockAuthService), mockUserService, mockMFAService)
+
+	client := &models.OAuthClient{ClientID: "spa", Name: "SPA"}
+	user := &models.User{ID: uuid.New()}
+
+	mockOIDCService.On("ValidateAuthorizeRequest", mock.AnythingOfType("*dto.AuthorizeRequest")).Return(client, nil)
+	mockUserService.On("Authenticate", "a@example.com", "password", mock.Anything).Return(user, nil)
+	mockUserService.On("CheckPasswordExpiry", user).Return(nil)
+	mockUserService.On("CheckEmailVerification", user).Return(nil)
+	mockMFAService.On("VerifyLoginCode", user, "", mock.Anything).Return(nil)
+	mockOIDCService.On("Authorize", mock.AnythingOfType("*dto.AuthorizeRequest"), user).Return("the-code", nil)
+
+	form := url.Values{
+		"response_type": {"code"},
+		"client_id":     {"spa"},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"state":         {"xyz"},
+		"email":         {"a@example.com"},
+		"password":      {"password"},
+	}
+
+	router := gin.New()
+	router.POST("/oauth/authorize", handler.AuthorizeLogin)
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", location.Host)
+	assert.Equal(t, "the-code", location.Query().Get("code"))
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+}
+
+func TestAuthorizeLogin_AsksForMFACode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockOIDCService := new(serviceMock.MockOIDCService)
+	mockUserService := new(serviceMock.MockUserService)
+	mockMFAService := new(serviceMock.MockMFAService)
+	handler := handlers.NewOAuthHandler(mockOIDCService, new(serviceMock.MockAuthService), mockUserService, mockMFAService)
+
+	client := &models.OAuthClient{ClientID: "spa", Name: "SPA"}
+	user := &models.User{ID: uuid.New()}
+
+	mockOIDCService.On("ValidateAuthorizeRequest", mock.AnythingOfType("*dto.AuthorizeRequest")).Return(client, nil)
+	mockUserService.On("Authenticate", "a@example.com", "password", mock.Anything).Return(user, nil)
+	mockUserService.On("CheckPasswordExpiry", user).Return(nil)
+	mockUserService.On("CheckEmailVerification", user).Return(nil)
+	mockMFAService.On("VerifyLoginCode", user, "", mock.Anything).Return(services.ErrMFARequired)
+
+	form := url.Values{
+		"response_type": {"code"},
+		"client_id":     {"spa"},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"email":         {"a@example.com"},
+		"password":      {"password"},
+	}
+
+	router := gin.New()
+	router.POST("/oauth/authorize", handler.AuthorizeLogin)
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `name="mfa_code"`)
+	mockOIDCService.AssertNotCalled(t, "Authorize", mock.Anything, mock.Anything)
+}
+
+func TestAuthorizeLogin_EmailNotVerified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockOIDCService := new(serviceMock.MockOIDCService)
+	mockUserService := new(serviceMock.MockUserService)
+	mockMFAService := new(serviceMock.MockMFAService)
+	handler := handlers.NewOAuthHandler(mockOIDCService, new(serviceMock.MockAuthService), mockUserService, mockMFAService)
+
+	client := &models.OAuthClient{ClientID: "spa", Name: "SPA"}
+	user := &models.User{ID: uuid.New()}
+
+	mockOIDCService.On("ValidateAuthorizeRequest", mock.AnythingOfType("*dto.AuthorizeRequest")).Return(client, nil)
+	mockUserService.On("Authenticate", "a@example.com", "password", mock.Anything).Return(user, nil)
+	mockUserService.On("CheckPasswordExpiry", user).Return(nil)
+	mockUserService.On("CheckEmailVerification", user).Return(services.ErrEmailNotVerified)
+
+	form := url.Values{
+		"response_type": {"code"},
+		"client_id":     {"spa"},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"email":         {"a@example.com"},
+		"password":      {"password"},
+	}
+
+	router := gin.New()
+	router.POST("/oauth/authorize", handler.AuthorizeLogin)
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockMFAService.AssertNotCalled(t, "VerifyLoginCode", mock.Anything, mock.Anything, mock.Anything)
+	mockOIDCService.AssertNotCalled(t, "Authorize", mock.Anything, mock.Anything)
+}
+
+func TestToken_InvalidClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockOIDCService := new(serviceMock.MockOIDCService)
+	handler := handlers.NewOAuthHandler(mockOIDCService, new(serviceMock.MockAuthService), new(serviceMock.MockUserService), new(serviceMock.MockMFAService))
+
+	mockOIDCService.On("Token", mock.MatchedBy(func(req *dto.TokenRequest) bool {
+		return req.ClientID == "backend" && req.ClientSecret == "wrong"
+	})).Return(nil, &services.OAuthError{Code: "invalid_client", Description: "client authentication failed"})
+
+	router := gin.New()
+	router.POST("/oauth/token", handler.Token)
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader("grant_type=authorization_code&code=abc"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("backend", "wrong")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"invalid_client"`)
+}
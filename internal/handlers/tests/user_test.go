@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+	serviceMock "github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetUsers_ClientWithoutTenant_Forbidden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	claims := &utils.Claims{ClientID: uuid.NewString(), Scope: "user:read"}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+	mockUserService := new(serviceMock.MockUserService)
+	handler := handlers.NewUserHandler(mockUserService, new(serviceMock.MockLockoutService), nil)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuthMiddleware(nil, mockAuthService, &serviceMock.MockSessionService{}, nil))
+	router.Use(middleware.AutoRBAC(nil))
+	router.GET("/api/users", handler.GetUsers)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockUserService.AssertNotCalled(t, "GetUsers", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+)
+
+type TokenClaimHandler interface {
+	GetClaimConfigs(*gin.Context)
+	SaveClaimConfig(*gin.Context)
+	DeleteClaimConfig(*gin.Context)
+}
+
+type TokenClaimHandlerImpl struct {
+	service services.ClaimsService
+}
+
+func NewTokenClaimHandler(service services.ClaimsService) TokenClaimHandler {
+	return &TokenClaimHandlerImpl{service: service}
+}
+
+func (h *TokenClaimHandlerImpl) GetClaimConfigs(c *gin.Context) {
+	page, limit := utils.GetPageAndLimit(c)
+
+	requestor := utils.GetCurrentUser(c)
+
+	configs, err := h.service.GetClaimConfigs(requestor, page, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get token claim configs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, configs)
+}
+
+// SaveClaimConfig creates a config, or replaces the one given by the id query
+// parameter.
+func (h *TokenClaimHandlerImpl) SaveClaimConfig(c *gin.Context) {
+	var req dto.TokenClaimConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config := &models.TokenClaimConfig{
+		ID:                 uuid.New(),
+		Audience:           req.Audience,
+		IncludeTenant:      req.IncludeTenant,
+		IncludeRole:        req.IncludeRole,
+		IncludePermissions: req.IncludePermissions,
+		IncludeEmail:       req.IncludeEmail,
+	}
+	if id, ok := c.GetQuery("id"); ok {
+		configID, err := uuid.Parse(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		config.ID = configID
+	}
+	if req.TenantID != "" {
+		tenantID, err := uuid.Parse(req.TenantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant id"})
+			return
+		}
+		config.TenantID = &tenantID
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	if err := h.service.SaveClaimConfig(requestor, config); err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save token claim config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+func (h *TokenClaimHandlerImpl) DeleteClaimConfig(c *gin.Context) {
+	id, ok := c.GetQuery("id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required in query"})
+		return
+	}
+
+	requestor := utils.GetCurrentUser(c)
+
+	if err := h.service.DeleteClaimConfig(requestor, id); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthorized):
+			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "token claim config not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete token claim config"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "token claim config deleted successfully"})
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -15,18 +14,19 @@ type UserHandler interface {
 	GetUsers(*gin.Context)
 	GetUserById(*gin.Context)
 	UpdateUserRole(*gin.Context)
-	SendResetPassword(*gin.Context)
-	ResetPassword(*gin.Context)
 	DeleteUser(c *gin.Context)
+	UnlockUser(c *gin.Context)
+	ImportUsers(c *gin.Context)
 }
 
 type UserHandlerImpl struct {
-	userService services.UserService
-	db          *gorm.DB
+	userService    services.UserService
+	lockoutService services.LockoutService
+	db             *gorm.DB
 }
 
-func NewUserHandler(userService services.UserService, db *gorm.DB) UserHandler {
-	return &UserHandlerImpl{userService: userService, db: db}
+func NewUserHandler(userService services.UserService, lockoutService services.LockoutService, db *gorm.DB) UserHandler {
+	return &UserHandlerImpl{userService: userService, lockoutService: lockoutService, db: db}
 }
 
 func (u *UserHandlerImpl) GetUsers(c *gin.Context) {
@@ -116,34 +116,34 @@ func (u *UserHandlerImpl) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
 }
 
-func (u *UserHandlerImpl) SendResetPassword(c *gin.Context) {
+// UnlockUser lifts a login lockout on a member of the caller's tenant.
+func (u *UserHandlerImpl) UnlockUser(c *gin.Context) {
 	user := utils.GetCurrentUser(c)
-	token, err := u.userService.InitResetPassword(user.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed while initiating reset password"})
+
+	if err := u.lockoutService.Unlock(user, c.Param("id")); err != nil {
+		serviceErrorResponse(c, err, "could not unlock user")
 		return
 	}
 
-	// send token to user via mail
-	fmt.Println(token)
-
-	c.JSON(http.StatusOK, gin.H{"message": "reset password message sent"})
+	c.JSON(http.StatusOK, gin.H{"message": "user unlocked successfully"})
 }
 
-func (u *UserHandlerImpl) ResetPassword(c *gin.Context) {
-	user := utils.GetCurrentUser(c)
-
-	var req dto.ResetPasswordRequest
-	err := c.ShouldBindJSON(&req)
-	if err != nil {
+// ImportUsers creates users in the caller's tenant with password hashes
+// exported from another system.
+func (u *UserHandlerImpl) ImportUsers(c *gin.Context) {
+	var req dto.ImportUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	err = u.userService.ResetPassword(user.TenantID.String(), user.ID.String(), req.Token, req.Password)
+	user := utils.GetCurrentUser(c)
+
+	imported, err := u.userService.ImportUsers(user, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serviceErrorResponse(c, err, "could not import users")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "password updated successfully"})
+	c.JSON(http.StatusCreated, gin.H{"imported": imported})
 }
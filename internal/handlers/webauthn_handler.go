@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+type WebAuthnHandler interface {
+	BeginRegistration(*gin.Context)
+	FinishRegistration(*gin.Context)
+	GetCredentials(*gin.Context)
+	DeleteCredential(*gin.Context)
+	BeginLogin(*gin.Context)
+	FinishLogin(*gin.Context)
+	BeginMFA(*gin.Context)
+	FinishMFA(*gin.Context)
+}
+
+type WebAuthnHandlerImpl struct {
+	webauthnService services.WebAuthnService
+}
+
+func NewWebAuthnHandler(webauthnService services.WebAuthnService) WebAuthnHandler {
+	return &WebAuthnHandlerImpl{webauthnService: webauthnService}
+}
+
+func (h *WebAuthnHandlerImpl) BeginRegistration(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	options, err := h.webauthnService.BeginRegistration(user)
+	if err != nil {
+		webAuthnErrorResponse(c, err, "could not start passkey registration")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, options)
+}
+
+func (h *WebAuthnHandlerImpl) FinishRegistration(c *gin.Context) {
+	var req dto.WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := utils.GetCurrentUser(c)
+
+	credential, err := h.webauthnService.FinishRegistration(user, &req)
+	if err != nil {
+		webAuthnErrorResponse(c, err, "could not register passkey")
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+func (h *WebAuthnHandlerImpl) GetCredentials(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	credentials, err := h.webauthnService.GetCredentials(user)
+	if err != nil {
+		webAuthnErrorResponse(c, err, "could not get passkeys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"credentials": credentials})
+}
+
+func (h *WebAuthnHandlerImpl) DeleteCredential(c *gin.Context) {
+	user := utils.GetCurrentUser(c)
+
+	if err := h.webauthnService.DeleteCredential(user, c.Param("id")); err != nil {
+		webAuthnErrorResponse(c, err, "could not delete passkey")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "passkey deleted"})
+}
+
+// BeginLogin starts a passkey login, an alternative to email and password.
+func (h *WebAuthnHandlerImpl) BeginLogin(c *gin.Context) {
+	options, err := h.webauthnService.BeginLogin()
+	if err != nil {
+		webAuthnErrorResponse(c, err, "could not start passkey login")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, options)
+}
+
+func (h *WebAuthnHandlerImpl) FinishLogin(c *gin.Context) {
+	var req dto.WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.webauthnService.FinishLogin(&req)
+	if err != nil {
+		webAuthnErrorResponse(c, err, "could not complete login")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	tokensResponse(c, tokens)
+}
+
+// BeginMFA starts a passkey assertion for the second step of password login.
+func (h *WebAuthnHandlerImpl) BeginMFA(c *gin.Context) {
+	var req dto.MFATokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options, err := h.webauthnService.BeginMFA(req.MFAToken)
+	if err != nil {
+		webAuthnErrorResponse(c, err, "could not start passkey verification")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, options)
+}
+
+func (h *WebAuthnHandlerImpl) FinishMFA(c *gin.Context) {
+	var req dto.WebAuthnMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.webauthnService.FinishMFA(&req, c.ClientIP())
+	if err != nil {
+		webAuthnErrorResponse(c, err, "could not complete login")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	tokensResponse(c, tokens)
+}
+
+func webAuthnErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrWebAuthnDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrInvalidWebAuthnSession), errors.Is(err, services.ErrInvalidPasskey):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	serviceErrorResponse(c, err, message)
+}
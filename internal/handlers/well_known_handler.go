@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+)
+
+type WellKnownHandler interface {
+	JWKS(*gin.Context)
+}
+
+type WellKnownHandlerImpl struct {
+	keyService services.KeyService
+}
+
+func NewWellKnownHandler(keyService services.KeyService) WellKnownHandler {
+	return &WellKnownHandlerImpl{keyService: keyService}
+}
+
+func (w *WellKnownHandlerImpl) JWKS(c *gin.Context) {
+	jwks, err := w.keyService.JWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load signing keys"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
@@ -0,0 +1,80 @@
+package lockout
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Counter is the failed-attempt state of one key, such as an account or a
+// client IP address.
+type Counter struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// Store keeps failed login attempt counters. Stores are chosen with
+// LOCKOUT_STORE.
+type Store interface {
+	// Get returns the counter for key. Unknown keys have a zero Counter.
+	Get(key string) (Counter, error)
+	// RecordFailure adds a failure to key and returns the new count.
+	// Failures are forgotten once none has happened for window.
+	RecordFailure(key string, window time.Duration, now time.Time) (int, error)
+	// Lock refuses logins for key until the given time.
+	Lock(key string, until time.Time) error
+	// Reset forgets key's failures and lifts any lock.
+	Reset(key string) error
+}
+
+// New returns the store named by LOCKOUT_STORE: "memory" or "postgres". The
+// memory store is the default and only counts attempts seen by this
+// instance; deployments running several instances should use postgres.
+func New(db *gorm.DB) (Store, error) {
+	switch store := strings.ToLower(viper.GetString("LOCKOUT_STORE")); store {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown LOCKOUT_STORE %q", store)
+	}
+}
+
+// Policy turns a failure count into how long further attempts are refused.
+// The first DelayAfter failures are free. After that each failure doubles
+// the delay, starting at BaseDelay, until Threshold failures lock the key
+// for Duration.
+type Policy struct {
+	DelayAfter int
+	BaseDelay  time.Duration
+	Threshold  int
+	Duration   time.Duration
+}
+
+// LockFor returns how long to refuse attempts after the given number of
+// consecutive failures.
+func (p Policy) LockFor(failures int) time.Duration {
+	if failures >= p.Threshold {
+		return p.Duration
+	}
+	if failures < p.DelayAfter {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.DelayAfter; i < failures && delay < p.Duration; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.Duration)
+}
+
+// Locks reports whether failures reaches the lockout threshold rather than
+// just a delay.
+func (p Policy) Locks(failures int) bool {
+	return failures >= p.Threshold
+}
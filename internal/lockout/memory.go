@@ -0,0 +1,90 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	failures      int
+	lastFailureAt time.Time
+	lockedUntil   time.Time
+}
+
+// MemoryStore keeps counters in process memory. Entries that are neither
+// counting nor locked are swept at most once per window.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	sweptAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Get(key string) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return Counter{}, nil
+	}
+
+	return Counter{Failures: entry.failures, LockedUntil: entry.lockedUntil}, nil
+}
+
+func (s *MemoryStore) RecordFailure(key string, window time.Duration, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.sweptAt) > window {
+		s.sweep(window, now)
+	}
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	if now.Sub(entry.lastFailureAt) > window {
+		entry.failures = 0
+	}
+	entry.failures++
+	entry.lastFailureAt = now
+
+	return entry.failures, nil
+}
+
+func (s *MemoryStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	entry.lockedUntil = until
+
+	return nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+func (s *MemoryStore) sweep(window time.Duration, now time.Time) {
+	for key, entry := range s.entries {
+		if now.Sub(entry.lastFailureAt) > window && !entry.lockedUntil.After(now) {
+			delete(s.entries, key)
+		}
+	}
+	s.sweptAt = now
+}
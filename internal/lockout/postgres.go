@@ -0,0 +1,87 @@
+package lockout
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// PostgresStore shares counters between instances through the
+// login_throttles table. Failures are counted with a single upsert so
+// concurrent attempts are never lost.
+type PostgresStore struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	sweptAt time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Get(key string) (Counter, error) {
+	var throttle models.LoginThrottle
+	err := s.db.Where("key = ?", key).First(&throttle).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Counter{}, nil
+	}
+	if err != nil {
+		return Counter{}, err
+	}
+
+	counter := Counter{Failures: throttle.Failures}
+	if throttle.LockedUntil != nil {
+		counter.LockedUntil = *throttle.LockedUntil
+	}
+
+	return counter, nil
+}
+
+func (s *PostgresStore) RecordFailure(key string, window time.Duration, now time.Time) (int, error) {
+	if err := s.maybeSweep(window, now); err != nil {
+		return 0, err
+	}
+
+	var failures int
+	err := s.db.Raw(`
+		INSERT INTO login_throttles (key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`,
+		key, now, now.Add(-window),
+	).Scan(&failures).Error
+
+	return failures, err
+}
+
+func (s *PostgresStore) Lock(key string, until time.Time) error {
+	return s.db.Model(&models.LoginThrottle{}).
+		Where("key = ?", key).
+		Update("locked_until", until).Error
+}
+
+func (s *PostgresStore) Reset(key string) error {
+	return s.db.Where("key = ?", key).Delete(&models.LoginThrottle{}).Error
+}
+
+// maybeSweep deletes rows that are neither counting nor locked, at most once
+// per window per instance.
+func (s *PostgresStore) maybeSweep(window time.Duration, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.sweptAt) <= window {
+		s.mu.Unlock()
+		return nil
+	}
+	s.sweptAt = now
+	s.mu.Unlock()
+
+	return s.db.
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-window), now).
+		Delete(&models.LoginThrottle{}).Error
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/lockout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_ProgressiveDelayThenLockout(t *testing.T) {
+	policy := lockout.Policy{DelayAfter: 3, BaseDelay: time.Second, Threshold: 8, Duration: 15 * time.Minute}
+
+	assert.Zero(t, policy.LockFor(1))
+	assert.Zero(t, policy.LockFor(2))
+	assert.Equal(t, time.Second, policy.LockFor(3))
+	assert.Equal(t, 2*time.Second, policy.LockFor(4))
+	assert.Equal(t, 16*time.Second, policy.LockFor(7))
+	assert.Equal(t, 15*time.Minute, policy.LockFor(8))
+	assert.Equal(t, 15*time.Minute, policy.LockFor(50))
+	assert.False(t, policy.Locks(7))
+	assert.True(t, policy.Locks(8))
+}
+
+func TestPolicy_DelayNeverExceedsDuration(t *testing.T) {
+	policy := lockout.Policy{DelayAfter: 1, BaseDelay: time.Minute, Threshold: 100, Duration: 10 * time.Minute}
+
+	assert.Equal(t, 10*time.Minute, policy.LockFor(99))
+}
+
+func TestMemoryStore_CountsWithinWindow(t *testing.T) {
+	store := lockout.NewMemoryStore()
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		failures, err := store.RecordFailure("account:a@example.com", time.Hour, now)
+		require.NoError(t, err)
+		assert.Equal(t, i, failures)
+	}
+
+	// a failure after a quiet window starts counting again
+	failures, err := store.RecordFailure("account:a@example.com", time.Hour, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures)
+}
+
+func TestMemoryStore_LockAndReset(t *testing.T) {
+	store := lockout.NewMemoryStore()
+	until := time.Now().Add(time.Minute)
+
+	_, err := store.RecordFailure("ip:10.0.0.1", time.Hour, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, store.Lock("ip:10.0.0.1", until))
+
+	counter, err := store.Get("ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter.Failures)
+	assert.Equal(t, until, counter.LockedUntil)
+
+	require.NoError(t, store.Reset("ip:10.0.0.1"))
+	counter, err = store.Get("ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Zero(t, counter)
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes each message to its own .eml file in a directory, for
+// tests and local development.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, errors.New("MAIL_FILE_DIR must be set for the file mail driver")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (f *FileMailer) Send(msg *Message) error {
+	body, err := Build(f.from, msg)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), hex.EncodeToString(suffix))
+
+	return os.WriteFile(filepath.Join(f.dir, name), body, 0o600)
+}
+
+// LogMailer writes messages to the log instead of sending them. Messages may
+// carry login links, so it is for development only.
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (l *LogMailer) Send(msg *Message) error {
+	body := msg.Text
+	if body == "" {
+		body = msg.HTML
+	}
+	log.Printf("mail from %q to %q: %s\n%s", l.from, msg.To, msg.Subject, body)
+	return nil
+}
@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Message is an email ready to send. Text is the plain-text alternative to
+// HTML and may be empty.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Mailer delivers a message. Drivers are chosen with MAIL_DRIVER.
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// defaultFrom is the sender for the file and log drivers when MAIL_FROM is
+// not set.
+const defaultFrom = "no-reply@localhost"
+
+// New returns the driver named by MAIL_DRIVER: "smtp", "file" or "log". The
+// log driver is the default so development setups work without a mail
+// server.
+func New() (Mailer, error) {
+	from := viper.GetString("MAIL_FROM")
+
+	switch driver := strings.ToLower(viper.GetString("MAIL_DRIVER")); driver {
+	case "smtp":
+		if from == "" {
+			return nil, fmt.Errorf("MAIL_FROM must be set for the smtp mail driver")
+		}
+		return NewSMTPMailer(SMTPConfig{
+			Host:        viper.GetString("SMTP_HOST"),
+			Port:        viper.GetInt("SMTP_PORT"),
+			Username:    viper.GetString("SMTP_USERNAME"),
+			Password:    viper.GetString("SMTP_PASSWORD"),
+			ImplicitTLS: viper.GetBool("SMTP_IMPLICIT_TLS"),
+			From:        from,
+		})
+	case "file":
+		return NewFileMailer(viper.GetString("MAIL_FILE_DIR"), cmp.Or(from, defaultFrom))
+	case "", "log":
+		return NewLogMailer(cmp.Or(from, defaultFrom)), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+}
@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Built-in templates. Tenants can override any of them.
+const (
+	TemplateInvite        = "invite"
+	TemplateResetPassword = "reset_password"
+	TemplateVerifyEmail   = "verify_email"
+	TemplateSecurityAlert = "security_alert"
+	TemplateMagicLink     = "magic_link"
+	TemplateLoginCode     = "login_code"
+)
+
+var templateNames = []string{
+	TemplateInvite,
+	TemplateResetPassword,
+	TemplateVerifyEmail,
+	TemplateSecurityAlert,
+	TemplateMagicLink,
+	TemplateLoginCode,
+}
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Template is the source of an email in Go template syntax. HTML is parsed
+// with html/template so that data is escaped; Subject and Text are plain
+// text.
+type Template struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// Names lists the built-in templates.
+func Names() []string {
+	return slices.Clone(templateNames)
+}
+
+func IsTemplate(name string) bool {
+	return slices.Contains(templateNames, name)
+}
+
+// Default returns the built-in template with the given name. Each file under
+// templates/ defines "subject", "html" and "text".
+func Default(name string) (*Template, error) {
+	if !IsTemplate(name) {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	source, err := defaultTemplates.ReadFile("templates/" + name + ".tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := template.New(name).Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	define := func(block string) string {
+		if t := parsed.Lookup(block); t != nil && t.Tree != nil {
+			return t.Tree.Root.String()
+		}
+		return ""
+	}
+
+	return &Template{
+		Subject: strings.TrimSpace(define("subject")),
+		HTML:    define("html"),
+		Text:    define("text"),
+	}, nil
+}
+
+// Render executes the template for one recipient.
+func (t *Template) Render(to string, data any) (*Message, error) {
+	subject, err := executeText("subject", t.Subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := executeHTML(t.HTML, data)
+	if err != nil {
+		return nil, err
+	}
+
+	text := ""
+	if t.Text != "" {
+		if text, err = executeText("text", t.Text, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject), " "),
+		HTML:    html,
+		Text:    text,
+	}, nil
+}
+
+func executeText(name, source string, data any) (string, error) {
+	t, err := template.New(name).Option("missingkey=zero").Parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func executeHTML(source string, data any) (string, error) {
+	t, err := htmltemplate.New("html").Option("missingkey=zero").Parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
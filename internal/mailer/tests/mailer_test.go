@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTemplates_Render(t *testing.T) {
+	data := map[string]any{
+		"AppName":   "Vexop",
+		"Link":      "https://app.example.com/x?token=abc",
+		"Code":      "123456",
+		"ExpiresIn": "15 minutes",
+		"Event":     "Your password was changed.",
+	}
+
+	for _, name := range mailer.Names() {
+		source, err := mailer.Default(name)
+		require.NoError(t, err, name)
+
+		message, err := source.Render("user@example.com", data)
+		require.NoError(t, err, name)
+		assert.Equal(t, "user@example.com", message.To)
+		assert.NotEmpty(t, message.Subject, name)
+		assert.NotEmpty(t, message.HTML, name)
+		assert.NotEmpty(t, message.Text, name)
+	}
+}
+
+func TestDefault_UnknownTemplate(t *testing.T) {
+	_, err := mailer.Default("newsletter")
+
+	assert.Error(t, err)
+}
+
+func TestRender_EscapesHTMLOnly(t *testing.T) {
+	source := &mailer.Template{
+		Subject: "Hi {{.Name}}",
+		HTML:    "<p>{{.Name}}</p>",
+		Text:    "{{.Name}}",
+	}
+
+	message, err := source.Render("user@example.com", map[string]any{"Name": "<b>Sam</b>"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Hi <b>Sam</b>", message.Subject)
+	assert.Equal(t, "<p>&lt;b&gt;Sam&lt;/b&gt;</p>", message.HTML)
+	assert.Equal(t, "<b>Sam</b>", message.Text)
+}
+
+func TestRender_RejectsBrokenTemplate(t *testing.T) {
+	source := &mailer.Template{Subject: "Hi", HTML: "<p>{{.Name</p>"}
+
+	_, err := source.Render("user@example.com", nil)
+
+	assert.Error(t, err)
+}
+
+func TestBuild_MultipartAlternative(t *testing.T) {
+	body, err := mailer.Build("Vexop <no-reply@example.com>", &mailer.Message{
+		To:      "user@example.com",
+		Subject: "Welcome",
+		HTML:    "<p>Hello</p>",
+		Text:    "Hello",
+	})
+
+	require.NoError(t, err)
+	raw := string(body)
+	assert.Contains(t, raw, "From: \"Vexop\" <no-reply@example.com>")
+	assert.Contains(t, raw, "To: <user@example.com>")
+	assert.Contains(t, raw, "Subject: Welcome")
+	assert.Contains(t, raw, "Message-ID: <")
+	assert.Contains(t, raw, "multipart/alternative")
+	assert.Contains(t, raw, "text/plain")
+	assert.Contains(t, raw, "text/html")
+}
+
+func TestBuild_RejectsInvalidRecipient(t *testing.T) {
+	_, err := mailer.Build("no-reply@example.com", &mailer.Message{To: "not an address", Subject: "Hi", Text: "Hi"})
+
+	assert.Error(t, err)
+}
+
+func TestFileMailer_WritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	fileMailer, err := mailer.NewFileMailer(dir, "no-reply@example.com")
+	require.NoError(t, err)
+
+	err = fileMailer.Send(&mailer.Message{To: "user@example.com", Subject: "Sign in", Text: "Your code is 123456"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(raw), "Subject: Sign in"))
+	assert.True(t, strings.Contains(string(raw), "123456"))
+}
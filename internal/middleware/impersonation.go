@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+// DenyImpersonation refuses requests made with an impersonation token. It
+// guards what only the user themselves may do, such as changing how they
+// log in, and runs after JWTAuthMiddleware.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if utils.GetImpersonator(c) != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,54 +1,253 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
 	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
 
-func JWTAuthMiddleware(db *gorm.DB, jwtSecret []byte) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid authorization header"})
-			return
+// Authenticator resolves the user behind a bearer access token. When
+// JWT_AUDIENCE is set the token must be issued for it. Tokens an OAuth client
+// obtained on behalf of a user carry the user's role whatever scope was
+// granted, so they are only accepted when issued for JWT_AUDIENCE. With JWT_STATELESS the
+// user is rebuilt from the token's claims instead of being loaded from the DB;
+// tokens without a role claim still fall back to the DB. Tokens issued to a
+// client acting as itself never touch the DB. Users whose email address is
+// not verified are always loaded from the DB so their tenant's
+// EmailVerificationPolicy can block them or restrict them to a limited role.
+// Personal access tokens are accepted in place of access tokens unless
+// tokenService is nil.
+type Authenticator struct {
+	db           *gorm.DB
+	authService  services.AuthService
+	tokenService services.PersonalAccessTokenService
+	audience     string
+	stateless    bool
+}
+
+func NewAuthenticator(db *gorm.DB, authService services.AuthService, tokenService services.PersonalAccessTokenService) *Authenticator {
+	return &Authenticator{
+		db:           db,
+		authService:  authService,
+		tokenService: tokenService,
+		audience:     viper.GetString("JWT_AUDIENCE"),
+		stateless:    viper.GetBool("JWT_STATELESS"),
+	}
+}
+
+// Authenticate checks the Authorization header value. Failures are returned
+// as a *utils.AppError, 401 for bad tokens and 403 for users their tenant's
+// email verification policy shuts out and for clients without a tenant.
+func (a *Authenticator) Authenticate(authHeader string) (*models.User, *utils.Claims, error) {
+	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, nil, utils.NewAppError(http.StatusUnauthorized, "missing or invalid authorization header")
+	}
+
+	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+	if strings.HasPrefix(tokenStr, utils.PersonalAccessTokenPrefix) {
+		return a.authenticatePersonalAccessToken(tokenStr)
+	}
+
+	claims, err := a.authService.ValidateAccessToken(tokenStr)
+	if err != nil {
+		if errors.Is(err, services.ErrTokenRevoked) {
+			return nil, nil, utils.NewAppError(http.StatusUnauthorized, "token revoked")
 		}
+		return nil, nil, utils.NewAppError(http.StatusUnauthorized, "invalid token")
+	}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+	if a.audience != "" && !slices.Contains(claims.Audience, a.audience) {
+		return nil, nil, utils.NewAppError(http.StatusUnauthorized, "invalid token")
+	}
+	if claims.ClientID != "" && !claims.IsClient() && a.audience == "" {
+		return nil, nil, utils.NewAppError(http.StatusUnauthorized, "token was issued to another client")
+	}
 
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			return
+	var user models.User
+	if claims.IsClient() {
+		// a client calling as itself acts within its tenant; global clients
+		// have none to act in
+		if claims.TenantID == "" {
+			return nil, nil, utils.NewAppError(http.StatusForbidden, "clients without a tenant cannot call this API")
+		}
+		user = clientUserFromClaims(claims)
+	} else if a.stateless && claims.Role != "" && (claims.EmailVerified == nil || *claims.EmailVerified) {
+		user = userFromClaims(claims)
+	} else {
+		loaded, err := a.loadUser(parseUUID(claims.UserID))
+		if err != nil {
+			return nil, nil, err
 		}
+		user = *loaded
+	}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid claims"})
-			return
+	return &user, claims, nil
+}
+
+// authenticatePersonalAccessToken resolves the owner of a personal access
+// token. The claims stand in for those of an access token; their scope is
+// the token's, which HasPermission holds requests to.
+func (a *Authenticator) authenticatePersonalAccessToken(tokenStr string) (*models.User, *utils.Claims, error) {
+	if a.tokenService == nil {
+		return nil, nil, utils.NewAppError(http.StatusUnauthorized, "personal access tokens are not accepted here")
+	}
+
+	token, err := a.tokenService.Authenticate(tokenStr)
+	if err != nil {
+		return nil, nil, utils.NewAppError(http.StatusUnauthorized, "invalid token")
+	}
+
+	user, err := a.loadUser(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims := &utils.Claims{
+		UserID:                user.ID.String(),
+		Email:                 user.Email,
+		Role:                  user.Role.Name,
+		Scope:                 strings.Join(token.Scopes, " "),
+		PersonalAccessTokenID: token.ID.String(),
+	}
+	if user.TenantID != nil {
+		claims.TenantID = user.TenantID.String()
+	}
+
+	return user, claims, nil
+}
+
+// loadUser loads a user with their role, as their tenant's email
+// verification policy lets them act.
+func (a *Authenticator) loadUser(userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := a.db.Preload("Role").First(&user, "id =?", userID).Error; err != nil {
+		return nil, utils.NewAppError(http.StatusUnauthorized, "user not found")
+	}
+	if err := a.applyEmailVerificationPolicy(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// applyEmailVerificationPolicy enforces the tenant's policy for users who
+// have not verified their email address. In restrict mode the user acts with
+// the policy's role instead of their own for this request.
+func (a *Authenticator) applyEmailVerificationPolicy(user *models.User) error {
+	if user.EmailVerifiedAt != nil || user.TenantID == nil {
+		return nil
+	}
+
+	var policy models.EmailVerificationPolicy
+	err := a.db.Where("tenant_id = ?", user.TenantID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return utils.NewAppError(http.StatusInternalServerError, "failed to load email verification policy")
+	}
+
+	switch policy.Mode {
+	case utils.EmailVerificationBlock:
+		return utils.NewAppError(http.StatusForbidden, services.ErrEmailNotVerified.Error())
+	case utils.EmailVerificationRestrict:
+		var role models.Role
+		err := a.db.Preload("Permissions").
+			Where("tenant_id = ? AND name = ?", user.TenantID, policy.RestrictedRole).
+			First(&role).Error
+		if err != nil {
+			return utils.NewAppError(http.StatusForbidden, services.ErrEmailNotVerified.Error())
 		}
+		user.Role = role
+		user.RoleID = role.ID.String()
+	}
+
+	return nil
+}
 
-		userId := parseUUID(claims["id"])
+// JWTAuthMiddleware authenticates requests by their bearer access token, or
+// a personal access token unless tokenService is nil, and stores the user
+// and claims in the context, along with the impersonator when the token is
+// an impersonation token. The session an access token was issued in is
+// touched so its last-seen time, IP and user agent stay current;
+// sessionService writes those in batches.
+func JWTAuthMiddleware(db *gorm.DB, authService services.AuthService, sessionService services.SessionService, tokenService services.PersonalAccessTokenService) gin.HandlerFunc {
+	authenticator := NewAuthenticator(db, authService, tokenService)
 
-		var user models.User
-		if err := db.Preload("Role").First(&user, "id =?", userId).Error; err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+	return func(c *gin.Context) {
+		user, claims, err := authenticator.Authenticate(c.GetHeader("Authorization"))
+		if err != nil {
+			status := http.StatusUnauthorized
+			if appError, ok := err.(*utils.AppError); ok {
+				status = appError.Code
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.Set(utils.UserContextKey, user)
+		if claims.SessionID != "" {
+			sessionService.Touch(claims.SessionID, c.ClientIP(), c.Request.UserAgent())
+		}
+
+		c.Set(utils.UserContextKey, *user)
+		c.Set(utils.ClaimsContextKey, claims)
+		if claims.IsImpersonation() {
+			c.Set(utils.ImpersonatorContextKey, claims.Act)
+		}
 		c.Next()
 	}
 }
 
+// userFromClaims builds the request's user from token claims alone. The role
+// carries the permissions from the token so AutoRBAC can decide without a DB
+// round trip when they are present.
+func userFromClaims(claims *utils.Claims) models.User {
+	user := models.User{
+		ID:    parseUUID(claims.UserID),
+		Email: claims.Email,
+		Role:  models.Role{Name: claims.Role},
+	}
+
+	if claims.TenantID != "" {
+		tenantID := parseUUID(claims.TenantID)
+		user.TenantID = &tenantID
+		user.Role.TenantID = &tenantID
+	}
+
+	for _, code := range claims.Permissions {
+		user.Role.Permissions = append(user.Role.Permissions, &models.Permission{Code: code})
+	}
+
+	return user
+}
+
+// clientUserFromClaims stands in for a user when an OAuth client of a tenant
+// calls as itself. Its permissions are the permission codes in the token's scope.
+func clientUserFromClaims(claims *utils.Claims) models.User {
+	tenantID := parseUUID(claims.TenantID)
+	user := models.User{
+		ID:       parseUUID(claims.ClientID),
+		TenantID: &tenantID,
+		Role:     models.Role{Name: utils.RoleClient, TenantID: &tenantID},
+	}
+
+	for _, scope := range strings.Fields(claims.Scope) {
+		if strings.Contains(scope, ":") {
+			user.Role.Permissions = append(user.Role.Permissions, &models.Permission{Code: scope})
+		}
+	}
+
+	return user
+}
+
 func parseUUID(v interface{}) uuid.UUID {
 	str, _ := v.(string)
 	id, _ := uuid.Parse(str)
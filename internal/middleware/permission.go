@@ -3,9 +3,11 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/samvibes/vexop/auth-service/internal/models"
 	"github.com/samvibes/vexop/auth-service/internal/utils"
 	"gorm.io/gorm"
@@ -43,18 +45,40 @@ func requirePermission(action, resource string, db *gorm.DB) gin.HandlerFunc {
 }
 
 func hasPermission(c *gin.Context, action, resource string, db *gorm.DB) bool {
-	user := GetCurrentUser(c)
+	return HasPermission(db, GetCurrentUser(c), utils.GetCurrentClaims(c), action, resource)
+}
+
+// HasPermission reports whether user may perform action on resource. claims
+// may be nil when the user did not come from an access token.
+func HasPermission(db *gorm.DB, user *models.User, claims *utils.Claims, action, resource string) bool {
+	code := fmt.Sprintf("%s:%s", resource, action)
+
+	// a client acting as itself may only do what its token's scope allows
+	if claims != nil && claims.IsClient() {
+		return slices.Contains(strings.Fields(claims.Scope), code)
+	}
+
+	// a personal access token may do what its scopes allow, as long as its
+	// owner's role still does
+	if claims != nil && claims.IsPersonalAccessToken() && !slices.Contains(strings.Fields(claims.Scope), code) {
+		return false
+	}
 
 	if strings.ToLower(user.Role.Name) == "superadmin" {
 		return true
 	}
 
-	// preload permissions if not already done
+	// preload permissions if not already done. A loaded role is kept as is
+	// since it may differ from the user's stored one, e.g. while restricted
+	// by the email verification policy.
 	if len(user.Role.Permissions) == 0 {
-		db.Preload("Role.Permissions").First(&user, "id = ?", user.ID)
+		if user.Role.ID != uuid.Nil {
+			db.Preload("Permissions").First(&user.Role, "id = ?", user.Role.ID)
+		} else {
+			db.Preload("Role.Permissions").First(user, "id = ?", user.ID)
+		}
 	}
 
-	code := fmt.Sprintf("%s:%s", resource, action)
 	for _, p := range user.Role.Permissions {
 		if p.Code == code {
 			return true
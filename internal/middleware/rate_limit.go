@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/ratelimit"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// What a rate limit bucket is shared by
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByUser   = "user"
+	RateLimitByTenant = "tenant"
+)
+
+// RateLimitRule limits the requests of one route group.
+type RateLimitRule struct {
+	Name  string
+	Limit ratelimit.Limit
+	KeyBy string
+}
+
+// defaultRateLimits are the route groups' limits unless RATE_LIMIT_<NAME>
+// and RATE_LIMIT_<NAME>_KEY override them. Login and the other public auth
+// endpoints are kept tight; OAuth endpoints are called by gateways on every
+// request and are not limited unless configured.
+var defaultRateLimits = []struct {
+	name, limit, keyBy string
+}{
+	{"auth", "20/m", RateLimitByIP},
+	{"oauth", "off", RateLimitByIP},
+	{"users", "300/m", RateLimitByUser},
+	{"api", "600/m", RateLimitByUser},
+}
+
+type RateLimitRules map[string]RateLimitRule
+
+// LoadRateLimitRules reads each route group's limit, e.g. RATE_LIMIT_AUTH=10/m,
+// and what it is keyed by, e.g. RATE_LIMIT_USERS_KEY=tenant.
+func LoadRateLimitRules() (RateLimitRules, error) {
+	rules := make(RateLimitRules)
+	for _, d := range defaultRateLimits {
+		env := "RATE_LIMIT_" + strings.ToUpper(d.name)
+
+		value := viper.GetString(env)
+		if value == "" {
+			value = d.limit
+		}
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env, err)
+		}
+
+		keyBy := strings.ToLower(viper.GetString(env + "_KEY"))
+		switch keyBy {
+		case "":
+			keyBy = d.keyBy
+		case RateLimitByIP, RateLimitByUser, RateLimitByTenant:
+		default:
+			return nil, fmt.Errorf("%s_KEY must be ip, user or tenant, not %q", env, keyBy)
+		}
+
+		rules[d.name] = RateLimitRule{Name: d.name, Limit: limit, KeyBy: keyBy}
+	}
+
+	return rules, nil
+}
+
+// RateLimit limits requests with a token bucket per key. Every response gets
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers; refused
+// requests get 429 with Retry-After. Keying by user or tenant needs the user
+// in the context, so the middleware must run after JWTAuthMiddleware;
+// requests without one are keyed by IP. If the store fails the request is let
+// through.
+func RateLimit(store ratelimit.Store, rule RateLimitRule) gin.HandlerFunc {
+	if !rule.Limit.Enabled() {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		result, err := store.Take(rule.Name+":"+rateLimitKey(c, rule.KeyBy), rule.Limit, time.Now())
+		if err != nil {
+			log.Printf("rate limit %s: %v", rule.Name, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+
+		if !result.Allowed {
+			c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func rateLimitKey(c *gin.Context, keyBy string) string {
+	user := utils.GetCurrentUser(c)
+	switch {
+	case keyBy == RateLimitByTenant && user != nil && user.TenantID != nil:
+		return "tenant:" + user.TenantID.String()
+	case keyBy != RateLimitByIP && user != nil:
+		return "user:" + user.ID.String()
+	default:
+		return "ip:" + c.ClientIP()
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// ResourceRule maps requests under PathPrefix to a permission resource. The
+// action comes from the request method unless Actions overrides it. A rule
+// without a resource only requires the caller to be authenticated.
+type ResourceRule struct {
+	PathPrefix string            `json:"path_prefix"`
+	Resource   string            `json:"resource"`
+	Actions    map[string]string `json:"actions,omitempty"`
+}
+
+type ResourceRules []ResourceRule
+
+// LoadResourceRules reads the rules used to authorize requests for other
+// services, from the JSON file at FORWARD_AUTH_RULES_FILE or inline JSON in
+// FORWARD_AUTH_RULES. Neither being set yields no rules.
+func LoadResourceRules() (ResourceRules, error) {
+	data := []byte(viper.GetString("FORWARD_AUTH_RULES"))
+	if path := viper.GetString("FORWARD_AUTH_RULES_FILE"); path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("reading forward auth rules: %w", err)
+		}
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+
+	var rules ResourceRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parsing forward auth rules: %w", err)
+	}
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule.PathPrefix, "/") {
+			return nil, fmt.Errorf("forward auth rule path_prefix %q must start with /", rule.PathPrefix)
+		}
+	}
+
+	return rules, nil
+}
+
+// Match finds the rule with the longest prefix matching path on a segment
+// boundary and returns the action and resource it requires.
+func (r ResourceRules) Match(method, path string) (action, resource string, ok bool) {
+	var best *ResourceRule
+	for i := range r {
+		rule := &r[i]
+		prefix := strings.TrimSuffix(rule.PathPrefix, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if best == nil || len(rule.PathPrefix) > len(best.PathPrefix) {
+			best = rule
+		}
+	}
+	if best == nil {
+		return "", "", false
+	}
+
+	action = utils.MethodToAction[method]
+	if override, found := best.Actions[method]; found {
+		action = override
+	}
+
+	return action, best.Resource, true
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+// TrackSessions records the IP and user agent a session was started or
+// refreshed from. Handlers issuing tokens put the session in the context.
+func TrackSessions(sessionService services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if session_id := c.GetString(utils.SessionContextKey); session_id != "" {
+			sessionService.Touch(session_id, c.ClientIP(), c.Request.UserAgent())
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	serviceMock "github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAuthMiddleware_StatelessBuildsUserFromClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_STATELESS", true)
+	t.Cleanup(func() { viper.Set("JWT_STATELESS", false) })
+
+	tenantID := uuid.New()
+	claims := &utils.Claims{
+		UserID:      uuid.NewString(),
+		TenantID:    tenantID.String(),
+		Role:        "admin",
+		Permissions: []string{"user:read"},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID: "jti",
+		},
+	}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+
+	router := gin.New()
+	// no DB: a lookup would panic
+	router.Use(middleware.JWTAuthMiddleware(nil, mockAuthService, &serviceMock.MockSessionService{}, nil))
+	router.Use(middleware.AutoRBAC(nil))
+	router.GET("/api/users", func(c *gin.Context) {
+		user := utils.GetCurrentUser(c)
+		require.NotNil(t, user)
+		assert.Equal(t, claims.UserID, user.ID.String())
+		assert.Equal(t, tenantID, *user.TenantID)
+		assert.Equal(t, "admin", user.Role.Name)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestJWTAuthMiddleware_RejectsOtherAudience(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_AUDIENCE", "vexop")
+	t.Cleanup(func() { viper.Set("JWT_AUDIENCE", "") })
+
+	claims := &utils.Claims{
+		UserID: uuid.NewString(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience: jwt.ClaimStrings{"billing"},
+		},
+	}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuthMiddleware(nil, mockAuthService, &serviceMock.MockSessionService{}, nil))
+	router.GET("/api/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestJWTAuthMiddleware_RejectsTokenIssuedToClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	claims := &utils.Claims{
+		UserID:      uuid.NewString(),
+		Role:        "admin",
+		Permissions: []string{"user:read"},
+		ClientID:    "spa",
+		Scope:       "openid email",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience: jwt.ClaimStrings{"spa"},
+		},
+	}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuthMiddleware(nil, mockAuthService, &serviceMock.MockSessionService{}, nil))
+	router.GET("/api/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAutoRBAC_ClientScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	claims := &utils.Claims{
+		ClientID: uuid.NewString(),
+		TenantID: uuid.NewString(),
+		Scope:    "user:read",
+	}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuthMiddleware(nil, mockAuthService, &serviceMock.MockSessionService{}, nil))
+	router.Use(middleware.AutoRBAC(nil))
+	router.GET("/api/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.DELETE("/api/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for method, expected := range map[string]int{
+		http.MethodGet:    http.StatusOK,
+		http.MethodDelete: http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(method, "/api/users", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Code, method)
+	}
+}
+
+func TestJWTAuthMiddleware_TouchesSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_STATELESS", true)
+	t.Cleanup(func() { viper.Set("JWT_STATELESS", false) })
+
+	claims := &utils.Claims{
+		UserID:    uuid.NewString(),
+		Role:      "member",
+		SessionID: uuid.NewString(),
+	}
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "token").Return(claims, nil)
+	mockSessionService := new(serviceMock.MockSessionService)
+	mockSessionService.On("Touch", claims.SessionID, "192.0.2.1", "test-agent").Return()
+
+	router := gin.New()
+	router.Use(middleware.JWTAuthMiddleware(nil, mockAuthService, mockSessionService, nil))
+	router.GET("/api/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("User-Agent", "test-agent")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockSessionService.AssertExpectations(t)
+}
+
+func TestHasPermission_PersonalAccessTokenScopes(t *testing.T) {
+	user := &models.User{
+		ID: uuid.New(),
+		Role: models.Role{
+			ID:          uuid.New(),
+			Name:        "admin",
+			Permissions: []*models.Permission{{Code: "user:read"}, {Code: "user:delete"}},
+		},
+	}
+	claims := &utils.Claims{
+		UserID:                user.ID.String(),
+		Scope:                 "user:read invite:read",
+		PersonalAccessTokenID: uuid.NewString(),
+	}
+
+	assert.True(t, middleware.HasPermission(nil, user, claims, "read", "user"))
+	// not among the token's scopes
+	assert.False(t, middleware.HasPermission(nil, user, claims, "delete", "user"))
+	// among the scopes, but no longer allowed to the owner
+	assert.False(t, middleware.HasPermission(nil, user, claims, "read", "invite"))
+}
+
+func TestJWTAuthMiddleware_PersonalAccessTokenNotAccepted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuthMiddleware(nil, new(serviceMock.MockAuthService), &serviceMock.MockSessionService{}, nil))
+	router.GET("/api/auth/access-tokens", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/access-tokens", nil)
+	req.Header.Set("Authorization", "Bearer "+utils.PersonalAccessTokenPrefix+"secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_STATELESS", true)
+	t.Cleanup(func() { viper.Set("JWT_STATELESS", false) })
+
+	impersonatorID := uuid.NewString()
+	mockAuthService := new(serviceMock.MockAuthService)
+	mockAuthService.On("ValidateAccessToken", "user-token").Return(&utils.Claims{UserID: uuid.NewString(), Role: "member"}, nil)
+	mockAuthService.On("ValidateAccessToken", "impersonation-token").Return(&utils.Claims{
+		UserID: uuid.NewString(),
+		Role:   "member",
+		Act:    &utils.ActorClaim{Subject: impersonatorID},
+	}, nil)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuthMiddleware(nil, mockAuthService, new(serviceMock.MockSessionService), nil))
+	router.GET("/api/auth/sessions", func(c *gin.Context) {
+		impersonator := utils.GetImpersonator(c)
+		require.NotNil(t, impersonator)
+		assert.Equal(t, impersonatorID, impersonator.Subject)
+		c.Status(http.StatusOK)
+	})
+	router.POST("/api/auth/mfa/totp", middleware.DenyImpersonation(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/auth/sessions", "impersonation-token"))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/auth/mfa/totp", "impersonation-token"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/auth/mfa/totp", "user-token"))
+}
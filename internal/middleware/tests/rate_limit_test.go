@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/ratelimit"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rateLimitRouter(rule middleware.RateLimitRule) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RateLimit(ratelimit.NewMemoryStore(), rule))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	return router
+}
+
+func TestRateLimit_RefusesWithHeaders(t *testing.T) {
+	router := rateLimitRouter(middleware.RateLimitRule{
+		Name:  "auth",
+		Limit: ratelimit.Limit{Rate: 1.0 / 60, Burst: 2},
+		KeyBy: middleware.RateLimitByIP,
+	})
+
+	codes := []int{}
+	var last *httptest.ResponseRecorder
+	for range 3 {
+		last = httptest.NewRecorder()
+		router.ServeHTTP(last, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes = append(codes, last.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+	assert.Equal(t, "2", last.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", last.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "120", last.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "60", last.Header().Get("Retry-After"))
+}
+
+func TestRateLimit_KeysByTenant(t *testing.T) {
+	tenantID := uuid.New()
+	rule := middleware.RateLimitRule{Name: "users", Limit: ratelimit.Limit{Rate: 0.01, Burst: 1}, KeyBy: middleware.RateLimitByTenant}
+	store := ratelimit.NewMemoryStore()
+	gin.SetMode(gin.TestMode)
+
+	serve := func(user models.User) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set(utils.UserContextKey, user) })
+		router.Use(middleware.RateLimit(store, rule))
+		router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(models.User{ID: uuid.New(), TenantID: &tenantID}))
+	// another member of the same tenant shares the bucket
+	assert.Equal(t, http.StatusTooManyRequests, serve(models.User{ID: uuid.New(), TenantID: &tenantID}))
+
+	otherTenant := uuid.New()
+	assert.Equal(t, http.StatusOK, serve(models.User{ID: uuid.New(), TenantID: &otherTenant}))
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	router := rateLimitRouter(middleware.RateLimitRule{Name: "oauth"})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
+
+func TestLoadRateLimitRules(t *testing.T) {
+	viper.Set("RATE_LIMIT_AUTH", "5/s")
+	viper.Set("RATE_LIMIT_USERS_KEY", "tenant")
+	t.Cleanup(func() {
+		viper.Set("RATE_LIMIT_AUTH", "")
+		viper.Set("RATE_LIMIT_USERS_KEY", "")
+	})
+
+	rules, err := middleware.LoadRateLimitRules()
+	require.NoError(t, err)
+	assert.Equal(t, 5, rules["auth"].Limit.Burst)
+	assert.Equal(t, middleware.RateLimitByIP, rules["auth"].KeyBy)
+	assert.Equal(t, middleware.RateLimitByTenant, rules["users"].KeyBy)
+	assert.False(t, rules["oauth"].Limit.Enabled())
+
+	viper.Set("RATE_LIMIT_USERS_KEY", "session")
+	_, err = middleware.LoadRateLimitRules()
+	assert.Error(t, err)
+}
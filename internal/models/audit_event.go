@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent records a security relevant action. ActorID is who performed it
+// and UserID whose account it concerns; either is empty when not known, e.g.
+// for failed logins to an address without an account.
+type AuditEvent struct {
+	ID       uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TenantID *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+	ActorID  *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"`
+	UserID   *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	Event    string     `gorm:"not null;index" json:"event"`
+	IP       string     `json:"ip,omitempty"`
+	Detail   string     `json:"detail,omitempty"`
+
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuthorizationCode is a single-use code handed to a client at the end of the
+// authorization step. Only its hash is stored.
+type AuthorizationCode struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CodeHash      string    `gorm:"uniqueIndex;not null"`
+	ClientID      string    `gorm:"not null;index"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null"`
+	RedirectURI   string    `gorm:"not null"`
+	Scope         string    `gorm:"not null"`
+	Nonce         string
+	CodeChallenge string    `gorm:"not null"`
+	AuthTime      time.Time `gorm:"not null"`
+	ExpiresAt     time.Time `gorm:"not null;index"`
+	UsedAt        *time.Time
+
+	CreatedAt time.Time
+}
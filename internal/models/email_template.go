@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailTemplate is a tenant's replacement for one of the built-in email
+// templates.
+type EmailTemplate struct {
+	ID       uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"-"`
+	TenantID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_email_template_tenant_name" json:"tenant_id"`
+	Name     string    `gorm:"not null;uniqueIndex:idx_email_template_tenant_name" json:"name"`
+	Subject  string    `gorm:"not null" json:"subject"`
+	HTML     string    `gorm:"not null" json:"html"`
+	Text     string    `json:"text"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailVerificationPolicy decides what users of a tenant can do before they
+// verify their email address: everything ("off"), nothing ("block"), or only
+// what RestrictedRole allows ("restrict").
+type EmailVerificationPolicy struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"-"`
+	TenantID       uuid.UUID `gorm:"type:uuid;uniqueIndex;not null" json:"tenant_id"`
+	Mode           string    `gorm:"not null;default:off" json:"mode"`
+	RestrictedRole string    `json:"restricted_role,omitempty"`
+
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
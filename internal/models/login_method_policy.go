@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginMethodPolicy controls which passwordless login methods a tenant's
+// users may use. Both are off until the tenant turns them on.
+type LoginMethodPolicy struct {
+	ID               uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"-"`
+	TenantID         uuid.UUID `gorm:"type:uuid;uniqueIndex;not null" json:"tenant_id"`
+	MagicLinkEnabled bool      `gorm:"not null;default:false" json:"magic_link_enabled"`
+	EmailOTPEnabled  bool      `gorm:"not null;default:false" json:"email_otp_enabled"`
+
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
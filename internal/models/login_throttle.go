@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginThrottle counts failed logins for one key, an account or a client IP
+// address, when lockout counters are kept in Postgres.
+type LoginThrottle struct {
+	Key           string    `gorm:"primaryKey"`
+	Failures      int       `gorm:"not null"`
+	LastFailureAt time.Time `gorm:"index"`
+	LockedUntil   *time.Time
+}
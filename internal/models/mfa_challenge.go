@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFAChallenge is handed out after a correct password when a second factor is
+// needed, and exchanged for tokens together with a valid code. Only its hash
+// is stored.
+type MFAChallenge struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+
+	CreatedAt time.Time
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFAPolicy makes a second factor mandatory in a tenant, either for everyone
+// or for users with one of RequiredRoles.
+type MFAPolicy struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TenantID      uuid.UUID `gorm:"type:uuid;uniqueIndex;not null" json:"tenant_id"`
+	Required      bool      `gorm:"not null;default:false" json:"required"`
+	RequiredRoles []string  `gorm:"serializer:json" json:"required_roles"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OAuthClient is an application registered to sign users in through the OIDC
+// endpoints or, for confidential clients, to call APIs as itself with the
+// client credentials grant. Public clients have no secret and must use PKCE. A
+// client bound to a tenant only accepts that tenant's users and its own tokens
+// are scoped to that tenant. After a secret rotation the previous secret keeps
+// working until PreviousSecretExpiresAt.
+type OAuthClient struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ClientID         string     `gorm:"uniqueIndex;not null" json:"client_id"`
+	Name             string     `gorm:"not null" json:"name"`
+	TenantID         *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id"`
+	ClientSecretHash string     `json:"-"`
+	Public           bool       `gorm:"not null;default:false" json:"public"`
+	RedirectURIs     []string   `gorm:"serializer:json" json:"redirect_uris"`
+	Scopes           []string   `gorm:"serializer:json" json:"scopes"`
+	GrantTypes       []string   `gorm:"serializer:json" json:"grant_types"`
+
+	PreviousSecretHash      string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"-"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
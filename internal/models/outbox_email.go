@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEmail is an email waiting to be sent. It is written in the same
+// transaction as the change it reports, so nothing is sent for a change that
+// rolled back. Bodies are cleared once sent since they can carry login links.
+type OutboxEmail struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID      *uuid.UUID `gorm:"type:uuid"`
+	Template      string     `gorm:"not null"`
+	To            string     `gorm:"not null"`
+	Subject       string     `gorm:"not null"`
+	HTMLBody      string
+	TextBody      string
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index"`
+	LastError     string
+	SentAt        *time.Time
+	FailedAt      *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
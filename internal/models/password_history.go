@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordHistory is a hash of a password a user had before, kept to stop
+// recent passwords from being reused.
+type PasswordHistory struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	PasswordHash string    `gorm:"not null"`
+
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordPolicy is the set of rules a tenant's passwords must follow. Tenants
+// without one use the service defaults. HistorySize is how many previous
+// passwords, the current one included, may not be reused, and MaxAgeDays how
+// long a password may be used before it has to be changed; 0 turns either
+// rule off. CheckBreached rejects passwords found in the breached password
+// filter, when one is loaded.
+type PasswordPolicy struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"-"`
+	TenantID      uuid.UUID `gorm:"type:uuid;uniqueIndex;not null" json:"tenant_id"`
+	MinLength     int       `gorm:"not null" json:"min_length"`
+	MaxLength     int       `gorm:"not null" json:"max_length"`
+	RequireUpper  bool      `gorm:"not null" json:"require_upper"`
+	RequireLower  bool      `gorm:"not null" json:"require_lower"`
+	RequireDigit  bool      `gorm:"not null" json:"require_digit"`
+	RequireSymbol bool      `gorm:"not null" json:"require_symbol"`
+	DisallowEmail bool      `gorm:"not null" json:"disallow_email"`
+	HistorySize   int       `gorm:"not null" json:"history_size"`
+	MaxAgeDays    int       `gorm:"not null" json:"max_age_days"`
+	CheckBreached bool      `gorm:"not null;default:false" json:"check_breached"`
+
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RateLimitBucket is a token bucket of the rate limiter when buckets are kept
+// in Postgres. Buckets are deleted once FullAt has passed.
+type RateLimitBucket struct {
+	Key        string    `gorm:"primaryKey"`
+	Tokens     float64   `gorm:"not null"`
+	RefilledAt time.Time `gorm:"not null"`
+	FullAt     time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login on one device. It shares its id with the refresh token
+// family it was started with, and access tokens carry it as their sid claim
+// so revoking the session rejects them too. IP and UserAgent are those the
+// session was last seen with.
+type Session struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TenantID   *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id"`
+	ClientID   string     `gorm:"not null;default:''" json:"client_id"`
+	IP         string     `gorm:"not null;default:''" json:"ip"`
+	UserAgent  string     `gorm:"not null;default:''" json:"user_agent"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SigningKey is a token signing key. Keys move from next (published, not yet
+// used) to active (signing) to retired (verification only) and are deleted
+// once every token they signed has expired.
+type SigningKey struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	KID                 string     `gorm:"uniqueIndex;not null" json:"kid"`
+	Algorithm           string     `gorm:"not null" json:"algorithm"`
+	State               string     `gorm:"not null;index" json:"state"`
+	EncryptedPrivateKey string     `gorm:"not null" json:"-"`
+	ActivatedAt         *time.Time `json:"activated_at"`
+	RetiredAt           *time.Time `json:"retired_at"`
+	ExpiresAt           *time.Time `gorm:"index" json:"expires_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
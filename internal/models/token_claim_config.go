@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenClaimConfig controls which optional claims go into access tokens. A nil
+// TenantID applies to all tenants and an empty Audience to all audiences; the
+// most specific match wins.
+type TokenClaimConfig struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TenantID           *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id"`
+	Audience           string     `gorm:"not null;default:''" json:"audience"`
+	IncludeTenant      bool       `gorm:"not null" json:"include_tenant"`
+	IncludeRole        bool       `gorm:"not null" json:"include_role"`
+	IncludePermissions bool       `gorm:"not null" json:"include_permissions"`
+	IncludeEmail       bool       `gorm:"not null" json:"include_email"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
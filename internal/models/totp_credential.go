@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TOTPCredential is a user's authenticator app secret, encrypted at rest. It
+// only counts as a second factor once ConfirmedAt is set.
+type TOTPCredential struct {
+	ID              uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID          uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"`
+	EncryptedSecret string    `gorm:"not null"`
+	ConfirmedAt     *time.Time
+	// the time step of the last accepted code, so codes cannot be replayed
+	LastUsedStep int64 `gorm:"not null;default:0"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -7,15 +7,23 @@ import (
 	"gorm.io/gorm"
 )
 
+// User is a member of a tenant. Service accounts are users too, so they take
+// a role and pass through AutoRBAC the same way, but they have no password
+// and only authenticate with their personal access tokens. Their email is a
+// name under utils.ServiceAccountDomain, which nothing can be sent to.
 type User struct {
 	ID                     uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	TenantID               *uuid.UUID `gorm:"type:uuid"`
 	Email                  string     `gorm:"uniqueIndex:idx_email;not null" json:"email"`
 	PasswordHash           string     `gorm:"not null" json:"-"`
+	PasswordChangedAt      *time.Time `json:"password_changed_at,omitempty"`
 	RoleID                 string     `json:"role_id"`
 	Role                   Role       `gorm:"foreignKey:RoleID" json:"role"`
 	IsOwner                bool       `gorm:"not null;default:false" json:"is_owner"`
-	ResetPasswordTokenHash string     `json:"-"`
+	IsServiceAccount       bool       `gorm:"not null;default:false;index" json:"is_service_account"`
+	EmailVerifiedAt        *time.Time `json:"email_verified_at"`
+	ResetPasswordTokenHash string     `gorm:"index" json:"-"`
+	ResetPasswordExpiresAt *time.Time `json:"-"`
 
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebAuthnSession holds the challenge of a registration or login ceremony
+// between its begin and finish steps. Only the hash of the session id handed
+// to the client is stored.
+type WebAuthnSession struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TokenHash string     `gorm:"uniqueIndex;not null"`
+	Purpose   string     `gorm:"not null"`
+	UserID    *uuid.UUID `gorm:"type:uuid"`
+	Data      string     `gorm:"not null"`
+	ExpiresAt time.Time  `gorm:"not null;index"`
+
+	CreatedAt time.Time
+}
@@ -0,0 +1,180 @@
+package passhash
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Scheme is one way of hashing passwords, identified by the prefix of the
+// PHC string format ($<id>$...) its hashes are stored in.
+type Scheme interface {
+	// Identifies reports whether encoded was made with this scheme.
+	Identifies(encoded string) bool
+	// Verify reports whether password matches encoded. It fails for hashes
+	// it cannot parse.
+	Verify(password, encoded string) (bool, error)
+}
+
+// Hasher is a scheme new password hashes can be made with.
+type Hasher interface {
+	Scheme
+	Hash(password string) (string, error)
+	// Outdated reports whether encoded, made with this scheme, used other
+	// parameters than the hasher is configured with.
+	Outdated(encoded string) bool
+}
+
+// Passwords hashes new passwords with the current hasher and verifies
+// hashes made with any known scheme, so stored hashes can be moved to a
+// new algorithm or cost as users log in.
+type Passwords struct {
+	current Hasher
+	schemes []Scheme
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+// NewPasswords returns Passwords hashing with current and also verifying
+// the other schemes.
+func NewPasswords(current Hasher, others ...Scheme) *Passwords {
+	return &Passwords{current: current, schemes: append([]Scheme{current}, others...)}
+}
+
+// New returns Passwords hashing with PASSWORD_HASHER, "argon2id" (the
+// default) or "bcrypt", and verifying argon2id, bcrypt, scrypt and PBKDF2
+// hashes. Argon2id is tuned with ARGON2_MEMORY (in KiB), ARGON2_ITERATIONS
+// and ARGON2_PARALLELISM, bcrypt with BCRYPT_COST.
+func New() (*Passwords, error) {
+	argon := &Argon2id{
+		Memory:      uint32(utils.GetInt("ARGON2_MEMORY", 19*1024)),
+		Iterations:  uint32(utils.GetInt("ARGON2_ITERATIONS", 2)),
+		Parallelism: uint8(utils.GetInt("ARGON2_PARALLELISM", 1)),
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	bcryptHasher := &Bcrypt{Cost: utils.GetInt("BCRYPT_COST", bcrypt.DefaultCost)}
+	if bcryptHasher.Cost < bcrypt.MinCost || bcryptHasher.Cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if argon.Memory == 0 || argon.Iterations == 0 || argon.Parallelism == 0 {
+		return nil, fmt.Errorf("argon2id parameters must be positive")
+	}
+
+	switch hasher := strings.ToLower(viper.GetString("PASSWORD_HASHER")); hasher {
+	case "", "argon2id":
+		return NewPasswords(argon, bcryptHasher, Scrypt{}, PBKDF2{}), nil
+	case "bcrypt":
+		return NewPasswords(bcryptHasher, argon, Scrypt{}, PBKDF2{}), nil
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASHER %q", hasher)
+	}
+}
+
+// Hash hashes password with the current hasher.
+func (p *Passwords) Hash(password string) (string, error) {
+	return p.current.Hash(password)
+}
+
+// Verify reports whether password matches encoded. An empty or unknown
+// hash never matches, but takes as long to reject as a real one, so
+// callers cannot be told apart by timing whether an account exists.
+func (p *Passwords) Verify(password, encoded string) bool {
+	scheme := p.scheme(encoded)
+	if scheme == nil {
+		p.current.Verify(password, p.dummyHash())
+		return false
+	}
+
+	ok, err := scheme.Verify(password, encoded)
+	return err == nil && ok
+}
+
+// NeedsRehash reports whether encoded should be replaced with a hash from
+// the current hasher, because it uses another scheme or other parameters.
+func (p *Passwords) NeedsRehash(encoded string) bool {
+	return !p.current.Identifies(encoded) || p.current.Outdated(encoded)
+}
+
+// Supports reports whether encoded is a hash of a known scheme, such as one
+// imported from another system.
+func (p *Passwords) Supports(encoded string) bool {
+	return p.scheme(encoded) != nil
+}
+
+func (p *Passwords) scheme(encoded string) Scheme {
+	for _, scheme := range p.schemes {
+		if scheme.Identifies(encoded) {
+			return scheme
+		}
+	}
+	return nil
+}
+
+func (p *Passwords) dummyHash() string {
+	p.dummyOnce.Do(func() {
+		p.dummy, _ = p.current.Hash("dummy password")
+	})
+	return p.dummy
+}
+
+// phcFields splits a PHC string "$id$params$salt$hash" into its fields,
+// leaving out the "v=" version field some schemes add.
+func phcFields(encoded, id string) (params map[string]string, salt, hash []byte, err error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) > 2 && strings.HasPrefix(fields[2], "v=") {
+		fields = append(fields[:2], fields[3:]...)
+	}
+	if len(fields) != 5 || fields[0] != "" || fields[1] != id {
+		return nil, nil, nil, fmt.Errorf("invalid %s hash", id)
+	}
+
+	params = make(map[string]string)
+	for _, param := range strings.Split(fields[2], ",") {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("invalid %s parameter %q", id, param)
+		}
+		params[key] = value
+	}
+
+	if salt, err = decodeBase64(fields[3]); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid %s salt: %w", id, err)
+	}
+	if hash, err = decodeBase64(fields[4]); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid %s hash: %w", id, err)
+	}
+	// an empty key would match any password
+	if len(hash) == 0 {
+		return nil, nil, nil, fmt.Errorf("invalid %s hash: empty key", id)
+	}
+
+	return params, salt, hash, nil
+}
+
+// decodeBase64 decodes the unpadded base64 PHC strings use. It also takes
+// padding and the "." for "+" of passlib's variant, which exported hashes
+// often come in.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.ReplaceAll(s, ".", "+"), "=")
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+func randomSalt(n uint32) ([]byte, error) {
+	salt := make([]byte, n)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
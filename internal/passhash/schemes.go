@@ -0,0 +1,178 @@
+package passhash
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Argon2id hashes passwords as
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func (a *Argon2id) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt, err := randomSalt(a.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Iterations, a.Parallelism, encodeBase64(salt), encodeBase64(key)), nil
+}
+
+func (a *Argon2id) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := a.parse(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	derived := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(derived, key) == 1, nil
+}
+
+func (a *Argon2id) Outdated(encoded string) bool {
+	params, salt, key, err := a.parse(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != a.Memory || params.Iterations != a.Iterations || params.Parallelism != a.Parallelism ||
+		uint32(len(salt)) != a.SaltLength || uint32(len(key)) != a.KeyLength
+}
+
+func (a *Argon2id) parse(encoded string) (*Argon2id, []byte, []byte, error) {
+	if !strings.HasPrefix(encoded, fmt.Sprintf("$argon2id$v=%d$", argon2.Version)) {
+		return nil, nil, nil, errors.New("unsupported argon2id version")
+	}
+	fields, salt, key, err := phcFields(encoded, "argon2id")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	memory, errM := strconv.ParseUint(fields["m"], 10, 32)
+	iterations, errT := strconv.ParseUint(fields["t"], 10, 32)
+	parallelism, errP := strconv.ParseUint(fields["p"], 10, 8)
+	if errM != nil || errT != nil || errP != nil || memory == 0 || iterations == 0 || parallelism == 0 {
+		return nil, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	return &Argon2id{Memory: uint32(memory), Iterations: uint32(iterations), Parallelism: uint8(parallelism)}, salt, key, nil
+}
+
+// Bcrypt hashes passwords in bcrypt's own $2a$<cost>$... format, which
+// predates PHC strings but follows the same layout.
+type Bcrypt struct {
+	Cost int
+}
+
+func (b *Bcrypt) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b *Bcrypt) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(hashed), err
+}
+
+func (b *Bcrypt) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *Bcrypt) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
+}
+
+// Scrypt verifies hashes imported from other systems, in the PHC format
+// $scrypt$ln=<log2 N>,r=<block size>,p=<parallelism>$<salt>$<key>.
+type Scrypt struct{}
+
+func (Scrypt) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$scrypt$")
+}
+
+func (Scrypt) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := phcFields(encoded, "scrypt")
+	if err != nil {
+		return false, err
+	}
+
+	ln, errN := strconv.Atoi(params["ln"])
+	r, errR := strconv.Atoi(params["r"])
+	p, errP := strconv.Atoi(params["p"])
+	if errN != nil || errR != nil || errP != nil || ln < 1 || ln > 20 {
+		return false, errors.New("invalid scrypt parameters")
+	}
+
+	derived, err := scrypt.Key([]byte(password), salt, 1<<ln, r, p, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(derived, key) == 1, nil
+}
+
+// PBKDF2 verifies hashes imported from other systems, in the PHC format
+// $pbkdf2-<sha1|sha256|sha512>$i=<iterations>$<salt>$<key>, or passlib's
+// $pbkdf2-<digest>$<iterations>$<salt>$<key>.
+type PBKDF2 struct{}
+
+var pbkdf2Digests = map[string]func() hash.Hash{
+	"pbkdf2-sha1":   sha1.New,
+	"pbkdf2-sha256": sha256.New,
+	"pbkdf2-sha512": sha512.New,
+}
+
+func (PBKDF2) Identifies(encoded string) bool {
+	id, _, _ := strings.Cut(strings.TrimPrefix(encoded, "$"), "$")
+	return strings.HasPrefix(encoded, "$") && pbkdf2Digests[id] != nil
+}
+
+func (PBKDF2) Verify(password, encoded string) (bool, error) {
+	id, _, _ := strings.Cut(strings.TrimPrefix(encoded, "$"), "$")
+	digest, ok := pbkdf2Digests[id]
+	if !ok {
+		return false, errors.New("unsupported pbkdf2 digest")
+	}
+
+	fields := strings.Split(encoded, "$")
+	if len(fields) > 2 && !strings.Contains(fields[2], "=") {
+		fields[2] = "i=" + fields[2]
+	}
+	params, salt, key, err := phcFields(strings.Join(fields, "$"), id)
+	if err != nil {
+		return false, err
+	}
+	iterations, err := strconv.Atoi(params["i"])
+	if err != nil || iterations < 1 {
+		return false, errors.New("invalid pbkdf2 iterations")
+	}
+
+	derived := pbkdf2.Key([]byte(password), salt, iterations, len(key), digest)
+	return subtle.ConstantTimeCompare(derived, key) == 1, nil
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/samvibes/vexop/auth-service/internal/passhash"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters keep the tests fast
+func testArgon2id() *passhash.Argon2id {
+	return &passhash.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestArgon2id_HashAndVerify(t *testing.T) {
+	passwords := passhash.NewPasswords(testArgon2id())
+
+	encoded, err := passwords.Hash("hunter2")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, passwords.Verify("hunter2", encoded))
+	assert.False(t, passwords.Verify("hunter3", encoded))
+	assert.False(t, passwords.NeedsRehash(encoded))
+}
+
+func TestArgon2id_SaltsEveryHash(t *testing.T) {
+	passwords := passhash.NewPasswords(testArgon2id())
+
+	first, err := passwords.Hash("hunter2")
+	require.NoError(t, err)
+	second, err := passwords.Hash("hunter2")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+}
+
+func TestNeedsRehash_ParametersChanged(t *testing.T) {
+	old := passhash.NewPasswords(testArgon2id())
+	encoded, err := old.Hash("hunter2")
+	require.NoError(t, err)
+
+	stronger := testArgon2id()
+	stronger.Iterations = 2
+	passwords := passhash.NewPasswords(stronger)
+
+	assert.True(t, passwords.Verify("hunter2", encoded))
+	assert.True(t, passwords.NeedsRehash(encoded))
+}
+
+func TestNeedsRehash_BcryptToArgon2id(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	require.NoError(t, err)
+	passwords := passhash.NewPasswords(testArgon2id(), &passhash.Bcrypt{Cost: bcrypt.MinCost})
+
+	assert.True(t, passwords.Verify("hunter2", string(hashed)))
+	assert.True(t, passwords.NeedsRehash(string(hashed)))
+}
+
+func TestNeedsRehash_BcryptCost(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	assert.False(t, passhash.NewPasswords(&passhash.Bcrypt{Cost: bcrypt.MinCost}).NeedsRehash(string(hashed)))
+	assert.True(t, passhash.NewPasswords(&passhash.Bcrypt{Cost: bcrypt.MinCost + 1}).NeedsRehash(string(hashed)))
+}
+
+// the imported hashes below were made with Python's hashlib
+func TestVerify_ImportedHashes(t *testing.T) {
+	passwords := passhash.NewPasswords(testArgon2id(), passhash.Scrypt{}, passhash.PBKDF2{})
+
+	for _, encoded := range []string{
+		"$scrypt$ln=10,r=8,p=1$c2FsdHNhbHRzYWx0MTIzNA$iHACWgdfYHNZHUpp6sPLZoVnZyxr779E9OA1gAabx3w",
+		"$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0MTIzNA$fIKZdZ7B5XJPx7My3DohkKloWvdeNcMNXs3u1sIBy+Q",
+		// passlib's variant, with a bare iteration count and "." for "+"
+		"$pbkdf2-sha1$1000$c2FsdHNhbHRzYWx0MTIzNA$yHoIN2j9V/PLz0l.HmEEIAAHeuY",
+	} {
+		assert.True(t, passwords.Supports(encoded), encoded)
+		assert.True(t, passwords.Verify("hunter2", encoded), encoded)
+		assert.False(t, passwords.Verify("hunter3", encoded), encoded)
+		assert.True(t, passwords.NeedsRehash(encoded), encoded)
+	}
+}
+
+func TestVerify_RejectsUnknownAndMalformedHashes(t *testing.T) {
+	passwords := passhash.NewPasswords(testArgon2id(), passhash.Scrypt{}, passhash.PBKDF2{})
+
+	for _, encoded := range []string{
+		"",
+		"hunter2",
+		"$md5$abc",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+		"$scrypt$ln=10,r=8,p=1$c2FsdA",
+		"$pbkdf2-sha256$i=0$c2FsdA$fIKZdZ7B5XJPx7My3DohkKloWvdeNcMNXs3u1sIBy+Q",
+	} {
+		assert.False(t, passwords.Verify("hunter2", encoded), encoded)
+	}
+	assert.False(t, passwords.Supports(""))
+	assert.False(t, passwords.Supports("$md5$abc"))
+}
+
+func TestNew_UnknownHasher(t *testing.T) {
+	viper.Set("PASSWORD_HASHER", "md5")
+	t.Cleanup(func() { viper.Set("PASSWORD_HASHER", "") })
+
+	_, err := passhash.New()
+
+	assert.Error(t, err)
+}
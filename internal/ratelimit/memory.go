@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often stores forget buckets that have refilled.
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	bucket Bucket
+	fullAt time.Time
+}
+
+// MemoryStore keeps buckets in process memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	sweptAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.sweptAt) > sweepInterval {
+		for k, entry := range s.entries {
+			if entry.fullAt.Before(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.sweptAt = now
+	}
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+
+	result := entry.bucket.Take(limit, now)
+	entry.fullAt = entry.bucket.FullAt(limit)
+
+	return result, nil
+}
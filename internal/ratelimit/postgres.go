@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore shares buckets between instances through the
+// rate_limit_buckets table. Each take locks the bucket's row, so concurrent
+// requests on different instances never spend the same token.
+type PostgresStore struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	sweptAt time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	if err := s.maybeSweep(now); err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// a missing bucket is a full one
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RateLimitBucket{
+			Key:        key,
+			Tokens:     float64(limit.Burst),
+			RefilledAt: now,
+			FullAt:     now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var row models.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&row).Error; err != nil {
+			return err
+		}
+
+		bucket := Bucket{Tokens: row.Tokens, RefilledAt: row.RefilledAt}
+		result = bucket.Take(limit, now)
+
+		return tx.Model(&models.RateLimitBucket{}).Where("key = ?", key).Updates(map[string]any{
+			"tokens":      bucket.Tokens,
+			"refilled_at": bucket.RefilledAt,
+			"full_at":     bucket.FullAt(limit),
+		}).Error
+	})
+
+	return result, err
+}
+
+// maybeSweep deletes buckets that have refilled, at most once per
+// sweepInterval per instance.
+func (s *PostgresStore) maybeSweep(now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.sweptAt) <= sweepInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.sweptAt = now
+	s.mu.Unlock()
+
+	return s.db.Where("full_at < ?", now).Delete(&models.RateLimitBucket{}).Error
+}
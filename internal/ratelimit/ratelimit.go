@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Limit is a token bucket: it holds up to Burst requests and refills at Rate
+// requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit reads limits such as "20/m": 20 requests a minute, all of which
+// may be used at once. The units are s, m and h. An empty string or "off"
+// is the zero Limit, which disables limiting.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+
+	count, unit, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", s)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", s)
+	}
+
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit unit in %q", s)
+	}
+
+	return Limit{Rate: float64(n) / per.Seconds(), Burst: n}, nil
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Burst > 0 && l.Rate > 0
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket size and Remaining the whole tokens left in it.
+	Limit     int
+	Remaining int
+	// ResetAfter is how long until the bucket is full again and RetryAfter,
+	// for refused requests, how long until the next token.
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// Bucket is the state of one token bucket.
+type Bucket struct {
+	Tokens     float64
+	RefilledAt time.Time
+}
+
+// Take refills the bucket for the time passed since it was last used and
+// takes a token from it if there is one. A zero Bucket starts full.
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	burst := float64(limit.Burst)
+	if b.RefilledAt.IsZero() {
+		b.Tokens = burst
+	} else if elapsed := now.Sub(b.RefilledAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*limit.Rate)
+	}
+	if now.After(b.RefilledAt) {
+		b.RefilledAt = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.Tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.Tokens)
+	result.ResetAfter = seconds((burst - b.Tokens) / limit.Rate)
+
+	return result
+}
+
+// FullAt is when the bucket will have refilled completely, after which
+// forgetting it changes nothing.
+func (b *Bucket) FullAt(limit Limit) time.Time {
+	return b.RefilledAt.Add(seconds((float64(limit.Burst) - b.Tokens) / limit.Rate))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps token buckets. Stores are chosen with RATE_LIMIT_STORE.
+type Store interface {
+	// Take takes a token from the bucket for key.
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+// New returns the store named by RATE_LIMIT_STORE: "memory" or "postgres".
+// The memory store is the default and only limits requests seen by this
+// instance; deployments running several instances should use postgres.
+func New(db *gorm.DB) (Store, error) {
+	switch store := strings.ToLower(viper.GetString("RATE_LIMIT_STORE")); store {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", store)
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("20/m")
+	require.NoError(t, err)
+	assert.Equal(t, 20, limit.Burst)
+	assert.InDelta(t, 20.0/60, limit.Rate, 1e-9)
+
+	limit, err = ratelimit.ParseLimit("off")
+	require.NoError(t, err)
+	assert.False(t, limit.Enabled())
+
+	for _, invalid := range []string{"20", "x/m", "0/s", "5/d"} {
+		_, err := ratelimit.ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBucket_BurstThenRefill(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 1, Burst: 3}
+	now := time.Now()
+	var bucket ratelimit.Bucket
+
+	for i := 2; i >= 0; i-- {
+		result := bucket.Take(limit, now)
+		require.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := bucket.Take(limit, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.ResetAfter)
+
+	result = bucket.Take(limit, now.Add(1500*time.Millisecond))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// refills never go past the burst
+	result = bucket.Take(limit, now.Add(time.Hour))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryStore_SeparateKeys(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Rate: 0.1, Burst: 1}
+	now := time.Now()
+
+	result, err := store.Take("auth:ip:10.0.0.1", limit, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = store.Take("auth:ip:10.0.0.1", limit, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	result, err = store.Take("auth:ip:10.0.0.2", limit, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryStore_ForgetsRefilledBuckets(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Rate: 1, Burst: 1}
+	now := time.Now()
+
+	_, err := store.Take("a", limit, now)
+	require.NoError(t, err)
+
+	// after a sweep the bucket starts full again, as it would have anyway
+	result, err := store.Take("a", limit, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
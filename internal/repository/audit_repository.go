@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	CreateEvent(event *models.AuditEvent) error
+	GetEvents(tenant_id string, page, limit int) ([]*models.AuditEvent, error)
+}
+
+type AuditRepo struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &AuditRepo{db: db}
+}
+
+func (a *AuditRepo) CreateEvent(event *models.AuditEvent) error {
+	return a.db.Create(event).Error
+}
+
+// GetEvents returns a tenant's events, newest first.
+func (a *AuditRepo) GetEvents(tenant_id string, page, limit int) ([]*models.AuditEvent, error) {
+	offset := (page - 1) * limit
+	var events []*models.AuditEvent
+	if err := a.db.Where("tenant_id = ?", tenant_id).Order("created_at DESC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AuthorizationCodeRepository interface {
+	CreateAuthorizationCode(code *models.AuthorizationCode) error
+	ConsumeAuthorizationCode(code_hash string, now time.Time) (*models.AuthorizationCode, error)
+	DeleteExpiredAuthorizationCodes(now time.Time) error
+}
+
+type AuthorizationCodeRepo struct {
+	db *gorm.DB
+}
+
+func NewAuthorizationCodeRepository(db *gorm.DB) AuthorizationCodeRepository {
+	return &AuthorizationCodeRepo{db: db}
+}
+
+func (a *AuthorizationCodeRepo) CreateAuthorizationCode(code *models.AuthorizationCode) error {
+	return a.db.Create(code).Error
+}
+
+// ConsumeAuthorizationCode marks an unused, unexpired code as used and returns
+// it. Marking and checking happen in one statement so a code can only be
+// redeemed once even under concurrent requests.
+func (a *AuthorizationCodeRepo) ConsumeAuthorizationCode(code_hash string, now time.Time) (*models.AuthorizationCode, error) {
+	var codes []models.AuthorizationCode
+	res := a.db.Model(&codes).
+		Clauses(clause.Returning{}).
+		Where("code_hash = ? AND used_at IS NULL AND expires_at > ?", code_hash, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 || len(codes) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &codes[0], nil
+}
+
+func (a *AuthorizationCodeRepo) DeleteExpiredAuthorizationCodes(now time.Time) error {
+	return a.db.Where("expires_at <= ?", now).Delete(&models.AuthorizationCode{}).Error
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmailTemplateRepository interface {
+	FindTemplate(tenant_id, name string) (*models.EmailTemplate, error)
+	FindTemplates(tenant_id string) ([]*models.EmailTemplate, error)
+	SaveTemplate(template *models.EmailTemplate) error
+	DeleteTemplate(tenant_id, name string) error
+}
+
+type EmailTemplateRepo struct {
+	db *gorm.DB
+}
+
+func NewEmailTemplateRepository(db *gorm.DB) EmailTemplateRepository {
+	return &EmailTemplateRepo{db: db}
+}
+
+func (e *EmailTemplateRepo) FindTemplate(tenant_id, name string) (*models.EmailTemplate, error) {
+	var template models.EmailTemplate
+	if err := e.db.Where("tenant_id = ? AND name = ?", tenant_id, name).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (e *EmailTemplateRepo) FindTemplates(tenant_id string) ([]*models.EmailTemplate, error) {
+	var templates []*models.EmailTemplate
+	if err := e.db.Where("tenant_id = ?", tenant_id).Order("name").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (e *EmailTemplateRepo) SaveTemplate(template *models.EmailTemplate) error {
+	return e.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject", "html", "text", "updated_at"}),
+	}).Create(template).Error
+}
+
+func (e *EmailTemplateRepo) DeleteTemplate(tenant_id, name string) error {
+	res := e.db.Where("tenant_id = ? AND name = ?", tenant_id, name).Delete(&models.EmailTemplate{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
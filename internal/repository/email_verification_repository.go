@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmailVerificationRepository interface {
+	CreateVerificationTx(tx *gorm.DB, verification *models.EmailVerification) error
+	FindActiveVerification(token_hash string, now time.Time) (*models.EmailVerification, error)
+	ConsumeVerificationTx(tx *gorm.DB, id string, now time.Time) error
+	FindPolicy(tenant_id string) (*models.EmailVerificationPolicy, error)
+	SavePolicy(policy *models.EmailVerificationPolicy) error
+}
+
+type EmailVerificationRepo struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &EmailVerificationRepo{db: db}
+}
+
+// CreateVerificationTx stores verification and drops the user's unused
+// ones, so only the most recent link works.
+func (e *EmailVerificationRepo) CreateVerificationTx(tx *gorm.DB, verification *models.EmailVerification) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND used_at IS NULL", verification.UserID).
+			Delete(&models.EmailVerification{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(verification).Error
+	})
+}
+
+func (e *EmailVerificationRepo) FindActiveVerification(token_hash string, now time.Time) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	err := e.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", token_hash, now).
+		First(&verification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// ConsumeVerificationTx marks the verification used. It returns
+// gorm.ErrRecordNotFound if it already was, so a link works only once.
+func (e *EmailVerificationRepo) ConsumeVerificationTx(tx *gorm.DB, id string, now time.Time) error {
+	result := tx.Model(&models.EmailVerification{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (e *EmailVerificationRepo) FindPolicy(tenant_id string) (*models.EmailVerificationPolicy, error) {
+	var policy models.EmailVerificationPolicy
+	if err := e.db.Where("tenant_id = ?", tenant_id).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (e *EmailVerificationRepo) SavePolicy(policy *models.EmailVerificationPolicy) error {
+	return e.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mode", "restricted_role", "updated_at"}),
+	}).Create(policy).Error
+}
@@ -16,6 +16,8 @@ type InviteRepository interface {
 	GetInviteByEmailTenant(string, string) (*models.Invitation, error)
 	RemoveInvite(string) error
 	AcceptInviteTx(tx *gorm.DB, inviteID uuid.UUID) error
+	CreateInviteTx(tx *gorm.DB, invitation *models.Invitation) error
+	RenewInviteTx(tx *gorm.DB, inviteID uuid.UUID, tokenHash string, expiresAt time.Time) error
 }
 
 type InviteRepo struct {
@@ -97,3 +99,16 @@ func (i *InviteRepo) RemoveInvite(inviteID string) error {
 func (i *InviteRepo) AcceptInviteTx(tx *gorm.DB, inviteID uuid.UUID) error {
 	return tx.Model(&models.Invitation{}).Where("id = ?", inviteID).Update("accepted", true).Error
 }
+
+func (i *InviteRepo) CreateInviteTx(tx *gorm.DB, invitation *models.Invitation) error {
+	return tx.Create(invitation).Error
+}
+
+// RenewInviteTx replaces the invite's token, so links from earlier emails stop
+// working, and pushes back its expiry.
+func (i *InviteRepo) RenewInviteTx(tx *gorm.DB, inviteID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	return tx.Model(&models.Invitation{}).Where("id = ?", inviteID).Updates(map[string]any{
+		"token_hash": tokenHash,
+		"expires_at": expiresAt,
+	}).Error
+}
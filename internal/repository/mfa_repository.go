@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository interface {
+	FindTOTPCredential(user_id string) (*models.TOTPCredential, error)
+	SaveTOTPCredential(credential *models.TOTPCredential) error
+	UseTOTPStep(user_id string, step int64) error
+	DeleteMFA(user_id string) error
+	ReplaceRecoveryCodes(user_id string, codes []*models.RecoveryCode) error
+	ConsumeRecoveryCode(user_id, code_hash string, now time.Time) error
+	CreateChallenge(challenge *models.MFAChallenge) error
+	FindActiveChallenge(token_hash string, now time.Time, max_attempts int) (*models.MFAChallenge, error)
+	IncrementChallengeAttempts(id string) error
+	ConsumeChallenge(id string, now time.Time) error
+	FindMFAPolicy(tenant_id string) (*models.MFAPolicy, error)
+	SaveMFAPolicy(policy *models.MFAPolicy) error
+}
+
+type MFARepo struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &MFARepo{db: db}
+}
+
+func (m *MFARepo) FindTOTPCredential(user_id string) (*models.TOTPCredential, error) {
+	var credential models.TOTPCredential
+	if err := m.db.Where("user_id = ?", user_id).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (m *MFARepo) SaveTOTPCredential(credential *models.TOTPCredential) error {
+	return m.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"encrypted_secret", "confirmed_at", "last_used_step", "updated_at"}),
+	}).Create(credential).Error
+}
+
+// UseTOTPStep records step as the last one used. It fails when a code from
+// that step or a later one was already accepted, so of two concurrent logins
+// with the same code only one succeeds.
+func (m *MFARepo) UseTOTPStep(user_id string, step int64) error {
+	res := m.db.Model(&models.TOTPCredential{}).
+		Where("user_id = ? AND last_used_step < ?", user_id, step).
+		Update("last_used_step", step)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteMFA removes a user's TOTP secret and recovery codes.
+func (m *MFARepo) DeleteMFA(user_id string) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user_id).Delete(&models.TOTPCredential{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user_id).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+func (m *MFARepo) ReplaceRecoveryCodes(user_id string, codes []*models.RecoveryCode) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user_id).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// ConsumeRecoveryCode marks an unused code as used in a single statement so
+// it can only be redeemed once.
+func (m *MFARepo) ConsumeRecoveryCode(user_id, code_hash string, now time.Time) error {
+	res := m.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user_id, code_hash).
+		Update("used_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m *MFARepo) CreateChallenge(challenge *models.MFAChallenge) error {
+	return m.db.Create(challenge).Error
+}
+
+func (m *MFARepo) FindActiveChallenge(token_hash string, now time.Time, max_attempts int) (*models.MFAChallenge, error) {
+	var challenge models.MFAChallenge
+	err := m.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ? AND attempts < ?", token_hash, now, max_attempts).
+		First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (m *MFARepo) IncrementChallengeAttempts(id string) error {
+	return m.db.Model(&models.MFAChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+func (m *MFARepo) ConsumeChallenge(id string, now time.Time) error {
+	res := m.db.Model(&models.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m *MFARepo) FindMFAPolicy(tenant_id string) (*models.MFAPolicy, error) {
+	var policy models.MFAPolicy
+	if err := m.db.Where("tenant_id = ?", tenant_id).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (m *MFARepo) SaveMFAPolicy(policy *models.MFAPolicy) error {
+	return m.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"required", "required_roles", "updated_at"}),
+	}).Create(policy).Error
+}
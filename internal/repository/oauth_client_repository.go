@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type OAuthClientRepository interface {
+	CreateClient(client *models.OAuthClient) error
+	FindClientByClientId(client_id string) (*models.OAuthClient, error)
+	GetClients(page, limit int) ([]*models.OAuthClient, error)
+	GetTenantClients(tenant_id string, page, limit int) ([]*models.OAuthClient, error)
+	UpdateClient(client *models.OAuthClient) error
+	DeleteClient(client_id string) error
+}
+
+type OAuthClientRepo struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &OAuthClientRepo{db: db}
+}
+
+func (o *OAuthClientRepo) CreateClient(client *models.OAuthClient) error {
+	return o.db.Create(client).Error
+}
+
+func (o *OAuthClientRepo) FindClientByClientId(client_id string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if err := o.db.Where("client_id = ?", client_id).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (o *OAuthClientRepo) GetClients(page, limit int) ([]*models.OAuthClient, error) {
+	offset := (page - 1) * limit
+	var clients []*models.OAuthClient
+	if err := o.db.Offset(offset).Limit(limit).Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (o *OAuthClientRepo) GetTenantClients(tenant_id string, page, limit int) ([]*models.OAuthClient, error) {
+	offset := (page - 1) * limit
+	var clients []*models.OAuthClient
+	if err := o.db.Where("tenant_id = ?", tenant_id).Offset(offset).Limit(limit).Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (o *OAuthClientRepo) UpdateClient(client *models.OAuthClient) error {
+	return o.db.Save(client).Error
+}
+
+func (o *OAuthClientRepo) DeleteClient(client_id string) error {
+	res := o.db.Where("client_id = ?", client_id).Delete(&models.OAuthClient{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
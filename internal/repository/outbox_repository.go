@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository interface {
+	EnqueueTx(tx *gorm.DB, email *models.OutboxEmail) error
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]*models.OutboxEmail, error)
+	MarkSent(id string, now time.Time) error
+	RecordFailure(email *models.OutboxEmail) error
+}
+
+type OutboxRepo struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &OutboxRepo{db: db}
+}
+
+func (o *OutboxRepo) EnqueueTx(tx *gorm.DB, email *models.OutboxEmail) error {
+	return tx.Create(email).Error
+}
+
+// ClaimDue picks up to limit emails that are due and pushes their next
+// attempt back by lease, so other instances skip them while they are sent.
+// Rows locked by another instance are skipped rather than waited for.
+func (o *OutboxRepo) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*models.OutboxEmail, error) {
+	var emails []*models.OutboxEmail
+
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&emails).Error
+		if err != nil || len(emails) == 0 {
+			return err
+		}
+
+		ids := make([]string, 0, len(emails))
+		for _, email := range emails {
+			ids = append(ids, email.ID.String())
+		}
+		return tx.Model(&models.OutboxEmail{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return emails, nil
+}
+
+func (o *OutboxRepo) MarkSent(id string, now time.Time) error {
+	return o.db.Model(&models.OutboxEmail{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"sent_at": now, "html_body": "", "text_body": "", "last_error": ""}).Error
+}
+
+// RecordFailure saves the attempt count, next attempt and error of an email
+// that could not be sent.
+func (o *OutboxRepo) RecordFailure(email *models.OutboxEmail) error {
+	return o.db.Model(email).
+		Select("attempts", "next_attempt_at", "last_error", "failed_at").
+		Updates(email).Error
+}
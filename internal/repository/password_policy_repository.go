@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PasswordPolicyRepository interface {
+	FindPolicy(tenant_id string) (*models.PasswordPolicy, error)
+	SavePolicy(policy *models.PasswordPolicy) error
+	GetHistory(user_id string, limit int) ([]*models.PasswordHistory, error)
+	AddHistoryTx(tx *gorm.DB, entry *models.PasswordHistory, keep int) error
+}
+
+type PasswordPolicyRepo struct {
+	db *gorm.DB
+}
+
+func NewPasswordPolicyRepository(db *gorm.DB) PasswordPolicyRepository {
+	return &PasswordPolicyRepo{db: db}
+}
+
+func (p *PasswordPolicyRepo) FindPolicy(tenant_id string) (*models.PasswordPolicy, error) {
+	var policy models.PasswordPolicy
+	if err := p.db.Where("tenant_id = ?", tenant_id).First(&policy).Error; err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+func (p *PasswordPolicyRepo) SavePolicy(policy *models.PasswordPolicy) error {
+	return p.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "tenant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"min_length", "max_length", "require_upper", "require_lower", "require_digit",
+			"require_symbol", "disallow_email", "history_size", "max_age_days", "check_breached", "updated_at",
+		}),
+	}).Create(policy).Error
+}
+
+// GetHistory returns a user's most recent previous password hashes, newest
+// first.
+func (p *PasswordPolicyRepo) GetHistory(user_id string, limit int) ([]*models.PasswordHistory, error) {
+	var history []*models.PasswordHistory
+	if err := p.db.Where("user_id = ?", user_id).Order("created_at DESC").Limit(limit).Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// AddHistoryTx stores entry and deletes all but the user's keep most recent
+// entries.
+func (p *PasswordPolicyRepo) AddHistoryTx(tx *gorm.DB, entry *models.PasswordHistory, keep int) error {
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+
+	recent := tx.Model(&models.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", entry.UserID).
+		Order("created_at DESC").
+		Limit(keep)
+
+	return tx.Where("user_id = ? AND id NOT IN (?)", entry.UserID, recent).Delete(&models.PasswordHistory{}).Error
+}
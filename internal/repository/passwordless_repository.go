@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PasswordlessRepository interface {
+	CreateLoginTokenTx(tx *gorm.DB, token *models.LoginToken) error
+	UseLoginTokenAttempt(user_id, kind string, now time.Time, max_attempts int) (*models.LoginToken, error)
+	FindActiveLoginTokenByHash(token_hash, kind string, now time.Time) (*models.LoginToken, error)
+	ConsumeLoginToken(id string, now time.Time) error
+	FindLoginMethodPolicy(tenant_id string) (*models.LoginMethodPolicy, error)
+	SaveLoginMethodPolicy(policy *models.LoginMethodPolicy) error
+}
+
+type PasswordlessRepo struct {
+	db *gorm.DB
+}
+
+func NewPasswordlessRepository(db *gorm.DB) PasswordlessRepository {
+	return &PasswordlessRepo{db: db}
+}
+
+// CreateLoginTokenTx stores token and drops the user's unused tokens of the
+// same kind, so only the most recently sent link or code works.
+func (p *PasswordlessRepo) CreateLoginTokenTx(tx *gorm.DB, token *models.LoginToken) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND kind = ? AND used_at IS NULL", token.UserID, token.Kind).
+			Delete(&models.LoginToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// UseLoginTokenAttempt counts a guess against the user's latest active token
+// and returns it. The check and the increment are a single statement, so
+// concurrent guesses cannot go past max_attempts. It returns
+// gorm.ErrRecordNotFound when there is no active token or it has no
+// attempts left.
+func (p *PasswordlessRepo) UseLoginTokenAttempt(user_id, kind string, now time.Time, max_attempts int) (*models.LoginToken, error) {
+	var token models.LoginToken
+	latest := p.db.Model(&models.LoginToken{}).
+		Select("id").
+		Where("user_id = ? AND kind = ? AND used_at IS NULL AND expires_at > ?", user_id, kind, now).
+		Order("created_at DESC").
+		Limit(1)
+	res := p.db.Model(&token).
+		Clauses(clause.Returning{}).
+		Where("id = (?) AND attempts < ?", latest, max_attempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
+
+func (p *PasswordlessRepo) FindActiveLoginTokenByHash(token_hash, kind string, now time.Time) (*models.LoginToken, error) {
+	var token models.LoginToken
+	err := p.db.Where("token_hash = ? AND kind = ? AND used_at IS NULL AND expires_at > ?", token_hash, kind, now).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ConsumeLoginToken marks an unused token as used in a single statement so
+// it logs in only once.
+func (p *PasswordlessRepo) ConsumeLoginToken(id string, now time.Time) error {
+	res := p.db.Model(&models.LoginToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (p *PasswordlessRepo) FindLoginMethodPolicy(tenant_id string) (*models.LoginMethodPolicy, error) {
+	var policy models.LoginMethodPolicy
+	if err := p.db.Where("tenant_id = ?", tenant_id).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (p *PasswordlessRepo) SaveLoginMethodPolicy(policy *models.LoginMethodPolicy) error {
+	return p.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"magic_link_enabled", "email_otp_enabled", "updated_at"}),
+	}).Create(policy).Error
+}
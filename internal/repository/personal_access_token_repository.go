@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenRepository interface {
+	CreateToken(token *models.PersonalAccessToken) error
+	FindTokenByHash(tokenHash string) (*models.PersonalAccessToken, error)
+	FindTokenById(id string) (*models.PersonalAccessToken, error)
+	GetUserTokens(user_id string) ([]*models.PersonalAccessToken, error)
+	RevokeToken(id string, now time.Time) (bool, error)
+	TouchToken(id string, now time.Time) error
+}
+
+type PersonalAccessTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepo{db: db}
+}
+
+func (r *PersonalAccessTokenRepo) CreateToken(token *models.PersonalAccessToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *PersonalAccessTokenRepo) FindTokenByHash(tokenHash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *PersonalAccessTokenRepo) FindTokenById(id string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := r.db.Where("id = ? AND revoked_at IS NULL", id).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetUserTokens returns a user's tokens that were not revoked, newest first.
+// Expired ones are included so their owner sees why a script stopped working.
+func (r *PersonalAccessTokenRepo) GetUserTokens(user_id string) ([]*models.PersonalAccessToken, error) {
+	var tokens []*models.PersonalAccessToken
+	if err := r.db.Where("user_id = ? AND revoked_at IS NULL", user_id).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeToken returns false when the token was already revoked.
+func (r *PersonalAccessTokenRepo) RevokeToken(id string, now time.Time) (bool, error) {
+	res := r.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *PersonalAccessTokenRepo) TouchToken(id string, now time.Time) error {
+	return r.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", now).Error
+}
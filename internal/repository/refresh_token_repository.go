@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	FindRefreshTokenByHash(tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(current, next *models.RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(family_id string) error
+	RevokeUserRefreshTokens(user_id string) error
+}
+
+type RefreshTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &RefreshTokenRepo{db: db}
+}
+
+func (r *RefreshTokenRepo) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *RefreshTokenRepo) FindRefreshTokenByHash(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken marks current as used and stores next in one transaction.
+// It returns false when current was already used or revoked by a concurrent
+// request, which callers must treat as a replay.
+func (r *RefreshTokenRepo) RotateRefreshToken(current, next *models.RefreshToken) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{"used_at": time.Now(), "replaced_by_id": next.ID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		rotated = true
+		return nil
+	})
+
+	return rotated, err
+}
+
+func (r *RefreshTokenRepo) RevokeRefreshTokenFamily(family_id string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", family_id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *RefreshTokenRepo) RevokeUserRefreshTokens(user_id string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", user_id).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevocationRepository interface {
+	RevokeToken(token *models.RevokedToken) error
+	RevokeUserTokens(revocation *models.UserTokenRevocation) error
+	GetRevokedTokens(now time.Time) ([]*models.RevokedToken, error)
+	GetUserTokenRevocations(now time.Time) ([]*models.UserTokenRevocation, error)
+	GetRevokedSessions(since time.Time) ([]*models.Session, error)
+	DeleteExpiredRevocations(now time.Time) error
+}
+
+type RevocationRepo struct {
+	db *gorm.DB
+}
+
+func NewRevocationRepository(db *gorm.DB) RevocationRepository {
+	return &RevocationRepo{db: db}
+}
+
+func (r *RevocationRepo) RevokeToken(token *models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+func (r *RevocationRepo) RevokeUserTokens(revocation *models.UserTokenRevocation) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "expires_at", "updated_at"}),
+	}).Create(revocation).Error
+}
+
+func (r *RevocationRepo) GetRevokedTokens(now time.Time) ([]*models.RevokedToken, error) {
+	var tokens []*models.RevokedToken
+	if err := r.db.Where("expires_at > ?", now).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *RevocationRepo) GetUserTokenRevocations(now time.Time) ([]*models.UserTokenRevocation, error) {
+	var revocations []*models.UserTokenRevocation
+	if err := r.db.Where("expires_at > ?", now).Find(&revocations).Error; err != nil {
+		return nil, err
+	}
+	return revocations, nil
+}
+
+// GetRevokedSessions returns sessions revoked after since. Older ones can
+// have no access tokens left that are still valid.
+func (r *RevocationRepo) GetRevokedSessions(since time.Time) ([]*models.Session, error) {
+	var sessions []*models.Session
+	if err := r.db.Select("id", "revoked_at").Where("revoked_at > ?", since).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *RevocationRepo) DeleteExpiredRevocations(now time.Time) error {
+	if err := r.db.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("expires_at <= ?", now).Delete(&models.UserTokenRevocation{}).Error
+}
@@ -12,6 +12,7 @@ import (
 
 type RoleRepository interface {
 	GetRoleByName(tenant_id, role_name string) (*models.Role, error)
+	GetRoleById(id string) (*models.Role, error)
 	GetRoles(tenant_id string, page, limit int) ([]*dto.RoleResponse, error)
 	AddRole(tenant_id, name string) error
 	DeleteRole(id string) error
@@ -37,6 +38,14 @@ func (r *RoleRepo) GetRoleByName(tenant_id, role_name string) (*models.Role, err
 	return &role, nil
 }
 
+func (r *RoleRepo) GetRoleById(id string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.Preload("Permissions").Where("id = ?", id).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
 func (r *RoleRepo) GetRoles(tenant_id string, page, limit int) ([]*dto.RoleResponse, error) {
 	offset := (page - 1) * limit
 
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	CreateSession(session *models.Session) error
+	FindActiveSession(session_id string, now time.Time) (*models.Session, error)
+	GetActiveSessions(user_id string, now time.Time) ([]*models.Session, error)
+	RevokeSession(session_id string, now time.Time) (bool, error)
+	TouchSessions(sessions []*models.Session) error
+}
+
+type SessionRepo struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &SessionRepo{db: db}
+}
+
+func (r *SessionRepo) CreateSession(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// activeSessions limits a query to sessions that were not revoked and still
+// hold a refresh token that can be redeemed. Sessions ended by logout, by a
+// replayed refresh token or by expiry drop out without being marked.
+func activeSessions(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("sessions.revoked_at IS NULL").
+			Where(`EXISTS (SELECT 1 FROM refresh_tokens rt WHERE rt.family_id = sessions.id
+				AND rt.used_at IS NULL AND rt.revoked_at IS NULL AND rt.deleted_at IS NULL AND rt.expires_at > ?)`, now)
+	}
+}
+
+func (r *SessionRepo) FindActiveSession(session_id string, now time.Time) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Scopes(activeSessions(now)).Where("id = ?", session_id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveSessions returns a user's sessions, most recently seen first.
+func (r *SessionRepo) GetActiveSessions(user_id string, now time.Time) ([]*models.Session, error) {
+	var sessions []*models.Session
+	if err := r.db.Scopes(activeSessions(now)).Where("user_id = ?", user_id).Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession marks a session revoked and revokes its refresh token family
+// in one transaction. It returns false when the session was already revoked.
+func (r *SessionRepo) RevokeSession(session_id string, now time.Time) (bool, error) {
+	revoked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", session_id).
+			Update("revoked_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", session_id).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		revoked = true
+		return nil
+	})
+
+	return revoked, err
+}
+
+// TouchSessions stores when and from where each session was last seen.
+func (r *SessionRepo) TouchSessions(sessions []*models.Session) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, session := range sessions {
+			err := tx.Model(&models.Session{}).
+				Where("id = ? AND last_seen_at < ?", session.ID, session.LastSeenAt).
+				Updates(map[string]interface{}{
+					"last_seen_at": session.LastSeenAt,
+					"ip":           session.IP,
+					"user_agent":   session.UserAgent,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
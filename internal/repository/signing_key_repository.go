@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SigningKeyRepository interface {
+	GetSigningKeys(now time.Time) ([]*models.SigningKey, error)
+	RotateSigningKeys(next *models.SigningKey, activatedBefore, retiredUntil time.Time) (bool, error)
+}
+
+type SigningKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) SigningKeyRepository {
+	return &SigningKeyRepo{db: db}
+}
+
+// GetSigningKeys returns the next and active keys plus retired keys that may
+// still have unexpired tokens.
+func (s *SigningKeyRepo) GetSigningKeys(now time.Time) ([]*models.SigningKey, error) {
+	var keys []*models.SigningKey
+	err := s.db.
+		Where("state IN ? OR (state = ? AND expires_at > ?)", []string{utils.KeyStateNext, utils.KeyStateActive}, utils.KeyStateRetired, now).
+		Order("created_at").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RotateSigningKeys retires the active key, promotes the next key and stores
+// a new next key. Rotation is skipped, returning false, when the active key
+// was activated after activatedBefore, so that concurrent schedulers on
+// several instances only rotate once.
+func (s *SigningKeyRepo) RotateSigningKeys(next *models.SigningKey, activatedBefore, retiredUntil time.Time) (bool, error) {
+	rotated := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var active models.SigningKey
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("state = ?", utils.KeyStateActive).First(&active).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		hasActive := err == nil
+
+		if hasActive && active.ActivatedAt != nil && active.ActivatedAt.After(activatedBefore) {
+			return nil
+		}
+
+		if hasActive {
+			if err := tx.Model(&active).Updates(map[string]interface{}{
+				"state":      utils.KeyStateRetired,
+				"retired_at": now,
+				"expires_at": retiredUntil,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.SigningKey{}).Where("state = ?", utils.KeyStateNext).Updates(map[string]interface{}{
+			"state":        utils.KeyStateActive,
+			"activated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("state = ? AND expires_at <= ?", utils.KeyStateRetired, now).Delete(&models.SigningKey{}).Error; err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+
+	return rotated, err
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type TokenClaimConfigRepository interface {
+	FindClaimConfigs(tenant_id *string, audience string) ([]*models.TokenClaimConfig, error)
+	GetClaimConfigs(page, limit int) ([]*models.TokenClaimConfig, error)
+	SaveClaimConfig(config *models.TokenClaimConfig) error
+	DeleteClaimConfig(id string) error
+}
+
+type TokenClaimConfigRepo struct {
+	db *gorm.DB
+}
+
+func NewTokenClaimConfigRepository(db *gorm.DB) TokenClaimConfigRepository {
+	return &TokenClaimConfigRepo{db: db}
+}
+
+// FindClaimConfigs returns every config that applies to the tenant and
+// audience, including the tenant-wide and global fallbacks.
+func (t *TokenClaimConfigRepo) FindClaimConfigs(tenant_id *string, audience string) ([]*models.TokenClaimConfig, error) {
+	var configs []*models.TokenClaimConfig
+
+	query := t.db.Where("audience = ? OR audience = ''", audience)
+	if tenant_id != nil {
+		query = query.Where("tenant_id = ? OR tenant_id IS NULL", *tenant_id)
+	} else {
+		query = query.Where("tenant_id IS NULL")
+	}
+
+	if err := query.Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+func (t *TokenClaimConfigRepo) GetClaimConfigs(page, limit int) ([]*models.TokenClaimConfig, error) {
+	offset := (page - 1) * limit
+	var configs []*models.TokenClaimConfig
+	if err := t.db.Offset(offset).Limit(limit).Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+func (t *TokenClaimConfigRepo) SaveClaimConfig(config *models.TokenClaimConfig) error {
+	return t.db.Save(config).Error
+}
+
+func (t *TokenClaimConfigRepo) DeleteClaimConfig(id string) error {
+	res := t.db.Where("id = ?", id).Delete(&models.TokenClaimConfig{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
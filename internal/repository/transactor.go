@@ -0,0 +1,21 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Transactor runs fn in a database transaction. Repository methods ending in
+// Tx take the transaction, so their writes commit or roll back together.
+type Transactor interface {
+	Transaction(fn func(tx *gorm.DB) error) error
+}
+
+type GormTransactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &GormTransactor{db: db}
+}
+
+func (g *GormTransactor) Transaction(fn func(tx *gorm.DB) error) error {
+	return g.db.Transaction(fn)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/samvibes/vexop/auth-service/internal/models"
@@ -15,10 +16,19 @@ type UserRepository interface {
 	FindUserByEmailAndTenant(email string, tenant_id string) (*models.User, error)
 	RemoveUserById(tenant_id, user_id string) error
 	RemoveUserByEmail(tenant_id string, email string) error
-	SetResetPasswordTokenHash(id, tokenHash string) error
+	SetResetPasswordTokenHashTx(tx *gorm.DB, id, tokenHash string, expiresAt time.Time) error
+	FindUserByResetTokenHash(tokenHash string, now time.Time) (*models.User, error)
+	ResetPasswordTx(tx *gorm.DB, id, tokenHash, passwordHash string, now time.Time) error
+	UpdatePasswordTx(tx *gorm.DB, id, passwordHash string, now time.Time) error
+	RehashPassword(id, oldHash, newHash string) error
+	VerifyEmailTx(tx *gorm.DB, id, email string, now time.Time) error
 	GetUsers(tenant_id string, page, limit int) ([]*models.User, error)
 	GetUserById(tenant_id, user_id string) (*models.User, error)
+	FindUserById(user_id string) (*models.User, error)
 	UpdateUser(user *models.User) error
+	CreateServiceAccount(user *models.User) error
+	GetServiceAccounts(tenant_id string) ([]*models.User, error)
+	GetServiceAccountById(tenant_id, id string) (*models.User, error)
 }
 
 type UserRepo struct {
@@ -29,6 +39,12 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &UserRepo{db: db}
 }
 
+// humanUsers limits a query to users who are people. Service accounts have
+// no password, cannot be found by email to log in with and take no seat.
+func humanUsers(db *gorm.DB) *gorm.DB {
+	return db.Where("is_service_account = false")
+}
+
 func (u *UserRepo) CreateUser(user *models.User) error {
 	// fetch default role - there MUST be one default role
 	var role models.Role
@@ -54,7 +70,7 @@ func (u *UserRepo) CreateUserTx(tx *gorm.DB, user *models.User) error {
 
 func (u *UserRepo) FindUserByEmail(email string) (*models.User, error) {
 	var user models.User
-	if err := u.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := u.db.Scopes(humanUsers).Where("email = ?", email).First(&user).Error; err != nil {
 		return nil, err
 	}
 
@@ -63,7 +79,7 @@ func (u *UserRepo) FindUserByEmail(email string) (*models.User, error) {
 
 func (u *UserRepo) FindUserByEmailAndTenant(email string, tenant_id string) (*models.User, error) {
 	var user models.User
-	if err := u.db.Where("email = ? AND tenant_id = ?", email, tenant_id).First(&user).Error; err != nil {
+	if err := u.db.Scopes(humanUsers).Where("email = ? AND tenant_id = ?", email, tenant_id).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -85,19 +101,66 @@ func (u *UserRepo) RemoveUserByEmail(tenant_id string, email string) error {
 	return nil
 }
 
-func (u *UserRepo) SetResetPasswordTokenHash(id, tokenHash string) error {
-	if err := u.db.Where("id = ?", id).First(&models.User{}).Update("reset_password_token_hash", tokenHash).Error; err != nil {
-		return err
+func (u *UserRepo) SetResetPasswordTokenHashTx(tx *gorm.DB, id, tokenHash string, expiresAt time.Time) error {
+	return tx.Model(&models.User{}).Where("id = ?", id).Updates(map[string]any{
+		"reset_password_token_hash": tokenHash,
+		"reset_password_expires_at": expiresAt,
+	}).Error
+}
+
+func (u *UserRepo) FindUserByResetTokenHash(tokenHash string, now time.Time) (*models.User, error) {
+	var user models.User
+	if err := u.db.Where("reset_password_token_hash = ? AND reset_password_expires_at > ?", tokenHash, now).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ResetPasswordTx sets the password and clears the reset token in one
+// statement, so a token cannot be used twice. It returns
+// gorm.ErrRecordNotFound when the token was already used or has expired.
+func (u *UserRepo) ResetPasswordTx(tx *gorm.DB, id, tokenHash, passwordHash string, now time.Time) error {
+	result := tx.Model(&models.User{}).
+		Where("id = ? AND reset_password_token_hash = ? AND reset_password_expires_at > ?", id, tokenHash, now).
+		Updates(map[string]any{
+			"password_hash":             passwordHash,
+			"password_changed_at":       now,
+			"reset_password_token_hash": "",
+			"reset_password_expires_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
 
 	return nil
 }
 
+func (u *UserRepo) UpdatePasswordTx(tx *gorm.DB, id, passwordHash string, now time.Time) error {
+	return tx.Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"password_hash":       passwordHash,
+			"password_changed_at": now,
+		}).Error
+}
+
+// RehashPassword replaces a user's password hash with a new hash of the same
+// password, unless the password was changed in the meantime.
+func (u *UserRepo) RehashPassword(id, oldHash, newHash string) error {
+	return u.db.Model(&models.User{}).
+		Where("id = ? AND password_hash = ?", id, oldHash).
+		Update("password_hash", newHash).Error
+}
+
 func (u *UserRepo) GetUsers(tenant_id string, page, limit int) ([]*models.User, error) {
 	offset := (page - 1) * limit
 	var users []*models.User
 	// if err := u.db.Preload("Role").Preload("Role.Permissions").Where("tenant_id = ?", tenant_id).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
-	if err := u.db.Preload("Role").Where("tenant_id = ?", tenant_id).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+	if err := u.db.Scopes(humanUsers).Preload("Role").Where("tenant_id = ?", tenant_id).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
 		return nil, err
 	}
 
@@ -114,6 +177,46 @@ func (u *UserRepo) GetUserById(tenant_id, user_id string) (*models.User, error)
 	return user, nil
 }
 
+func (u *UserRepo) FindUserById(user_id string) (*models.User, error) {
+	var user models.User
+	if err := u.db.Preload("Role").Where("id = ?", user_id).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (u *UserRepo) UpdateUser(user *models.User) error {
 	return u.db.Save(user).Error
 }
+
+// CreateServiceAccount stores a service account with the role it was given,
+// unlike CreateUser which assigns the default role.
+func (u *UserRepo) CreateServiceAccount(user *models.User) error {
+	return u.db.Omit("Role").Create(user).Error
+}
+
+func (u *UserRepo) GetServiceAccounts(tenant_id string) ([]*models.User, error) {
+	var users []*models.User
+	if err := u.db.Preload("Role").Where("tenant_id = ? AND is_service_account = true", tenant_id).Order("created_at").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (u *UserRepo) GetServiceAccountById(tenant_id, id string) (*models.User, error) {
+	var user models.User
+	if err := u.db.Preload("Role").Where("tenant_id = ? AND id = ? AND is_service_account = true", tenant_id, id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// VerifyEmailTx marks email as the user's verified address, replacing the
+// current one if it differs.
+func (u *UserRepo) VerifyEmailTx(tx *gorm.DB, id, email string, now time.Time) error {
+	return tx.Model(&models.User{}).Where("id = ?", id).Updates(map[string]any{
+		"email":             email,
+		"email_verified_at": now,
+	}).Error
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebAuthnRepository interface {
+	CreateCredential(credential *models.WebAuthnCredential) error
+	FindUserCredentials(user_id string) ([]*models.WebAuthnCredential, error)
+	FindCredentialByCredentialId(credential_id []byte) (*models.WebAuthnCredential, error)
+	CountUserCredentials(user_id string) (int64, error)
+	UpdateCredential(credential *models.WebAuthnCredential) error
+	DeleteCredential(user_id, id string) error
+	CreateSession(session *models.WebAuthnSession) error
+	ConsumeSession(token_hash, purpose string, now time.Time) (*models.WebAuthnSession, error)
+}
+
+type WebAuthnRepo struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnRepository(db *gorm.DB) WebAuthnRepository {
+	return &WebAuthnRepo{db: db}
+}
+
+func (w *WebAuthnRepo) CreateCredential(credential *models.WebAuthnCredential) error {
+	return w.db.Create(credential).Error
+}
+
+func (w *WebAuthnRepo) FindUserCredentials(user_id string) ([]*models.WebAuthnCredential, error) {
+	var credentials []*models.WebAuthnCredential
+	if err := w.db.Where("user_id = ?", user_id).Order("created_at").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (w *WebAuthnRepo) FindCredentialByCredentialId(credential_id []byte) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	if err := w.db.Where("credential_id = ?", credential_id).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (w *WebAuthnRepo) CountUserCredentials(user_id string) (int64, error) {
+	var count int64
+	err := w.db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", user_id).Count(&count).Error
+	return count, err
+}
+
+func (w *WebAuthnRepo) UpdateCredential(credential *models.WebAuthnCredential) error {
+	return w.db.Save(credential).Error
+}
+
+func (w *WebAuthnRepo) DeleteCredential(user_id, id string) error {
+	res := w.db.Where("user_id = ? AND id = ?", user_id, id).Delete(&models.WebAuthnCredential{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (w *WebAuthnRepo) CreateSession(session *models.WebAuthnSession) error {
+	return w.db.Create(session).Error
+}
+
+// ConsumeSession deletes an unexpired session and returns it, so each
+// ceremony can be finished only once.
+func (w *WebAuthnRepo) ConsumeSession(token_hash, purpose string, now time.Time) (*models.WebAuthnSession, error) {
+	var sessions []models.WebAuthnSession
+	res := w.db.Clauses(clause.Returning{}).
+		Where("token_hash = ? AND purpose = ? AND expires_at > ?", token_hash, purpose, now).
+		Delete(&sessions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 || len(sessions) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &sessions[0], nil
+}
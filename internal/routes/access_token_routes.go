@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+)
+
+// RegisterAccessTokenRoutes lets tenant admins see and revoke their members'
+// personal access tokens. Like a user's own tokens, they are not managed
+// while impersonating.
+func RegisterAccessTokenRoutes(router *gin.RouterGroup, tokenHandler handlers.PersonalAccessTokenHandler) {
+	router.Use(middleware.DenyImpersonation())
+	router.GET("", tokenHandler.GetUserTokens)
+	router.DELETE("/:id", tokenHandler.RevokeUserToken)
+}
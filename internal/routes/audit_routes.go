@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+)
+
+func RegisterAuditRoutes(router *gin.RouterGroup, auditHandler handlers.AuditHandler) {
+	router.GET("", auditHandler.GetEvents)
+}
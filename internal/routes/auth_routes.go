@@ -3,10 +3,53 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
 )
 
-func RegisterAPIRoutes(group *gin.RouterGroup, authHandler handlers.AuthHandler) {
+func RegisterAPIRoutes(group *gin.RouterGroup, authHandler handlers.AuthHandler, forwardAuthHandler handlers.ForwardAuthHandler, mfaHandler handlers.MFAHandler, webauthnHandler handlers.WebAuthnHandler, passwordlessHandler handlers.PasswordlessHandler, emailVerificationHandler handlers.EmailVerificationHandler, sessionHandler handlers.SessionHandler, tokenHandler handlers.PersonalAccessTokenHandler, impersonationHandler handlers.ImpersonationHandler, authMiddleware, rateLimit gin.HandlerFunc) {
+	// health checks and forward auth run on every proxied request, so they
+	// are not rate limited
 	group.GET("/health", authHandler.Health)
+	group.Any("/verify", forwardAuthHandler.Verify)
+
+	group = group.Group("", rateLimit)
 	group.POST("/signup", authHandler.SignUp)
 	group.POST("/login", authHandler.Login)
+	group.POST("/login/mfa", mfaHandler.LoginVerify)
+	group.POST("/login/mfa/totp", mfaHandler.LoginEnrollTOTP)
+	group.POST("/login/mfa/webauthn/begin", webauthnHandler.BeginMFA)
+	group.POST("/login/mfa/webauthn/finish", webauthnHandler.FinishMFA)
+	group.POST("/login/webauthn/begin", webauthnHandler.BeginLogin)
+	group.POST("/login/webauthn/finish", webauthnHandler.FinishLogin)
+	group.POST("/magic-link", passwordlessHandler.RequestMagicLink)
+	group.POST("/magic-link/login", passwordlessHandler.MagicLinkLogin)
+	group.POST("/email-otp", passwordlessHandler.RequestEmailOTP)
+	group.POST("/email-otp/login", passwordlessHandler.EmailOTPLogin)
+	group.POST("/password/forgot", authHandler.ForgotPassword)
+	group.POST("/password/reset", authHandler.ResetPassword)
+	group.POST("/password/change", authHandler.ChangePassword)
+	group.POST("/email/verify", emailVerificationHandler.VerifyEmail)
+	group.POST("/email/verify/resend", emailVerificationHandler.ResendVerification)
+	group.POST("/refresh", authHandler.Refresh)
+	group.POST("/logout", authMiddleware, authHandler.Logout)
+	group.GET("/sessions", authMiddleware, sessionHandler.GetSessions)
+	group.GET("/access-tokens", authMiddleware, tokenHandler.GetTokens)
+	group.GET("/webauthn/credentials", authMiddleware, webauthnHandler.GetCredentials)
+	group.POST("/impersonation/stop", authMiddleware, impersonationHandler.StopImpersonation)
+
+	// changes to how the user logs in and to their sessions are theirs alone.
+	// Changing the password takes the current one, which impersonators lack.
+	account := group.Group("", authMiddleware, middleware.DenyImpersonation())
+	account.POST("/email/change", emailVerificationHandler.ChangeEmail)
+	account.POST("/logout/all", authHandler.LogoutAll)
+	account.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+	account.POST("/access-tokens", tokenHandler.CreateToken)
+	account.DELETE("/access-tokens/:id", tokenHandler.RevokeToken)
+	account.POST("/mfa/totp", mfaHandler.EnrollTOTP)
+	account.POST("/mfa/totp/activate", mfaHandler.ActivateTOTP)
+	account.DELETE("/mfa/totp", mfaHandler.DisableTOTP)
+	account.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	account.POST("/webauthn/register/begin", webauthnHandler.BeginRegistration)
+	account.POST("/webauthn/register/finish", webauthnHandler.FinishRegistration)
+	account.DELETE("/webauthn/credentials/:id", webauthnHandler.DeleteCredential)
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+)
+
+// RegisterClientRoutes lets tenant admins manage their OAuth clients. Client
+// secrets are credentials, which an impersonation may not mint.
+func RegisterClientRoutes(router *gin.RouterGroup, oauthClientHandler handlers.OAuthClientHandler) {
+	router.GET("/", oauthClientHandler.GetClients)
+	router.POST("/", middleware.DenyImpersonation(), oauthClientHandler.CreateClient)
+	router.PUT("/:client_id", oauthClientHandler.UpdateClient)
+	router.DELETE("/:client_id", oauthClientHandler.DeleteClient)
+	router.POST("/:client_id/secret", middleware.DenyImpersonation(), oauthClientHandler.RotateClientSecret)
+}
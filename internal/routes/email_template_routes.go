@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+)
+
+func RegisterEmailTemplateRoutes(router *gin.RouterGroup, emailTemplateHandler handlers.EmailTemplateHandler) {
+	router.GET("", emailTemplateHandler.GetTemplates)
+	router.GET("/:name", emailTemplateHandler.GetTemplate)
+	router.PUT("/:name", emailTemplateHandler.SaveTemplate)
+	router.DELETE("/:name", emailTemplateHandler.DeleteTemplate)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+)
+
+// RegisterImpersonationRoutes lets tenant owners impersonate their members.
+// An impersonation cannot start another one.
+func RegisterImpersonationRoutes(router *gin.RouterGroup, impersonationHandler handlers.ImpersonationHandler) {
+	router.POST("", middleware.DenyImpersonation(), impersonationHandler.StartImpersonation)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+)
+
+func RegisterOAuthRoutes(group *gin.RouterGroup, oauthHandler handlers.OAuthHandler) {
+	group.GET("/authorize", oauthHandler.Authorize)
+	group.POST("/authorize", oauthHandler.AuthorizeLogin)
+	group.POST("/token", oauthHandler.Token)
+	group.GET("/userinfo", oauthHandler.UserInfo)
+	group.POST("/userinfo", oauthHandler.UserInfo)
+	group.POST("/introspect", oauthHandler.Introspect)
+	group.POST("/revoke", oauthHandler.Revoke)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+)
+
+func RegisterPolicyRoutes(router *gin.RouterGroup, mfaHandler handlers.MFAHandler, passwordlessHandler handlers.PasswordlessHandler, emailVerificationHandler handlers.EmailVerificationHandler, passwordPolicyHandler handlers.PasswordPolicyHandler) {
+	router.GET("/mfa", mfaHandler.GetPolicy)
+	router.PUT("/mfa", mfaHandler.SavePolicy)
+	router.GET("/login-methods", passwordlessHandler.GetPolicy)
+	router.PUT("/login-methods", passwordlessHandler.SavePolicy)
+	router.GET("/email-verification", emailVerificationHandler.GetPolicy)
+	router.PUT("/email-verification", emailVerificationHandler.SavePolicy)
+	router.GET("/password", passwordPolicyHandler.GetPolicy)
+	router.PUT("/password", passwordPolicyHandler.SavePolicy)
+}
@@ -1,6 +1,9 @@
 package routes
 
 import (
+	"log"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/samvibes/vexop/auth-service/app"
 	"github.com/samvibes/vexop/auth-service/internal/middleware"
@@ -8,27 +11,75 @@ import (
 )
 
 func InitRoutes(container *app.AppContainer) *gin.Engine {
-	jwtSecret := []byte(viper.GetString("JWT_SECRET"))
+	authMiddleware := middleware.JWTAuthMiddleware(container.DB, container.AuthService, container.SessionService, container.PersonalAccessTokenService)
+	// managing the account, personal access tokens included, needs a login
+	loginMiddleware := middleware.JWTAuthMiddleware(container.DB, container.AuthService, container.SessionService, nil)
 
 	router := gin.Default()
-	auth_api := router.Group("/api/auth")
-	RegisterAPIRoutes(auth_api, container.AuthHandler)
+	// only trust X-Forwarded-For from the comma separated TRUSTED_PROXIES,
+	// otherwise clients could pick the IP address they are rate limited and
+	// locked out by
+	var trustedProxies []string
+	for _, proxy := range strings.Split(viper.GetString("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	rateLimit := func(name string) gin.HandlerFunc {
+		return middleware.RateLimit(container.RateLimitStore, container.RateLimitRules[name])
+	}
+
+	well_known := router.Group("/.well-known")
+	RegisterWellKnownRoutes(well_known, container.WellKnownHandler, container.OAuthHandler)
+
+	oauth_api := router.Group("/oauth", rateLimit("oauth"))
+	RegisterOAuthRoutes(oauth_api, container.OAuthHandler)
 
-	router.Use(middleware.JWTAuthMiddleware(container.DB, jwtSecret))
+	auth_api := router.Group("/api/auth", middleware.TrackSessions(container.SessionService))
+	RegisterAPIRoutes(auth_api, container.AuthHandler, container.ForwardAuthHandler, container.MFAHandler, container.WebAuthnHandler, container.PasswordlessHandler, container.EmailVerificationHandler, container.SessionHandler, container.PersonalAccessTokenHandler, container.ImpersonationHandler, loginMiddleware, rateLimit("auth"))
+
+	router.Use(authMiddleware)
 	router.Use(middleware.AutoRBAC(container.DB))
 
 	// Super admin APIs
-	sa_api := router.Group("/api/sa")
-	RegisterSARoutes(sa_api, container.TenantHandler)
+	sa_api := router.Group("/api/sa", rateLimit("api"))
+	RegisterSARoutes(sa_api, container.TenantHandler, container.TokenClaimHandler, container.OAuthClientHandler, container.ImpersonationHandler)
 
-	invite_api := router.Group("/api/invites")
+	invite_api := router.Group("/api/invites", rateLimit("api"))
 	RegisterInviteRoutes(invite_api, container.InviteHandler)
 
-	user_api := router.Group("/api/users")
+	user_api := router.Group("/api/users", rateLimit("users"))
 	RegisterUserRoutes(user_api, container.UserHandler)
 
-	role_api := router.Group("/api/roles")
+	role_api := router.Group("/api/roles", rateLimit("api"))
 	RegisterRoleRoutes(role_api, container.RoleHandler)
 
+	client_api := router.Group("/api/clients", rateLimit("api"))
+	RegisterClientRoutes(client_api, container.OAuthClientHandler)
+
+	policy_api := router.Group("/api/policies", rateLimit("api"))
+	RegisterPolicyRoutes(policy_api, container.MFAHandler, container.PasswordlessHandler, container.EmailVerificationHandler, container.PasswordPolicyHandler)
+
+	email_template_api := router.Group("/api/email-templates", rateLimit("api"))
+	RegisterEmailTemplateRoutes(email_template_api, container.EmailTemplateHandler)
+
+	audit_api := router.Group("/api/audit-events", rateLimit("api"))
+	RegisterAuditRoutes(audit_api, container.AuditHandler)
+
+	session_api := router.Group("/api/sessions", rateLimit("api"))
+	RegisterSessionRoutes(session_api, container.SessionHandler)
+
+	access_token_api := router.Group("/api/access-tokens", rateLimit("api"))
+	RegisterAccessTokenRoutes(access_token_api, container.PersonalAccessTokenHandler)
+
+	service_account_api := router.Group("/api/service-accounts", rateLimit("api"))
+	RegisterServiceAccountRoutes(service_account_api, container.ServiceAccountHandler)
+
+	impersonation_api := router.Group("/api/impersonations", rateLimit("api"))
+	RegisterImpersonationRoutes(impersonation_api, container.ImpersonationHandler)
+
 	return router
 }
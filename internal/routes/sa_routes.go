@@ -3,10 +3,29 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
 )
 
-func RegisterSARoutes(group *gin.RouterGroup, tenantHandler handlers.TenantHandler) {
+func RegisterSARoutes(
+	group *gin.RouterGroup,
+	tenantHandler handlers.TenantHandler,
+	tokenClaimHandler handlers.TokenClaimHandler,
+	oauthClientHandler handlers.OAuthClientHandler,
+	impersonationHandler handlers.ImpersonationHandler,
+) {
 	group.GET("/tenants", tenantHandler.GetTenants)
 	group.POST("/tenants", tenantHandler.CreateTenant)
 	group.DELETE("/tenants", tenantHandler.DeleteTenant)
+
+	group.GET("/token-claims", tokenClaimHandler.GetClaimConfigs)
+	group.PUT("/token-claims", tokenClaimHandler.SaveClaimConfig)
+	group.DELETE("/token-claims", tokenClaimHandler.DeleteClaimConfig)
+
+	group.GET("/clients", oauthClientHandler.GetClients)
+	group.POST("/clients", oauthClientHandler.CreateClient)
+	group.PUT("/clients/:client_id", oauthClientHandler.UpdateClient)
+	group.DELETE("/clients/:client_id", oauthClientHandler.DeleteClient)
+	group.POST("/clients/:client_id/secret", oauthClientHandler.RotateClientSecret)
+
+	group.POST("/impersonations", middleware.DenyImpersonation(), impersonationHandler.StartImpersonation)
 }
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+	"github.com/samvibes/vexop/auth-service/internal/middleware"
+)
+
+// RegisterServiceAccountRoutes lets tenant admins manage service accounts.
+// Their keys are credentials, which an impersonation may not mint.
+func RegisterServiceAccountRoutes(router *gin.RouterGroup, serviceAccountHandler handlers.ServiceAccountHandler) {
+	router.GET("", serviceAccountHandler.GetServiceAccounts)
+	router.POST("", serviceAccountHandler.CreateServiceAccount)
+	router.DELETE("/:id", serviceAccountHandler.DeleteServiceAccount)
+	router.POST("/:id/keys", middleware.DenyImpersonation(), serviceAccountHandler.CreateKey)
+}
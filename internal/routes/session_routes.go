@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+)
+
+func RegisterSessionRoutes(router *gin.RouterGroup, sessionHandler handlers.SessionHandler) {
+	router.GET("", sessionHandler.GetUserSessions)
+	router.DELETE("/:id", sessionHandler.RevokeUserSession)
+}
@@ -6,10 +6,10 @@ import (
 )
 
 func RegisterUserRoutes(router *gin.RouterGroup, userHandler handlers.UserHandler) {
-	router.POST("/password/forgot", userHandler.SendResetPassword)
-	router.POST("/password/reset", userHandler.ResetPassword)
 	router.GET("/", userHandler.GetUsers)
 	router.GET("/:id", userHandler.GetUserById)
 	router.PUT("/role", userHandler.UpdateUserRole)
+	router.POST("/import", userHandler.ImportUsers)
 	router.DELETE("/:id", userHandler.DeleteUser)
+	router.PUT("/:id/unlock", userHandler.UnlockUser)
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/samvibes/vexop/auth-service/internal/handlers"
+)
+
+func RegisterWellKnownRoutes(group *gin.RouterGroup, wellKnownHandler handlers.WellKnownHandler, oauthHandler handlers.OAuthHandler) {
+	group.GET("/jwks.json", wellKnownHandler.JWKS)
+	group.GET("/openid-configuration", oauthHandler.Discovery)
+}
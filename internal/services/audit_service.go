@@ -0,0 +1,33 @@
+package services
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+)
+
+// AuditService records security relevant events, such as lockouts, and lets
+// tenant admins read their tenant's trail.
+type AuditService interface {
+	Record(event *models.AuditEvent) error
+	GetEvents(requestor *models.User, page, limit int) ([]*models.AuditEvent, error)
+}
+
+type AuditServiceImpl struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &AuditServiceImpl{repo: repo}
+}
+
+func (a *AuditServiceImpl) Record(event *models.AuditEvent) error {
+	return a.repo.CreateEvent(event)
+}
+
+func (a *AuditServiceImpl) GetEvents(requestor *models.User, page, limit int) ([]*models.AuditEvent, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	return a.repo.GetEvents(requestor.TenantID.String(), page, limit)
+}
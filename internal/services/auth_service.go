@@ -1,45 +1,457 @@
 package services
 
 import (
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
 	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/passhash"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
 	"github.com/spf13/viper"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	// impersonation tokens cannot be refreshed, so a support session needs a
+	// new one every IMPERSONATION_TTL
+	defaultImpersonationTTL = 10 * time.Minute
 )
 
 type AuthService interface {
 	HashPassword(password string) (string, error)
 	CompareHashAndPassword(password, hashed []byte) bool
+	PasswordNeedsRehash(hashed string) bool
+	SupportsPasswordHash(hashed string) bool
 	GenerateJWT(user *models.User) (string, error)
+	IssueTokens(user *models.User) (*dto.LoginResponse, error)
+	IssueClientTokens(user *models.User, client_id, scope string, offline bool) (*dto.LoginResponse, error)
+	IssueClientCredentialsToken(client *models.OAuthClient, scope string) (*dto.LoginResponse, error)
+	IssueImpersonationToken(user, impersonator *models.User) (*dto.LoginResponse, *utils.Claims, error)
+	RefreshTokens(refreshToken, client_id string) (*dto.LoginResponse, error)
+	ValidateAccessToken(tokenStr string) (*utils.Claims, error)
+	Logout(claims *utils.Claims, refreshToken string) error
+	RevokeAccessToken(claims *utils.Claims) error
+	FindActiveRefreshToken(refreshToken string) (*models.RefreshToken, error)
+	RevokeRefreshToken(refreshToken, client_id string) error
+	RevokeUserTokens(user_id string) error
 }
 
-type AuthServiceImpl struct{}
+type AuthServiceImpl struct {
+	refreshTokenRepo  repository.RefreshTokenRepository
+	sessionRepo       repository.SessionRepository
+	userRepo          repository.UserRepository
+	revocationService RevocationService
+	keyService        KeyService
+	claimsService     ClaimsService
+	passwords         *passhash.Passwords
+}
 
-func NewAuthService() AuthService {
-	return &AuthServiceImpl{}
+func NewAuthService(
+	refreshTokenRepo repository.RefreshTokenRepository,
+	sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository,
+	revocationService RevocationService,
+	keyService KeyService,
+	claimsService ClaimsService,
+	passwords *passhash.Passwords,
+) AuthService {
+	return &AuthServiceImpl{
+		refreshTokenRepo:  refreshTokenRepo,
+		sessionRepo:       sessionRepo,
+		userRepo:          userRepo,
+		revocationService: revocationService,
+		keyService:        keyService,
+		claimsService:     claimsService,
+		passwords:         passwords,
+	}
 }
 
+// HashPassword hashes password with the configured PASSWORD_HASHER.
 func (a *AuthServiceImpl) HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	return a.passwords.Hash(password)
 }
 
+// CompareHashAndPassword reports whether password matches hashed, which may
+// use any supported scheme. An empty hash never matches but takes as long
+// to reject as a real one.
 func (a *AuthServiceImpl) CompareHashAndPassword(password, hashed []byte) bool {
-	err := bcrypt.CompareHashAndPassword(hashed, password)
-	return err == nil
+	return a.passwords.Verify(string(password), string(hashed))
+}
+
+// PasswordNeedsRehash reports whether hashed was made with another scheme or
+// other parameters than HashPassword uses now.
+func (a *AuthServiceImpl) PasswordNeedsRehash(hashed string) bool {
+	return a.passwords.NeedsRehash(hashed)
+}
+
+// SupportsPasswordHash reports whether hashed, such as one imported from
+// another system, uses a scheme CompareHashAndPassword can verify.
+func (a *AuthServiceImpl) SupportsPasswordHash(hashed string) bool {
+	return a.passwords.Supports(hashed)
 }
 
 func (a *AuthServiceImpl) GenerateJWT(user *models.User) (string, error) {
-	userID := user.ID
-	claims := jwt.MapClaims{
-		"id":  userID.String(),
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+	return a.generateAccessToken(user, "", "", "")
+}
+
+// generateAccessToken mints an access token for user in session_id, which is
+// empty for tokens issued without a refresh token. Tokens issued to an OAuth
+// client are addressed to it and carry the scope it was granted.
+func (a *AuthServiceImpl) generateAccessToken(user *models.User, session_id, client_id, scope string) (string, error) {
+	claims, err := a.claimsService.BuildClaims(user, client_id)
+	if err != nil {
+		return "", err
+	}
+	claims.ClientID = client_id
+	claims.Scope = scope
+	claims.SessionID = session_id
+
+	return signToken(a.keyService, claims)
+}
+
+func signToken(keyService KeyService, claims jwt.Claims) (string, error) {
+	key, err := keyService.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.KID != "" {
+		token.Header["kid"] = key.KID
+	}
+	return token.SignedString(key.PrivateKey)
+}
+
+// ValidateAccessToken checks the signature and expiry of an access token and
+// rejects it if it has been revoked. The verification key is picked by kid and
+// the token's alg must match that key, so a token cannot choose how it is
+// verified. ID tokens and other tokens whose token_use is not access are
+// rejected. When JWT_ISSUER is set the iss claim must match it. The audience is
+// not checked here since tokens may be minted for other services; callers that
+// serve a single audience check it themselves.
+func (a *AuthServiceImpl) ValidateAccessToken(tokenStr string) (*utils.Claims, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods(a.keyService.Algorithms())}
+	if issuer := viper.GetString("JWT_ISSUER"); issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+
+	claims := &utils.Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := a.keyService.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.PublicKey, nil
+	}, options...)
+	if err != nil || !token.Valid || claims.TokenUse != utils.TokenUseAccess {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := a.revocationService.IsRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// Logout revokes the presented access token and, when given, the refresh token
+// family it was issued with.
+func (a *AuthServiceImpl) Logout(claims *utils.Claims, refreshToken string) error {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := a.RevokeAccessToken(claims); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := a.refreshTokenRepo.FindRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if stored.UserID != userID {
+		return nil
+	}
+
+	return a.refreshTokenRepo.RevokeRefreshTokenFamily(stored.FamilyID.String())
+}
+
+// RevokeAccessToken puts a single access token on the revocation list until it
+// expires. Tokens issued to a client acting as itself have no user.
+func (a *AuthServiceImpl) RevokeAccessToken(claims *utils.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	userID := uuid.Nil
+	if !claims.IsClient() {
+		parsed, err := uuid.Parse(claims.UserID)
+		if err != nil {
+			return ErrInvalidToken
+		}
+		userID = parsed
+	}
+
+	return a.revocationService.RevokeToken(claims.ID, userID, claims.ExpiresAt.Time)
+}
+
+// FindActiveRefreshToken returns a refresh token that can still be redeemed.
+func (a *AuthServiceImpl) FindActiveRefreshToken(refreshToken string) (*models.RefreshToken, error) {
+	stored, err := a.refreshTokenRepo.FindRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if stored.RevokedAt != nil || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return stored, nil
+}
+
+// RevokeRefreshToken revokes the family of a refresh token issued to client_id.
+// Unknown tokens are ignored; tokens of another client are refused.
+func (a *AuthServiceImpl) RevokeRefreshToken(refreshToken, client_id string) error {
+	stored, err := a.refreshTokenRepo.FindRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if stored.ClientID != client_id {
+		return ErrUnauthorized
+	}
+
+	return a.refreshTokenRepo.RevokeRefreshTokenFamily(stored.FamilyID.String())
+}
+
+// RevokeUserTokens logs a user out of every session by revoking all of their
+// refresh tokens and every access token issued so far.
+func (a *AuthServiceImpl) RevokeUserTokens(user_id string) error {
+	userID, err := uuid.Parse(user_id)
+	if err != nil {
+		return utils.NewAppError(http.StatusBadRequest, "invalid user id")
+	}
+
+	if err := a.revocationService.RevokeUserTokens(userID); err != nil {
+		return err
 	}
 
-	jwtSecret := []byte(viper.GetString("JWT_SECRET"))
+	return a.refreshTokenRepo.RevokeUserRefreshTokens(user_id)
+}
+
+// IssueTokens mints an access token and starts a new session, with a new
+// refresh token family.
+func (a *AuthServiceImpl) IssueTokens(user *models.User) (*dto.LoginResponse, error) {
+	return a.IssueClientTokens(user, "", "", true)
+}
+
+// IssueClientTokens mints tokens for user on behalf of an OAuth client. A
+// refresh token, and with it a session, is only issued when offline access
+// was granted.
+func (a *AuthServiceImpl) IssueClientTokens(user *models.User, client_id, scope string, offline bool) (*dto.LoginResponse, error) {
+	if !offline {
+		return a.tokenResponse(user, "", "", client_id, scope)
+	}
+
+	session := &models.Session{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		TenantID:   user.TenantID,
+		ClientID:   client_id,
+		LastSeenAt: time.Now(),
+	}
+	rawToken, refreshToken, err := newRefreshToken(user, session.ID, client_id, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.sessionRepo.CreateSession(session); err != nil {
+		return nil, err
+	}
+	if err := a.refreshTokenRepo.CreateRefreshToken(refreshToken); err != nil {
+		return nil, err
+	}
+
+	return a.tokenResponse(user, rawToken, session.ID.String(), client_id, scope)
+}
+
+// IssueClientCredentialsToken mints an access token for a client acting as
+// itself. No refresh token is issued since the client can always ask again.
+func (a *AuthServiceImpl) IssueClientCredentialsToken(client *models.OAuthClient, scope string) (*dto.LoginResponse, error) {
+	claims := a.claimsService.BuildClientClaims(client, "")
+	claims.Scope = scope
+
+	accessToken, err := signToken(a.keyService, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(accessTokenTTL().Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// IssueImpersonationToken mints an access token that lets impersonator act
+// as user, with impersonator in its act claim. It expires after
+// IMPERSONATION_TTL and belongs to no session, so it cannot be refreshed.
+// The claims are returned for the caller to record.
+func (a *AuthServiceImpl) IssueImpersonationToken(user, impersonator *models.User) (*dto.LoginResponse, *utils.Claims, error) {
+	claims, err := a.claimsService.BuildClaims(user, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ttl := impersonationTTL()
+	claims.ExpiresAt = jwt.NewNumericDate(claims.IssuedAt.Add(ttl))
+	claims.Act = &utils.ActorClaim{Subject: impersonator.ID.String(), Email: impersonator.Email}
+
+	accessToken, err := signToken(a.keyService, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &dto.LoginResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl.Seconds()),
+	}, claims, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new access/refresh pair. A
+// refresh token can only be used once; presenting one that was already rotated
+// is treated as theft and revokes every token in its family. A refresh token
+// can only be redeemed by the client it was issued to; client_id is empty for
+// tokens from the login API.
+func (a *AuthServiceImpl) RefreshTokens(refreshToken, client_id string) (*dto.LoginResponse, error) {
+	stored, err := a.refreshTokenRepo.FindRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if stored.RevokedAt != nil || stored.ClientID != client_id {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.UsedAt != nil {
+		return nil, a.revokeFamily(stored)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := a.userRepo.FindUserById(stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	rawToken, next, err := newRefreshToken(user, stored.FamilyID, stored.ClientID, stored.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := a.refreshTokenRepo.RotateRefreshToken(stored, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, a.revokeFamily(stored)
+	}
+
+	return a.tokenResponse(user, rawToken, stored.FamilyID.String(), stored.ClientID, stored.Scope)
+}
+
+func (a *AuthServiceImpl) revokeFamily(token *models.RefreshToken) error {
+	if err := a.refreshTokenRepo.RevokeRefreshTokenFamily(token.FamilyID.String()); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (a *AuthServiceImpl) tokenResponse(user *models.User, refreshToken, session_id, client_id, scope string) (*dto.LoginResponse, error) {
+	accessToken, err := a.generateAccessToken(user, session_id, client_id, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTokenTTL().Seconds()),
+		Scope:        scope,
+		SessionID:    session_id,
+	}, nil
+}
+
+func newRefreshToken(user *models.User, familyID uuid.UUID, client_id, scope string) (string, *models.RefreshToken, error) {
+	rawToken, hashedToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		FamilyID:  familyID,
+		TokenHash: hashedToken,
+		ClientID:  client_id,
+		Scope:     scope,
+		ExpiresAt: time.Now().Add(utils.GetDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)),
+	}
+
+	return rawToken, token, nil
+}
+
+func accessTokenTTL() time.Duration {
+	return utils.GetDuration("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+func impersonationTTL() time.Duration {
+	return utils.GetDuration("IMPERSONATION_TTL", defaultImpersonationTTL)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+// maxTokenTTL is the longest any JWT signed by this service stays valid.
+// Access and ID tokens share ACCESS_TOKEN_TTL.
+func maxTokenTTL() time.Duration {
+	return max(accessTokenTTL(), impersonationTTL())
 }
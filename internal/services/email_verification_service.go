@@ -0,0 +1,265 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const defaultEmailVerificationTTL = 24 * time.Hour
+
+// EmailVerificationService confirms that users control their email address,
+// both after signup and before an email change takes effect. Links point at
+// EMAIL_VERIFICATION_URL and expire after EMAIL_VERIFICATION_TTL. What an
+// unverified user may do is up to their tenant's EmailVerificationPolicy.
+type EmailVerificationService interface {
+	SendVerificationTx(tx *gorm.DB, user *models.User) error
+	ResendVerification(email string) error
+	VerifyEmail(token string) error
+	RequestEmailChange(user *models.User, email, password string) error
+	CheckLogin(user *models.User) error
+	GetPolicy(requestor *models.User) (*models.EmailVerificationPolicy, error)
+	SavePolicy(requestor *models.User, req *dto.EmailVerificationPolicyRequest) (*models.EmailVerificationPolicy, error)
+}
+
+type EmailVerificationServiceImpl struct {
+	repo        repository.EmailVerificationRepository
+	userRepo    repository.UserRepository
+	roleRepo    repository.RoleRepository
+	authService AuthService
+	mailService MailService
+	transactor  repository.Transactor
+}
+
+func NewEmailVerificationService(
+	repo repository.EmailVerificationRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	authService AuthService,
+	mailService MailService,
+	transactor repository.Transactor,
+) EmailVerificationService {
+	return &EmailVerificationServiceImpl{
+		repo:        repo,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		authService: authService,
+		mailService: mailService,
+		transactor:  transactor,
+	}
+}
+
+// SendVerificationTx emails user a link to verify their current address, as
+// part of tx.
+func (e *EmailVerificationServiceImpl) SendVerificationTx(tx *gorm.DB, user *models.User) error {
+	return e.sendTx(tx, user, user.Email)
+}
+
+// ResendVerification sends a new link to an unverified address. Like the
+// forgot-password flow it does nothing, without an error, for unknown or
+// already verified addresses.
+func (e *EmailVerificationServiceImpl) ResendVerification(email string) error {
+	user, err := e.userRepo.FindUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return e.transactor.Transaction(func(tx *gorm.DB) error {
+		return e.sendTx(tx, user, user.Email)
+	})
+}
+
+// VerifyEmail marks the address the token was sent to as verified. For an
+// email change this is when the account moves to the new address; the old
+// one is told about it.
+func (e *EmailVerificationServiceImpl) VerifyEmail(token string) error {
+	now := time.Now()
+
+	verification, err := e.repo.FindActiveVerification(utils.HashToken(token), now)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	user, err := e.userRepo.FindUserById(verification.UserID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	previous := user.Email
+	changed := !strings.EqualFold(verification.Email, previous)
+	if changed {
+		if err := e.ensureEmailAvailable(verification.Email); err != nil {
+			return err
+		}
+	}
+
+	return e.transactor.Transaction(func(tx *gorm.DB) error {
+		if err := e.repo.ConsumeVerificationTx(tx, verification.ID.String(), now); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidVerificationToken
+			}
+			return err
+		}
+		if err := e.userRepo.VerifyEmailTx(tx, user.ID.String(), verification.Email, now); err != nil {
+			if utils.UniqueViolation(err) {
+				return utils.NewAppError(http.StatusConflict, "email address already in use")
+			}
+			return err
+		}
+		if !changed {
+			return nil
+		}
+		return e.mailService.EnqueueTx(tx, user.TenantID, mailer.TemplateSecurityAlert, previous, map[string]any{
+			"Event": fmt.Sprintf("The email address for your account was changed to %s.", verification.Email),
+		})
+	})
+}
+
+// RequestEmailChange sends a verification link to the new address. The
+// account keeps its current address until the link is used. The user's
+// password is required so a stolen session cannot take over the account.
+func (e *EmailVerificationServiceImpl) RequestEmailChange(user *models.User, email, password string) error {
+	stored, err := e.userRepo.FindUserById(user.ID.String())
+	if err != nil {
+		return err
+	}
+
+	if !e.authService.CompareHashAndPassword([]byte(password), []byte(stored.PasswordHash)) {
+		return ErrInvalidCredentials
+	}
+	if strings.EqualFold(email, stored.Email) {
+		return utils.NewAppError(http.StatusBadRequest, "new email address is the same as the current one")
+	}
+	if err := e.ensureEmailAvailable(email); err != nil {
+		return err
+	}
+
+	return e.transactor.Transaction(func(tx *gorm.DB) error {
+		return e.sendTx(tx, stored, email)
+	})
+}
+
+// CheckLogin returns ErrEmailNotVerified when user's tenant blocks sign-in
+// until the address is verified.
+func (e *EmailVerificationServiceImpl) CheckLogin(user *models.User) error {
+	if user.EmailVerifiedAt != nil || user.TenantID == nil {
+		return nil
+	}
+
+	policy, err := e.findPolicy(*user.TenantID)
+	if err != nil {
+		return err
+	}
+	if policy.Mode == utils.EmailVerificationBlock {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+func (e *EmailVerificationServiceImpl) GetPolicy(requestor *models.User) (*models.EmailVerificationPolicy, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	return e.findPolicy(*requestor.TenantID)
+}
+
+func (e *EmailVerificationServiceImpl) SavePolicy(requestor *models.User, req *dto.EmailVerificationPolicyRequest) (*models.EmailVerificationPolicy, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy := &models.EmailVerificationPolicy{TenantID: *requestor.TenantID, Mode: req.Mode}
+	if req.Mode == utils.EmailVerificationRestrict {
+		if req.RestrictedRole == "" {
+			return nil, utils.NewAppError(http.StatusBadRequest, "restricted_role is required in restrict mode")
+		}
+		if _, err := e.roleRepo.GetRoleByName(requestor.TenantID.String(), req.RestrictedRole); err != nil {
+			return nil, utils.NewAppError(http.StatusBadRequest, "invalid role name")
+		}
+		policy.RestrictedRole = req.RestrictedRole
+	}
+
+	if err := e.repo.SavePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (e *EmailVerificationServiceImpl) findPolicy(tenant_id uuid.UUID) (*models.EmailVerificationPolicy, error) {
+	policy, err := e.repo.FindPolicy(tenant_id.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.EmailVerificationPolicy{TenantID: tenant_id, Mode: utils.EmailVerificationOff}, nil
+		}
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (e *EmailVerificationServiceImpl) ensureEmailAvailable(email string) error {
+	_, err := e.userRepo.FindUserByEmail(email)
+	if err == nil {
+		return utils.NewAppError(http.StatusConflict, "email address already in use")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (e *EmailVerificationServiceImpl) sendTx(tx *gorm.DB, user *models.User, email string) error {
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	link, err := emailLink(viper.GetString("EMAIL_VERIFICATION_URL"), url.Values{"token": {token}})
+	if err != nil {
+		return err
+	}
+
+	ttl := utils.GetDuration("EMAIL_VERIFICATION_TTL", defaultEmailVerificationTTL)
+	err = e.repo.CreateVerificationTx(tx, &models.EmailVerification{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	return e.mailService.EnqueueTx(tx, user.TenantID, mailer.TemplateVerifyEmail, email, map[string]any{
+		"Link":      link,
+		"ExpiresIn": utils.HumanizeDuration(ttl),
+	})
+}
@@ -1,5 +1,97 @@
 package services
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var ErrUnauthorized = errors.New("unauthorized to perform this action")
+
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+var ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+// LoginLockedError refuses a login because of earlier failures for the
+// account or the client's IP address. It is the same whether or not the
+// account exists.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+var (
+	ErrWeakPassword    = errors.New("password does not meet the password policy")
+	ErrPasswordExpired = errors.New("password has expired and must be changed")
+)
+
+// PasswordViolation is one password policy rule a password breaks.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password breaks, so clients can
+// show them all at once.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrWeakPassword.Error()
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification link")
+	ErrEmailNotVerified         = errors.New("email address not verified")
+)
+
+var (
+	ErrInvalidMFAToken = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode  = errors.New("invalid authentication code")
+	ErrMFARequired     = errors.New("authentication code required")
+)
+
+var (
+	ErrInvalidLoginToken = errors.New("invalid or expired login link")
+	ErrInvalidLoginCode  = errors.New("invalid or expired login code")
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+)
+
+// OAuthError is reported to OAuth clients using the error codes of RFC 6749.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+var (
+	ErrWebAuthnDisabled       = errors.New("webauthn is not enabled")
+	ErrInvalidWebAuthnSession = errors.New("invalid or expired webauthn session")
+	ErrInvalidPasskey         = errors.New("passkey verification failed")
+)
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+)
+
+// ImpersonationService lets support staff act as a user to see what they
+// see. The super admin may impersonate anyone but another super admin, and
+// tenant owners the members of their own tenant. Every impersonation is
+// audited when it starts and, unless its token simply expires, when it
+// stops.
+type ImpersonationService interface {
+	StartImpersonation(requestor *models.User, user_id string) (*dto.LoginResponse, error)
+	StopImpersonation(user *models.User, claims *utils.Claims) error
+}
+
+type ImpersonationServiceImpl struct {
+	userRepo     repository.UserRepository
+	authService  AuthService
+	auditService AuditService
+}
+
+func NewImpersonationService(userRepo repository.UserRepository, authService AuthService, auditService AuditService) ImpersonationService {
+	return &ImpersonationServiceImpl{userRepo: userRepo, authService: authService, auditService: auditService}
+}
+
+// StartImpersonation issues an impersonation token for user_id to the
+// requestor. No token is handed out unless its start was audited.
+func (s *ImpersonationServiceImpl) StartImpersonation(requestor *models.User, user_id string) (*dto.LoginResponse, error) {
+	if _, err := uuid.Parse(user_id); err != nil {
+		return nil, utils.NewAppError(http.StatusNotFound, "user not found")
+	}
+	if user_id == requestor.ID.String() {
+		return nil, utils.NewAppError(http.StatusBadRequest, "cannot impersonate yourself")
+	}
+
+	user, err := s.findTarget(requestor, user_id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role.Name == utils.RoleSuperAdmin {
+		return nil, utils.NewAppError(http.StatusForbidden, "cannot impersonate the super admin")
+	}
+
+	tokens, claims, err := s.authService.IssueImpersonationToken(user, requestor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auditService.Record(&models.AuditEvent{
+		TenantID: user.TenantID,
+		ActorID:  &requestor.ID,
+		UserID:   &user.ID,
+		Event:    utils.AuditImpersonationStarted,
+		Detail:   fmt.Sprintf("token %s valid until %s", claims.ID, claims.ExpiresAt.Time.Format(time.RFC3339)),
+	}); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// findTarget loads the user the requestor wants to impersonate, if they may.
+// Ownership is checked against the DB since tokens do not carry it.
+func (s *ImpersonationServiceImpl) findTarget(requestor *models.User, user_id string) (*models.User, error) {
+	var (
+		user *models.User
+		err  error
+	)
+	if requestor.Role.Name == utils.RoleSuperAdmin {
+		user, err = s.userRepo.FindUserById(user_id)
+	} else {
+		if requestor.TenantID == nil {
+			return nil, ErrUnauthorized
+		}
+		owner, ownerErr := s.userRepo.GetUserById(requestor.TenantID.String(), requestor.ID.String())
+		if ownerErr != nil || !owner.IsOwner {
+			return nil, utils.NewAppError(http.StatusForbidden, "only tenant owners may impersonate members")
+		}
+		user, err = s.userRepo.GetUserById(requestor.TenantID.String(), user_id)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "user not found")
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// StopImpersonation ends the impersonation claims are from by revoking its
+// token.
+func (s *ImpersonationServiceImpl) StopImpersonation(user *models.User, claims *utils.Claims) error {
+	if !claims.IsImpersonation() {
+		return utils.NewAppError(http.StatusBadRequest, "not impersonating")
+	}
+	impersonatorID, err := uuid.Parse(claims.Act.Subject)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := s.authService.RevokeAccessToken(claims); err != nil {
+		return err
+	}
+
+	return s.auditService.Record(&models.AuditEvent{
+		TenantID: user.TenantID,
+		ActorID:  &impersonatorID,
+		UserID:   &user.ID,
+		Event:    utils.AuditImpersonationStopped,
+		Detail:   "token " + claims.ID,
+	})
+}
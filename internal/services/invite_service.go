@@ -3,13 +3,16 @@ package services
 import (
 	"errors"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
 	"github.com/samvibes/vexop/auth-service/internal/models"
 	"github.com/samvibes/vexop/auth-service/internal/repository"
 	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -17,44 +20,60 @@ import (
 type InviteService interface {
 	GetInvites(requestor *models.User, page, limit int) ([]*dto.InviteResponse, error)
 	GetInviteById(invite_id string) (*models.Invitation, error)
-	CreateInvite(requestor *models.User, email, role string) (string, string, error)
+	CreateInvite(requestor *models.User, email, role string) (string, error)
 	RemoveInvite(invite_id string) error
 	AcceptInvite(acceptInviteReq dto.AcceptInviteRequest, db *gorm.DB) error
-	ResendInvite(invite_id string) error
+	ResendInvite(requestor *models.User, invite_id string) (*models.Invitation, error)
 }
 
+const inviteTTL = 7 * 24 * time.Hour
+
 type InviteServiceImpl struct {
-	inviteRepo repository.InviteRepository
-	userRepo   repository.UserRepository
-	roleRepo   repository.RoleRepository
+	inviteRepo     repository.InviteRepository
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	mailService    MailService
+	transactor     repository.Transactor
+	passwordPolicy PasswordPolicyService
+	authService    AuthService
 }
 
-func NewInviteService(inviteRepo repository.InviteRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository) InviteService {
-	return &InviteServiceImpl{inviteRepo: inviteRepo, userRepo: userRepo, roleRepo: roleRepo}
+func NewInviteService(
+	inviteRepo repository.InviteRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	mailService MailService,
+	transactor repository.Transactor,
+	passwordPolicy PasswordPolicyService,
+	authService AuthService,
+) InviteService {
+	return &InviteServiceImpl{inviteRepo: inviteRepo, userRepo: userRepo, roleRepo: roleRepo, mailService: mailService, transactor: transactor, passwordPolicy: passwordPolicy, authService: authService}
 }
 
-func (i *InviteServiceImpl) CreateInvite(requestor *models.User, email, role string) (string, string, error) {
+// CreateInvite stores the invite and emails its link to the invitee, and
+// returns the invite's id.
+func (i *InviteServiceImpl) CreateInvite(requestor *models.User, email, role string) (string, error) {
 
 	existing_invite, _ := i.inviteRepo.GetInviteByEmailTenant(email, requestor.TenantID.String())
 
 	if existing_invite != nil {
 		err := utils.NewAppError(http.StatusConflict, "invite already exists")
-		return "", "", err
+		return "", err
 	}
 
 	token, hashedToken, err := utils.GenerateRandomToken()
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
 
 	_, err = i.roleRepo.GetRoleByName(requestor.TenantID.String(), role)
 	if err != nil {
-		return "", "", utils.NewAppError(http.StatusBadRequest, "invalid role name")
+		return "", utils.NewAppError(http.StatusBadRequest, "invalid role name")
 	}
 
 	newId, err := uuid.NewUUID()
 	if err != nil {
-		return "", "", nil
+		return "", err
 	}
 
 	invite := &models.Invitation{
@@ -63,16 +82,21 @@ func (i *InviteServiceImpl) CreateInvite(requestor *models.User, email, role str
 		TenantID:  *requestor.TenantID,
 		Role:      role,
 		TokenHash: hashedToken,
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
+		ExpiresAt: time.Now().Add(inviteTTL),
 		CreatedBy: requestor.ID,
 		Creator:   *requestor,
 	}
-	err = i.inviteRepo.CreateInvite(invite)
+	err = i.transactor.Transaction(func(tx *gorm.DB) error {
+		if err := i.inviteRepo.CreateInviteTx(tx, invite); err != nil {
+			return err
+		}
+		return i.enqueueInviteTx(tx, requestor, invite, token)
+	})
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
 
-	return token, newId.String(), nil
+	return newId.String(), nil
 }
 
 func (i *InviteServiceImpl) GetInviteById(invite_id string) (*models.Invitation, error) {
@@ -149,19 +173,27 @@ func (i *InviteServiceImpl) AcceptInvite(acceptInviteReq dto.AcceptInviteRequest
 		return appError
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return errors.New("failed to hash password: " + err.Error())
+	// the invite link was delivered to this address, which verifies it
+	now := time.Now()
+	user = &models.User{
+		TenantID:          &tenantID,
+		Email:             email,
+		RoleID:            role.ID.String(),
+		Role:              role,
+		PasswordChangedAt: &now,
+		EmailVerifiedAt:   &now,
 	}
 
-	user = &models.User{
-		TenantID:     &tenantID,
-		Email:        email,
-		RoleID:       role.ID.String(),
-		Role:         role,
-		PasswordHash: string(hashedPassword),
+	if err := i.passwordPolicy.Validate(user, password); err != nil {
+		return err
 	}
 
+	hashedPassword, err := i.authService.HashPassword(password)
+	if err != nil {
+		return errors.New("failed to hash password: " + err.Error())
+	}
+	user.PasswordHash = hashedPassword
+
 	err = db.Transaction(func(tx *gorm.DB) error {
 		err := i.userRepo.CreateUserTx(tx, user)
 		if err != nil {
@@ -173,7 +205,7 @@ func (i *InviteServiceImpl) AcceptInvite(acceptInviteReq dto.AcceptInviteRequest
 			return errors.New("failed to accept invite: " + err.Error())
 		}
 
-		return nil
+		return i.passwordPolicy.RecordTx(tx, user)
 	})
 
 	if err != nil {
@@ -183,11 +215,62 @@ func (i *InviteServiceImpl) AcceptInvite(acceptInviteReq dto.AcceptInviteRequest
 	return nil
 }
 
-func (i *InviteServiceImpl) ResendInvite(invite_id string) error {
-	_, err := i.inviteRepo.GetInviteById(invite_id)
+// ResendInvite emails the invite again with a new token, so only the latest
+// link works, and restarts its expiry.
+func (i *InviteServiceImpl) ResendInvite(requestor *models.User, invite_id string) (*models.Invitation, error) {
+	if _, err := uuid.Parse(invite_id); err != nil {
+		return nil, utils.NewAppError(http.StatusBadRequest, "invalid invite id")
+	}
+
+	invite, err := i.inviteRepo.GetInviteById(invite_id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "invite not found")
+		}
+		return nil, err
+	}
+	if requestor.TenantID == nil || invite.TenantID != *requestor.TenantID {
+		return nil, utils.NewAppError(http.StatusNotFound, "invite not found")
+	}
+	if invite.Accepted {
+		return nil, utils.NewAppError(http.StatusBadRequest, "invite already accepted")
+	}
+
+	token, hashedToken, err := utils.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite.TokenHash = hashedToken
+	invite.ExpiresAt = time.Now().Add(inviteTTL)
+	err = i.transactor.Transaction(func(tx *gorm.DB) error {
+		if err := i.inviteRepo.RenewInviteTx(tx, invite.ID, invite.TokenHash, invite.ExpiresAt); err != nil {
+			return err
+		}
+		return i.enqueueInviteTx(tx, requestor, invite, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// enqueueInviteTx emails a link to INVITE_URL carrying the invite id and
+// token that AcceptInvite expects.
+func (i *InviteServiceImpl) enqueueInviteTx(tx *gorm.DB, requestor *models.User, invite *models.Invitation, token string) error {
+	link, err := emailLink(viper.GetString("INVITE_URL"), url.Values{
+		"invite_id": {invite.ID.String()},
+		"token":     {token},
+	})
 	if err != nil {
 		return err
 	}
-	// TODO: send mail
-	return nil
+
+	return i.mailService.EnqueueTx(tx, &invite.TenantID, mailer.TemplateInvite, invite.Email, map[string]any{
+		"Link":         link,
+		"InviterEmail": requestor.Email,
+		"Role":         invite.Role,
+		"ExpiresIn":    utils.HumanizeDuration(inviteTTL),
+	})
 }
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// SigningKey is a key used to sign or verify access tokens. For HS256 the
+// private and public key are the same shared secret.
+type SigningKey struct {
+	KID        string
+	Method     jwt.SigningMethod
+	PrivateKey interface{}
+	PublicKey  interface{}
+}
+
+type KeyService interface {
+	SigningKey() (*SigningKey, error)
+	VerificationKey(kid string) (*SigningKey, error)
+	Algorithms() []string
+	JWKS() (*dto.JWKSet, error)
+}
+
+// StaticKeyService signs with a single key taken from configuration:
+// JWT_SIGNING_ALG selects HS256 (default, using JWT_SECRET), RS256, ES256 or
+// EdDSA, with the PEM private key in JWT_PRIVATE_KEY or JWT_PRIVATE_KEY_FILE.
+type StaticKeyService struct {
+	key *SigningKey
+}
+
+func NewKeyService() (KeyService, error) {
+	alg := viper.GetString("JWT_SIGNING_ALG")
+	if alg == "" {
+		alg = jwt.SigningMethodHS256.Alg()
+	}
+
+	if alg == jwt.SigningMethodHS256.Alg() {
+		secret := viper.GetString("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("JWT_SECRET must be set for HS256 signing")
+		}
+		return &StaticKeyService{key: &SigningKey{
+			Method:     jwt.SigningMethodHS256,
+			PrivateKey: []byte(secret),
+			PublicKey:  []byte(secret),
+		}}, nil
+	}
+
+	pemData := []byte(viper.GetString("JWT_PRIVATE_KEY"))
+	if path := viper.GetString("JWT_PRIVATE_KEY_FILE"); len(pemData) == 0 && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		pemData = data
+	}
+	if len(pemData) == 0 {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY or JWT_PRIVATE_KEY_FILE must be set for %s signing", alg)
+	}
+
+	privateKey, err := utils.ParsePrivateKeyPEM(pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := NewSigningKey(viper.GetString("JWT_KEY_ID"), alg, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticKeyService{key: key}, nil
+}
+
+// NewSigningKey checks that privateKey suits alg and derives the kid from the
+// public key thumbprint when none is given.
+func NewSigningKey(kid, alg string, privateKey crypto.Signer) (*SigningKey, error) {
+	method, err := asymmetricMethod(alg, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := privateKey.Public()
+	if kid == "" {
+		kid, err = utils.JWKThumbprint(publicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &SigningKey{
+		KID:        kid,
+		Method:     method,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	}, nil
+}
+
+func (s *StaticKeyService) SigningKey() (*SigningKey, error) {
+	return s.key, nil
+}
+
+func (s *StaticKeyService) VerificationKey(kid string) (*SigningKey, error) {
+	if kid != s.key.KID {
+		return nil, ErrInvalidToken
+	}
+	return &SigningKey{KID: s.key.KID, Method: s.key.Method, PublicKey: s.key.PublicKey}, nil
+}
+
+func (s *StaticKeyService) Algorithms() []string {
+	return []string{s.key.Method.Alg()}
+}
+
+func (s *StaticKeyService) JWKS() (*dto.JWKSet, error) {
+	jwks := &dto.JWKSet{Keys: []dto.JWK{}}
+
+	// a shared secret must never be published
+	if s.key.Method == jwt.SigningMethodHS256 {
+		return jwks, nil
+	}
+
+	jwk, err := utils.PublicJWK(s.key.KID, s.key.Method.Alg(), s.key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	jwks.Keys = append(jwks.Keys, jwk)
+
+	return jwks, nil
+}
+
+func asymmetricMethod(alg string, privateKey crypto.Signer) (jwt.SigningMethod, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		if _, ok := privateKey.(*rsa.PrivateKey); ok {
+			return jwt.SigningMethodRS256, nil
+		}
+	case jwt.SigningMethodES256.Alg():
+		if key, ok := privateKey.(*ecdsa.PrivateKey); ok && key.Curve == elliptic.P256() {
+			return jwt.SigningMethodES256, nil
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		if _, ok := privateKey.(ed25519.PrivateKey); ok {
+			return jwt.SigningMethodEdDSA, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	return nil, fmt.Errorf("private key type %T cannot be used with %s", privateKey, alg)
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultKeyCacheTTL       = time.Minute
+	defaultKeyRotationPeriod = 30 * 24 * time.Hour
+	minKeyReloadInterval     = 10 * time.Second
+)
+
+// KeyRotator is implemented by key services whose keys can be rotated.
+type KeyRotator interface {
+	RotateKeys() error
+	RotateKeysIfDue() error
+}
+
+type storedSigningKey struct {
+	key   *SigningKey
+	state string
+}
+
+// KeyStoreService keeps signing keys in Postgres with their private halves
+// encrypted under JWT_KEY_ENCRYPTION_KEY. It signs with the active key, and
+// verifies and publishes the next, active and unexpired retired keys so that
+// rotating does not invalidate tokens already handed out. Keys are cached and
+// reloaded every JWT_KEY_CACHE_TTL so rotations by other instances are picked
+// up.
+type KeyStoreService struct {
+	repo           repository.SigningKeyRepository
+	alg            string
+	encryptionKey  []byte
+	cacheTTL       time.Duration
+	rotationPeriod time.Duration
+
+	mu       sync.RWMutex
+	keys     map[string]*storedSigningKey
+	active   *SigningKey
+	loadedAt time.Time
+}
+
+func NewKeyStoreService(repo repository.SigningKeyRepository) (*KeyStoreService, error) {
+	alg := viper.GetString("JWT_SIGNING_ALG")
+	switch alg {
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg():
+	default:
+		return nil, errors.New("JWT_SIGNING_ALG must be RS256, ES256 or EdDSA for the postgres key store")
+	}
+
+	encryptionKey, err := base64.StdEncoding.DecodeString(viper.GetString("JWT_KEY_ENCRYPTION_KEY"))
+	if err != nil || len(encryptionKey) != 32 {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY must be 32 base64-encoded bytes")
+	}
+
+	return &KeyStoreService{
+		repo:           repo,
+		alg:            alg,
+		encryptionKey:  encryptionKey,
+		cacheTTL:       utils.GetDuration("JWT_KEY_CACHE_TTL", defaultKeyCacheTTL),
+		rotationPeriod: utils.GetDuration("JWT_KEY_ROTATION_PERIOD", defaultKeyRotationPeriod),
+		keys:           make(map[string]*storedSigningKey),
+	}, nil
+}
+
+// EnsureKeys creates an active and a next key when the store is empty.
+func (k *KeyStoreService) EnsureKeys() error {
+	for i := 0; i < 2; i++ {
+		if err := k.reload(); err != nil {
+			return err
+		}
+
+		k.mu.RLock()
+		ready := k.active != nil && k.hasState(utils.KeyStateNext)
+		k.mu.RUnlock()
+		if ready {
+			return nil
+		}
+
+		if err := k.rotate(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return k.reload()
+}
+
+func (k *KeyStoreService) RotateKeys() error {
+	if err := k.rotate(time.Now()); err != nil {
+		return err
+	}
+	return k.reload()
+}
+
+// RotateKeysIfDue rotates once the active key is older than
+// JWT_KEY_ROTATION_PERIOD.
+func (k *KeyStoreService) RotateKeysIfDue() error {
+	if err := k.rotate(time.Now().Add(-k.rotationPeriod)); err != nil {
+		return err
+	}
+	return k.reload()
+}
+
+// StartRotation checks every interval whether the active key is due for
+// rotation. It is safe to run on every instance.
+func (k *KeyStoreService) StartRotation(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := k.RotateKeysIfDue(); err != nil {
+				log.Println("signing key rotation failed: ", err)
+			}
+		}
+	}()
+}
+
+func (k *KeyStoreService) SigningKey() (*SigningKey, error) {
+	if err := k.ensureFresh(); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.active == nil {
+		return nil, errors.New("no active signing key")
+	}
+	return k.active, nil
+}
+
+func (k *KeyStoreService) VerificationKey(kid string) (*SigningKey, error) {
+	if err := k.ensureFresh(); err != nil {
+		return nil, err
+	}
+
+	if key := k.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	// the key may have been created by another instance since the last load
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) > minKeyReloadInterval
+	k.mu.RUnlock()
+	if stale {
+		if err := k.reload(); err != nil {
+			return nil, err
+		}
+		if key := k.lookup(kid); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, ErrInvalidToken
+}
+
+func (k *KeyStoreService) Algorithms() []string {
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+func (k *KeyStoreService) JWKS() (*dto.JWKSet, error) {
+	if err := k.ensureFresh(); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	jwks := &dto.JWKSet{Keys: []dto.JWK{}}
+	for _, stored := range k.keys {
+		jwk, err := utils.PublicJWK(stored.key.KID, stored.key.Method.Alg(), stored.key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks, nil
+}
+
+func (k *KeyStoreService) lookup(kid string) *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	stored, ok := k.keys[kid]
+	if !ok {
+		return nil
+	}
+	return &SigningKey{KID: stored.key.KID, Method: stored.key.Method, PublicKey: stored.key.PublicKey}
+}
+
+func (k *KeyStoreService) hasState(state string) bool {
+	for _, stored := range k.keys {
+		if stored.state == state {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *KeyStoreService) rotate(activatedBefore time.Time) error {
+	next, err := k.newStoredKey()
+	if err != nil {
+		return err
+	}
+
+	// a retired key stays published until every token it signed has expired.
+	// Other instances keep signing with it until they next reload, up to
+	// JWT_KEY_CACHE_TTL after the rotation.
+	retiredUntil := time.Now().Add(k.cacheTTL + maxTokenTTL())
+	_, err = k.repo.RotateSigningKeys(next, activatedBefore, retiredUntil)
+	return err
+}
+
+func (k *KeyStoreService) newStoredKey() (*models.SigningKey, error) {
+	privateKey, err := generatePrivateKey(k.alg)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := utils.JWKThumbprint(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	pemData, err := utils.EncodePrivateKeyPEM(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := utils.Encrypt(k.encryptionKey, pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		ID:                  uuid.New(),
+		KID:                 kid,
+		Algorithm:           k.alg,
+		State:               utils.KeyStateNext,
+		EncryptedPrivateKey: encrypted,
+	}, nil
+}
+
+func (k *KeyStoreService) ensureFresh() error {
+	k.mu.RLock()
+	fresh := time.Since(k.loadedAt) < k.cacheTTL
+	k.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	return k.reload()
+}
+
+func (k *KeyStoreService) reload() error {
+	now := time.Now()
+	storedKeys, err := k.repo.GetSigningKeys(now)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*storedSigningKey, len(storedKeys))
+	var active *SigningKey
+	for _, stored := range storedKeys {
+		pemData, err := utils.Decrypt(k.encryptionKey, stored.EncryptedPrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt signing key %s: %w", stored.KID, err)
+		}
+
+		privateKey, err := utils.ParsePrivateKeyPEM(pemData)
+		if err != nil {
+			return err
+		}
+
+		key, err := NewSigningKey(stored.KID, stored.Algorithm, privateKey)
+		if err != nil {
+			return err
+		}
+
+		keys[stored.KID] = &storedSigningKey{key: key, state: stored.State}
+		if stored.State == utils.KeyStateActive {
+			active = key
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.active = active
+	k.loadedAt = now
+	k.mu.Unlock()
+
+	return nil
+}
+
+func generatePrivateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256.Alg():
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/lockout"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+)
+
+const defaultLockoutWindow = time.Hour
+
+// LockoutService throttles password logins. Failures are counted per account
+// (by email, so unknown addresses are throttled too) and per client IP
+// address. Past a few failures each further one is answered with a growing
+// delay, and at the threshold the key is locked out for a while. Lockouts
+// are recorded as audit events.
+type LockoutService interface {
+	Check(email, ip string) error
+	RecordFailure(email, ip string, user *models.User) error
+	RecordSuccess(email string) error
+	Unlock(requestor *models.User, user_id string) error
+}
+
+type LockoutServiceImpl struct {
+	store        lockout.Store
+	userRepo     repository.UserRepository
+	auditService AuditService
+	window       time.Duration
+	account      lockout.Policy
+	ip           lockout.Policy
+}
+
+func NewLockoutService(store lockout.Store, userRepo repository.UserRepository, auditService AuditService) LockoutService {
+	return &LockoutServiceImpl{
+		store:        store,
+		userRepo:     userRepo,
+		auditService: auditService,
+		window:       utils.GetDuration("LOCKOUT_WINDOW", defaultLockoutWindow),
+		account: lockout.Policy{
+			DelayAfter: utils.GetInt("LOCKOUT_DELAY_AFTER", 3),
+			BaseDelay:  utils.GetDuration("LOCKOUT_BASE_DELAY", time.Second),
+			Threshold:  utils.GetInt("LOCKOUT_THRESHOLD", 10),
+			Duration:   utils.GetDuration("LOCKOUT_DURATION", 15*time.Minute),
+		},
+		ip: lockout.Policy{
+			DelayAfter: utils.GetInt("LOCKOUT_IP_DELAY_AFTER", 20),
+			BaseDelay:  utils.GetDuration("LOCKOUT_BASE_DELAY", time.Second),
+			Threshold:  utils.GetInt("LOCKOUT_IP_THRESHOLD", 100),
+			Duration:   utils.GetDuration("LOCKOUT_DURATION", 15*time.Minute),
+		},
+	}
+}
+
+// Check returns a *LoginLockedError while either the account or the IP
+// address is delayed or locked out.
+func (l *LockoutServiceImpl) Check(email, ip string) error {
+	now := time.Now()
+
+	var until time.Time
+	for _, key := range l.keys(email, ip) {
+		counter, err := l.store.Get(key)
+		if err != nil {
+			return err
+		}
+		if counter.LockedUntil.After(until) {
+			until = counter.LockedUntil
+		}
+	}
+
+	if until.After(now) {
+		return &LoginLockedError{RetryAfter: until.Sub(now)}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed login. user is nil when no account has the
+// address.
+func (l *LockoutServiceImpl) RecordFailure(email, ip string, user *models.User) error {
+	now := time.Now()
+
+	if err := l.recordFailure(accountKey(email), l.account, now, func(failures int, d time.Duration) {
+		l.audit(&models.AuditEvent{
+			Event:  utils.AuditLoginLocked,
+			IP:     ip,
+			Detail: fmt.Sprintf("email %s locked for %s after %d failed logins", email, d, failures),
+		}, user)
+	}); err != nil {
+		return err
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	return l.recordFailure(ipKey(ip), l.ip, now, func(failures int, d time.Duration) {
+		l.audit(&models.AuditEvent{
+			Event:  utils.AuditLoginIPLocked,
+			IP:     ip,
+			Detail: fmt.Sprintf("ip %s locked for %s after %d failed logins", ip, d, failures),
+		}, user)
+	})
+}
+
+// RecordSuccess clears the account's failures. The IP address counter is
+// left alone so one working account cannot be used to reset it.
+func (l *LockoutServiceImpl) RecordSuccess(email string) error {
+	return l.store.Reset(accountKey(email))
+}
+
+// Unlock lifts a lockout on a member of the requestor's tenant.
+func (l *LockoutServiceImpl) Unlock(requestor *models.User, user_id string) error {
+	if requestor.TenantID == nil {
+		return ErrUnauthorized
+	}
+
+	user, err := l.userRepo.GetUserById(requestor.TenantID.String(), user_id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(http.StatusNotFound, "user not found")
+		}
+		return err
+	}
+
+	if err := l.store.Reset(accountKey(user.Email)); err != nil {
+		return err
+	}
+
+	return l.auditService.Record(&models.AuditEvent{
+		TenantID: user.TenantID,
+		ActorID:  &requestor.ID,
+		UserID:   &user.ID,
+		Event:    utils.AuditAccountUnlocked,
+	})
+}
+
+// recordFailure counts a failure for key and applies policy. locked is
+// called whenever the failure count is at or past the lockout threshold.
+func (l *LockoutServiceImpl) recordFailure(key string, policy lockout.Policy, now time.Time, locked func(failures int, d time.Duration)) error {
+	failures, err := l.store.RecordFailure(key, l.window, now)
+	if err != nil {
+		return err
+	}
+
+	d := policy.LockFor(failures)
+	if d == 0 {
+		return nil
+	}
+	if err := l.store.Lock(key, now.Add(d)); err != nil {
+		return err
+	}
+	if policy.Locks(failures) {
+		locked(failures, d)
+	}
+
+	return nil
+}
+
+// audit records a lockout. A failed write is only logged; it must not turn a
+// rejected login into a server error.
+func (l *LockoutServiceImpl) audit(event *models.AuditEvent, user *models.User) {
+	if user != nil {
+		event.TenantID = user.TenantID
+		event.UserID = &user.ID
+	}
+	if err := l.auditService.Record(event); err != nil {
+		log.Printf("failed to record audit event %s: %v", event.Event, err)
+	}
+}
+
+func (l *LockoutServiceImpl) keys(email, ip string) []string {
+	keys := []string{accountKey(email)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
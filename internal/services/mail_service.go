@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const defaultMailAppName = "auth-service"
+
+// MailService renders emails from templates and queues them in the outbox.
+// Tenants may replace the built-in templates with their own. Every template
+// gets AppName (MAIL_APP_NAME) and Email, the recipient, on top of the data
+// passed in.
+type MailService interface {
+	EnqueueTx(tx *gorm.DB, tenant_id *uuid.UUID, name, to string, data map[string]any) error
+	GetTemplates(requestor *models.User) ([]*dto.EmailTemplateResponse, error)
+	GetTemplate(requestor *models.User, name string) (*dto.EmailTemplateResponse, error)
+	SaveTemplate(requestor *models.User, name string, req *dto.EmailTemplateRequest) (*dto.EmailTemplateResponse, error)
+	DeleteTemplate(requestor *models.User, name string) error
+}
+
+type MailServiceImpl struct {
+	outboxRepo   repository.OutboxRepository
+	templateRepo repository.EmailTemplateRepository
+}
+
+func NewMailService(outboxRepo repository.OutboxRepository, templateRepo repository.EmailTemplateRepository) MailService {
+	return &MailServiceImpl{outboxRepo: outboxRepo, templateRepo: templateRepo}
+}
+
+// EnqueueTx queues an email within tx, so it is only sent if tx commits.
+func (m *MailServiceImpl) EnqueueTx(tx *gorm.DB, tenant_id *uuid.UUID, name, to string, data map[string]any) error {
+	message, err := m.render(tenant_id, name, to, data)
+	if err != nil {
+		return err
+	}
+
+	return m.outboxRepo.EnqueueTx(tx, &models.OutboxEmail{
+		ID:            uuid.New(),
+		TenantID:      tenant_id,
+		Template:      name,
+		To:            message.To,
+		Subject:       message.Subject,
+		HTMLBody:      message.HTML,
+		TextBody:      message.Text,
+		NextAttemptAt: time.Now(),
+	})
+}
+
+func (m *MailServiceImpl) GetTemplates(requestor *models.User) ([]*dto.EmailTemplateResponse, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	custom, err := m.templateRepo.FindTemplates(requestor.TenantID.String())
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*models.EmailTemplate, len(custom))
+	for _, template := range custom {
+		byName[template.Name] = template
+	}
+
+	templates := make([]*dto.EmailTemplateResponse, 0, len(mailer.Names()))
+	for _, name := range mailer.Names() {
+		if template, ok := byName[name]; ok {
+			templates = append(templates, customTemplateResponse(template))
+			continue
+		}
+		template, err := defaultTemplateResponse(name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, nil
+}
+
+func (m *MailServiceImpl) GetTemplate(requestor *models.User, name string) (*dto.EmailTemplateResponse, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+	if !mailer.IsTemplate(name) {
+		return nil, utils.NewAppError(http.StatusNotFound, "email template not found")
+	}
+
+	template, err := m.templateRepo.FindTemplate(requestor.TenantID.String(), name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultTemplateResponse(name)
+		}
+		return nil, err
+	}
+
+	return customTemplateResponse(template), nil
+}
+
+// SaveTemplate replaces a built-in template for the requestor's tenant. The
+// template is test-rendered first so a broken one is never stored.
+func (m *MailServiceImpl) SaveTemplate(requestor *models.User, name string, req *dto.EmailTemplateRequest) (*dto.EmailTemplateResponse, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+	if !mailer.IsTemplate(name) {
+		return nil, utils.NewAppError(http.StatusNotFound, "email template not found")
+	}
+
+	source := &mailer.Template{Subject: req.Subject, HTML: req.HTML, Text: req.Text}
+	if _, err := source.Render(requestor.Email, templateData(requestor.Email, nil)); err != nil {
+		return nil, utils.NewAppError(http.StatusBadRequest, "invalid template: "+err.Error())
+	}
+
+	template := &models.EmailTemplate{
+		TenantID: *requestor.TenantID,
+		Name:     name,
+		Subject:  req.Subject,
+		HTML:     req.HTML,
+		Text:     req.Text,
+	}
+	if err := m.templateRepo.SaveTemplate(template); err != nil {
+		return nil, err
+	}
+
+	return customTemplateResponse(template), nil
+}
+
+// DeleteTemplate goes back to the built-in template.
+func (m *MailServiceImpl) DeleteTemplate(requestor *models.User, name string) error {
+	if requestor.TenantID == nil {
+		return ErrUnauthorized
+	}
+
+	if err := m.templateRepo.DeleteTemplate(requestor.TenantID.String(), name); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(http.StatusNotFound, "email template not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// render uses the tenant's template when there is one. Should it fail to
+// render, the built-in template is used instead so the email still goes out.
+func (m *MailServiceImpl) render(tenant_id *uuid.UUID, name, to string, data map[string]any) (*mailer.Message, error) {
+	data = templateData(to, data)
+
+	if tenant_id != nil {
+		custom, err := m.templateRepo.FindTemplate(tenant_id.String(), name)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if custom != nil {
+			source := &mailer.Template{Subject: custom.Subject, HTML: custom.HTML, Text: custom.Text}
+			message, err := source.Render(to, data)
+			if err == nil {
+				return message, nil
+			}
+			log.Printf("email template %s of tenant %s failed to render: %v", name, tenant_id, err)
+		}
+	}
+
+	source, err := mailer.Default(name)
+	if err != nil {
+		return nil, err
+	}
+	return source.Render(to, data)
+}
+
+func templateData(to string, data map[string]any) map[string]any {
+	appName := viper.GetString("MAIL_APP_NAME")
+	if appName == "" {
+		appName = defaultMailAppName
+	}
+
+	merged := map[string]any{"AppName": appName, "Email": to}
+	maps.Copy(merged, data)
+	return merged
+}
+
+func defaultTemplateResponse(name string) (*dto.EmailTemplateResponse, error) {
+	source, err := mailer.Default(name)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.EmailTemplateResponse{Name: name, Subject: source.Subject, HTML: source.HTML, Text: source.Text}, nil
+}
+
+func customTemplateResponse(template *models.EmailTemplate) *dto.EmailTemplateResponse {
+	return &dto.EmailTemplateResponse{
+		Name:    template.Name,
+		Subject: template.Subject,
+		HTML:    template.HTML,
+		Text:    template.Text,
+		Custom:  true,
+	}
+}
+
+// emailLink adds query to a frontend URL from config, such as INVITE_URL.
+func emailLink(base string, query url.Values) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	values := link.Query()
+	for key := range query {
+		values.Set(key, query.Get(key))
+	}
+	link.RawQuery = values.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,622 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMFAChallengeTTL  = 5 * time.Minute
+	maxMFAChallengeAttempts = 5
+	recoveryCodeCount       = 10
+	defaultTOTPIssuer       = "auth-service"
+)
+
+// MFAService handles TOTP enrollment, recovery codes, the second step of
+// login and the tenant policies that make a second factor mandatory. TOTP
+// secrets are encrypted under MFA_ENCRYPTION_KEY, which must be set before
+// anyone can enroll. Registered passkeys also count as a second factor; their
+// ceremonies live in WebAuthnService, which completes challenges through
+// CompleteChallenge. Wrong codes count as failed logins towards the account's
+// lockout, and a completed login clears them.
+type MFAService interface {
+	EnrollTOTP(user *models.User) (*dto.TOTPEnrollment, error)
+	ActivateTOTP(user *models.User, code string) ([]string, error)
+	DisableTOTP(user *models.User, code string) error
+	RegenerateRecoveryCodes(user *models.User, code string) ([]string, error)
+	BeginLogin(user *models.User) (*dto.MFAChallengeResponse, error)
+	EnrollChallenge(mfa_token string) (*dto.TOTPEnrollment, error)
+	CompleteLogin(req *dto.MFALoginRequest, ip string) (*dto.LoginResponse, error)
+	ChallengeUser(mfa_token string) (*models.User, error)
+	CompleteChallenge(mfa_token, ip string, verify func(user *models.User) error) (*dto.LoginResponse, error)
+	EnrolledMethods(user_id string) ([]string, error)
+	RequiresMFA(user *models.User) (bool, error)
+	VerifyLoginCode(user *models.User, code, ip string) error
+	GetPolicy(requestor *models.User) (*models.MFAPolicy, error)
+	SavePolicy(requestor *models.User, req *dto.MFAPolicyRequest) (*models.MFAPolicy, error)
+}
+
+type MFAServiceImpl struct {
+	repo           repository.MFARepository
+	webauthnRepo   repository.WebAuthnRepository
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	authService    AuthService
+	lockoutService LockoutService
+}
+
+func NewMFAService(
+	repo repository.MFARepository,
+	webauthnRepo repository.WebAuthnRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	authService AuthService,
+	lockoutService LockoutService,
+) MFAService {
+	return &MFAServiceImpl{
+		repo:           repo,
+		webauthnRepo:   webauthnRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		authService:    authService,
+		lockoutService: lockoutService,
+	}
+}
+
+// EnrollTOTP starts enrollment with a fresh secret. It replaces a pending one
+// but not one that is already active.
+func (m *MFAServiceImpl) EnrollTOTP(user *models.User) (*dto.TOTPEnrollment, error) {
+	credential, err := m.findTOTPCredential(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if credential != nil && credential.ConfirmedAt != nil {
+		return nil, utils.NewAppError(http.StatusConflict, "totp is already enabled")
+	}
+
+	encryptionKey, err := mfaEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encryptedSecret, err := utils.Encrypt(encryptionKey, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.repo.SaveTOTPCredential(&models.TOTPCredential{
+		UserID:          user.ID,
+		EncryptedSecret: encryptedSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := viper.GetString("MFA_TOTP_ISSUER")
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+
+	return &dto.TOTPEnrollment{Secret: secret, OTPAuthURI: utils.TOTPURI(issuer, user.Email, secret)}, nil
+}
+
+// ActivateTOTP confirms a pending secret with a code from the authenticator
+// and returns a new set of recovery codes.
+func (m *MFAServiceImpl) ActivateTOTP(user *models.User, code string) ([]string, error) {
+	credential, err := m.findTOTPCredential(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, utils.NewAppError(http.StatusBadRequest, "totp enrollment has not been started")
+	}
+	if credential.ConfirmedAt != nil {
+		return nil, utils.NewAppError(http.StatusConflict, "totp is already enabled")
+	}
+
+	if err := m.verifyTOTP(credential, code); err != nil {
+		return nil, err
+	}
+
+	return m.confirmTOTP(credential)
+}
+
+// DisableTOTP removes the user's authenticator and recovery codes. It needs a
+// current code and is refused while the tenant's policy requires MFA and the
+// user has no passkey to fall back on.
+func (m *MFAServiceImpl) DisableTOTP(user *models.User, code string) error {
+	if err := m.verifyEnrolledCode(user, code); err != nil {
+		return err
+	}
+
+	required, err := m.policyRequiresMFA(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		passkeys, err := m.webauthnRepo.CountUserCredentials(user.ID.String())
+		if err != nil {
+			return err
+		}
+		if passkeys == 0 {
+			return utils.NewAppError(http.StatusForbidden, "mfa is required by your organization")
+		}
+	}
+
+	return m.repo.DeleteMFA(user.ID.String())
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not.
+func (m *MFAServiceImpl) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if err := m.verifyEnrolledCode(user, code); err != nil {
+		return nil, err
+	}
+
+	return m.generateRecoveryCodes(user.ID)
+}
+
+// BeginLogin is called once the password has been checked. It returns nil
+// when no second factor is needed, and otherwise a challenge to complete with
+// CompleteLogin.
+func (m *MFAServiceImpl) BeginLogin(user *models.User) (*dto.MFAChallengeResponse, error) {
+	methods, err := m.EnrolledMethods(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	enrolled := len(methods) > 0
+	required := enrolled
+	if !enrolled {
+		if required, err = m.policyRequiresMFA(user); err != nil {
+			return nil, err
+		}
+	}
+	if !required {
+		return nil, nil
+	}
+
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := utils.GetDuration("MFA_CHALLENGE_TTL", defaultMFAChallengeTTL)
+	err = m.repo.CreateChallenge(&models.MFAChallenge{
+		ID:        uuid.New(),
+		TokenHash: tokenHash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !enrolled {
+		methods = []string{utils.MFAMethodTOTP}
+	}
+
+	return &dto.MFAChallengeResponse{
+		MFARequired:        true,
+		MFAToken:           token,
+		Methods:            methods,
+		EnrollmentRequired: !enrolled,
+		ExpiresIn:          int64(ttl.Seconds()),
+	}, nil
+}
+
+// EnrollChallenge lets a user whose tenant requires MFA set up an
+// authenticator during login. The challenge is then completed with a code
+// from it. Users who already have a second factor must complete the challenge
+// with it, or anyone knowing their password could enroll one of their own.
+func (m *MFAServiceImpl) EnrollChallenge(mfa_token string) (*dto.TOTPEnrollment, error) {
+	challenge, err := m.findChallenge(mfa_token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := m.userRepo.FindUserById(challenge.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := m.EnrolledMethods(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) > 0 {
+		return nil, utils.NewAppError(http.StatusForbidden, "complete the challenge with your enrolled second factor")
+	}
+
+	required, err := m.policyRequiresMFA(user)
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return nil, utils.NewAppError(http.StatusForbidden, "multi-factor authentication is not required")
+	}
+
+	return m.EnrollTOTP(user)
+}
+
+// CompleteLogin exchanges a challenge and a TOTP or recovery code for tokens.
+// A pending secret set up with EnrollChallenge is activated by its first code,
+// and the response then carries the new recovery codes. Wrong codes count as
+// failed logins from ip.
+func (m *MFAServiceImpl) CompleteLogin(req *dto.MFALoginRequest, ip string) (*dto.LoginResponse, error) {
+	var credential *models.TOTPCredential
+	user, err := m.consumeChallenge(req.MFAToken, ip, func(user *models.User) error {
+		var err error
+		if credential, err = m.findTOTPCredential(user.ID.String()); err != nil {
+			return err
+		}
+
+		switch {
+		case credential == nil:
+			return ErrInvalidMFACode
+		case req.RecoveryCode != "" && credential.ConfirmedAt != nil:
+			return m.useRecoveryCode(user.ID.String(), req.RecoveryCode)
+		default:
+			return m.verifyTOTP(credential, req.Code)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if credential.ConfirmedAt == nil {
+		if recoveryCodes, err = m.confirmTOTP(credential); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := m.authService.IssueTokens(user)
+	if err != nil {
+		return nil, err
+	}
+	tokens.RecoveryCodes = recoveryCodes
+
+	return tokens, nil
+}
+
+// ChallengeUser returns the user an open challenge belongs to.
+func (m *MFAServiceImpl) ChallengeUser(mfa_token string) (*models.User, error) {
+	challenge, err := m.findChallenge(mfa_token)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.userRepo.FindUserById(challenge.UserID.String())
+}
+
+// CompleteChallenge exchanges a challenge for tokens once verify accepts the
+// second factor. A verify error of ErrInvalidMFACode counts as a failed
+// attempt, and as a failed login from ip.
+func (m *MFAServiceImpl) CompleteChallenge(mfa_token, ip string, verify func(user *models.User) error) (*dto.LoginResponse, error) {
+	user, err := m.consumeChallenge(mfa_token, ip, verify)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.authService.IssueTokens(user)
+}
+
+// EnrolledMethods lists the second factors the user can complete a
+// challenge with. It is empty when the user has none.
+func (m *MFAServiceImpl) EnrolledMethods(user_id string) ([]string, error) {
+	methods := []string{}
+
+	credential, err := m.findTOTPCredential(user_id)
+	if err != nil {
+		return nil, err
+	}
+	if credential != nil && credential.ConfirmedAt != nil {
+		methods = append(methods, utils.MFAMethodTOTP, utils.MFAMethodRecoveryCode)
+	}
+
+	passkeys, err := m.webauthnRepo.CountUserCredentials(user_id)
+	if err != nil {
+		return nil, err
+	}
+	if passkeys > 0 {
+		methods = append(methods, utils.MFAMethodWebAuthn)
+	}
+
+	return methods, nil
+}
+
+// RequiresMFA reports whether the user's tenant requires a second factor
+// from them.
+func (m *MFAServiceImpl) RequiresMFA(user *models.User) (bool, error) {
+	return m.policyRequiresMFA(user)
+}
+
+// VerifyLoginCode is for sign-in forms that ask for the password and code
+// together. It passes when no second factor is needed, and returns
+// ErrMFARequired when one is needed but code is empty. code may be a TOTP or
+// a recovery code. A wrong code counts as a failed login from ip, and the
+// account's failures are cleared once it passes.
+func (m *MFAServiceImpl) VerifyLoginCode(user *models.User, code, ip string) error {
+	if err := m.verifyLoginCode(user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := m.lockoutService.RecordFailure(user.Email, ip, user); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	return m.lockoutService.RecordSuccess(user.Email)
+}
+
+func (m *MFAServiceImpl) verifyLoginCode(user *models.User, code string) error {
+	credential, err := m.findTOTPCredential(user.ID.String())
+	if err != nil {
+		return err
+	}
+
+	if credential == nil || credential.ConfirmedAt == nil {
+		passkeys, err := m.webauthnRepo.CountUserCredentials(user.ID.String())
+		if err != nil {
+			return err
+		}
+		if passkeys > 0 {
+			return utils.NewAppError(http.StatusForbidden, "sign in with your passkey")
+		}
+
+		required, err := m.policyRequiresMFA(user)
+		if err != nil {
+			return err
+		}
+		if required {
+			return utils.NewAppError(http.StatusForbidden, "set up multi-factor authentication before signing in")
+		}
+		return nil
+	}
+
+	if code == "" {
+		return ErrMFARequired
+	}
+	if len(code) == utils.TOTPDigits {
+		return m.verifyTOTP(credential, code)
+	}
+	return m.useRecoveryCode(user.ID.String(), code)
+}
+
+func (m *MFAServiceImpl) GetPolicy(requestor *models.User) (*models.MFAPolicy, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := m.repo.FindMFAPolicy(requestor.TenantID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.MFAPolicy{TenantID: *requestor.TenantID, RequiredRoles: []string{}}, nil
+		}
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (m *MFAServiceImpl) SavePolicy(requestor *models.User, req *dto.MFAPolicyRequest) (*models.MFAPolicy, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	requiredRoles := req.RequiredRoles
+	if requiredRoles == nil {
+		requiredRoles = []string{}
+	}
+
+	policy := &models.MFAPolicy{
+		TenantID:      *requestor.TenantID,
+		Required:      req.Required,
+		RequiredRoles: requiredRoles,
+	}
+	if err := m.repo.SaveMFAPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// policyRequiresMFA reports whether the user's tenant requires a second
+// factor from them.
+func (m *MFAServiceImpl) policyRequiresMFA(user *models.User) (bool, error) {
+	if user.TenantID == nil {
+		return false, nil
+	}
+
+	policy, err := m.repo.FindMFAPolicy(user.TenantID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if policy.Required {
+		return true, nil
+	}
+	if len(policy.RequiredRoles) == 0 {
+		return false, nil
+	}
+
+	role, err := m.roleRepo.GetRoleById(user.RoleID)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(policy.RequiredRoles, role.Name), nil
+}
+
+// findTOTPCredential returns nil without an error when the user has none.
+func (m *MFAServiceImpl) findTOTPCredential(user_id string) (*models.TOTPCredential, error) {
+	credential, err := m.repo.FindTOTPCredential(user_id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return credential, nil
+}
+
+// consumeChallenge runs verify for the challenge's user and marks the
+// challenge used once it passes. Wrong codes also count towards the account's
+// lockout, so new challenges cannot be used to keep guessing.
+func (m *MFAServiceImpl) consumeChallenge(mfa_token, ip string, verify func(user *models.User) error) (*models.User, error) {
+	challenge, err := m.findChallenge(mfa_token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := m.userRepo.FindUserById(challenge.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verify(user); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := m.repo.IncrementChallengeAttempts(challenge.ID.String()); err != nil {
+				return nil, err
+			}
+			if err := m.lockoutService.RecordFailure(user.Email, ip, user); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := m.repo.ConsumeChallenge(challenge.ID.String(), time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	if err := m.lockoutService.RecordSuccess(user.Email); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (m *MFAServiceImpl) findChallenge(mfa_token string) (*models.MFAChallenge, error) {
+	challenge, err := m.repo.FindActiveChallenge(utils.HashToken(mfa_token), time.Now(), maxMFAChallengeAttempts)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
+
+func (m *MFAServiceImpl) verifyEnrolledCode(user *models.User, code string) error {
+	credential, err := m.findTOTPCredential(user.ID.String())
+	if err != nil {
+		return err
+	}
+	if credential == nil || credential.ConfirmedAt == nil {
+		return utils.NewAppError(http.StatusBadRequest, "totp is not enabled")
+	}
+
+	return m.verifyTOTP(credential, code)
+}
+
+// verifyTOTP checks code and records its time step so it cannot be used
+// again.
+func (m *MFAServiceImpl) verifyTOTP(credential *models.TOTPCredential, code string) error {
+	encryptionKey, err := mfaEncryptionKey()
+	if err != nil {
+		return err
+	}
+	secret, err := utils.Decrypt(encryptionKey, credential.EncryptedSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := utils.VerifyTOTP(string(secret), code, time.Now(), credential.LastUsedStep)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	if err := m.repo.UseTOTPStep(credential.UserID.String(), step); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	credential.LastUsedStep = step
+
+	return nil
+}
+
+func (m *MFAServiceImpl) useRecoveryCode(user_id, code string) error {
+	codeHash := utils.HashToken(utils.NormalizeRecoveryCode(code))
+	if err := m.repo.ConsumeRecoveryCode(user_id, codeHash, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *MFAServiceImpl) confirmTOTP(credential *models.TOTPCredential) ([]string, error) {
+	confirmedAt := time.Now()
+	credential.ConfirmedAt = &confirmedAt
+	if err := m.repo.SaveTOTPCredential(credential); err != nil {
+		return nil, err
+	}
+
+	return m.generateRecoveryCodes(credential.UserID)
+}
+
+func (m *MFAServiceImpl) generateRecoveryCodes(user_id uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	stored := make([]*models.RecoveryCode, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		stored = append(stored, &models.RecoveryCode{
+			ID:       uuid.New(),
+			UserID:   user_id,
+			CodeHash: utils.HashToken(utils.NormalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := m.repo.ReplaceRecoveryCodes(user_id.String(), stored); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func mfaEncryptionKey() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(viper.GetString("MFA_ENCRYPTION_KEY"))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("MFA_ENCRYPTION_KEY must be 32 base64-encoded bytes")
+	}
+	return key, nil
+}
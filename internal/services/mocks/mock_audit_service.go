@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) Record(event *models.AuditEvent) error {
+	args := m.Called(event)
+
+	return args.Error(0)
+}
+
+func (m *MockAuditService) GetEvents(requestor *models.User, page, limit int) ([]*models.AuditEvent, error) {
+	args := m.Called(requestor, page, limit)
+
+	if events, ok := args.Get(0).([]*models.AuditEvent); ok {
+		return events, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
@@ -1,7 +1,9 @@
 package mocks
 
 import (
+	"github.com/samvibes/vexop/auth-service/internal/dto"
 	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -18,6 +20,16 @@ func (m *MockAuthService) CompareHashAndPassword(password, hashed []byte) bool {
 	return args.Bool(0)
 }
 
+func (m *MockAuthService) PasswordNeedsRehash(hashed string) bool {
+	args := m.Called(hashed)
+	return args.Bool(0)
+}
+
+func (m *MockAuthService) SupportsPasswordHash(hashed string) bool {
+	args := m.Called(hashed)
+	return args.Bool(0)
+}
+
 func (m *MockAuthService) HashPassword(password string) (string, error) {
 	args := m.Called(password)
 	return args.String(0), args.Error(1)
@@ -27,3 +39,92 @@ func (m *MockAuthService) GenerateJWT(user *models.User) (string, error) {
 	args := m.Called(user)
 	return args.String(0), args.Error(1)
 }
+
+func (m *MockAuthService) IssueTokens(user *models.User) (*dto.LoginResponse, error) {
+	args := m.Called(user)
+
+	if tokens, ok := args.Get(0).(*dto.LoginResponse); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) IssueClientTokens(user *models.User, client_id, scope string, offline bool) (*dto.LoginResponse, error) {
+	args := m.Called(user, client_id, scope, offline)
+
+	if tokens, ok := args.Get(0).(*dto.LoginResponse); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) IssueClientCredentialsToken(client *models.OAuthClient, scope string) (*dto.LoginResponse, error) {
+	args := m.Called(client, scope)
+
+	if tokens, ok := args.Get(0).(*dto.LoginResponse); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) IssueImpersonationToken(user, impersonator *models.User) (*dto.LoginResponse, *utils.Claims, error) {
+	args := m.Called(user, impersonator)
+
+	tokens, _ := args.Get(0).(*dto.LoginResponse)
+	claims, _ := args.Get(1).(*utils.Claims)
+
+	return tokens, claims, args.Error(2)
+}
+
+func (m *MockAuthService) RefreshTokens(refreshToken, client_id string) (*dto.LoginResponse, error) {
+	args := m.Called(refreshToken, client_id)
+
+	if tokens, ok := args.Get(0).(*dto.LoginResponse); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) ValidateAccessToken(tokenStr string) (*utils.Claims, error) {
+	args := m.Called(tokenStr)
+
+	if claims, ok := args.Get(0).(*utils.Claims); ok {
+		return claims, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) Logout(claims *utils.Claims, refreshToken string) error {
+	args := m.Called(claims, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeUserTokens(user_id string) error {
+	args := m.Called(user_id)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeAccessToken(claims *utils.Claims) error {
+	args := m.Called(claims)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) FindActiveRefreshToken(refreshToken string) (*models.RefreshToken, error) {
+	args := m.Called(refreshToken)
+
+	if token, ok := args.Get(0).(*models.RefreshToken); ok {
+		return token, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) RevokeRefreshToken(refreshToken, client_id string) error {
+	args := m.Called(refreshToken, client_id)
+	return args.Error(0)
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthorizationCodeRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthorizationCodeRepository) CreateAuthorizationCode(code *models.AuthorizationCode) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *MockAuthorizationCodeRepository) ConsumeAuthorizationCode(code_hash string, now time.Time) (*models.AuthorizationCode, error) {
+	args := m.Called(code_hash, now)
+
+	if code, ok := args.Get(0).(*models.AuthorizationCode); ok {
+		return code, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthorizationCodeRepository) DeleteExpiredAuthorizationCodes(now time.Time) error {
+	args := m.Called(now)
+	return args.Error(0)
+}
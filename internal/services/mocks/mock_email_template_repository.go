@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEmailTemplateRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailTemplateRepository) FindTemplate(tenant_id, name string) (*models.EmailTemplate, error) {
+	args := m.Called(tenant_id, name)
+
+	if template, ok := args.Get(0).(*models.EmailTemplate); ok {
+		return template, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockEmailTemplateRepository) FindTemplates(tenant_id string) ([]*models.EmailTemplate, error) {
+	args := m.Called(tenant_id)
+
+	if templates, ok := args.Get(0).([]*models.EmailTemplate); ok {
+		return templates, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockEmailTemplateRepository) SaveTemplate(template *models.EmailTemplate) error {
+	args := m.Called(template)
+
+	return args.Error(0)
+}
+
+func (m *MockEmailTemplateRepository) DeleteTemplate(tenant_id, name string) error {
+	args := m.Called(tenant_id, name)
+
+	return args.Error(0)
+}
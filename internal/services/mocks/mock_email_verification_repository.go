@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockEmailVerificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationRepository) CreateVerificationTx(tx *gorm.DB, verification *models.EmailVerification) error {
+	args := m.Called(tx, verification)
+
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationRepository) FindActiveVerification(token_hash string, now time.Time) (*models.EmailVerification, error) {
+	args := m.Called(token_hash, now)
+
+	if verification, ok := args.Get(0).(*models.EmailVerification); ok {
+		return verification, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockEmailVerificationRepository) ConsumeVerificationTx(tx *gorm.DB, id string, now time.Time) error {
+	args := m.Called(tx, id, now)
+
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationRepository) FindPolicy(tenant_id string) (*models.EmailVerificationPolicy, error) {
+	args := m.Called(tenant_id)
+
+	if policy, ok := args.Get(0).(*models.EmailVerificationPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockEmailVerificationRepository) SavePolicy(policy *models.EmailVerificationPolicy) error {
+	args := m.Called(policy)
+
+	return args.Error(0)
+}
@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockEmailVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationService) SendVerificationTx(tx *gorm.DB, user *models.User) error {
+	args := m.Called(tx, user)
+
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) ResendVerification(email string) error {
+	args := m.Called(email)
+
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) VerifyEmail(token string) error {
+	args := m.Called(token)
+
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) RequestEmailChange(user *models.User, email, password string) error {
+	args := m.Called(user, email, password)
+
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) CheckLogin(user *models.User) error {
+	args := m.Called(user)
+
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) GetPolicy(requestor *models.User) (*models.EmailVerificationPolicy, error) {
+	args := m.Called(requestor)
+
+	if policy, ok := args.Get(0).(*models.EmailVerificationPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockEmailVerificationService) SavePolicy(requestor *models.User, req *dto.EmailVerificationPolicyRequest) (*models.EmailVerificationPolicy, error) {
+	args := m.Called(requestor, req)
+
+	if policy, ok := args.Get(0).(*models.EmailVerificationPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
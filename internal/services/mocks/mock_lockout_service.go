@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLockoutService struct {
+	mock.Mock
+}
+
+func (m *MockLockoutService) Check(email, ip string) error {
+	args := m.Called(email, ip)
+
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) RecordFailure(email, ip string, user *models.User) error {
+	args := m.Called(email, ip, user)
+
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) RecordSuccess(email string) error {
+	args := m.Called(email)
+
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) Unlock(requestor *models.User, user_id string) error {
+	args := m.Called(requestor, user_id)
+
+	return args.Error(0)
+}
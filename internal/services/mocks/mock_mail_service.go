@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockMailService struct {
+	mock.Mock
+}
+
+func (m *MockMailService) EnqueueTx(tx *gorm.DB, tenant_id *uuid.UUID, name, to string, data map[string]any) error {
+	args := m.Called(tx, tenant_id, name, to, data)
+
+	return args.Error(0)
+}
+
+func (m *MockMailService) GetTemplates(requestor *models.User) ([]*dto.EmailTemplateResponse, error) {
+	args := m.Called(requestor)
+
+	if templates, ok := args.Get(0).([]*dto.EmailTemplateResponse); ok {
+		return templates, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMailService) GetTemplate(requestor *models.User, name string) (*dto.EmailTemplateResponse, error) {
+	args := m.Called(requestor, name)
+
+	if template, ok := args.Get(0).(*dto.EmailTemplateResponse); ok {
+		return template, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMailService) SaveTemplate(requestor *models.User, name string, req *dto.EmailTemplateRequest) (*dto.EmailTemplateResponse, error) {
+	args := m.Called(requestor, name, req)
+
+	if template, ok := args.Get(0).(*dto.EmailTemplateResponse); ok {
+		return template, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMailService) DeleteTemplate(requestor *models.User, name string) error {
+	args := m.Called(requestor, name)
+
+	return args.Error(0)
+}
@@ -0,0 +1,96 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) FindTOTPCredential(user_id string) (*models.TOTPCredential, error) {
+	args := m.Called(user_id)
+
+	if credential, ok := args.Get(0).(*models.TOTPCredential); ok {
+		return credential, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFARepository) SaveTOTPCredential(credential *models.TOTPCredential) error {
+	args := m.Called(credential)
+
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseTOTPStep(user_id string, step int64) error {
+	args := m.Called(user_id, step)
+
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) DeleteMFA(user_id string) error {
+	args := m.Called(user_id)
+
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ReplaceRecoveryCodes(user_id string, codes []*models.RecoveryCode) error {
+	args := m.Called(user_id, codes)
+
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ConsumeRecoveryCode(user_id, code_hash string, now time.Time) error {
+	args := m.Called(user_id, code_hash, now)
+
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) CreateChallenge(challenge *models.MFAChallenge) error {
+	args := m.Called(challenge)
+
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) FindActiveChallenge(token_hash string, now time.Time, max_attempts int) (*models.MFAChallenge, error) {
+	args := m.Called(token_hash, now, max_attempts)
+
+	if challenge, ok := args.Get(0).(*models.MFAChallenge); ok {
+		return challenge, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFARepository) IncrementChallengeAttempts(id string) error {
+	args := m.Called(id)
+
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ConsumeChallenge(id string, now time.Time) error {
+	args := m.Called(id, now)
+
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) FindMFAPolicy(tenant_id string) (*models.MFAPolicy, error) {
+	args := m.Called(tenant_id)
+
+	if policy, ok := args.Get(0).(*models.MFAPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFARepository) SaveMFAPolicy(policy *models.MFAPolicy) error {
+	args := m.Called(policy)
+
+	return args.Error(0)
+}
@@ -0,0 +1,147 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) EnrollTOTP(user *models.User) (*dto.TOTPEnrollment, error) {
+	args := m.Called(user)
+
+	if enrollment, ok := args.Get(0).(*dto.TOTPEnrollment); ok {
+		return enrollment, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) ActivateTOTP(user *models.User, code string) ([]string, error) {
+	args := m.Called(user, code)
+
+	if codes, ok := args.Get(0).([]string); ok {
+		return codes, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) DisableTOTP(user *models.User, code string) error {
+	args := m.Called(user, code)
+
+	return args.Error(0)
+}
+
+func (m *MockMFAService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	args := m.Called(user, code)
+
+	if codes, ok := args.Get(0).([]string); ok {
+		return codes, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) BeginLogin(user *models.User) (*dto.MFAChallengeResponse, error) {
+	args := m.Called(user)
+
+	if challenge, ok := args.Get(0).(*dto.MFAChallengeResponse); ok {
+		return challenge, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) EnrollChallenge(mfa_token string) (*dto.TOTPEnrollment, error) {
+	args := m.Called(mfa_token)
+
+	if enrollment, ok := args.Get(0).(*dto.TOTPEnrollment); ok {
+		return enrollment, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) CompleteLogin(req *dto.MFALoginRequest, ip string) (*dto.LoginResponse, error) {
+	args := m.Called(req, ip)
+
+	if tokens, ok := args.Get(0).(*dto.LoginResponse); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) VerifyLoginCode(user *models.User, code, ip string) error {
+	args := m.Called(user, code, ip)
+
+	return args.Error(0)
+}
+
+func (m *MockMFAService) GetPolicy(requestor *models.User) (*models.MFAPolicy, error) {
+	args := m.Called(requestor)
+
+	if policy, ok := args.Get(0).(*models.MFAPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) SavePolicy(requestor *models.User, req *dto.MFAPolicyRequest) (*models.MFAPolicy, error) {
+	args := m.Called(requestor, req)
+
+	if policy, ok := args.Get(0).(*models.MFAPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) ChallengeUser(mfa_token string) (*models.User, error) {
+	args := m.Called(mfa_token)
+
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+// CompleteChallenge runs verify with the user given to Return before handing
+// back the tokens, so tests exercise the caller's verification.
+func (m *MockMFAService) CompleteChallenge(mfa_token, ip string, verify func(user *models.User) error) (*dto.LoginResponse, error) {
+	args := m.Called(mfa_token, ip, verify)
+
+	if user, ok := args.Get(0).(*models.User); ok {
+		if err := verify(user); err != nil {
+			return nil, err
+		}
+	}
+
+	if tokens, ok := args.Get(1).(*dto.LoginResponse); ok {
+		return tokens, args.Error(2)
+	}
+
+	return nil, args.Error(2)
+}
+
+func (m *MockMFAService) EnrolledMethods(user_id string) ([]string, error) {
+	args := m.Called(user_id)
+
+	if methods, ok := args.Get(0).([]string); ok {
+		return methods, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) RequiresMFA(user *models.User) (bool, error) {
+	args := m.Called(user)
+
+	return args.Bool(0), args.Error(1)
+}
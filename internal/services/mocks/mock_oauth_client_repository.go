@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOAuthClientRepository struct {
+	mock.Mock
+}
+
+func (m *MockOAuthClientRepository) CreateClient(client *models.OAuthClient) error {
+	args := m.Called(client)
+	return args.Error(0)
+}
+
+func (m *MockOAuthClientRepository) FindClientByClientId(client_id string) (*models.OAuthClient, error) {
+	args := m.Called(client_id)
+
+	if client, ok := args.Get(0).(*models.OAuthClient); ok {
+		return client, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockOAuthClientRepository) GetClients(page, limit int) ([]*models.OAuthClient, error) {
+	args := m.Called(page, limit)
+
+	if clients, ok := args.Get(0).([]*models.OAuthClient); ok {
+		return clients, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockOAuthClientRepository) GetTenantClients(tenant_id string, page, limit int) ([]*models.OAuthClient, error) {
+	args := m.Called(tenant_id, page, limit)
+
+	if clients, ok := args.Get(0).([]*models.OAuthClient); ok {
+		return clients, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockOAuthClientRepository) UpdateClient(client *models.OAuthClient) error {
+	args := m.Called(client)
+	return args.Error(0)
+}
+
+func (m *MockOAuthClientRepository) DeleteClient(client_id string) error {
+	args := m.Called(client_id)
+	return args.Error(0)
+}
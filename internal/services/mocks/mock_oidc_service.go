@@ -0,0 +1,67 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) Discovery() *dto.OpenIDConfiguration {
+	args := m.Called()
+	return args.Get(0).(*dto.OpenIDConfiguration)
+}
+
+func (m *MockOIDCService) ValidateAuthorizeRequest(req *dto.AuthorizeRequest) (*models.OAuthClient, error) {
+	args := m.Called(req)
+
+	if client, ok := args.Get(0).(*models.OAuthClient); ok {
+		return client, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockOIDCService) Authorize(req *dto.AuthorizeRequest, user *models.User) (string, error) {
+	args := m.Called(req, user)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOIDCService) Token(req *dto.TokenRequest) (*dto.LoginResponse, error) {
+	args := m.Called(req)
+
+	if tokens, ok := args.Get(0).(*dto.LoginResponse); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockOIDCService) UserInfo(claims *utils.Claims) (map[string]interface{}, error) {
+	args := m.Called(claims)
+
+	if info, ok := args.Get(0).(map[string]interface{}); ok {
+		return info, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockOIDCService) Introspect(req *dto.TokenActionRequest) (*dto.IntrospectionResponse, error) {
+	args := m.Called(req)
+
+	if response, ok := args.Get(0).(*dto.IntrospectionResponse); ok {
+		return response, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockOIDCService) Revoke(req *dto.TokenActionRequest) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) EnqueueTx(tx *gorm.DB, email *models.OutboxEmail) error {
+	args := m.Called(tx, email)
+
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*models.OutboxEmail, error) {
+	args := m.Called(now, lease, limit)
+
+	if emails, ok := args.Get(0).([]*models.OutboxEmail); ok {
+		return emails, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkSent(id string, now time.Time) error {
+	args := m.Called(id, now)
+
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) RecordFailure(email *models.OutboxEmail) error {
+	args := m.Called(email)
+
+	return args.Error(0)
+}
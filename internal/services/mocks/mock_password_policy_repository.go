@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockPasswordPolicyRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordPolicyRepository) FindPolicy(tenant_id string) (*models.PasswordPolicy, error) {
+	args := m.Called(tenant_id)
+
+	if policy, ok := args.Get(0).(*models.PasswordPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordPolicyRepository) SavePolicy(policy *models.PasswordPolicy) error {
+	args := m.Called(policy)
+
+	return args.Error(0)
+}
+
+func (m *MockPasswordPolicyRepository) GetHistory(user_id string, limit int) ([]*models.PasswordHistory, error) {
+	args := m.Called(user_id, limit)
+
+	if history, ok := args.Get(0).([]*models.PasswordHistory); ok {
+		return history, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordPolicyRepository) AddHistoryTx(tx *gorm.DB, entry *models.PasswordHistory, keep int) error {
+	args := m.Called(tx, entry, keep)
+
+	return args.Error(0)
+}
@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockPasswordPolicyService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordPolicyService) Validate(user *models.User, password string) error {
+	args := m.Called(user, password)
+
+	return args.Error(0)
+}
+
+func (m *MockPasswordPolicyService) RecordTx(tx *gorm.DB, user *models.User) error {
+	args := m.Called(tx, user)
+
+	return args.Error(0)
+}
+
+func (m *MockPasswordPolicyService) CheckExpiry(user *models.User) error {
+	args := m.Called(user)
+
+	return args.Error(0)
+}
+
+func (m *MockPasswordPolicyService) GetPolicy(requestor *models.User) (*models.PasswordPolicy, error) {
+	args := m.Called(requestor)
+
+	if policy, ok := args.Get(0).(*models.PasswordPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordPolicyService) SavePolicy(requestor *models.User, req *dto.PasswordPolicyRequest) (*models.PasswordPolicy, error) {
+	args := m.Called(requestor, req)
+
+	if policy, ok := args.Get(0).(*models.PasswordPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
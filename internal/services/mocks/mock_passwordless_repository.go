@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockPasswordlessRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordlessRepository) CreateLoginTokenTx(tx *gorm.DB, token *models.LoginToken) error {
+	args := m.Called(tx, token)
+
+	return args.Error(0)
+}
+
+func (m *MockPasswordlessRepository) UseLoginTokenAttempt(user_id, kind string, now time.Time, max_attempts int) (*models.LoginToken, error) {
+	args := m.Called(user_id, kind, now, max_attempts)
+
+	if token, ok := args.Get(0).(*models.LoginToken); ok {
+		return token, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordlessRepository) FindActiveLoginTokenByHash(token_hash, kind string, now time.Time) (*models.LoginToken, error) {
+	args := m.Called(token_hash, kind, now)
+
+	if token, ok := args.Get(0).(*models.LoginToken); ok {
+		return token, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordlessRepository) ConsumeLoginToken(id string, now time.Time) error {
+	args := m.Called(id, now)
+
+	return args.Error(0)
+}
+
+func (m *MockPasswordlessRepository) FindLoginMethodPolicy(tenant_id string) (*models.LoginMethodPolicy, error) {
+	args := m.Called(tenant_id)
+
+	if policy, ok := args.Get(0).(*models.LoginMethodPolicy); ok {
+		return policy, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordlessRepository) SaveLoginMethodPolicy(policy *models.LoginMethodPolicy) error {
+	args := m.Called(policy)
+
+	return args.Error(0)
+}
@@ -0,0 +1,123 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPersonalAccessTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockPersonalAccessTokenRepository) CreateToken(token *models.PersonalAccessToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockPersonalAccessTokenRepository) FindTokenByHash(tokenHash string) (*models.PersonalAccessToken, error) {
+	args := m.Called(tokenHash)
+
+	if token, ok := args.Get(0).(*models.PersonalAccessToken); ok {
+		return token, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenRepository) FindTokenById(id string) (*models.PersonalAccessToken, error) {
+	args := m.Called(id)
+
+	if token, ok := args.Get(0).(*models.PersonalAccessToken); ok {
+		return token, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenRepository) GetUserTokens(user_id string) ([]*models.PersonalAccessToken, error) {
+	args := m.Called(user_id)
+
+	if tokens, ok := args.Get(0).([]*models.PersonalAccessToken); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenRepository) RevokeToken(id string, now time.Time) (bool, error) {
+	args := m.Called(id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenRepository) TouchToken(id string, now time.Time) error {
+	args := m.Called(id, now)
+	return args.Error(0)
+}
+
+type MockPersonalAccessTokenService struct {
+	mock.Mock
+}
+
+func (m *MockPersonalAccessTokenService) CreateToken(user *models.User, req *dto.PersonalAccessTokenRequest) (*dto.PersonalAccessTokenResponse, error) {
+	args := m.Called(user, req)
+
+	if token, ok := args.Get(0).(*dto.PersonalAccessTokenResponse); ok {
+		return token, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenService) GetTokens(user *models.User) ([]*models.PersonalAccessToken, error) {
+	args := m.Called(user)
+
+	if tokens, ok := args.Get(0).([]*models.PersonalAccessToken); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenService) RevokeToken(user *models.User, id string) error {
+	args := m.Called(user, id)
+	return args.Error(0)
+}
+
+func (m *MockPersonalAccessTokenService) GetUserTokens(requestor *models.User, user_id string) ([]*models.PersonalAccessToken, error) {
+	args := m.Called(requestor, user_id)
+
+	if tokens, ok := args.Get(0).([]*models.PersonalAccessToken); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenService) RevokeUserToken(requestor *models.User, id string) error {
+	args := m.Called(requestor, id)
+	return args.Error(0)
+}
+
+func (m *MockPersonalAccessTokenService) Authenticate(rawToken string) (*models.PersonalAccessToken, error) {
+	args := m.Called(rawToken)
+
+	if token, ok := args.Get(0).(*models.PersonalAccessToken); ok {
+		return token, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenService) CreateServiceAccountToken(requestor *models.User, claims *utils.Claims, user_id string, req *dto.PersonalAccessTokenRequest) (*dto.PersonalAccessTokenResponse, error) {
+	args := m.Called(requestor, claims, user_id, req)
+
+	if res, ok := args.Get(0).(*dto.PersonalAccessTokenResponse); ok {
+		return res, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
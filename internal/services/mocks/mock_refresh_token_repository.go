@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	args := m.Called(token)
+
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) FindRefreshTokenByHash(tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(tokenHash)
+
+	if token, ok := args.Get(0).(*models.RefreshToken); ok {
+		return token, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RotateRefreshToken(current, next *models.RefreshToken) (bool, error) {
+	args := m.Called(current, next)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeRefreshTokenFamily(family_id string) error {
+	args := m.Called(family_id)
+
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeUserRefreshTokens(user_id string) error {
+	args := m.Called(user_id)
+
+	return args.Error(0)
+}
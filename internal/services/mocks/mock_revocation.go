@@ -0,0 +1,82 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRevocationRepository struct {
+	mock.Mock
+}
+
+func (m *MockRevocationRepository) RevokeToken(token *models.RevokedToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockRevocationRepository) RevokeUserTokens(revocation *models.UserTokenRevocation) error {
+	args := m.Called(revocation)
+	return args.Error(0)
+}
+
+func (m *MockRevocationRepository) GetRevokedTokens(now time.Time) ([]*models.RevokedToken, error) {
+	args := m.Called(now)
+
+	if tokens, ok := args.Get(0).([]*models.RevokedToken); ok {
+		return tokens, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockRevocationRepository) GetUserTokenRevocations(now time.Time) ([]*models.UserTokenRevocation, error) {
+	args := m.Called(now)
+
+	if revocations, ok := args.Get(0).([]*models.UserTokenRevocation); ok {
+		return revocations, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockRevocationRepository) GetRevokedSessions(since time.Time) ([]*models.Session, error) {
+	args := m.Called(since)
+
+	if sessions, ok := args.Get(0).([]*models.Session); ok {
+		return sessions, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockRevocationRepository) DeleteExpiredRevocations(now time.Time) error {
+	args := m.Called(now)
+	return args.Error(0)
+}
+
+type MockRevocationService struct {
+	mock.Mock
+}
+
+func (m *MockRevocationService) RevokeToken(jti string, userID uuid.UUID, expiresAt time.Time) error {
+	args := m.Called(jti, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRevocationService) RevokeUserTokens(userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockRevocationService) RevokeSession(session_id string) {
+	m.Called(session_id)
+}
+
+func (m *MockRevocationService) IsRevoked(claims *utils.Claims) (bool, error) {
+	args := m.Called(claims)
+	return args.Bool(0), args.Error(1)
+}
@@ -22,6 +22,16 @@ func (m *MockRoleRepository) GetRoleByName(tenant_id, role_name string) (*models
 	return nil, args.Error(1)
 }
 
+func (m *MockRoleRepository) GetRoleById(id string) (*models.Role, error) {
+	args := m.Called(id)
+
+	if role, ok := args.Get(0).(*models.Role); ok {
+		return role, nil
+	}
+
+	return nil, args.Error(1)
+}
+
 func (m *MockRoleRepository) GetRoles(tenant_id string, page, limit int) ([]*dto.RoleResponse, error) {
 	args := m.Called(tenant_id, page, limit)
 
@@ -38,6 +48,12 @@ func (m *MockRoleRepository) AddRole(tenant_id, name string) error {
 	return args.Error(0)
 }
 
+func (m *MockRoleRepository) DeleteRole(id string) error {
+	args := m.Called(id)
+
+	return args.Error(0)
+}
+
 func (m *MockRoleRepository) AddRolePermission(tenant_id, id string, permission *models.Permission) error {
 	args := m.Called(tenant_id, id, permission)
 
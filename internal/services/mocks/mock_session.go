@@ -0,0 +1,94 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) CreateSession(session *models.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) FindActiveSession(session_id string, now time.Time) (*models.Session, error) {
+	args := m.Called(session_id, now)
+
+	if session, ok := args.Get(0).(*models.Session); ok {
+		return session, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockSessionRepository) GetActiveSessions(user_id string, now time.Time) ([]*models.Session, error) {
+	args := m.Called(user_id, now)
+
+	if sessions, ok := args.Get(0).([]*models.Session); ok {
+		return sessions, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeSession(session_id string, now time.Time) (bool, error) {
+	args := m.Called(session_id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) TouchSessions(sessions []*models.Session) error {
+	args := m.Called(sessions)
+	return args.Error(0)
+}
+
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) GetSessions(user *models.User) ([]*models.Session, error) {
+	args := m.Called(user)
+
+	if sessions, ok := args.Get(0).([]*models.Session); ok {
+		return sessions, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockSessionService) RevokeSession(user *models.User, session_id string) error {
+	args := m.Called(user, session_id)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) GetUserSessions(requestor *models.User, user_id string) ([]*models.Session, error) {
+	args := m.Called(requestor, user_id)
+
+	if sessions, ok := args.Get(0).([]*models.Session); ok {
+		return sessions, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockSessionService) RevokeUserSession(requestor *models.User, session_id string) error {
+	args := m.Called(requestor, session_id)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) Touch(session_id, ip, user_agent string) {
+	m.Called(session_id, ip, user_agent)
+}
+
+func (m *MockSessionService) Flush() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSessionService) StartFlusher(interval time.Duration) {
+	m.Called(interval)
+}
@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTenantService struct {
+	mock.Mock
+}
+
+func (m *MockTenantService) CreateTenant(requester *models.User, name string) (*models.Tenant, error) {
+	args := m.Called(requester, name)
+
+	if tenant, ok := args.Get(0).(*models.Tenant); ok {
+		return tenant, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockTenantService) GetTenants(requestor *models.User, page, limit int) ([]*models.Tenant, error) {
+	args := m.Called(requestor, page, limit)
+
+	if tenants, ok := args.Get(0).([]*models.Tenant); ok {
+		return tenants, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockTenantService) GetTenantById(requestor *models.User, id string) (*models.Tenant, error) {
+	args := m.Called(requestor, id)
+
+	if tenant, ok := args.Get(0).(*models.Tenant); ok {
+		return tenant, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockTenantService) DeleteTenantById(requestor *models.User, id string) (bool, error) {
+	args := m.Called(requestor, id)
+
+	return args.Bool(0), args.Error(1)
+}
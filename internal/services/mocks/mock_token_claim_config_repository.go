@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTokenClaimConfigRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenClaimConfigRepository) FindClaimConfigs(tenant_id *string, audience string) ([]*models.TokenClaimConfig, error) {
+	args := m.Called(tenant_id, audience)
+
+	if configs, ok := args.Get(0).([]*models.TokenClaimConfig); ok {
+		return configs, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockTokenClaimConfigRepository) GetClaimConfigs(page, limit int) ([]*models.TokenClaimConfig, error) {
+	args := m.Called(page, limit)
+
+	if configs, ok := args.Get(0).([]*models.TokenClaimConfig); ok {
+		return configs, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockTokenClaimConfigRepository) SaveClaimConfig(config *models.TokenClaimConfig) error {
+	args := m.Called(config)
+	return args.Error(0)
+}
+
+func (m *MockTokenClaimConfigRepository) DeleteClaimConfig(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
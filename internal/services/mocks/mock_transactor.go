@@ -0,0 +1,11 @@
+package mocks
+
+import "gorm.io/gorm"
+
+// MockTransactor runs fn straight away with a nil tx; the mocked repositories
+// never touch it.
+type MockTransactor struct{}
+
+func (m *MockTransactor) Transaction(fn func(tx *gorm.DB) error) error {
+	return fn(nil)
+}
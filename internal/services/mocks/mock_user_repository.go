@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/samvibes/vexop/auth-service/internal/models"
 	"github.com/stretchr/testify/mock"
@@ -59,8 +61,24 @@ func (m *MockUserRepository) RemoveUserByEmail(tenant_id, email string) error {
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) SetResetPasswordTokenHash(id, tokenHash string) error {
-	args := m.Called(id, tokenHash)
+func (m *MockUserRepository) SetResetPasswordTokenHashTx(tx *gorm.DB, id, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(tx, id, tokenHash, expiresAt)
+
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) FindUserByResetTokenHash(tokenHash string, now time.Time) (*models.User, error) {
+	args := m.Called(tokenHash, now)
+
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) ResetPasswordTx(tx *gorm.DB, id, tokenHash, passwordHash string, now time.Time) error {
+	args := m.Called(tx, id, tokenHash, passwordHash, now)
 
 	return args.Error(0)
 }
@@ -84,8 +102,62 @@ func (m *MockUserRepository) GetUserById(tenant_id, user_id string) (*models.Use
 	return nil, args.Error(1)
 }
 
+func (m *MockUserRepository) FindUserById(user_id string) (*models.User, error) {
+	args := m.Called(user_id)
+
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func (m *MockUserRepository) UpdateUser(user *models.User) error {
 	args := m.Called(user)
 
 	return args.Error(0)
 }
+
+func (m *MockUserRepository) RehashPassword(id, oldHash, newHash string) error {
+	args := m.Called(id, oldHash, newHash)
+
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePasswordTx(tx *gorm.DB, id, passwordHash string, now time.Time) error {
+	args := m.Called(tx, id, passwordHash, now)
+
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) VerifyEmailTx(tx *gorm.DB, id, email string, now time.Time) error {
+	args := m.Called(tx, id, email, now)
+
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CreateServiceAccount(user *models.User) error {
+	args := m.Called(user)
+
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetServiceAccounts(tenant_id string) ([]*models.User, error) {
+	args := m.Called(tenant_id)
+
+	if users, ok := args.Get(0).([]*models.User); ok {
+		return users, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) GetServiceAccountById(tenant_id, id string) (*models.User, error) {
+	args := m.Called(tenant_id, id)
+
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
@@ -1,8 +1,10 @@
 package mocks
 
 import (
+	"github.com/samvibes/vexop/auth-service/internal/dto"
 	"github.com/samvibes/vexop/auth-service/internal/models"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 type MockUserService struct {
@@ -16,3 +18,108 @@ func (u *MockUserService) FindUserByEmail(email string) (*models.User, error) {
 
 	return user, args.Error(0)
 }
+
+func (u *MockUserService) CreateUser(user *models.User, db *gorm.DB) error {
+	args := u.Called(user, db)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) Authenticate(email, password, ip string) (*models.User, error) {
+	args := u.Called(email, password, ip)
+
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (u *MockUserService) Login(email, password, ip string) (*dto.LoginResponse, *dto.MFAChallengeResponse, error) {
+	args := u.Called(email, password, ip)
+
+	tokens, _ := args.Get(0).(*dto.LoginResponse)
+	challenge, _ := args.Get(1).(*dto.MFAChallengeResponse)
+
+	return tokens, challenge, args.Error(2)
+}
+
+func (u *MockUserService) RemoveUserById(tenant_id, user_id string) error {
+	args := u.Called(tenant_id, user_id)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) RemoveUserByEmail(tenant_id string, email string) error {
+	args := u.Called(tenant_id, email)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) InitResetPassword(email string) error {
+	args := u.Called(email)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) ResetPassword(token, password string) error {
+	args := u.Called(token, password)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) ImportUsers(requestor *models.User, req *dto.ImportUsersRequest) (int, error) {
+	args := u.Called(requestor, req)
+
+	return args.Int(0), args.Error(1)
+}
+
+func (u *MockUserService) ValidatePassword(user *models.User, password string) error {
+	args := u.Called(user, password)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) ChangePassword(email, currentPassword, newPassword, ip string) error {
+	args := u.Called(email, currentPassword, newPassword, ip)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) CheckPasswordExpiry(user *models.User) error {
+	args := u.Called(user)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) CheckEmailVerification(user *models.User) error {
+	args := u.Called(user)
+
+	return args.Error(0)
+}
+
+func (u *MockUserService) GetUsers(tenant_id string, page, limit int) ([]*models.User, error) {
+	args := u.Called(tenant_id, page, limit)
+
+	if users, ok := args.Get(0).([]*models.User); ok {
+		return users, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (u *MockUserService) GetUserById(tenant_id, user_id string) (*models.User, error) {
+	args := u.Called(tenant_id, user_id)
+
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (u *MockUserService) UpdateUserRole(tenant_id, user_id, role_name string) error {
+	args := u.Called(tenant_id, user_id, role_name)
+
+	return args.Error(0)
+}
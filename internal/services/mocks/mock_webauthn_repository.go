@@ -0,0 +1,72 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebAuthnRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebAuthnRepository) CreateCredential(credential *models.WebAuthnCredential) error {
+	args := m.Called(credential)
+
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnRepository) FindUserCredentials(user_id string) ([]*models.WebAuthnCredential, error) {
+	args := m.Called(user_id)
+
+	if credentials, ok := args.Get(0).([]*models.WebAuthnCredential); ok {
+		return credentials, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockWebAuthnRepository) FindCredentialByCredentialId(credential_id []byte) (*models.WebAuthnCredential, error) {
+	args := m.Called(credential_id)
+
+	if credential, ok := args.Get(0).(*models.WebAuthnCredential); ok {
+		return credential, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockWebAuthnRepository) CountUserCredentials(user_id string) (int64, error) {
+	args := m.Called(user_id)
+
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWebAuthnRepository) UpdateCredential(credential *models.WebAuthnCredential) error {
+	args := m.Called(credential)
+
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnRepository) DeleteCredential(user_id, id string) error {
+	args := m.Called(user_id, id)
+
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnRepository) CreateSession(session *models.WebAuthnSession) error {
+	args := m.Called(session)
+
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnRepository) ConsumeSession(token_hash, purpose string, now time.Time) (*models.WebAuthnSession, error) {
+	args := m.Called(token_hash, purpose, now)
+
+	if session, ok := args.Get(0).(*models.WebAuthnSession); ok {
+		return session, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
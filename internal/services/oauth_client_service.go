@@ -0,0 +1,261 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+)
+
+const defaultSecretRotationGrace = 24 * time.Hour
+
+var oidcScopes = []string{utils.ScopeOpenID, utils.ScopeProfile, utils.ScopeEmail, utils.ScopeOfflineAccess}
+
+// OAuthClientService manages OAuth clients. Super admins manage every client;
+// anyone else only the clients of their own tenant.
+type OAuthClientService interface {
+	CreateClient(requestor *models.User, req *dto.OAuthClientRequest) (*dto.OAuthClientResponse, error)
+	GetClients(requestor *models.User, page, limit int) ([]*models.OAuthClient, error)
+	UpdateClient(requestor *models.User, client_id string, req *dto.OAuthClientRequest) (*models.OAuthClient, error)
+	DeleteClient(requestor *models.User, client_id string) error
+	RotateClientSecret(requestor *models.User, client_id string) (*dto.OAuthClientResponse, error)
+}
+
+type OAuthClientServiceImpl struct {
+	repo     repository.OAuthClientRepository
+	roleRepo repository.RoleRepository
+}
+
+func NewOAuthClientService(repo repository.OAuthClientRepository, roleRepo repository.RoleRepository) OAuthClientService {
+	return &OAuthClientServiceImpl{repo: repo, roleRepo: roleRepo}
+}
+
+// CreateClient registers a client. The secret of a confidential client is
+// only returned here; just its hash is stored.
+func (s *OAuthClientServiceImpl) CreateClient(requestor *models.User, req *dto.OAuthClientRequest) (*dto.OAuthClientResponse, error) {
+	client := &models.OAuthClient{
+		ID:       uuid.New(),
+		ClientID: uuid.NewString(),
+		Public:   req.Public,
+	}
+	if err := s.applyClientRequest(requestor, client, req); err != nil {
+		return nil, err
+	}
+
+	response := &dto.OAuthClientResponse{Client: client}
+	if !client.Public {
+		secret, secretHash, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+		client.ClientSecretHash = secretHash
+		response.ClientSecret = secret
+	}
+
+	if err := s.repo.CreateClient(client); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (s *OAuthClientServiceImpl) GetClients(requestor *models.User, page, limit int) ([]*models.OAuthClient, error) {
+	if requestor.Role.Name == utils.RoleSuperAdmin {
+		return s.repo.GetClients(page, limit)
+	}
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.GetTenantClients(requestor.TenantID.String(), page, limit)
+}
+
+// UpdateClient replaces a client's settings. Whether it is public cannot be
+// changed since that decides whether it has a secret.
+func (s *OAuthClientServiceImpl) UpdateClient(requestor *models.User, client_id string, req *dto.OAuthClientRequest) (*models.OAuthClient, error) {
+	client, err := s.findOwnedClient(requestor, client_id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyClientRequest(requestor, client, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateClient(client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (s *OAuthClientServiceImpl) DeleteClient(requestor *models.User, client_id string) error {
+	if _, err := s.findOwnedClient(requestor, client_id); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteClient(client_id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(http.StatusNotFound, "client not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RotateClientSecret issues a new secret. The old one keeps working for
+// CLIENT_SECRET_ROTATION_GRACE so running jobs can be moved over.
+func (s *OAuthClientServiceImpl) RotateClientSecret(requestor *models.User, client_id string) (*dto.OAuthClientResponse, error) {
+	client, err := s.findOwnedClient(requestor, client_id)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Public {
+		return nil, utils.NewAppError(http.StatusBadRequest, "public clients have no secret")
+	}
+
+	secret, secretHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	previousExpiresAt := time.Now().Add(utils.GetDuration("CLIENT_SECRET_ROTATION_GRACE", defaultSecretRotationGrace))
+	client.PreviousSecretHash = client.ClientSecretHash
+	client.PreviousSecretExpiresAt = &previousExpiresAt
+	client.ClientSecretHash = secretHash
+
+	if err := s.repo.UpdateClient(client); err != nil {
+		return nil, err
+	}
+
+	return &dto.OAuthClientResponse{Client: client, ClientSecret: secret}, nil
+}
+
+func (s *OAuthClientServiceImpl) findOwnedClient(requestor *models.User, client_id string) (*models.OAuthClient, error) {
+	client, err := s.repo.FindClientByClientId(client_id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "client not found")
+		}
+		return nil, err
+	}
+
+	if requestor.Role.Name == utils.RoleSuperAdmin {
+		return client, nil
+	}
+
+	// clients of other tenants are reported as missing
+	if requestor.TenantID == nil || client.TenantID == nil || *client.TenantID != *requestor.TenantID {
+		return nil, utils.NewAppError(http.StatusNotFound, "client not found")
+	}
+
+	return client, nil
+}
+
+func (s *OAuthClientServiceImpl) applyClientRequest(requestor *models.User, client *models.OAuthClient, req *dto.OAuthClientRequest) error {
+	for _, redirectURI := range req.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			return utils.NewAppError(http.StatusBadRequest, "redirect uris must be absolute and have no fragment")
+		}
+	}
+
+	for _, grantType := range req.GrantTypes {
+		switch grantType {
+		case utils.GrantTypeAuthorizationCode, utils.GrantTypeRefreshToken:
+		case utils.GrantTypeClientCredentials:
+			if client.Public {
+				return utils.NewAppError(http.StatusBadRequest, "public clients cannot use the client_credentials grant")
+			}
+		default:
+			return utils.NewAppError(http.StatusBadRequest, "unsupported grant type "+grantType)
+		}
+	}
+
+	if err := s.checkScopes(requestor, req.Scopes); err != nil {
+		return err
+	}
+
+	client.Name = req.Name
+	client.RedirectURIs = req.RedirectURIs
+	client.Scopes = req.Scopes
+	client.GrantTypes = req.GrantTypes
+
+	if requestor.Role.Name != utils.RoleSuperAdmin {
+		if requestor.TenantID == nil {
+			return ErrUnauthorized
+		}
+		client.TenantID = requestor.TenantID
+		return nil
+	}
+
+	client.TenantID = nil
+	if req.TenantID != "" {
+		tenantID, err := uuid.Parse(req.TenantID)
+		if err != nil {
+			return utils.NewAppError(http.StatusBadRequest, "invalid tenant id")
+		}
+		client.TenantID = &tenantID
+	}
+
+	// clients calling as themselves act within their tenant, so only tenant
+	// clients get permissions for the client credentials grant
+	if client.TenantID == nil && slices.Contains(req.GrantTypes, utils.GrantTypeClientCredentials) && slices.ContainsFunc(req.Scopes, isPermissionScope) {
+		return utils.NewAppError(http.StatusBadRequest, "clients using the client_credentials grant need a tenant for permission scopes")
+	}
+
+	return nil
+}
+
+func isPermissionScope(scope string) bool {
+	return strings.Contains(scope, ":")
+}
+
+// checkScopes allows the OpenID Connect scopes plus permission codes. Tenant
+// users can only grant a client permissions their own role has.
+func (s *OAuthClientServiceImpl) checkScopes(requestor *models.User, scopes []string) error {
+	var granted []string
+	if requestor.Role.Name != utils.RoleSuperAdmin {
+		role, err := s.roleRepo.GetRoleById(requestor.RoleID)
+		if err != nil {
+			return err
+		}
+		for _, permission := range role.Permissions {
+			granted = append(granted, permission.Code)
+		}
+	}
+
+	for _, scope := range scopes {
+		if slices.Contains(oidcScopes, scope) {
+			continue
+		}
+		if _, _, ok := strings.Cut(scope, ":"); !ok {
+			return utils.NewAppError(http.StatusBadRequest, "unknown scope "+scope)
+		}
+		if requestor.Role.Name != utils.RoleSuperAdmin && !slices.Contains(granted, scope) {
+			return utils.NewAppError(http.StatusForbidden, "cannot grant scope "+scope)
+		}
+	}
+
+	return nil
+}
+
+// clientAllowsGrant reports whether client may use grantType. Clients
+// registered without grant types may use the authorization code flow.
+func clientAllowsGrant(client *models.OAuthClient, grantType string) bool {
+	if len(client.GrantTypes) == 0 {
+		return grantType == utils.GrantTypeAuthorizationCode || grantType == utils.GrantTypeRefreshToken
+	}
+	return slices.Contains(client.GrantTypes, grantType)
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const defaultAuthorizationCodeTTL = time.Minute
+
+type OIDCService interface {
+	Discovery() *dto.OpenIDConfiguration
+	ValidateAuthorizeRequest(req *dto.AuthorizeRequest) (*models.OAuthClient, error)
+	Authorize(req *dto.AuthorizeRequest, user *models.User) (string, error)
+	Token(req *dto.TokenRequest) (*dto.LoginResponse, error)
+	UserInfo(claims *utils.Claims) (map[string]interface{}, error)
+	Introspect(req *dto.TokenActionRequest) (*dto.IntrospectionResponse, error)
+	Revoke(req *dto.TokenActionRequest) error
+}
+
+type OIDCServiceImpl struct {
+	clientRepo  repository.OAuthClientRepository
+	codeRepo    repository.AuthorizationCodeRepository
+	userRepo    repository.UserRepository
+	authService AuthService
+	keyService  KeyService
+}
+
+func NewOIDCService(
+	clientRepo repository.OAuthClientRepository,
+	codeRepo repository.AuthorizationCodeRepository,
+	userRepo repository.UserRepository,
+	authService AuthService,
+	keyService KeyService,
+) OIDCService {
+	return &OIDCServiceImpl{
+		clientRepo:  clientRepo,
+		codeRepo:    codeRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		keyService:  keyService,
+	}
+}
+
+// Discovery describes this provider. Every URL is derived from JWT_ISSUER,
+// which must be the externally visible base URL of the service.
+func (o *OIDCServiceImpl) Discovery() *dto.OpenIDConfiguration {
+	issuer := strings.TrimSuffix(viper.GetString("JWT_ISSUER"), "/")
+
+	return &dto.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/oauth/userinfo",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{utils.GrantTypeAuthorizationCode, utils.GrantTypeRefreshToken, utils.GrantTypeClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  o.keyService.Algorithms(),
+		ScopesSupported:                   []string{utils.ScopeOpenID, utils.ScopeProfile, utils.ScopeEmail, utils.ScopeOfflineAccess},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified", "tenant_id", "role"},
+	}
+}
+
+// ValidateAuthorizeRequest checks an authorization request. When the client or
+// redirect URI cannot be trusted no client is returned and the error must be
+// shown to the user; otherwise the error can be sent back to the redirect URI.
+func (o *OIDCServiceImpl) ValidateAuthorizeRequest(req *dto.AuthorizeRequest) (*models.OAuthClient, error) {
+	client, err := o.clientRepo.FindClientByClientId(req.ClientID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newOAuthError("invalid_client", "unknown client")
+		}
+		return nil, err
+	}
+
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, newOAuthError("invalid_request", "redirect_uri is not registered for this client")
+	}
+
+	if !clientAllowsGrant(client, utils.GrantTypeAuthorizationCode) {
+		return client, newOAuthError("unauthorized_client", "client may not use the authorization code flow")
+	}
+
+	if req.ResponseType != "code" {
+		return client, newOAuthError("unsupported_response_type", "only the code response type is supported")
+	}
+
+	// PKCE is required for every client, as recommended by OAuth 2.1
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return client, newOAuthError("invalid_request", "a S256 code_challenge is required")
+	}
+
+	if req.Scope == "" {
+		req.Scope = utils.ScopeOpenID
+	}
+	for _, scope := range strings.Fields(req.Scope) {
+		if !slices.Contains(client.Scopes, scope) {
+			return client, newOAuthError("invalid_scope", "scope "+scope+" is not allowed for this client")
+		}
+	}
+
+	return client, nil
+}
+
+// Authorize issues an authorization code for a user who has signed in.
+func (o *OIDCServiceImpl) Authorize(req *dto.AuthorizeRequest, user *models.User) (string, error) {
+	client, err := o.ValidateAuthorizeRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	if client.TenantID != nil && (user.TenantID == nil || *user.TenantID != *client.TenantID) {
+		return "", newOAuthError("access_denied", "user does not belong to the client's tenant")
+	}
+
+	rawCode, codeHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	code := &models.AuthorizationCode{
+		ID:            uuid.New(),
+		CodeHash:      codeHash,
+		ClientID:      client.ClientID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         req.Scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      now,
+		ExpiresAt:     now.Add(utils.GetDuration("OAUTH_CODE_TTL", defaultAuthorizationCodeTTL)),
+	}
+	if err := o.codeRepo.CreateAuthorizationCode(code); err != nil {
+		return "", err
+	}
+
+	if err := o.codeRepo.DeleteExpiredAuthorizationCodes(now); err != nil {
+		log.Println("failed to delete expired authorization codes: ", err)
+	}
+
+	return rawCode, nil
+}
+
+// Token implements the token endpoint for the grants supported by this
+// provider.
+func (o *OIDCServiceImpl) Token(req *dto.TokenRequest) (*dto.LoginResponse, error) {
+	client, err := o.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if !clientAllowsGrant(client, req.GrantType) {
+		return nil, newOAuthError("unauthorized_client", "client may not use grant type "+req.GrantType)
+	}
+
+	switch req.GrantType {
+	case utils.GrantTypeClientCredentials:
+		return o.clientCredentials(client, req.Scope)
+	case utils.GrantTypeAuthorizationCode:
+		return o.exchangeAuthorizationCode(client, req)
+	case utils.GrantTypeRefreshToken:
+		tokens, err := o.authService.RefreshTokens(req.RefreshToken, client.ClientID)
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			return nil, newOAuthError("invalid_grant", err.Error())
+		}
+		return tokens, err
+	default:
+		return nil, newOAuthError("unsupported_grant_type", "grant type "+req.GrantType+" is not supported")
+	}
+}
+
+// UserInfo returns the claims about the token's user that its scope allows.
+func (o *OIDCServiceImpl) UserInfo(claims *utils.Claims) (map[string]interface{}, error) {
+	scopes := strings.Fields(claims.Scope)
+	if !slices.Contains(scopes, utils.ScopeOpenID) {
+		return nil, newOAuthError("insufficient_scope", "the openid scope is required")
+	}
+
+	user, err := o.userRepo.FindUserById(claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	info := map[string]interface{}{"sub": user.ID.String()}
+	if slices.Contains(scopes, utils.ScopeEmail) {
+		info["email"] = user.Email
+		info["email_verified"] = user.EmailVerifiedAt != nil
+	}
+	if slices.Contains(scopes, utils.ScopeProfile) {
+		if user.TenantID != nil {
+			info["tenant_id"] = user.TenantID.String()
+		}
+		info["role"] = user.Role.Name
+	}
+
+	return info, nil
+}
+
+// Introspect reports whether a token is active (RFC 7662). Access tokens go
+// through the same validation as authenticated API requests. Only confidential
+// clients may introspect, and a client bound to a tenant only sees that
+// tenant's tokens as active.
+func (o *OIDCServiceImpl) Introspect(req *dto.TokenActionRequest) (*dto.IntrospectionResponse, error) {
+	client, err := o.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.Public {
+		return nil, newOAuthError("unauthorized_client", "public clients cannot introspect tokens")
+	}
+
+	inactive := &dto.IntrospectionResponse{Active: false}
+
+	var response *dto.IntrospectionResponse
+	if req.TokenTypeHint == utils.TokenTypeRefreshToken {
+		response, err = o.introspectRefreshToken(req.Token)
+		if err == nil && response == nil {
+			response, err = o.introspectAccessToken(req.Token)
+		}
+	} else {
+		response, err = o.introspectAccessToken(req.Token)
+		if err == nil && response == nil {
+			response, err = o.introspectRefreshToken(req.Token)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return inactive, nil
+	}
+
+	if client.TenantID != nil && response.TenantID != client.TenantID.String() {
+		return inactive, nil
+	}
+
+	return response, nil
+}
+
+// Revoke revokes an access or refresh token issued to the calling client (RFC
+// 7009). Invalid tokens are not an error.
+func (o *OIDCServiceImpl) Revoke(req *dto.TokenActionRequest) error {
+	client, err := o.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	if req.TokenTypeHint != utils.TokenTypeRefreshToken {
+		claims, err := o.authService.ValidateAccessToken(req.Token)
+		if err == nil {
+			if claims.ClientID != client.ClientID {
+				return newOAuthError("unauthorized_client", "token was issued to another client")
+			}
+			return o.authService.RevokeAccessToken(claims)
+		}
+		if !errors.Is(err, ErrInvalidToken) && !errors.Is(err, ErrTokenRevoked) {
+			return err
+		}
+	}
+
+	err = o.authService.RevokeRefreshToken(req.Token, client.ClientID)
+	if errors.Is(err, ErrUnauthorized) {
+		return newOAuthError("unauthorized_client", "token was issued to another client")
+	}
+	return err
+}
+
+// introspectAccessToken returns nil when the token is not an active access
+// token.
+func (o *OIDCServiceImpl) introspectAccessToken(token string) (*dto.IntrospectionResponse, error) {
+	claims, err := o.authService.ValidateAccessToken(token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	response := &dto.IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		TenantID:  claims.TenantID,
+		Role:      claims.Role,
+	}
+	if response.Sub == "" {
+		response.Sub = claims.UserID
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		response.Nbf = claims.NotBefore.Unix()
+	}
+
+	return response, nil
+}
+
+// introspectRefreshToken returns nil when the token is not an active refresh
+// token.
+func (o *OIDCServiceImpl) introspectRefreshToken(token string) (*dto.IntrospectionResponse, error) {
+	stored, err := o.authService.FindActiveRefreshToken(token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	response := &dto.IntrospectionResponse{
+		Active:    true,
+		Scope:     stored.Scope,
+		ClientID:  stored.ClientID,
+		TokenType: utils.TokenTypeRefreshToken,
+		Sub:       stored.UserID.String(),
+		Iss:       viper.GetString("JWT_ISSUER"),
+		Exp:       stored.ExpiresAt.Unix(),
+		Iat:       stored.CreatedAt.Unix(),
+	}
+	if stored.TenantID != nil {
+		response.TenantID = stored.TenantID.String()
+	}
+
+	return response, nil
+}
+
+// clientCredentials issues a token for the client itself, limited to the
+// requested scopes or, when none are requested, to every scope it may use.
+func (o *OIDCServiceImpl) clientCredentials(client *models.OAuthClient, scope string) (*dto.LoginResponse, error) {
+	if client.Public {
+		return nil, newOAuthError("unauthorized_client", "public clients cannot use the client_credentials grant")
+	}
+
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, requested := range scopes {
+		if !slices.Contains(client.Scopes, requested) {
+			return nil, newOAuthError("invalid_scope", "scope "+requested+" is not allowed for this client")
+		}
+	}
+
+	return o.authService.IssueClientCredentialsToken(client, strings.Join(scopes, " "))
+}
+
+func (o *OIDCServiceImpl) exchangeAuthorizationCode(client *models.OAuthClient, req *dto.TokenRequest) (*dto.LoginResponse, error) {
+	code, err := o.codeRepo.ConsumeAuthorizationCode(utils.HashToken(req.Code), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newOAuthError("invalid_grant", "authorization code is invalid, expired or already used")
+		}
+		return nil, err
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, newOAuthError("invalid_grant", "authorization code was issued to another client or redirect_uri")
+	}
+
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, newOAuthError("invalid_grant", "code_verifier does not match the code_challenge")
+	}
+
+	user, err := o.userRepo.FindUserById(code.UserID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newOAuthError("invalid_grant", "user no longer exists")
+		}
+		return nil, err
+	}
+
+	scopes := strings.Fields(code.Scope)
+	tokens, err := o.authService.IssueClientTokens(user, client.ClientID, code.Scope, slices.Contains(scopes, utils.ScopeOfflineAccess))
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(scopes, utils.ScopeOpenID) {
+		tokens.IDToken, err = o.generateIDToken(user, client, code, scopes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
+
+func (o *OIDCServiceImpl) generateIDToken(user *models.User, client *models.OAuthClient, code *models.AuthorizationCode, scopes []string) (string, error) {
+	now := time.Now()
+	claims := &utils.IDTokenClaims{
+		Nonce:    code.Nonce,
+		AuthTime: jwt.NewNumericDate(code.AuthTime),
+		TokenUse: utils.TokenUseID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    viper.GetString("JWT_ISSUER"),
+			Subject:   user.ID.String(),
+			Audience:  jwt.ClaimStrings{client.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL())),
+		},
+	}
+	if slices.Contains(scopes, utils.ScopeEmail) {
+		verified := user.EmailVerifiedAt != nil
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+	if slices.Contains(scopes, utils.ScopeProfile) && user.TenantID != nil {
+		claims.TenantID = user.TenantID.String()
+	}
+
+	return signToken(o.keyService, claims)
+}
+
+// authenticateClient checks the client's secret. Public clients have none and
+// rely on PKCE instead.
+func (o *OIDCServiceImpl) authenticateClient(client_id, client_secret string) (*models.OAuthClient, error) {
+	if client_id == "" {
+		return nil, newOAuthError("invalid_client", "client authentication is required")
+	}
+
+	client, err := o.clientRepo.FindClientByClientId(client_id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newOAuthError("invalid_client", "client authentication failed")
+		}
+		return nil, err
+	}
+
+	if client.Public {
+		if client_secret != "" {
+			return nil, newOAuthError("invalid_client", "public clients must not send a secret")
+		}
+		return client, nil
+	}
+
+	if client_secret == "" {
+		return nil, newOAuthError("invalid_client", "client authentication failed")
+	}
+
+	secretHash := []byte(utils.HashToken(client_secret))
+	if subtle.ConstantTimeCompare(secretHash, []byte(client.ClientSecretHash)) == 1 {
+		return client, nil
+	}
+
+	// the secret replaced by the last rotation stays valid for a grace period
+	if client.PreviousSecretHash != "" && client.PreviousSecretExpiresAt != nil &&
+		time.Now().Before(*client.PreviousSecretExpiresAt) &&
+		subtle.ConstantTimeCompare(secretHash, []byte(client.PreviousSecretHash)) == 1 {
+		return client, nil
+	}
+
+	return nil, newOAuthError("invalid_client", "client authentication failed")
+}
+
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if verifier == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultOutboxBatchSize = 20
+	defaultMailMaxAttempts = 8
+	outboxLease            = 5 * time.Minute
+	outboxInitialBackoff   = 30 * time.Second
+	outboxMaxBackoff       = time.Hour
+)
+
+// OutboxService sends queued emails. A failed email is retried with
+// exponential backoff until MAIL_MAX_ATTEMPTS is reached, after which it is
+// marked failed and left for inspection.
+type OutboxService interface {
+	ProcessDue() (int, error)
+	StartDispatcher(interval time.Duration)
+}
+
+type OutboxServiceImpl struct {
+	repo        repository.OutboxRepository
+	mailer      mailer.Mailer
+	maxAttempts int
+}
+
+func NewOutboxService(repo repository.OutboxRepository, m mailer.Mailer) OutboxService {
+	maxAttempts := viper.GetInt("MAIL_MAX_ATTEMPTS")
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMailMaxAttempts
+	}
+	return &OutboxServiceImpl{repo: repo, mailer: m, maxAttempts: maxAttempts}
+}
+
+// ProcessDue sends one batch of due emails and returns how many went out.
+func (o *OutboxServiceImpl) ProcessDue() (int, error) {
+	emails, err := o.repo.ClaimDue(time.Now(), outboxLease, defaultOutboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, email := range emails {
+		err := o.mailer.Send(&mailer.Message{
+			To:      email.To,
+			Subject: email.Subject,
+			HTML:    email.HTMLBody,
+			Text:    email.TextBody,
+		})
+		if err != nil {
+			if err := o.recordFailure(email, err); err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		if err := o.repo.MarkSent(email.ID.String(), time.Now()); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// StartDispatcher processes the outbox every interval. It is safe to run on
+// every instance.
+func (o *OutboxServiceImpl) StartDispatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := o.ProcessDue(); err != nil {
+				log.Println("sending queued emails failed: ", err)
+			}
+		}
+	}()
+}
+
+func (o *OutboxServiceImpl) recordFailure(email *models.OutboxEmail, sendErr error) error {
+	now := time.Now()
+	email.Attempts++
+	email.LastError = sendErr.Error()
+
+	if email.Attempts >= o.maxAttempts {
+		email.FailedAt = &now
+		log.Printf("giving up on email %s to %s after %d attempts: %v", email.ID, email.To, email.Attempts, sendErr)
+	} else {
+		email.NextAttemptAt = now.Add(outboxBackoff(email.Attempts))
+	}
+
+	return o.repo.RecordFailure(email)
+}
+
+// outboxBackoff doubles the wait after each failed attempt, up to an hour.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxInitialBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, outboxMaxBackoff)
+}
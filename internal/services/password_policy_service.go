@@ -0,0 +1,269 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/breach"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+)
+
+// bcrypt ignores everything past the first 72 bytes of a password.
+const maxPasswordBytes = 72
+
+// PasswordPolicyService checks new passwords against their tenant's
+// PasswordPolicy and the breached password filter, keeps the password
+// history it needs and tells when a password has expired.
+type PasswordPolicyService interface {
+	Validate(user *models.User, password string) error
+	RecordTx(tx *gorm.DB, user *models.User) error
+	CheckExpiry(user *models.User) error
+	GetPolicy(requestor *models.User) (*models.PasswordPolicy, error)
+	SavePolicy(requestor *models.User, req *dto.PasswordPolicyRequest) (*models.PasswordPolicy, error)
+}
+
+type PasswordPolicyServiceImpl struct {
+	repo        repository.PasswordPolicyRepository
+	authService AuthService
+	breached    breach.Checker
+}
+
+func NewPasswordPolicyService(repo repository.PasswordPolicyRepository, authService AuthService, breached breach.Checker) PasswordPolicyService {
+	return &PasswordPolicyServiceImpl{repo: repo, authService: authService, breached: breached}
+}
+
+// Validate checks password as the new password of user, who only needs a
+// tenant and email when they do not exist yet. It returns a
+// *PasswordPolicyError listing every rule the password breaks.
+func (p *PasswordPolicyServiceImpl) Validate(user *models.User, password string) error {
+	policy, err := p.policyFor(user.TenantID)
+	if err != nil {
+		return err
+	}
+
+	violations := checkPassword(policy, user.Email, password)
+	if policy.CheckBreached && p.breached.Contains(password) {
+		violations = append(violations, PasswordViolation{
+			Rule:    utils.PasswordRuleBreached,
+			Message: "has appeared in a data breach and must not be used",
+		})
+	}
+	// comparing against old hashes is slow, so only bother with an
+	// otherwise acceptable password
+	if len(violations) == 0 && policy.HistorySize > 0 && user.ID != uuid.Nil {
+		reused, err := p.reused(user, password, policy.HistorySize)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, PasswordViolation{
+				Rule:    utils.PasswordRuleHistory,
+				Message: fmt.Sprintf("must not be one of your last %d passwords", policy.HistorySize),
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// RecordTx adds user's current password hash to their history, as part of
+// tx, so it cannot be reused while it is within the tenant's history size.
+func (p *PasswordPolicyServiceImpl) RecordTx(tx *gorm.DB, user *models.User) error {
+	policy, err := p.policyFor(user.TenantID)
+	if err != nil {
+		return err
+	}
+	if policy.HistorySize == 0 {
+		return nil
+	}
+
+	entry := &models.PasswordHistory{UserID: user.ID, PasswordHash: user.PasswordHash}
+	return p.repo.AddHistoryTx(tx, entry, policy.HistorySize)
+}
+
+// CheckExpiry returns ErrPasswordExpired when user's password is older than
+// their tenant allows. Passwords set before changes were tracked count from
+// when the account was created.
+func (p *PasswordPolicyServiceImpl) CheckExpiry(user *models.User) error {
+	policy, err := p.policyFor(user.TenantID)
+	if err != nil {
+		return err
+	}
+	if policy.MaxAgeDays == 0 {
+		return nil
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	if time.Since(changedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour {
+		return ErrPasswordExpired
+	}
+
+	return nil
+}
+
+func (p *PasswordPolicyServiceImpl) GetPolicy(requestor *models.User) (*models.PasswordPolicy, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	return p.policyFor(requestor.TenantID)
+}
+
+func (p *PasswordPolicyServiceImpl) SavePolicy(requestor *models.User, req *dto.PasswordPolicyRequest) (*models.PasswordPolicy, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+	if req.MinLength > req.MaxLength {
+		return nil, utils.NewAppError(http.StatusBadRequest, "min_length must not be greater than max_length")
+	}
+
+	policy := &models.PasswordPolicy{
+		TenantID:      *requestor.TenantID,
+		MinLength:     req.MinLength,
+		MaxLength:     req.MaxLength,
+		RequireUpper:  req.RequireUpper,
+		RequireLower:  req.RequireLower,
+		RequireDigit:  req.RequireDigit,
+		RequireSymbol: req.RequireSymbol,
+		DisallowEmail: req.DisallowEmail,
+		HistorySize:   req.HistorySize,
+		MaxAgeDays:    req.MaxAgeDays,
+		CheckBreached: req.CheckBreached,
+	}
+	if err := p.repo.SavePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// policyFor returns the policy of tenant_id, or the defaults when the tenant
+// has not set one. Users outside any tenant get the defaults too.
+func (p *PasswordPolicyServiceImpl) policyFor(tenant_id *uuid.UUID) (*models.PasswordPolicy, error) {
+	if tenant_id == nil {
+		return defaultPasswordPolicy(uuid.Nil), nil
+	}
+
+	policy, err := p.repo.FindPolicy(tenant_id.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultPasswordPolicy(*tenant_id), nil
+		}
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// reused reports whether password is user's current password or one of
+// the ones before it that are still within the history size.
+func (p *PasswordPolicyServiceImpl) reused(user *models.User, password string, size int) (bool, error) {
+	if user.PasswordHash != "" && p.authService.CompareHashAndPassword([]byte(password), []byte(user.PasswordHash)) {
+		return true, nil
+	}
+
+	history, err := p.repo.GetHistory(user.ID.String(), size)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range history {
+		if p.authService.CompareHashAndPassword([]byte(password), []byte(entry.PasswordHash)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func defaultPasswordPolicy(tenant_id uuid.UUID) *models.PasswordPolicy {
+	return &models.PasswordPolicy{
+		TenantID:      tenant_id,
+		MinLength:     8,
+		MaxLength:     maxPasswordBytes,
+		DisallowEmail: true,
+		CheckBreached: true,
+	}
+}
+
+// checkPassword returns the rules of policy that password breaks, leaving
+// out the history, which needs the store.
+func checkPassword(policy *models.PasswordPolicy, email, password string) []PasswordViolation {
+	var violations []PasswordViolation
+	add := func(rule, message string) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		add(utils.PasswordRuleMinLength, fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+	}
+	if length > policy.MaxLength || len(password) > maxPasswordBytes {
+		add(utils.PasswordRuleMaxLength, fmt.Sprintf("must be at most %d characters long", policy.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		add(utils.PasswordRuleUpper, "must contain an uppercase letter")
+	}
+	if policy.RequireLower && !lower {
+		add(utils.PasswordRuleLower, "must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		add(utils.PasswordRuleDigit, "must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		add(utils.PasswordRuleSymbol, "must contain a symbol")
+	}
+
+	if policy.DisallowEmail && containsEmail(password, email) {
+		add(utils.PasswordRuleEmail, "must not contain your email address")
+	}
+
+	return violations
+}
+
+// containsEmail reports whether password contains email or, when it is long
+// enough to matter, the part before the @.
+func containsEmail(password, email string) bool {
+	if email == "" {
+		return false
+	}
+
+	password = strings.ToLower(password)
+	email = strings.ToLower(email)
+	if strings.Contains(password, email) {
+		return true
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+	return len(local) >= 3 && strings.Contains(password, local)
+}
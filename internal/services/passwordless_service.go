@@ -0,0 +1,321 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMagicLinkTTL = 15 * time.Minute
+	defaultEmailOTPTTL  = 10 * time.Minute
+	emailOTPDigits      = 6
+	maxEmailOTPAttempts = 5
+)
+
+// PasswordlessService signs users in with a single-use link or a six-digit
+// code sent to their email address, for tenants that turn these methods on.
+// Links and codes are stored hashed and expire after MAGIC_LINK_TTL and
+// EMAIL_OTP_TTL. As with password login, users enrolled in MFA still get a
+// challenge instead of tokens.
+type PasswordlessService interface {
+	RequestMagicLink(email string) error
+	LoginWithMagicLink(token string) (*dto.LoginResponse, *dto.MFAChallengeResponse, error)
+	RequestEmailOTP(email string) error
+	LoginWithEmailOTP(email, code, ip string) (*dto.LoginResponse, *dto.MFAChallengeResponse, error)
+	GetPolicy(requestor *models.User) (*models.LoginMethodPolicy, error)
+	SavePolicy(requestor *models.User, req *dto.LoginMethodPolicyRequest) (*models.LoginMethodPolicy, error)
+}
+
+type PasswordlessServiceImpl struct {
+	repo           repository.PasswordlessRepository
+	userRepo       repository.UserRepository
+	mfaService     MFAService
+	authService    AuthService
+	mailService    MailService
+	lockoutService LockoutService
+	transactor     repository.Transactor
+}
+
+func NewPasswordlessService(
+	repo repository.PasswordlessRepository,
+	userRepo repository.UserRepository,
+	mfaService MFAService,
+	authService AuthService,
+	mailService MailService,
+	lockoutService LockoutService,
+	transactor repository.Transactor,
+) PasswordlessService {
+	return &PasswordlessServiceImpl{
+		repo:           repo,
+		userRepo:       userRepo,
+		mfaService:     mfaService,
+		authService:    authService,
+		mailService:    mailService,
+		lockoutService: lockoutService,
+		transactor:     transactor,
+	}
+}
+
+// RequestMagicLink emails the user a link to MAGIC_LINK_URL with the token
+// in its query string. Nothing is sent, and no error returned, when the
+// address is unknown or its tenant has magic links turned off, so callers
+// can answer the same way either way.
+func (p *PasswordlessServiceImpl) RequestMagicLink(email string) error {
+	user, err := p.findEnabledUser(email, utils.LoginTokenMagicLink)
+	if err != nil || user == nil {
+		return err
+	}
+
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	link, err := emailLink(viper.GetString("MAGIC_LINK_URL"), url.Values{"token": {token}})
+	if err != nil {
+		return err
+	}
+
+	ttl := utils.GetDuration("MAGIC_LINK_TTL", defaultMagicLinkTTL)
+	return p.sendLoginToken(user, utils.LoginTokenMagicLink, tokenHash, ttl, mailer.TemplateMagicLink, map[string]any{
+		"Link": link,
+	})
+}
+
+func (p *PasswordlessServiceImpl) LoginWithMagicLink(token string) (*dto.LoginResponse, *dto.MFAChallengeResponse, error) {
+	loginToken, err := p.repo.FindActiveLoginTokenByHash(utils.HashToken(token), utils.LoginTokenMagicLink, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidLoginToken
+		}
+		return nil, nil, err
+	}
+
+	if err := p.consumeLoginToken(loginToken, ErrInvalidLoginToken); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := p.userRepo.FindUserById(loginToken.UserID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidLoginToken
+		}
+		return nil, nil, err
+	}
+
+	// the tenant may have turned magic links off since this one was sent
+	enabled, err := p.methodEnabled(user, utils.LoginTokenMagicLink)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !enabled {
+		return nil, nil, ErrInvalidLoginToken
+	}
+
+	return p.completeLogin(user)
+}
+
+// RequestEmailOTP emails the user a sign-in code, under the same conditions
+// as RequestMagicLink.
+func (p *PasswordlessServiceImpl) RequestEmailOTP(email string) error {
+	user, err := p.findEnabledUser(email, utils.LoginTokenEmailOTP)
+	if err != nil || user == nil {
+		return err
+	}
+
+	code, err := utils.GenerateNumericCode(emailOTPDigits)
+	if err != nil {
+		return err
+	}
+
+	ttl := utils.GetDuration("EMAIL_OTP_TTL", defaultEmailOTPTTL)
+	return p.sendLoginToken(user, utils.LoginTokenEmailOTP, utils.HashToken(code), ttl, mailer.TemplateLoginCode, map[string]any{
+		"Code": code,
+	})
+}
+
+// LoginWithEmailOTP checks code against the last one sent to email. A code
+// stops working after a few wrong guesses. Wrong guesses also count as
+// failed logins from ip, the same as wrong passwords, so asking for a new
+// code does not buy more guesses.
+func (p *PasswordlessServiceImpl) LoginWithEmailOTP(email, code, ip string) (*dto.LoginResponse, *dto.MFAChallengeResponse, error) {
+	if err := p.lockoutService.Check(email, ip); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := p.findEnabledUser(email, utils.LoginTokenEmailOTP)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, p.recordInvalidCode(email, ip, nil)
+	}
+
+	loginToken, err := p.repo.UseLoginTokenAttempt(user.ID.String(), utils.LoginTokenEmailOTP, time.Now(), maxEmailOTPAttempts)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, p.recordInvalidCode(email, ip, user)
+		}
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(code)), []byte(loginToken.TokenHash)) != 1 {
+		return nil, nil, p.recordInvalidCode(email, ip, user)
+	}
+
+	if err := p.consumeLoginToken(loginToken, ErrInvalidLoginCode); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, challenge, err := p.completeLogin(user)
+	if err != nil || challenge != nil {
+		return tokens, challenge, err
+	}
+
+	if err := p.lockoutService.RecordSuccess(user.Email); err != nil {
+		return nil, nil, err
+	}
+	return tokens, nil, nil
+}
+
+// recordInvalidCode counts a failed code login and returns
+// ErrInvalidLoginCode.
+func (p *PasswordlessServiceImpl) recordInvalidCode(email, ip string, user *models.User) error {
+	if err := p.lockoutService.RecordFailure(email, ip, user); err != nil {
+		return err
+	}
+	return ErrInvalidLoginCode
+}
+
+func (p *PasswordlessServiceImpl) GetPolicy(requestor *models.User) (*models.LoginMethodPolicy, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := p.repo.FindLoginMethodPolicy(requestor.TenantID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.LoginMethodPolicy{TenantID: *requestor.TenantID}, nil
+		}
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (p *PasswordlessServiceImpl) SavePolicy(requestor *models.User, req *dto.LoginMethodPolicyRequest) (*models.LoginMethodPolicy, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy := &models.LoginMethodPolicy{
+		TenantID:         *requestor.TenantID,
+		MagicLinkEnabled: req.MagicLinkEnabled,
+		EmailOTPEnabled:  req.EmailOTPEnabled,
+	}
+	if err := p.repo.SaveLoginMethodPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// findEnabledUser returns nil without an error when there is no user with
+// that email or their tenant does not allow the method.
+func (p *PasswordlessServiceImpl) findEnabledUser(email, kind string) (*models.User, error) {
+	user, err := p.userRepo.FindUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	enabled, err := p.methodEnabled(user, kind)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (p *PasswordlessServiceImpl) methodEnabled(user *models.User, kind string) (bool, error) {
+	if user.TenantID == nil {
+		return false, nil
+	}
+
+	policy, err := p.repo.FindLoginMethodPolicy(user.TenantID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	switch kind {
+	case utils.LoginTokenMagicLink:
+		return policy.MagicLinkEnabled, nil
+	case utils.LoginTokenEmailOTP:
+		return policy.EmailOTPEnabled, nil
+	}
+	return false, nil
+}
+
+// sendLoginToken stores the token and queues the email carrying it in one
+// transaction.
+func (p *PasswordlessServiceImpl) sendLoginToken(user *models.User, kind, token_hash string, ttl time.Duration, template string, data map[string]any) error {
+	data["ExpiresIn"] = utils.HumanizeDuration(ttl)
+
+	return p.transactor.Transaction(func(tx *gorm.DB) error {
+		err := p.repo.CreateLoginTokenTx(tx, &models.LoginToken{
+			ID:        uuid.New(),
+			UserID:    user.ID,
+			Kind:      kind,
+			TokenHash: token_hash,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+		if err != nil {
+			return err
+		}
+		return p.mailService.EnqueueTx(tx, user.TenantID, template, user.Email, data)
+	})
+}
+
+func (p *PasswordlessServiceImpl) consumeLoginToken(token *models.LoginToken, invalid error) error {
+	if err := p.repo.ConsumeLoginToken(token.ID.String(), time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalid
+		}
+		return err
+	}
+	return nil
+}
+
+// completeLogin issues tokens, or an MFA challenge when the user needs a
+// second factor.
+func (p *PasswordlessServiceImpl) completeLogin(user *models.User) (*dto.LoginResponse, *dto.MFAChallengeResponse, error) {
+	challenge, err := p.mfaService.BeginLogin(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
+	}
+
+	tokens, err := p.authService.IssueTokens(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, nil, nil
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+)
+
+// personalAccessTokenTouchInterval is how stale a token's last-used time may
+// get, so busy scripts do not cost a write on every request.
+const personalAccessTokenTouchInterval = time.Minute
+
+// PersonalAccessTokenService lets users create bearer tokens for scripts,
+// limited to some of their own permissions, and tenant admins see and revoke
+// their members' tokens. Tokens are also the API keys of service accounts,
+// which tenant admins create for them.
+type PersonalAccessTokenService interface {
+	CreateToken(user *models.User, req *dto.PersonalAccessTokenRequest) (*dto.PersonalAccessTokenResponse, error)
+	CreateServiceAccountToken(requestor *models.User, claims *utils.Claims, user_id string, req *dto.PersonalAccessTokenRequest) (*dto.PersonalAccessTokenResponse, error)
+	GetTokens(user *models.User) ([]*models.PersonalAccessToken, error)
+	RevokeToken(user *models.User, id string) error
+	GetUserTokens(requestor *models.User, user_id string) ([]*models.PersonalAccessToken, error)
+	RevokeUserToken(requestor *models.User, id string) error
+	Authenticate(rawToken string) (*models.PersonalAccessToken, error)
+}
+
+type PersonalAccessTokenServiceImpl struct {
+	repo         repository.PersonalAccessTokenRepository
+	userRepo     repository.UserRepository
+	roleRepo     repository.RoleRepository
+	auditService AuditService
+}
+
+func NewPersonalAccessTokenService(repo repository.PersonalAccessTokenRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, auditService AuditService) PersonalAccessTokenService {
+	return &PersonalAccessTokenServiceImpl{repo: repo, userRepo: userRepo, roleRepo: roleRepo, auditService: auditService}
+}
+
+// CreateToken issues a token for user. Its scopes must be permission codes
+// the user's role has; the super admin may pick any.
+func (p *PersonalAccessTokenServiceImpl) CreateToken(user *models.User, req *dto.PersonalAccessTokenRequest) (*dto.PersonalAccessTokenResponse, error) {
+	return p.createToken(user, user, req)
+}
+
+// CreateServiceAccountToken issues an API key for a service account of the
+// requestor's tenant. Its scopes must be permission codes of the service
+// account's role that the requestor could grant themselves, see
+// grantablePermissions. claims are those of the request and may be nil.
+func (p *PersonalAccessTokenServiceImpl) CreateServiceAccountToken(requestor *models.User, claims *utils.Claims, user_id string, req *dto.PersonalAccessTokenRequest) (*dto.PersonalAccessTokenResponse, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+	if _, err := uuid.Parse(user_id); err != nil {
+		return nil, utils.NewAppError(http.StatusNotFound, "service account not found")
+	}
+
+	account, err := p.userRepo.GetServiceAccountById(requestor.TenantID.String(), user_id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "service account not found")
+		}
+		return nil, err
+	}
+
+	granted, all, err := grantablePermissions(p.roleRepo, requestor, claims)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range req.Scopes {
+		if !all && !slices.Contains(granted, scope) {
+			return nil, utils.NewAppError(http.StatusForbidden, "cannot grant scope "+scope)
+		}
+	}
+
+	return p.createToken(requestor, account, req)
+}
+
+// createToken issues a token for user on behalf of actor.
+func (p *PersonalAccessTokenServiceImpl) createToken(actor, user *models.User, req *dto.PersonalAccessTokenRequest) (*dto.PersonalAccessTokenResponse, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, utils.NewAppError(http.StatusBadRequest, "expires_at must be in the future")
+	}
+	if err := p.checkScopes(user, req.Scopes); err != nil {
+		return nil, err
+	}
+
+	// the hash covers the prefix, which is part of the token
+	secret, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	rawToken := utils.PersonalAccessTokenPrefix + secret
+
+	token := &models.PersonalAccessToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		Name:      req.Name,
+		TokenHash: utils.HashToken(rawToken),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := p.repo.CreateToken(token); err != nil {
+		return nil, err
+	}
+
+	// the token exists either way, so a failed audit write is only logged
+	if err := p.auditService.Record(&models.AuditEvent{
+		TenantID: user.TenantID,
+		ActorID:  &actor.ID,
+		UserID:   &user.ID,
+		Event:    utils.AuditTokenCreated,
+		Detail:   "token " + token.ID.String() + " with scopes " + strings.Join(token.Scopes, " "),
+	}); err != nil {
+		log.Printf("failed to record audit event %s: %v", utils.AuditTokenCreated, err)
+	}
+
+	return &dto.PersonalAccessTokenResponse{Token: token, AccessToken: rawToken}, nil
+}
+
+func (p *PersonalAccessTokenServiceImpl) checkScopes(user *models.User, scopes []string) error {
+	var granted []string
+	if user.Role.Name != utils.RoleSuperAdmin {
+		role, err := p.roleRepo.GetRoleById(user.RoleID)
+		if err != nil {
+			return err
+		}
+		for _, permission := range role.Permissions {
+			granted = append(granted, permission.Code)
+		}
+	}
+
+	for _, scope := range scopes {
+		if _, _, ok := strings.Cut(scope, ":"); !ok {
+			return utils.NewAppError(http.StatusBadRequest, "unknown scope "+scope)
+		}
+		if user.Role.Name != utils.RoleSuperAdmin && !slices.Contains(granted, scope) {
+			return utils.NewAppError(http.StatusForbidden, "cannot grant scope "+scope)
+		}
+	}
+
+	return nil
+}
+
+// GetTokens lists the user's own tokens.
+func (p *PersonalAccessTokenServiceImpl) GetTokens(user *models.User) ([]*models.PersonalAccessToken, error) {
+	return p.repo.GetUserTokens(user.ID.String())
+}
+
+// RevokeToken revokes one of the user's own tokens.
+func (p *PersonalAccessTokenServiceImpl) RevokeToken(user *models.User, id string) error {
+	token, err := p.findToken(id)
+	if err != nil {
+		return err
+	}
+	if token.UserID != user.ID {
+		return utils.NewAppError(http.StatusNotFound, "token not found")
+	}
+
+	return p.revoke(user, token)
+}
+
+// GetUserTokens lists the tokens of a member of the requestor's tenant.
+func (p *PersonalAccessTokenServiceImpl) GetUserTokens(requestor *models.User, user_id string) ([]*models.PersonalAccessToken, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := p.userRepo.GetUserById(requestor.TenantID.String(), user_id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "user not found")
+		}
+		return nil, err
+	}
+
+	return p.repo.GetUserTokens(user.ID.String())
+}
+
+// RevokeUserToken revokes a token of any member of the requestor's tenant.
+func (p *PersonalAccessTokenServiceImpl) RevokeUserToken(requestor *models.User, id string) error {
+	if requestor.TenantID == nil {
+		return ErrUnauthorized
+	}
+
+	token, err := p.findToken(id)
+	if err != nil {
+		return err
+	}
+	if token.TenantID == nil || *token.TenantID != *requestor.TenantID {
+		return utils.NewAppError(http.StatusNotFound, "token not found")
+	}
+
+	return p.revoke(requestor, token)
+}
+
+func (p *PersonalAccessTokenServiceImpl) findToken(id string) (*models.PersonalAccessToken, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, utils.NewAppError(http.StatusNotFound, "token not found")
+	}
+
+	token, err := p.repo.FindTokenById(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "token not found")
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+func (p *PersonalAccessTokenServiceImpl) revoke(actor *models.User, token *models.PersonalAccessToken) error {
+	revoked, err := p.repo.RevokeToken(token.ID.String(), time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return nil
+	}
+
+	// as on create, the token is revoked either way
+	if err := p.auditService.Record(&models.AuditEvent{
+		TenantID: token.TenantID,
+		ActorID:  &actor.ID,
+		UserID:   &token.UserID,
+		Event:    utils.AuditTokenRevoked,
+		Detail:   "token " + token.ID.String(),
+	}); err != nil {
+		log.Printf("failed to record audit event %s: %v", utils.AuditTokenRevoked, err)
+	}
+
+	return nil
+}
+
+// Authenticate returns the token rawToken is, failing with ErrInvalidToken
+// when it is unknown, revoked or expired.
+func (p *PersonalAccessTokenServiceImpl) Authenticate(rawToken string) (*models.PersonalAccessToken, error) {
+	token, err := p.repo.FindTokenByHash(utils.HashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && now.After(*token.ExpiresAt)) {
+		return nil, ErrInvalidToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > personalAccessTokenTouchInterval {
+		if err := p.repo.TouchToken(token.ID.String(), now); err != nil {
+			log.Printf("failed to record use of personal access token %s: %v", token.ID, err)
+		}
+	}
+
+	return token, nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+)
+
+const defaultRevocationCacheTTL = 30 * time.Second
+
+type RevocationService interface {
+	RevokeToken(jti string, userID uuid.UUID, expiresAt time.Time) error
+	RevokeUserTokens(userID uuid.UUID) error
+	RevokeSession(session_id string)
+	IsRevoked(claims *utils.Claims) (bool, error)
+}
+
+// RevocationServiceImpl keeps an in-process snapshot of the revocation list so
+// token validation does not hit Postgres on every request. Revocations made by
+// this instance apply immediately; those made by other instances become visible
+// on the next reload, at most REVOCATION_CACHE_TTL later.
+type RevocationServiceImpl struct {
+	repo     repository.RevocationRepository
+	cacheTTL time.Duration
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	users    map[uuid.UUID]time.Time
+	sessions map[string]time.Time
+	loadedAt time.Time
+}
+
+func NewRevocationService(repo repository.RevocationRepository) RevocationService {
+	return &RevocationServiceImpl{
+		repo:     repo,
+		cacheTTL: utils.GetDuration("REVOCATION_CACHE_TTL", defaultRevocationCacheTTL),
+		tokens:   make(map[string]time.Time),
+		users:    make(map[uuid.UUID]time.Time),
+		sessions: make(map[string]time.Time),
+	}
+}
+
+func (r *RevocationServiceImpl) RevokeToken(jti string, userID uuid.UUID, expiresAt time.Time) error {
+	token := &models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	if err := r.repo.RevokeToken(token); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.tokens[jti] = expiresAt
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *RevocationServiceImpl) RevokeUserTokens(userID uuid.UUID) error {
+	// iat only has second precision. Tokens issued in the same second as the
+	// revocation cannot be told apart from those issued before it, so
+	// IsRevoked rejects them too. The revocation is kept until the longest
+	// lived of them, which may be an impersonation token, has expired.
+	now := time.Now().Truncate(time.Second)
+	revocation := &models.UserTokenRevocation{
+		UserID:    userID,
+		RevokedAt: now,
+		ExpiresAt: now.Add(maxTokenTTL()),
+	}
+	if err := r.repo.RevokeUserTokens(revocation); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.users[userID] = now
+	r.mu.Unlock()
+
+	return nil
+}
+
+// RevokeSession rejects the access tokens of a session right away. The
+// session must already be revoked in the DB, which is where other instances
+// pick it up from.
+func (r *RevocationServiceImpl) RevokeSession(session_id string) {
+	r.mu.Lock()
+	r.sessions[session_id] = time.Now()
+	r.mu.Unlock()
+}
+
+func (r *RevocationServiceImpl) IsRevoked(claims *utils.Claims) (bool, error) {
+	if err := r.ensureFresh(); err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.tokens[claims.ID]; ok && claims.ID != "" {
+		return true, nil
+	}
+
+	if _, ok := r.sessions[claims.SessionID]; ok && claims.SessionID != "" {
+		return true, nil
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return false, nil
+	}
+
+	if revokedAt, ok := r.users[userID]; ok {
+		if claims.IssuedAt == nil || !claims.IssuedAt.Time.After(revokedAt) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *RevocationServiceImpl) ensureFresh() error {
+	r.mu.RLock()
+	fresh := time.Since(r.loadedAt) < r.cacheTTL
+	r.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// another request may have reloaded while we waited for the lock
+	if time.Since(r.loadedAt) < r.cacheTTL {
+		return nil
+	}
+
+	now := time.Now()
+	if err := r.repo.DeleteExpiredRevocations(now); err != nil {
+		return err
+	}
+
+	revokedTokens, err := r.repo.GetRevokedTokens(now)
+	if err != nil {
+		return err
+	}
+	userRevocations, err := r.repo.GetUserTokenRevocations(now)
+	if err != nil {
+		return err
+	}
+	revokedSessions, err := r.repo.GetRevokedSessions(now.Add(-accessTokenTTL()))
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]time.Time, len(revokedTokens))
+	for _, token := range revokedTokens {
+		tokens[token.JTI] = token.ExpiresAt
+	}
+	users := make(map[uuid.UUID]time.Time, len(userRevocations))
+	for _, revocation := range userRevocations {
+		users[revocation.UserID] = revocation.RevokedAt
+	}
+
+	sessions := make(map[string]time.Time, len(revokedSessions))
+	for _, session := range revokedSessions {
+		sessions[session.ID.String()] = *session.RevokedAt
+	}
+
+	r.tokens = tokens
+	r.users = users
+	r.sessions = sessions
+	r.loadedAt = now
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+)
+
+var serviceAccountName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ServiceAccountService lets tenant admins manage the non-human members of
+// their tenant. A service account is a user with a role and no password;
+// its API keys are personal access tokens created with
+// PersonalAccessTokenService.CreateServiceAccountToken. Nobody can give a
+// service account permissions they do not have themselves.
+type ServiceAccountService interface {
+	GetServiceAccounts(requestor *models.User) ([]*models.User, error)
+	CreateServiceAccount(requestor *models.User, claims *utils.Claims, req *dto.ServiceAccountRequest) (*models.User, error)
+	DeleteServiceAccount(requestor *models.User, id string) error
+}
+
+type ServiceAccountServiceImpl struct {
+	userRepo     repository.UserRepository
+	roleRepo     repository.RoleRepository
+	auditService AuditService
+}
+
+func NewServiceAccountService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, auditService AuditService) ServiceAccountService {
+	return &ServiceAccountServiceImpl{userRepo: userRepo, roleRepo: roleRepo, auditService: auditService}
+}
+
+func (s *ServiceAccountServiceImpl) GetServiceAccounts(requestor *models.User) ([]*models.User, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	return s.userRepo.GetServiceAccounts(requestor.TenantID.String())
+}
+
+// CreateServiceAccount adds a service account to the requestor's tenant
+// with one of its roles. The role may not have any permission the requestor
+// could not grant, see grantablePermissions. claims are those of the
+// request and may be nil.
+func (s *ServiceAccountServiceImpl) CreateServiceAccount(requestor *models.User, claims *utils.Claims, req *dto.ServiceAccountRequest) (*models.User, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+	if !serviceAccountName.MatchString(req.Name) {
+		return nil, utils.NewAppError(http.StatusBadRequest, "name may only contain lowercase letters, digits and dashes")
+	}
+
+	role, err := s.roleRepo.GetRoleByName(requestor.TenantID.String(), req.Role)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusBadRequest, "unknown role "+req.Role)
+		}
+		return nil, err
+	}
+
+	granted, all, err := grantablePermissions(s.roleRepo, requestor, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !all {
+		// GetRoleByName does not load the role's permissions
+		withPermissions, err := s.roleRepo.GetRoleById(role.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, permission := range withPermissions.Permissions {
+			if !slices.Contains(granted, permission.Code) {
+				return nil, utils.NewAppError(http.StatusForbidden, "cannot grant role "+role.Name)
+			}
+		}
+	}
+
+	// there is no address to verify, and the tenant's email verification
+	// policy must not restrict the account
+	now := time.Now()
+	account := &models.User{
+		ID:               uuid.New(),
+		TenantID:         requestor.TenantID,
+		Email:            fmt.Sprintf("%s@%s.%s", req.Name, requestor.TenantID, utils.ServiceAccountDomain),
+		RoleID:           role.ID.String(),
+		Role:             *role,
+		IsServiceAccount: true,
+		EmailVerifiedAt:  &now,
+	}
+	err = s.userRepo.CreateServiceAccount(account)
+	if utils.UniqueViolation(err) {
+		return nil, utils.NewAppError(http.StatusConflict, "service account already exists: "+req.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auditService.Record(&models.AuditEvent{
+		TenantID: requestor.TenantID,
+		ActorID:  &requestor.ID,
+		UserID:   &account.ID,
+		Event:    utils.AuditServiceAccountCreated,
+		Detail:   fmt.Sprintf("service account %s with role %s", req.Name, role.Name),
+	}); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// DeleteServiceAccount removes a service account of the requestor's tenant.
+// Its API keys stop working with it.
+func (s *ServiceAccountServiceImpl) DeleteServiceAccount(requestor *models.User, id string) error {
+	if requestor.TenantID == nil {
+		return ErrUnauthorized
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return utils.NewAppError(http.StatusNotFound, "service account not found")
+	}
+
+	account, err := s.userRepo.GetServiceAccountById(requestor.TenantID.String(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(http.StatusNotFound, "service account not found")
+		}
+		return err
+	}
+
+	if err := s.userRepo.RemoveUserById(requestor.TenantID.String(), account.ID.String()); err != nil {
+		return err
+	}
+
+	return s.auditService.Record(&models.AuditEvent{
+		TenantID: requestor.TenantID,
+		ActorID:  &requestor.ID,
+		UserID:   &account.ID,
+		Event:    utils.AuditServiceAccountDeleted,
+		Detail:   "service account " + account.Email,
+	})
+}
+
+// grantablePermissions returns the permission codes requestor may pass on to
+// a service account, or all when there is no limit, as for the super admin.
+// A request made with a personal access token passes on no more than the
+// token's scopes, and a client acting as itself no more than its scope.
+func grantablePermissions(roleRepo repository.RoleRepository, requestor *models.User, claims *utils.Claims) ([]string, bool, error) {
+	if claims != nil && claims.IsClient() {
+		return scopePermissions(claims.Scope), false, nil
+	}
+	if requestor.Role.Name == utils.RoleSuperAdmin {
+		if claims != nil && claims.IsPersonalAccessToken() {
+			return scopePermissions(claims.Scope), false, nil
+		}
+		return nil, true, nil
+	}
+
+	// a loaded role is used as is since it may differ from the stored one,
+	// e.g. while restricted by the email verification policy
+	permissions := requestor.Role.Permissions
+	if len(permissions) == 0 {
+		roleID := requestor.RoleID
+		if requestor.Role.ID != uuid.Nil {
+			roleID = requestor.Role.ID.String()
+		}
+		role, err := roleRepo.GetRoleById(roleID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, false, ErrUnauthorized
+			}
+			return nil, false, err
+		}
+		permissions = role.Permissions
+	}
+
+	var scopes []string
+	if claims != nil && claims.IsPersonalAccessToken() {
+		scopes = strings.Fields(claims.Scope)
+	}
+
+	granted := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if scopes != nil && !slices.Contains(scopes, permission.Code) {
+			continue
+		}
+		granted = append(granted, permission.Code)
+	}
+
+	return granted, false, nil
+}
+
+// scopePermissions returns the permission codes in scope.
+func scopePermissions(scope string) []string {
+	var codes []string
+	for _, code := range strings.Fields(scope) {
+		if strings.Contains(code, ":") {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
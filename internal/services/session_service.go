@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"gorm.io/gorm"
+)
+
+// SessionService lets users see and end the sessions they are logged in
+// with, and tenant admins those of their tenant's members. Activity reported
+// with Touch is kept in memory and written in batches, so tracking last-seen
+// times costs requests no DB round trip.
+type SessionService interface {
+	GetSessions(user *models.User) ([]*models.Session, error)
+	RevokeSession(user *models.User, session_id string) error
+	GetUserSessions(requestor *models.User, user_id string) ([]*models.Session, error)
+	RevokeUserSession(requestor *models.User, session_id string) error
+	Touch(session_id, ip, user_agent string)
+	Flush() (int, error)
+	StartFlusher(interval time.Duration)
+}
+
+type SessionServiceImpl struct {
+	repo              repository.SessionRepository
+	userRepo          repository.UserRepository
+	revocationService RevocationService
+	auditService      AuditService
+
+	mu   sync.Mutex
+	seen map[uuid.UUID]*models.Session
+}
+
+func NewSessionService(repo repository.SessionRepository, userRepo repository.UserRepository, revocationService RevocationService, auditService AuditService) SessionService {
+	return &SessionServiceImpl{
+		repo:              repo,
+		userRepo:          userRepo,
+		revocationService: revocationService,
+		auditService:      auditService,
+		seen:              make(map[uuid.UUID]*models.Session),
+	}
+}
+
+// GetSessions lists the user's active sessions, most recently seen first.
+func (s *SessionServiceImpl) GetSessions(user *models.User) ([]*models.Session, error) {
+	return s.repo.GetActiveSessions(user.ID.String(), time.Now())
+}
+
+// RevokeSession ends one of the user's own sessions.
+func (s *SessionServiceImpl) RevokeSession(user *models.User, session_id string) error {
+	session, err := s.findSession(session_id)
+	if err != nil {
+		return err
+	}
+	if session.UserID != user.ID {
+		return utils.NewAppError(http.StatusNotFound, "session not found")
+	}
+
+	return s.revoke(user, session)
+}
+
+// GetUserSessions lists the active sessions of a member of the requestor's
+// tenant.
+func (s *SessionServiceImpl) GetUserSessions(requestor *models.User, user_id string) ([]*models.Session, error) {
+	if requestor.TenantID == nil {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.userRepo.GetUserById(requestor.TenantID.String(), user_id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "user not found")
+		}
+		return nil, err
+	}
+
+	return s.repo.GetActiveSessions(user.ID.String(), time.Now())
+}
+
+// RevokeUserSession ends a session of any member of the requestor's tenant.
+func (s *SessionServiceImpl) RevokeUserSession(requestor *models.User, session_id string) error {
+	if requestor.TenantID == nil {
+		return ErrUnauthorized
+	}
+
+	session, err := s.findSession(session_id)
+	if err != nil {
+		return err
+	}
+	if session.TenantID == nil || *session.TenantID != *requestor.TenantID {
+		return utils.NewAppError(http.StatusNotFound, "session not found")
+	}
+
+	return s.revoke(requestor, session)
+}
+
+func (s *SessionServiceImpl) findSession(session_id string) (*models.Session, error) {
+	if _, err := uuid.Parse(session_id); err != nil {
+		return nil, utils.NewAppError(http.StatusNotFound, "session not found")
+	}
+
+	session, err := s.repo.FindActiveSession(session_id, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "session not found")
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// revoke ends session, together with its refresh tokens and the access
+// tokens issued in it, on behalf of actor.
+func (s *SessionServiceImpl) revoke(actor *models.User, session *models.Session) error {
+	revoked, err := s.repo.RevokeSession(session.ID.String(), time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return nil
+	}
+	s.revocationService.RevokeSession(session.ID.String())
+
+	return s.auditService.Record(&models.AuditEvent{
+		TenantID: session.TenantID,
+		ActorID:  &actor.ID,
+		UserID:   &session.UserID,
+		Event:    utils.AuditSessionRevoked,
+		Detail:   fmt.Sprintf("session %s last seen from %s", session.ID, session.IP),
+	})
+}
+
+// Touch records that a session was just used from ip and user_agent. It only
+// updates memory; Flush writes what was seen since the last flush.
+func (s *SessionServiceImpl) Touch(session_id, ip, user_agent string) {
+	id, err := uuid.Parse(session_id)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.seen[id] = &models.Session{ID: id, IP: ip, UserAgent: user_agent, LastSeenAt: time.Now()}
+	s.mu.Unlock()
+}
+
+// Flush writes the sessions touched since the last flush and returns how
+// many there were. Activity that fails to be written is dropped; the next
+// request of the session reports it again.
+func (s *SessionServiceImpl) Flush() (int, error) {
+	s.mu.Lock()
+	seen := s.seen
+	s.seen = make(map[uuid.UUID]*models.Session, len(seen))
+	s.mu.Unlock()
+
+	if len(seen) == 0 {
+		return 0, nil
+	}
+
+	sessions := make([]*models.Session, 0, len(seen))
+	for _, session := range seen {
+		sessions = append(sessions, session)
+	}
+	if err := s.repo.TouchSessions(sessions); err != nil {
+		return 0, err
+	}
+	return len(sessions), nil
+}
+
+// StartFlusher flushes session activity every interval.
+func (s *SessionServiceImpl) StartFlusher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.Flush(); err != nil {
+				log.Println("recording session activity failed: ", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,338 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/passhash"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func newTestAuthService(
+	t *testing.T,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	userRepo repository.UserRepository,
+	revocationService services.RevocationService,
+) services.AuthService {
+	viper.Set("JWT_SIGNING_ALG", "")
+	viper.Set("JWT_SECRET", "test_secret")
+
+	keyService, err := services.NewKeyService()
+	require.NoError(t, err)
+
+	sessionRepo := &mocks.MockSessionRepository{}
+	sessionRepo.On("CreateSession", mock.AnythingOfType("*models.Session")).Return(nil)
+
+	return services.NewAuthService(refreshTokenRepo, sessionRepo, userRepo, revocationService, keyService, newTestClaimsService(), newTestPasswords())
+}
+
+// newTestClaimsService builds claims with the default config.
+func newTestClaimsService() services.ClaimsService {
+	mockConfigRepo := &mocks.MockTokenClaimConfigRepository{}
+	mockConfigRepo.On("FindClaimConfigs", mock.Anything, mock.Anything).Return(nil, nil)
+	return services.NewClaimsService(mockConfigRepo, &mocks.MockRoleRepository{})
+}
+
+// newTestPasswords hashes with the cheapest bcrypt cost to keep tests fast.
+func newTestPasswords() *passhash.Passwords {
+	return passhash.NewPasswords(&passhash.Bcrypt{Cost: bcrypt.MinCost})
+}
+
+func TestIssueTokens_Success(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	mockUserRepo := &mocks.MockUserRepository{}
+	authService := newTestAuthService(t, mockRefreshRepo, mockUserRepo, &mocks.MockRevocationService{})
+
+	user := &models.User{ID: uuid.New()}
+
+	mockRefreshRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	tokens, err := authService.IssueTokens(user)
+
+	assert.NoError(t, err)
+	require.NotNil(t, tokens)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+
+	stored := mockRefreshRepo.Calls[0].Arguments.Get(0).(*models.RefreshToken)
+	assert.Equal(t, user.ID, stored.UserID)
+	assert.Equal(t, utils.HashToken(tokens.RefreshToken), stored.TokenHash)
+	assert.NotEqual(t, tokens.RefreshToken, stored.TokenHash)
+
+	// the refresh token family is the session the access token carries
+	assert.Equal(t, stored.FamilyID.String(), tokens.SessionID)
+	claims := &utils.Claims{}
+	_, _, err = jwt.NewParser().ParseUnverified(tokens.AccessToken, claims)
+	require.NoError(t, err)
+	assert.Equal(t, tokens.SessionID, claims.SessionID)
+}
+
+func TestIssueImpersonationToken(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	authService := newTestAuthService(t, mockRefreshRepo, &mocks.MockUserRepository{}, &mocks.MockRevocationService{})
+
+	user := &models.User{ID: uuid.New()}
+	impersonator := &models.User{ID: uuid.New(), Email: "support@example.com"}
+
+	tokens, issued, err := authService.IssueImpersonationToken(user, impersonator)
+	require.NoError(t, err)
+
+	assert.Empty(t, tokens.RefreshToken)
+	assert.Equal(t, int64(600), tokens.ExpiresIn)
+	mockRefreshRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+
+	claims := &utils.Claims{}
+	_, _, err = jwt.NewParser().ParseUnverified(tokens.AccessToken, claims)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID.String(), claims.UserID)
+	require.True(t, claims.IsImpersonation())
+	assert.Equal(t, impersonator.ID.String(), claims.Act.Subject)
+	assert.Equal(t, issued.ID, claims.ID)
+	assert.Empty(t, claims.SessionID)
+}
+
+func TestRefreshTokens_Rotates(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	mockUserRepo := &mocks.MockUserRepository{}
+	authService := newTestAuthService(t, mockRefreshRepo, mockUserRepo, &mocks.MockRevocationService{})
+
+	user := &models.User{ID: uuid.New()}
+	rawToken := "refresh_token"
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		TokenHash: utils.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mockRefreshRepo.On("FindRefreshTokenByHash", stored.TokenHash).Return(stored, nil)
+	mockUserRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	mockRefreshRepo.On("RotateRefreshToken", stored, mock.AnythingOfType("*models.RefreshToken")).Return(true, nil)
+
+	tokens, err := authService.RefreshTokens(rawToken, "")
+
+	assert.NoError(t, err)
+	require.NotNil(t, tokens)
+	assert.NotEqual(t, rawToken, tokens.RefreshToken)
+
+	next := mockRefreshRepo.Calls[1].Arguments.Get(1).(*models.RefreshToken)
+	assert.Equal(t, stored.FamilyID, next.FamilyID)
+	assert.Equal(t, utils.HashToken(tokens.RefreshToken), next.TokenHash)
+	mockRefreshRepo.AssertNotCalled(t, "RevokeRefreshTokenFamily", mock.Anything)
+}
+
+func TestRefreshTokens_ReusedToken_RevokesFamily(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	mockUserRepo := &mocks.MockUserRepository{}
+	authService := newTestAuthService(t, mockRefreshRepo, mockUserRepo, &mocks.MockRevocationService{})
+
+	usedAt := time.Now().Add(-time.Minute)
+	rawToken := "refresh_token"
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		FamilyID:  uuid.New(),
+		TokenHash: utils.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+
+	mockRefreshRepo.On("FindRefreshTokenByHash", stored.TokenHash).Return(stored, nil)
+	mockRefreshRepo.On("RevokeRefreshTokenFamily", stored.FamilyID.String()).Return(nil)
+
+	tokens, err := authService.RefreshTokens(rawToken, "")
+
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+	assert.Nil(t, tokens)
+	mockRefreshRepo.AssertCalled(t, "RevokeRefreshTokenFamily", stored.FamilyID.String())
+	mockUserRepo.AssertNotCalled(t, "FindUserById", mock.Anything)
+}
+
+func TestRefreshTokens_ConcurrentRotation_RevokesFamily(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	mockUserRepo := &mocks.MockUserRepository{}
+	authService := newTestAuthService(t, mockRefreshRepo, mockUserRepo, &mocks.MockRevocationService{})
+
+	user := &models.User{ID: uuid.New()}
+	rawToken := "refresh_token"
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		TokenHash: utils.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mockRefreshRepo.On("FindRefreshTokenByHash", stored.TokenHash).Return(stored, nil)
+	mockUserRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	mockRefreshRepo.On("RotateRefreshToken", stored, mock.Anything).Return(false, nil)
+	mockRefreshRepo.On("RevokeRefreshTokenFamily", stored.FamilyID.String()).Return(nil)
+
+	tokens, err := authService.RefreshTokens(rawToken, "")
+
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+	assert.Nil(t, tokens)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestRefreshTokens_Expired(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	mockUserRepo := &mocks.MockUserRepository{}
+	authService := newTestAuthService(t, mockRefreshRepo, mockUserRepo, &mocks.MockRevocationService{})
+
+	rawToken := "refresh_token"
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  uuid.New(),
+		TokenHash: utils.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+
+	mockRefreshRepo.On("FindRefreshTokenByHash", stored.TokenHash).Return(stored, nil)
+
+	_, err := authService.RefreshTokens(rawToken, "")
+
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+	mockRefreshRepo.AssertNotCalled(t, "RotateRefreshToken", mock.Anything, mock.Anything)
+}
+
+func TestRefreshTokens_Unknown(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	mockUserRepo := &mocks.MockUserRepository{}
+	authService := newTestAuthService(t, mockRefreshRepo, mockUserRepo, &mocks.MockRevocationService{})
+
+	mockRefreshRepo.On("FindRefreshTokenByHash", utils.HashToken("unknown")).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := authService.RefreshTokens("unknown", "")
+
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+}
+
+func TestValidateAccessToken_Revoked(t *testing.T) {
+	mockRevocationService := &mocks.MockRevocationService{}
+	authService := newTestAuthService(t, &mocks.MockRefreshTokenRepository{}, &mocks.MockUserRepository{}, mockRevocationService)
+
+	token, err := authService.GenerateJWT(&models.User{ID: uuid.New()})
+	require.NoError(t, err)
+
+	mockRevocationService.On("IsRevoked", mock.AnythingOfType("*utils.Claims")).Return(true, nil)
+
+	claims, err := authService.ValidateAccessToken(token)
+
+	assert.ErrorIs(t, err, services.ErrTokenRevoked)
+	assert.Nil(t, claims)
+}
+
+func TestValidateAccessToken_Success(t *testing.T) {
+	mockRevocationService := &mocks.MockRevocationService{}
+	authService := newTestAuthService(t, &mocks.MockRefreshTokenRepository{}, &mocks.MockUserRepository{}, mockRevocationService)
+
+	user := &models.User{ID: uuid.New()}
+	token, err := authService.GenerateJWT(user)
+	require.NoError(t, err)
+
+	mockRevocationService.On("IsRevoked", mock.AnythingOfType("*utils.Claims")).Return(false, nil)
+
+	claims, err := authService.ValidateAccessToken(token)
+
+	assert.NoError(t, err)
+	require.NotNil(t, claims)
+	assert.Equal(t, user.ID.String(), claims.UserID)
+	assert.NotEmpty(t, claims.ID)
+}
+
+func TestLogout_RevokesAccessTokenAndRefreshFamily(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	mockRevocationService := &mocks.MockRevocationService{}
+	authService := newTestAuthService(t, mockRefreshRepo, &mocks.MockUserRepository{}, mockRevocationService)
+
+	userID := uuid.New()
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	claims := &utils.Claims{
+		UserID: userID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	stored := &models.RefreshToken{ID: uuid.New(), UserID: userID, FamilyID: uuid.New()}
+
+	mockRevocationService.On("RevokeToken", "jti", userID, expiresAt).Return(nil)
+	mockRefreshRepo.On("FindRefreshTokenByHash", utils.HashToken("refresh")).Return(stored, nil)
+	mockRefreshRepo.On("RevokeRefreshTokenFamily", stored.FamilyID.String()).Return(nil)
+
+	err := authService.Logout(claims, "refresh")
+
+	assert.NoError(t, err)
+	mockRevocationService.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestRevokeUserTokens_Success(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	mockRevocationService := &mocks.MockRevocationService{}
+	authService := newTestAuthService(t, mockRefreshRepo, &mocks.MockUserRepository{}, mockRevocationService)
+
+	userID := uuid.New()
+
+	mockRevocationService.On("RevokeUserTokens", userID).Return(nil)
+	mockRefreshRepo.On("RevokeUserRefreshTokens", userID.String()).Return(nil)
+
+	err := authService.RevokeUserTokens(userID.String())
+
+	assert.NoError(t, err)
+	mockRevocationService.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestValidateAccessToken_WrongIssuer(t *testing.T) {
+	mockRevocationService := &mocks.MockRevocationService{}
+	authService := newTestAuthService(t, &mocks.MockRefreshTokenRepository{}, &mocks.MockUserRepository{}, mockRevocationService)
+	t.Cleanup(func() { viper.Set("JWT_ISSUER", "") })
+
+	viper.Set("JWT_ISSUER", "https://other.example.com")
+	token, err := authService.GenerateJWT(&models.User{ID: uuid.New()})
+	require.NoError(t, err)
+
+	viper.Set("JWT_ISSUER", "https://auth.example.com")
+	claims, err := authService.ValidateAccessToken(token)
+
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+	assert.Nil(t, claims)
+	mockRevocationService.AssertNotCalled(t, "IsRevoked", mock.Anything)
+}
+
+func TestRefreshTokens_OtherClient(t *testing.T) {
+	mockRefreshRepo := &mocks.MockRefreshTokenRepository{}
+	authService := newTestAuthService(t, mockRefreshRepo, &mocks.MockUserRepository{}, &mocks.MockRevocationService{})
+
+	rawToken := "refresh_token"
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  uuid.New(),
+		TokenHash: utils.HashToken(rawToken),
+		ClientID:  "spa",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mockRefreshRepo.On("FindRefreshTokenByHash", stored.TokenHash).Return(stored, nil)
+
+	_, err := authService.RefreshTokens(rawToken, "")
+
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+	mockRefreshRepo.AssertNotCalled(t, "RotateRefreshToken", mock.Anything, mock.Anything)
+}
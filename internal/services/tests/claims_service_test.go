@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildClaims_DefaultConfig(t *testing.T) {
+	viper.Set("JWT_ISSUER", "https://auth.example.com")
+	viper.Set("JWT_AUDIENCE", "vexop")
+	t.Cleanup(func() {
+		viper.Set("JWT_ISSUER", "")
+		viper.Set("JWT_AUDIENCE", "")
+	})
+
+	mockConfigRepo := &mocks.MockTokenClaimConfigRepository{}
+	mockRoleRepo := &mocks.MockRoleRepository{}
+	claimsService := services.NewClaimsService(mockConfigRepo, mockRoleRepo)
+
+	tenantID := uuid.New()
+	tenant_id := tenantID.String()
+	role := &models.Role{ID: uuid.New(), Name: "admin", Permissions: []*models.Permission{{Code: "user:read"}}}
+	user := &models.User{ID: uuid.New(), TenantID: &tenantID, Email: "a@example.com", RoleID: role.ID.String()}
+
+	mockConfigRepo.On("FindClaimConfigs", &tenant_id, "vexop").Return(nil, nil)
+	mockRoleRepo.On("GetRoleById", user.RoleID).Return(role, nil)
+
+	claims, err := claimsService.BuildClaims(user, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com", claims.Issuer)
+	assert.Equal(t, []string{"vexop"}, []string(claims.Audience))
+	assert.Equal(t, user.ID.String(), claims.Subject)
+	assert.NotEmpty(t, claims.ID)
+	assert.NotNil(t, claims.NotBefore)
+	assert.Equal(t, tenantID.String(), claims.TenantID)
+	assert.Equal(t, "admin", claims.Role)
+	assert.Empty(t, claims.Email)
+	assert.Empty(t, claims.Permissions)
+}
+
+func TestBuildClaims_MostSpecificConfigWins(t *testing.T) {
+	mockConfigRepo := &mocks.MockTokenClaimConfigRepository{}
+	mockRoleRepo := &mocks.MockRoleRepository{}
+	claimsService := services.NewClaimsService(mockConfigRepo, mockRoleRepo)
+
+	tenantID := uuid.New()
+	role := &models.Role{
+		ID:   uuid.New(),
+		Name: "member",
+		Permissions: []*models.Permission{
+			{Code: "user:read"},
+			{Code: "invite:create"},
+		},
+	}
+	user := &models.User{ID: uuid.New(), TenantID: &tenantID, Email: "a@example.com", RoleID: role.ID.String()}
+
+	configs := []*models.TokenClaimConfig{
+		{IncludeTenant: true},
+		{Audience: "billing", IncludeRole: true},
+		{TenantID: &tenantID, IncludeTenant: true},
+		{TenantID: &tenantID, Audience: "billing", IncludePermissions: true, IncludeEmail: true},
+	}
+	mockConfigRepo.On("FindClaimConfigs", mock.Anything, "billing").Return(configs, nil)
+	mockRoleRepo.On("GetRoleById", user.RoleID).Return(role, nil)
+
+	claims, err := claimsService.BuildClaims(user, "billing")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"billing"}, []string(claims.Audience))
+	assert.Empty(t, claims.TenantID)
+	assert.Empty(t, claims.Role)
+	assert.Equal(t, "a@example.com", claims.Email)
+	assert.Equal(t, []string{"user:read", "invite:create"}, claims.Permissions)
+}
+
+func TestSaveClaimConfig_RequiresSuperAdmin(t *testing.T) {
+	mockConfigRepo := &mocks.MockTokenClaimConfigRepository{}
+	claimsService := services.NewClaimsService(mockConfigRepo, &mocks.MockRoleRepository{})
+
+	requestor := &models.User{Role: models.Role{Name: "admin"}}
+
+	err := claimsService.SaveClaimConfig(requestor, &models.TokenClaimConfig{})
+
+	assert.ErrorIs(t, err, services.ErrUnauthorized)
+	mockConfigRepo.AssertNotCalled(t, "SaveClaimConfig", mock.Anything)
+}
@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type emailVerificationTestSetup struct {
+	repo        *mocks.MockEmailVerificationRepository
+	userRepo    *mocks.MockUserRepository
+	roleRepo    *mocks.MockRoleRepository
+	authService *mocks.MockAuthService
+	mailService *mocks.MockMailService
+	service     services.EmailVerificationService
+}
+
+func newEmailVerificationTestSetup() *emailVerificationTestSetup {
+	s := &emailVerificationTestSetup{
+		repo:        &mocks.MockEmailVerificationRepository{},
+		userRepo:    &mocks.MockUserRepository{},
+		roleRepo:    &mocks.MockRoleRepository{},
+		authService: &mocks.MockAuthService{},
+		mailService: &mocks.MockMailService{},
+	}
+	s.service = services.NewEmailVerificationService(s.repo, s.userRepo, s.roleRepo, s.authService, s.mailService, &mocks.MockTransactor{})
+	return s
+}
+
+func TestSendVerificationTx_EmailsLinkWithHashedToken(t *testing.T) {
+	viper.Set("EMAIL_VERIFICATION_URL", "https://app.example.com/verify")
+	t.Cleanup(func() { viper.Set("EMAIL_VERIFICATION_URL", "") })
+
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+
+	s.repo.On("CreateVerificationTx", mock.Anything, mock.AnythingOfType("*models.EmailVerification")).Return(nil)
+	s.mailService.On("EnqueueTx", mock.Anything, user.TenantID, mailer.TemplateVerifyEmail, user.Email, mock.Anything).Return(nil)
+
+	err := s.service.SendVerificationTx(nil, user)
+	require.NoError(t, err)
+
+	data := s.mailService.Calls[0].Arguments.Get(4).(map[string]any)
+	assert.Equal(t, "1 day", data["ExpiresIn"])
+	parsed, err := url.Parse(data["Link"].(string))
+	require.NoError(t, err)
+	token := parsed.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	stored := s.repo.Calls[0].Arguments.Get(1).(*models.EmailVerification)
+	assert.Equal(t, user.ID, stored.UserID)
+	assert.Equal(t, user.Email, stored.Email)
+	assert.Equal(t, utils.HashToken(token), stored.TokenHash)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), stored.ExpiresAt, time.Minute)
+}
+
+func TestResendVerification_SkipsVerifiedAndUnknown(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.userRepo.On("FindUserByEmail", "nobody@example.com").Return(nil, gorm.ErrRecordNotFound)
+
+	require.NoError(t, s.service.ResendVerification(user.Email))
+	require.NoError(t, s.service.ResendVerification("nobody@example.com"))
+	s.repo.AssertNotCalled(t, "CreateVerificationTx", mock.Anything, mock.Anything)
+	s.mailService.AssertNotCalled(t, "EnqueueTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyEmail_MarksAddressVerified(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+	verification := &models.EmailVerification{ID: uuid.New(), UserID: user.ID, Email: user.Email}
+
+	s.repo.On("FindActiveVerification", utils.HashToken("token"), mock.Anything).Return(verification, nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.repo.On("ConsumeVerificationTx", mock.Anything, verification.ID.String(), mock.Anything).Return(nil)
+	s.userRepo.On("VerifyEmailTx", mock.Anything, user.ID.String(), user.Email, mock.Anything).Return(nil)
+
+	err := s.service.VerifyEmail("token")
+
+	require.NoError(t, err)
+	s.userRepo.AssertExpectations(t)
+	s.mailService.AssertNotCalled(t, "EnqueueTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyEmail_ChangeNotifiesOldAddress(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+	verification := &models.EmailVerification{ID: uuid.New(), UserID: user.ID, Email: "new@example.com"}
+
+	s.repo.On("FindActiveVerification", utils.HashToken("token"), mock.Anything).Return(verification, nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.userRepo.On("FindUserByEmail", "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+	s.repo.On("ConsumeVerificationTx", mock.Anything, verification.ID.String(), mock.Anything).Return(nil)
+	s.userRepo.On("VerifyEmailTx", mock.Anything, user.ID.String(), "new@example.com", mock.Anything).Return(nil)
+	s.mailService.On("EnqueueTx", mock.Anything, user.TenantID, mailer.TemplateSecurityAlert, "mfa@example.com", mock.Anything).Return(nil)
+
+	err := s.service.VerifyEmail("token")
+
+	require.NoError(t, err)
+	s.mailService.AssertExpectations(t)
+	data := s.mailService.Calls[0].Arguments.Get(4).(map[string]any)
+	assert.Contains(t, data["Event"], "new@example.com")
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+
+	s.repo.On("FindActiveVerification", utils.HashToken("token"), mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	err := s.service.VerifyEmail("token")
+
+	assert.ErrorIs(t, err, services.ErrInvalidVerificationToken)
+}
+
+func TestVerifyEmail_AlreadyUsed(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+	verification := &models.EmailVerification{ID: uuid.New(), UserID: user.ID, Email: user.Email}
+
+	s.repo.On("FindActiveVerification", utils.HashToken("token"), mock.Anything).Return(verification, nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.repo.On("ConsumeVerificationTx", mock.Anything, verification.ID.String(), mock.Anything).Return(gorm.ErrRecordNotFound)
+
+	err := s.service.VerifyEmail("token")
+
+	assert.ErrorIs(t, err, services.ErrInvalidVerificationToken)
+	s.userRepo.AssertNotCalled(t, "VerifyEmailTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRequestEmailChange_SendsToNewAddress(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+	user.PasswordHash = "hash"
+
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.authService.On("CompareHashAndPassword", []byte("password"), []byte("hash")).Return(true)
+	s.userRepo.On("FindUserByEmail", "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+	s.repo.On("CreateVerificationTx", mock.Anything, mock.AnythingOfType("*models.EmailVerification")).Return(nil)
+	s.mailService.On("EnqueueTx", mock.Anything, user.TenantID, mailer.TemplateVerifyEmail, "new@example.com", mock.Anything).Return(nil)
+
+	err := s.service.RequestEmailChange(user, "new@example.com", "password")
+
+	require.NoError(t, err)
+	stored := s.repo.Calls[0].Arguments.Get(1).(*models.EmailVerification)
+	assert.Equal(t, "new@example.com", stored.Email)
+	s.userRepo.AssertNotCalled(t, "VerifyEmailTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRequestEmailChange_WrongPassword(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+	user.PasswordHash = "hash"
+
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.authService.On("CompareHashAndPassword", []byte("wrong"), []byte("hash")).Return(false)
+
+	err := s.service.RequestEmailChange(user, "new@example.com", "wrong")
+
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	s.repo.AssertNotCalled(t, "CreateVerificationTx", mock.Anything, mock.Anything)
+}
+
+func TestRequestEmailChange_AddressTaken(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+	user.PasswordHash = "hash"
+
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.authService.On("CompareHashAndPassword", []byte("password"), []byte("hash")).Return(true)
+	s.userRepo.On("FindUserByEmail", "taken@example.com").Return(&models.User{ID: uuid.New()}, nil)
+
+	err := s.service.RequestEmailChange(user, "taken@example.com", "password")
+
+	var appError *utils.AppError
+	require.ErrorAs(t, err, &appError)
+	assert.Equal(t, http.StatusConflict, appError.Code)
+}
+
+func TestCheckLogin_BlockMode(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(&models.EmailVerificationPolicy{Mode: utils.EmailVerificationBlock}, nil)
+
+	assert.ErrorIs(t, s.service.CheckLogin(user), services.ErrEmailNotVerified)
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	assert.NoError(t, s.service.CheckLogin(user))
+}
+
+func TestCheckLogin_NoPolicy(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(nil, gorm.ErrRecordNotFound)
+
+	assert.NoError(t, s.service.CheckLogin(user))
+}
+
+func TestSaveEmailVerificationPolicy_RestrictNeedsRole(t *testing.T) {
+	s := newEmailVerificationTestSetup()
+	user := newMFAUser()
+
+	s.roleRepo.On("GetRoleByName", user.TenantID.String(), "unverified").Return(&models.Role{Name: "unverified"}, nil)
+	s.repo.On("SavePolicy", mock.AnythingOfType("*models.EmailVerificationPolicy")).Return(nil)
+
+	_, err := s.service.SavePolicy(user, &dto.EmailVerificationPolicyRequest{Mode: utils.EmailVerificationRestrict})
+	require.Error(t, err)
+
+	policy, err := s.service.SavePolicy(user, &dto.EmailVerificationPolicyRequest{Mode: utils.EmailVerificationRestrict, RestrictedRole: "unverified"})
+	require.NoError(t, err)
+	assert.Equal(t, "unverified", policy.RestrictedRole)
+
+	policy, err = s.service.SavePolicy(user, &dto.EmailVerificationPolicyRequest{Mode: utils.EmailVerificationBlock, RestrictedRole: "unverified"})
+	require.NoError(t, err)
+	assert.Empty(t, policy.RestrictedRole)
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type impersonationTestSetup struct {
+	userRepo     *mocks.MockUserRepository
+	authService  *mocks.MockAuthService
+	auditService *mocks.MockAuditService
+	service      services.ImpersonationService
+}
+
+func newImpersonationTestSetup() *impersonationTestSetup {
+	s := &impersonationTestSetup{
+		userRepo:     &mocks.MockUserRepository{},
+		authService:  &mocks.MockAuthService{},
+		auditService: &mocks.MockAuditService{},
+	}
+	s.service = services.NewImpersonationService(s.userRepo, s.authService, s.auditService)
+	return s
+}
+
+func newSuperAdmin() *models.User {
+	return &models.User{ID: uuid.New(), Email: "support@example.com", Role: models.Role{Name: utils.RoleSuperAdmin}}
+}
+
+func impersonationClaims(user *models.User) *utils.Claims {
+	return &utils.Claims{
+		UserID: user.ID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(10 * time.Minute)),
+		},
+	}
+}
+
+func TestStartImpersonation_SuperAdmin(t *testing.T) {
+	s := newImpersonationTestSetup()
+	admin := newSuperAdmin()
+	user := newMFAUser()
+	tokens := &dto.LoginResponse{AccessToken: "impersonation-token"}
+
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.authService.On("IssueImpersonationToken", user, admin).Return(tokens, impersonationClaims(user), nil)
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	res, err := s.service.StartImpersonation(admin, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, tokens, res)
+
+	event := s.auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditImpersonationStarted, event.Event)
+	assert.Equal(t, admin.ID, *event.ActorID)
+	assert.Equal(t, user.ID, *event.UserID)
+	assert.Equal(t, user.TenantID, event.TenantID)
+}
+
+func TestStartImpersonation_NotAuditedNoToken(t *testing.T) {
+	s := newImpersonationTestSetup()
+	admin := newSuperAdmin()
+	user := newMFAUser()
+
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.authService.On("IssueImpersonationToken", user, admin).Return(&dto.LoginResponse{AccessToken: "impersonation-token"}, impersonationClaims(user), nil)
+	s.auditService.On("Record", mock.Anything).Return(errors.New("db down"))
+
+	res, err := s.service.StartImpersonation(admin, user.ID.String())
+
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestStartImpersonation_SuperAdminTarget(t *testing.T) {
+	s := newImpersonationTestSetup()
+	other := newSuperAdmin()
+
+	s.userRepo.On("FindUserById", other.ID.String()).Return(other, nil)
+
+	_, err := s.service.StartImpersonation(newSuperAdmin(), other.ID.String())
+
+	requireAppError(t, err, http.StatusForbidden)
+	s.authService.AssertNotCalled(t, "IssueImpersonationToken", mock.Anything, mock.Anything)
+}
+
+func TestStartImpersonation_Self(t *testing.T) {
+	s := newImpersonationTestSetup()
+	admin := newSuperAdmin()
+
+	_, err := s.service.StartImpersonation(admin, admin.ID.String())
+
+	requireAppError(t, err, http.StatusBadRequest)
+}
+
+func TestStartImpersonation_TenantOwner(t *testing.T) {
+	s := newImpersonationTestSetup()
+	owner := newMFAUser()
+	owner.IsOwner = true
+	member := newMFAUser()
+	member.TenantID = owner.TenantID
+
+	s.userRepo.On("GetUserById", owner.TenantID.String(), owner.ID.String()).Return(owner, nil)
+	s.userRepo.On("GetUserById", owner.TenantID.String(), member.ID.String()).Return(member, nil)
+	s.authService.On("IssueImpersonationToken", member, owner).Return(&dto.LoginResponse{AccessToken: "impersonation-token"}, impersonationClaims(member), nil)
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	_, err := s.service.StartImpersonation(owner, member.ID.String())
+
+	require.NoError(t, err)
+	s.userRepo.AssertNotCalled(t, "FindUserById", mock.Anything)
+}
+
+func TestStartImpersonation_NotOwner(t *testing.T) {
+	s := newImpersonationTestSetup()
+	admin := newMFAUser()
+	member := newMFAUser()
+
+	// the stored user is checked, the token may be stateless
+	s.userRepo.On("GetUserById", admin.TenantID.String(), admin.ID.String()).Return(admin, nil)
+
+	_, err := s.service.StartImpersonation(admin, member.ID.String())
+
+	requireAppError(t, err, http.StatusForbidden)
+	s.authService.AssertNotCalled(t, "IssueImpersonationToken", mock.Anything, mock.Anything)
+}
+
+func TestStartImpersonation_OwnerOtherTenant(t *testing.T) {
+	s := newImpersonationTestSetup()
+	owner := newMFAUser()
+	owner.IsOwner = true
+	stranger := newMFAUser()
+
+	s.userRepo.On("GetUserById", owner.TenantID.String(), owner.ID.String()).Return(owner, nil)
+	s.userRepo.On("GetUserById", owner.TenantID.String(), stranger.ID.String()).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := s.service.StartImpersonation(owner, stranger.ID.String())
+
+	requireAppError(t, err, http.StatusNotFound)
+}
+
+func TestStopImpersonation(t *testing.T) {
+	s := newImpersonationTestSetup()
+	user := newMFAUser()
+	impersonatorID := uuid.New()
+	claims := impersonationClaims(user)
+	claims.Act = &utils.ActorClaim{Subject: impersonatorID.String()}
+
+	s.authService.On("RevokeAccessToken", claims).Return(nil)
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	require.NoError(t, s.service.StopImpersonation(user, claims))
+
+	s.authService.AssertExpectations(t)
+	event := s.auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditImpersonationStopped, event.Event)
+	assert.Equal(t, impersonatorID, *event.ActorID)
+	assert.Equal(t, user.ID, *event.UserID)
+}
+
+func TestStopImpersonation_NotImpersonating(t *testing.T) {
+	s := newImpersonationTestSetup()
+	user := newMFAUser()
+
+	err := s.service.StopImpersonation(user, impersonationClaims(user))
+
+	requireAppError(t, err, http.StatusBadRequest)
+	s.authService.AssertNotCalled(t, "RevokeAccessToken", mock.Anything)
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func configureSigningKey(t *testing.T, alg string, key crypto.Signer) {
+	pemData, err := utils.EncodePrivateKeyPEM(key)
+	require.NoError(t, err)
+
+	viper.Set("JWT_SIGNING_ALG", alg)
+	viper.Set("JWT_PRIVATE_KEY", string(pemData))
+	viper.Set("JWT_KEY_ID", "")
+	t.Cleanup(func() {
+		viper.Set("JWT_SIGNING_ALG", "")
+		viper.Set("JWT_PRIVATE_KEY", "")
+	})
+}
+
+func newAuthServiceWithKeys(t *testing.T, keyService services.KeyService) services.AuthService {
+	mockRevocationService := &mocks.MockRevocationService{}
+	mockRevocationService.On("IsRevoked", mock.Anything).Return(false, nil)
+	return services.NewAuthService(&mocks.MockRefreshTokenRepository{}, &mocks.MockSessionRepository{}, &mocks.MockUserRepository{}, mockRevocationService, keyService, newTestClaimsService(), newTestPasswords())
+}
+
+func TestKeyService_RS256_SignsAndPublishes(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	configureSigningKey(t, "RS256", rsaKey)
+
+	keyService, err := services.NewKeyService()
+	require.NoError(t, err)
+	authService := newAuthServiceWithKeys(t, keyService)
+
+	user := &models.User{ID: uuid.New()}
+	tokenStr, err := authService.GenerateJWT(user)
+	require.NoError(t, err)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(tokenStr, &utils.Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+
+	jwks, err := keyService.JWKS()
+	require.NoError(t, err)
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, parsed.Header["kid"], jwks.Keys[0].Kid)
+
+	claims, err := authService.ValidateAccessToken(tokenStr)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID.String(), claims.UserID)
+}
+
+func TestKeyService_EdDSA(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	configureSigningKey(t, "EdDSA", edKey)
+
+	keyService, err := services.NewKeyService()
+	require.NoError(t, err)
+	authService := newAuthServiceWithKeys(t, keyService)
+
+	tokenStr, err := authService.GenerateJWT(&models.User{ID: uuid.New()})
+	require.NoError(t, err)
+
+	_, err = authService.ValidateAccessToken(tokenStr)
+	assert.NoError(t, err)
+
+	jwks, err := keyService.JWKS()
+	require.NoError(t, err)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+}
+
+func TestValidateAccessToken_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	configureSigningKey(t, "RS256", rsaKey)
+
+	keyService, err := services.NewKeyService()
+	require.NoError(t, err)
+	authService := newAuthServiceWithKeys(t, keyService)
+	signingKey, _ := keyService.SigningKey()
+
+	// sign with HS256 using the public key as the shared secret
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	claims := &utils.Claims{
+		UserID: uuid.NewString(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = signingKey.KID
+	forgedStr, err := forged.SignedString(publicPEM)
+	require.NoError(t, err)
+
+	_, err = authService.ValidateAccessToken(forgedStr)
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	unsignedStr, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, err = authService.ValidateAccessToken(unsignedStr)
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+}
+
+func TestKeyService_RejectsMismatchedKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	configureSigningKey(t, "ES256", rsaKey)
+
+	_, err = services.NewKeyService()
+	assert.Error(t, err)
+}
+
+func TestKeyService_HS256_PublishesNothing(t *testing.T) {
+	viper.Set("JWT_SIGNING_ALG", "")
+	viper.Set("JWT_SECRET", "test_secret")
+
+	keyService, err := services.NewKeyService()
+	require.NoError(t, err)
+
+	jwks, err := keyService.JWKS()
+	require.NoError(t, err)
+	assert.Empty(t, jwks.Keys)
+}
@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSigningKeyRepository mirrors SigningKeyRepo in memory.
+type fakeSigningKeyRepository struct {
+	mu   sync.Mutex
+	keys []*models.SigningKey
+}
+
+func (f *fakeSigningKeyRepository) GetSigningKeys(now time.Time) ([]*models.SigningKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []*models.SigningKey
+	for _, key := range f.keys {
+		if key.State != utils.KeyStateRetired || key.ExpiresAt.After(now) {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeSigningKeyRepository) RotateSigningKeys(next *models.SigningKey, activatedBefore, retiredUntil time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range f.keys {
+		if key.State == utils.KeyStateActive && key.ActivatedAt.After(activatedBefore) {
+			return false, nil
+		}
+	}
+	for _, key := range f.keys {
+		switch key.State {
+		case utils.KeyStateActive:
+			key.State = utils.KeyStateRetired
+			key.RetiredAt = &now
+			key.ExpiresAt = &retiredUntil
+		case utils.KeyStateNext:
+			key.State = utils.KeyStateActive
+			key.ActivatedAt = &now
+		}
+	}
+	f.keys = append(f.keys, next)
+	return true, nil
+}
+
+func (f *fakeSigningKeyRepository) count(state string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for _, key := range f.keys {
+		if key.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestKeyStore(t *testing.T, repo *fakeSigningKeyRepository) *services.KeyStoreService {
+	encryptionKey := make([]byte, 32)
+	_, err := rand.Read(encryptionKey)
+	require.NoError(t, err)
+
+	viper.Set("JWT_SIGNING_ALG", "ES256")
+	viper.Set("JWT_KEY_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(encryptionKey))
+	t.Cleanup(func() {
+		viper.Set("JWT_SIGNING_ALG", "")
+		viper.Set("JWT_KEY_ENCRYPTION_KEY", "")
+	})
+
+	keyStore, err := services.NewKeyStoreService(repo)
+	require.NoError(t, err)
+	require.NoError(t, keyStore.EnsureKeys())
+
+	return keyStore
+}
+
+func TestKeyStore_EnsureKeys_CreatesActiveAndNext(t *testing.T) {
+	repo := &fakeSigningKeyRepository{}
+	keyStore := newTestKeyStore(t, repo)
+
+	assert.Equal(t, 1, repo.count(utils.KeyStateActive))
+	assert.Equal(t, 1, repo.count(utils.KeyStateNext))
+
+	// private keys are never stored in the clear
+	for _, key := range repo.keys {
+		assert.NotContains(t, key.EncryptedPrivateKey, "PRIVATE KEY")
+	}
+
+	jwks, err := keyStore.JWKS()
+	require.NoError(t, err)
+	assert.Len(t, jwks.Keys, 2)
+
+	// running again on a populated store is a no-op
+	require.NoError(t, keyStore.EnsureKeys())
+	assert.Len(t, repo.keys, 2)
+}
+
+func TestKeyStore_Rotation_KeepsOldTokensValid(t *testing.T) {
+	repo := &fakeSigningKeyRepository{}
+	keyStore := newTestKeyStore(t, repo)
+	authService := newAuthServiceWithKeys(t, keyStore)
+
+	before, _ := keyStore.SigningKey()
+	oldToken, err := authService.GenerateJWT(&models.User{ID: uuid.New()})
+	require.NoError(t, err)
+
+	jwks, _ := keyStore.JWKS()
+	var published []string
+	for _, jwk := range jwks.Keys {
+		published = append(published, jwk.Kid)
+	}
+
+	require.NoError(t, keyStore.RotateKeys())
+
+	after, _ := keyStore.SigningKey()
+	assert.NotEqual(t, before.KID, after.KID)
+	// the promoted key was already published as the next key
+	assert.Contains(t, published, after.KID)
+
+	newToken, err := authService.GenerateJWT(&models.User{ID: uuid.New()})
+	require.NoError(t, err)
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, &utils.Claims{})
+	assert.Equal(t, after.KID, parsed.Header["kid"])
+
+	_, err = authService.ValidateAccessToken(oldToken)
+	assert.NoError(t, err)
+	_, err = authService.ValidateAccessToken(newToken)
+	assert.NoError(t, err)
+
+	jwks, _ = keyStore.JWKS()
+	assert.Len(t, jwks.Keys, 3)
+	assert.Equal(t, 1, repo.count(utils.KeyStateRetired))
+}
+
+func TestKeyStore_RetiredKeyOutlivesLongestToken(t *testing.T) {
+	viper.Set("ACCESS_TOKEN_TTL", "5m")
+	viper.Set("IMPERSONATION_TTL", "1h")
+	viper.Set("JWT_KEY_CACHE_TTL", "2m")
+	t.Cleanup(func() {
+		viper.Set("ACCESS_TOKEN_TTL", "")
+		viper.Set("IMPERSONATION_TTL", "")
+		viper.Set("JWT_KEY_CACHE_TTL", "")
+	})
+	repo := &fakeSigningKeyRepository{}
+	keyStore := newTestKeyStore(t, repo)
+
+	rotatedAt := time.Now()
+	require.NoError(t, keyStore.RotateKeys())
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, key := range repo.keys {
+		if key.State == utils.KeyStateRetired {
+			// tokens signed by other instances until they reload the keys
+			// are valid for another hour after that
+			assert.False(t, key.ExpiresAt.Before(rotatedAt.Add(time.Hour+2*time.Minute)))
+		}
+	}
+}
+
+func TestKeyStore_RetiredKeyDroppedAfterExpiry(t *testing.T) {
+	repo := &fakeSigningKeyRepository{}
+	keyStore := newTestKeyStore(t, repo)
+
+	require.NoError(t, keyStore.RotateKeys())
+
+	repo.mu.Lock()
+	for _, key := range repo.keys {
+		if key.State == utils.KeyStateRetired {
+			expired := time.Now().Add(-time.Second)
+			key.ExpiresAt = &expired
+		}
+	}
+	repo.mu.Unlock()
+
+	require.NoError(t, keyStore.RotateKeysIfDue())
+
+	jwks, err := keyStore.JWKS()
+	require.NoError(t, err)
+	assert.Len(t, jwks.Keys, 2)
+}
+
+func TestKeyStore_RotateKeysIfDue_SkipsFreshKey(t *testing.T) {
+	repo := &fakeSigningKeyRepository{}
+	keyStore := newTestKeyStore(t, repo)
+
+	before, _ := keyStore.SigningKey()
+	require.NoError(t, keyStore.RotateKeysIfDue())
+	after, _ := keyStore.SigningKey()
+
+	assert.Equal(t, before.KID, after.KID)
+	assert.Equal(t, 0, repo.count(utils.KeyStateRetired))
+}
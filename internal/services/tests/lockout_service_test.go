@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/lockout"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newLockoutService(t *testing.T, userRepo *mocks.MockUserRepository, auditService *mocks.MockAuditService) services.LockoutService {
+	viper.Set("LOCKOUT_DELAY_AFTER", 2)
+	viper.Set("LOCKOUT_THRESHOLD", 4)
+	viper.Set("LOCKOUT_IP_DELAY_AFTER", 5)
+	viper.Set("LOCKOUT_IP_THRESHOLD", 6)
+	t.Cleanup(func() {
+		viper.Set("LOCKOUT_DELAY_AFTER", 0)
+		viper.Set("LOCKOUT_THRESHOLD", 0)
+		viper.Set("LOCKOUT_IP_DELAY_AFTER", 0)
+		viper.Set("LOCKOUT_IP_THRESHOLD", 0)
+	})
+
+	return services.NewLockoutService(lockout.NewMemoryStore(), userRepo, auditService)
+}
+
+func TestLockout_DelaysThenLocksAccount(t *testing.T) {
+	auditService := &mocks.MockAuditService{}
+	service := newLockoutService(t, &mocks.MockUserRepository{}, auditService)
+	user := newMFAUser()
+
+	auditService.On("Record", mock.Anything).Return(nil)
+
+	require.NoError(t, service.RecordFailure(user.Email, "10.0.0.1", user))
+	require.NoError(t, service.Check(user.Email, "10.0.0.1"))
+
+	// the second failure reaches DelayAfter
+	require.NoError(t, service.RecordFailure(user.Email, "10.0.0.1", user))
+	var locked *services.LoginLockedError
+	require.ErrorAs(t, service.Check(user.Email, "10.0.0.2"), &locked)
+	assert.LessOrEqual(t, locked.RetryAfter, time.Second)
+	auditService.AssertNotCalled(t, "Record", mock.Anything)
+
+	require.NoError(t, service.RecordFailure(user.Email, "10.0.0.1", user))
+	require.NoError(t, service.RecordFailure(user.Email, "10.0.0.1", user))
+	require.ErrorAs(t, service.Check(user.Email, "10.0.0.2"), &locked)
+	assert.Greater(t, locked.RetryAfter, 14*time.Minute)
+
+	event := auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditLoginLocked, event.Event)
+	assert.Equal(t, user.TenantID, event.TenantID)
+	assert.Equal(t, &user.ID, event.UserID)
+}
+
+func TestLockout_UnknownEmailIsThrottledToo(t *testing.T) {
+	auditService := &mocks.MockAuditService{}
+	service := newLockoutService(t, &mocks.MockUserRepository{}, auditService)
+
+	auditService.On("Record", mock.Anything).Return(nil)
+
+	for range 4 {
+		require.NoError(t, service.RecordFailure("nobody@example.com", "10.0.0.1", nil))
+	}
+
+	assert.ErrorIs(t, service.Check("NOBODY@example.com", ""), services.ErrTooManyLoginAttempts)
+	event := auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Nil(t, event.UserID)
+	assert.Contains(t, event.Detail, "nobody@example.com")
+}
+
+func TestLockout_LocksIPAcrossAccounts(t *testing.T) {
+	auditService := &mocks.MockAuditService{}
+	service := newLockoutService(t, &mocks.MockUserRepository{}, auditService)
+
+	auditService.On("Record", mock.Anything).Return(nil)
+
+	for range 6 {
+		require.NoError(t, service.RecordFailure(uuid.NewString()+"@example.com", "10.0.0.9", nil))
+	}
+
+	assert.ErrorIs(t, service.Check("fresh@example.com", "10.0.0.9"), services.ErrTooManyLoginAttempts)
+	assert.NoError(t, service.Check("fresh@example.com", "10.0.0.10"))
+	event := auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditLoginIPLocked, event.Event)
+}
+
+func TestLockout_SuccessResetsAccount(t *testing.T) {
+	service := newLockoutService(t, &mocks.MockUserRepository{}, &mocks.MockAuditService{})
+
+	require.NoError(t, service.RecordFailure("user@example.com", "", nil))
+	require.NoError(t, service.RecordFailure("user@example.com", "", nil))
+	require.Error(t, service.Check("user@example.com", ""))
+
+	require.NoError(t, service.RecordSuccess("user@example.com"))
+	assert.NoError(t, service.Check("user@example.com", ""))
+}
+
+func TestLockout_AuditFailureDoesNotFailLogin(t *testing.T) {
+	auditService := &mocks.MockAuditService{}
+	service := newLockoutService(t, &mocks.MockUserRepository{}, auditService)
+
+	auditService.On("Record", mock.Anything).Return(errors.New("db down"))
+
+	for range 4 {
+		require.NoError(t, service.RecordFailure("user@example.com", "", nil))
+	}
+}
+
+func TestLockout_UnlockByTenantAdmin(t *testing.T) {
+	userRepo := &mocks.MockUserRepository{}
+	auditService := &mocks.MockAuditService{}
+	service := newLockoutService(t, userRepo, auditService)
+	user := newMFAUser()
+	admin := &models.User{ID: uuid.New(), TenantID: user.TenantID}
+
+	auditService.On("Record", mock.Anything).Return(nil)
+	userRepo.On("GetUserById", user.TenantID.String(), user.ID.String()).Return(user, nil)
+
+	for range 4 {
+		require.NoError(t, service.RecordFailure(user.Email, "", user))
+	}
+	require.Error(t, service.Check(user.Email, ""))
+
+	require.NoError(t, service.Unlock(admin, user.ID.String()))
+
+	assert.NoError(t, service.Check(user.Email, ""))
+	event := auditService.Calls[len(auditService.Calls)-1].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditAccountUnlocked, event.Event)
+	assert.Equal(t, &admin.ID, event.ActorID)
+	assert.Equal(t, &user.ID, event.UserID)
+}
@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type mailTestSetup struct {
+	outboxRepo   *mocks.MockOutboxRepository
+	templateRepo *mocks.MockEmailTemplateRepository
+	service      services.MailService
+}
+
+func newMailTestSetup() *mailTestSetup {
+	s := &mailTestSetup{
+		outboxRepo:   &mocks.MockOutboxRepository{},
+		templateRepo: &mocks.MockEmailTemplateRepository{},
+	}
+	s.service = services.NewMailService(s.outboxRepo, s.templateRepo)
+	return s
+}
+
+func (s *mailTestSetup) queued(t *testing.T) *models.OutboxEmail {
+	t.Helper()
+	for _, call := range s.outboxRepo.Calls {
+		if call.Method == "EnqueueTx" {
+			return call.Arguments.Get(1).(*models.OutboxEmail)
+		}
+	}
+	t.Fatal("no email was queued")
+	return nil
+}
+
+func TestEnqueueTx_UsesBuiltInTemplate(t *testing.T) {
+	s := newMailTestSetup()
+	user := newMFAUser()
+
+	s.templateRepo.On("FindTemplate", user.TenantID.String(), mailer.TemplateMagicLink).Return(nil, gorm.ErrRecordNotFound)
+	s.outboxRepo.On("EnqueueTx", mock.Anything, mock.AnythingOfType("*models.OutboxEmail")).Return(nil)
+
+	err := s.service.EnqueueTx(nil, user.TenantID, mailer.TemplateMagicLink, user.Email, map[string]any{
+		"Link":      "https://app.example.com/login?token=abc",
+		"ExpiresIn": "15 minutes",
+	})
+
+	require.NoError(t, err)
+	email := s.queued(t)
+	assert.Equal(t, user.TenantID, email.TenantID)
+	assert.Equal(t, mailer.TemplateMagicLink, email.Template)
+	assert.Equal(t, user.Email, email.To)
+	assert.Contains(t, email.Subject, "auth-service")
+	assert.Contains(t, email.TextBody, "https://app.example.com/login?token=abc")
+	assert.Contains(t, email.HTMLBody, "https://app.example.com/login?token=abc")
+	assert.False(t, email.NextAttemptAt.IsZero())
+}
+
+func TestEnqueueTx_UsesTenantTemplate(t *testing.T) {
+	s := newMailTestSetup()
+	user := newMFAUser()
+
+	s.templateRepo.On("FindTemplate", user.TenantID.String(), mailer.TemplateInvite).Return(&models.EmailTemplate{
+		Name:    mailer.TemplateInvite,
+		Subject: "Join Acme",
+		HTML:    `<p>{{.InviterEmail}} wants you on Acme: <a href="{{.Link}}">join</a></p>`,
+	}, nil)
+	s.outboxRepo.On("EnqueueTx", mock.Anything, mock.AnythingOfType("*models.OutboxEmail")).Return(nil)
+
+	err := s.service.EnqueueTx(nil, user.TenantID, mailer.TemplateInvite, "new@example.com", map[string]any{
+		"Link":         "https://app.example.com/invite",
+		"InviterEmail": "<admin@acme.com>",
+	})
+
+	require.NoError(t, err)
+	email := s.queued(t)
+	assert.Equal(t, "Join Acme", email.Subject)
+	assert.Equal(t, `<p>&lt;admin@acme.com&gt; wants you on Acme: <a href="https://app.example.com/invite">join</a></p>`, email.HTMLBody)
+}
+
+func TestEnqueueTx_FallsBackWhenTenantTemplateFails(t *testing.T) {
+	s := newMailTestSetup()
+	user := newMFAUser()
+
+	s.templateRepo.On("FindTemplate", user.TenantID.String(), mailer.TemplateLoginCode).Return(&models.EmailTemplate{
+		Name:    mailer.TemplateLoginCode,
+		Subject: "Code",
+		HTML:    `<p>{{index .Codes 3}}</p>`,
+	}, nil)
+	s.outboxRepo.On("EnqueueTx", mock.Anything, mock.AnythingOfType("*models.OutboxEmail")).Return(nil)
+
+	err := s.service.EnqueueTx(nil, user.TenantID, mailer.TemplateLoginCode, user.Email, map[string]any{"Code": "123456"})
+
+	require.NoError(t, err)
+	assert.Contains(t, s.queued(t).Subject, "123456")
+}
+
+func TestSaveTemplate_RejectsUnknownName(t *testing.T) {
+	s := newMailTestSetup()
+
+	_, err := s.service.SaveTemplate(newMFAUser(), "newsletter", &dto.EmailTemplateRequest{Subject: "Hi", HTML: "<p>Hi</p>"})
+
+	var appErr *utils.AppError
+	require.True(t, errors.As(err, &appErr))
+	assert.Equal(t, http.StatusNotFound, appErr.Code)
+	s.templateRepo.AssertNotCalled(t, "SaveTemplate", mock.Anything)
+}
+
+func TestSaveTemplate_RejectsBrokenTemplate(t *testing.T) {
+	s := newMailTestSetup()
+
+	_, err := s.service.SaveTemplate(newMFAUser(), mailer.TemplateInvite, &dto.EmailTemplateRequest{Subject: "Hi", HTML: "<p>{{.Link</p>"})
+
+	var appErr *utils.AppError
+	require.True(t, errors.As(err, &appErr))
+	assert.Equal(t, http.StatusBadRequest, appErr.Code)
+	s.templateRepo.AssertNotCalled(t, "SaveTemplate", mock.Anything)
+}
+
+func TestSaveTemplate_StoresForTenant(t *testing.T) {
+	s := newMailTestSetup()
+	user := newMFAUser()
+
+	s.templateRepo.On("SaveTemplate", mock.AnythingOfType("*models.EmailTemplate")).Return(nil)
+
+	template, err := s.service.SaveTemplate(user, mailer.TemplateInvite, &dto.EmailTemplateRequest{Subject: "Join us", HTML: `<a href="{{.Link}}">Join</a>`})
+
+	require.NoError(t, err)
+	assert.True(t, template.Custom)
+	stored := s.templateRepo.Calls[0].Arguments.Get(0).(*models.EmailTemplate)
+	assert.Equal(t, *user.TenantID, stored.TenantID)
+	assert.Equal(t, mailer.TemplateInvite, stored.Name)
+}
+
+func TestGetTemplates_MergesOverrides(t *testing.T) {
+	s := newMailTestSetup()
+	user := newMFAUser()
+
+	s.templateRepo.On("FindTemplates", user.TenantID.String()).Return([]*models.EmailTemplate{
+		{TenantID: *user.TenantID, Name: mailer.TemplateResetPassword, Subject: "Reset", HTML: "<p>Reset</p>"},
+	}, nil)
+
+	templates, err := s.service.GetTemplates(user)
+
+	require.NoError(t, err)
+	require.Len(t, templates, len(mailer.Names()))
+	for _, template := range templates {
+		assert.Equal(t, template.Name == mailer.TemplateResetPassword, template.Custom, template.Name)
+	}
+}
+
+func TestDeleteTemplate_NotFound(t *testing.T) {
+	s := newMailTestSetup()
+	user := newMFAUser()
+
+	s.templateRepo.On("DeleteTemplate", user.TenantID.String(), mailer.TemplateInvite).Return(gorm.ErrRecordNotFound)
+
+	err := s.service.DeleteTemplate(user, mailer.TemplateInvite)
+
+	var appErr *utils.AppError
+	require.True(t, errors.As(err, &appErr))
+	assert.Equal(t, http.StatusNotFound, appErr.Code)
+}
+
+func TestGetTemplates_RequiresTenant(t *testing.T) {
+	s := newMailTestSetup()
+
+	_, err := s.service.GetTemplates(&models.User{Email: "root@example.com"})
+
+	assert.ErrorIs(t, err, services.ErrUnauthorized)
+}
@@ -0,0 +1,432 @@
+package tests
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type mfaTestSetup struct {
+	repo           *mocks.MockMFARepository
+	webauthnRepo   *mocks.MockWebAuthnRepository
+	userRepo       *mocks.MockUserRepository
+	roleRepo       *mocks.MockRoleRepository
+	authService    *mocks.MockAuthService
+	lockoutService *mocks.MockLockoutService
+	service        services.MFAService
+	key            []byte
+}
+
+func newMFATestSetup(t *testing.T) *mfaTestSetup {
+	key := make([]byte, 32)
+	viper.Set("MFA_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(key))
+	t.Cleanup(func() { viper.Set("MFA_ENCRYPTION_KEY", "") })
+
+	s := &mfaTestSetup{
+		repo:           &mocks.MockMFARepository{},
+		webauthnRepo:   &mocks.MockWebAuthnRepository{},
+		userRepo:       &mocks.MockUserRepository{},
+		roleRepo:       &mocks.MockRoleRepository{},
+		authService:    &mocks.MockAuthService{},
+		lockoutService: &mocks.MockLockoutService{},
+		key:            key,
+	}
+	s.lockoutService.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	s.lockoutService.On("RecordSuccess", mock.Anything).Return(nil).Maybe()
+	s.service = services.NewMFAService(s.repo, s.webauthnRepo, s.userRepo, s.roleRepo, s.authService, s.lockoutService)
+	return s
+}
+
+// credential returns a TOTP credential for user along with its plain secret.
+func (s *mfaTestSetup) credential(t *testing.T, user *models.User, confirmed bool) (*models.TOTPCredential, string) {
+	secret, err := utils.GenerateTOTPSecret()
+	require.NoError(t, err)
+	encrypted, err := utils.Encrypt(s.key, []byte(secret))
+	require.NoError(t, err)
+
+	credential := &models.TOTPCredential{ID: uuid.New(), UserID: user.ID, EncryptedSecret: encrypted}
+	if confirmed {
+		confirmedAt := time.Now()
+		credential.ConfirmedAt = &confirmedAt
+	}
+	return credential, secret
+}
+
+func currentCode(t *testing.T, secret string) string {
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func newMFAUser() *models.User {
+	tenantID := uuid.New()
+	return &models.User{ID: uuid.New(), TenantID: &tenantID, Email: "mfa@example.com", RoleID: uuid.NewString()}
+}
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// base32 of the RFC's ASCII secret "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Unix(59, 0)))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	code, err = utils.TOTPCode(secret, utils.TOTPStep(time.Unix(1111111109, 0)))
+	require.NoError(t, err)
+	assert.Equal(t, "081804", code)
+}
+
+func TestEnrollAndActivateTOTP(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+
+	var saved *models.TOTPCredential
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(nil, gorm.ErrRecordNotFound).Once()
+	s.repo.On("SaveTOTPCredential", mock.AnythingOfType("*models.TOTPCredential")).
+		Run(func(args mock.Arguments) { saved = args.Get(0).(*models.TOTPCredential) }).
+		Return(nil)
+
+	enrollment, err := s.service.EnrollTOTP(user)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/")
+	assert.Contains(t, enrollment.OTPAuthURI, "secret="+enrollment.Secret)
+	require.NotNil(t, saved)
+	assert.NotContains(t, saved.EncryptedSecret, enrollment.Secret)
+	assert.Nil(t, saved.ConfirmedAt)
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(saved, nil)
+	s.repo.On("UseTOTPStep", user.ID.String(), mock.AnythingOfType("int64")).Return(nil)
+	s.repo.On("ReplaceRecoveryCodes", user.ID.String(), mock.AnythingOfType("[]*models.RecoveryCode")).Return(nil)
+
+	codes, err := s.service.ActivateTOTP(user, currentCode(t, enrollment.Secret))
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.NotNil(t, saved.ConfirmedAt)
+}
+
+func TestActivateTOTP_WrongCode(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, _ := s.credential(t, user, false)
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+
+	_, err := s.service.ActivateTOTP(user, "000000")
+
+	assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+	s.repo.AssertNotCalled(t, "SaveTOTPCredential", mock.Anything)
+}
+
+func TestBeginLogin_NoMFA(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(nil, gorm.ErrRecordNotFound)
+	s.repo.On("FindMFAPolicy", user.TenantID.String()).Return(nil, gorm.ErrRecordNotFound)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(0), nil)
+
+	challenge, err := s.service.BeginLogin(user)
+
+	require.NoError(t, err)
+	assert.Nil(t, challenge)
+}
+
+func TestBeginLogin_Enrolled(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, _ := s.credential(t, user, true)
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("CreateChallenge", mock.AnythingOfType("*models.MFAChallenge")).Return(nil)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(0), nil)
+
+	challenge, err := s.service.BeginLogin(user)
+
+	require.NoError(t, err)
+	require.NotNil(t, challenge)
+	assert.True(t, challenge.MFARequired)
+	assert.NotEmpty(t, challenge.MFAToken)
+	assert.False(t, challenge.EnrollmentRequired)
+	assert.Equal(t, []string{utils.MFAMethodTOTP, utils.MFAMethodRecoveryCode}, challenge.Methods)
+}
+
+func TestBeginLogin_PolicyRequiresRole(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(nil, gorm.ErrRecordNotFound)
+	s.repo.On("FindMFAPolicy", user.TenantID.String()).Return(&models.MFAPolicy{RequiredRoles: []string{"admin"}}, nil)
+	s.roleRepo.On("GetRoleById", user.RoleID).Return(&models.Role{Name: "admin"}, nil)
+	s.repo.On("CreateChallenge", mock.AnythingOfType("*models.MFAChallenge")).Return(nil)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(0), nil)
+
+	challenge, err := s.service.BeginLogin(user)
+
+	require.NoError(t, err)
+	require.NotNil(t, challenge)
+	assert.True(t, challenge.EnrollmentRequired)
+}
+
+func TestBeginLogin_PolicyOtherRole(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(nil, gorm.ErrRecordNotFound)
+	s.repo.On("FindMFAPolicy", user.TenantID.String()).Return(&models.MFAPolicy{RequiredRoles: []string{"admin"}}, nil)
+	s.roleRepo.On("GetRoleById", user.RoleID).Return(&models.Role{Name: "member"}, nil)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(0), nil)
+
+	challenge, err := s.service.BeginLogin(user)
+
+	require.NoError(t, err)
+	assert.Nil(t, challenge)
+}
+
+func (s *mfaTestSetup) challenge(user *models.User, token string) *models.MFAChallenge {
+	challenge := &models.MFAChallenge{ID: uuid.New(), TokenHash: utils.HashToken(token), UserID: user.ID}
+	s.repo.On("FindActiveChallenge", challenge.TokenHash, mock.AnythingOfType("time.Time"), 5).Return(challenge, nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	return challenge
+}
+
+func TestCompleteLogin_ValidCode(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, secret := s.credential(t, user, true)
+	challenge := s.challenge(user, "challenge")
+	tokens := &dto.LoginResponse{AccessToken: "access"}
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("UseTOTPStep", user.ID.String(), mock.AnythingOfType("int64")).Return(nil)
+	s.repo.On("ConsumeChallenge", challenge.ID.String(), mock.AnythingOfType("time.Time")).Return(nil)
+	s.authService.On("IssueTokens", user).Return(tokens, nil)
+
+	result, err := s.service.CompleteLogin(&dto.MFALoginRequest{MFAToken: "challenge", Code: currentCode(t, secret)}, "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "access", result.AccessToken)
+	assert.Empty(t, result.RecoveryCodes)
+	s.lockoutService.AssertCalled(t, "RecordSuccess", user.Email)
+}
+
+func TestCompleteLogin_WrongCodeCountsAttempt(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, _ := s.credential(t, user, true)
+	challenge := s.challenge(user, "challenge")
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("IncrementChallengeAttempts", challenge.ID.String()).Return(nil)
+
+	_, err := s.service.CompleteLogin(&dto.MFALoginRequest{MFAToken: "challenge", Code: "000000"}, "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+	s.repo.AssertCalled(t, "IncrementChallengeAttempts", challenge.ID.String())
+	s.authService.AssertNotCalled(t, "IssueTokens", mock.Anything)
+	s.lockoutService.AssertCalled(t, "RecordFailure", user.Email, "10.0.0.1", user)
+	s.lockoutService.AssertNotCalled(t, "RecordSuccess", mock.Anything)
+}
+
+func TestVerifyLoginCode_WrongCodesRecordFailures(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, _ := s.credential(t, user, true)
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("ConsumeRecoveryCode", user.ID.String(), mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+
+	for _, code := range []string{"000000", "aaaa-bbbb-cccc"} {
+		err := s.service.VerifyLoginCode(user, code, "10.0.0.1")
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode, code)
+	}
+
+	s.lockoutService.AssertNumberOfCalls(t, "RecordFailure", 2)
+	s.lockoutService.AssertCalled(t, "RecordFailure", user.Email, "10.0.0.1", user)
+	s.lockoutService.AssertNotCalled(t, "RecordSuccess", mock.Anything)
+}
+
+func TestVerifyLoginCode_ValidCodeRecordsSuccess(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, secret := s.credential(t, user, true)
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("UseTOTPStep", user.ID.String(), mock.AnythingOfType("int64")).Return(nil)
+
+	require.NoError(t, s.service.VerifyLoginCode(user, currentCode(t, secret), "10.0.0.1"))
+
+	s.lockoutService.AssertCalled(t, "RecordSuccess", user.Email)
+	s.lockoutService.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCompleteLogin_ReplayedCode(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, secret := s.credential(t, user, true)
+	credential.LastUsedStep = utils.TOTPStep(time.Now())
+	challenge := s.challenge(user, "challenge")
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("IncrementChallengeAttempts", challenge.ID.String()).Return(nil)
+
+	_, err := s.service.CompleteLogin(&dto.MFALoginRequest{MFAToken: "challenge", Code: currentCode(t, secret)}, "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+}
+
+func TestCompleteLogin_RecoveryCode(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, _ := s.credential(t, user, true)
+	challenge := s.challenge(user, "challenge")
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("ConsumeRecoveryCode", user.ID.String(), utils.HashToken("abcdefghijklmnop"), mock.AnythingOfType("time.Time")).Return(nil)
+	s.repo.On("ConsumeChallenge", challenge.ID.String(), mock.AnythingOfType("time.Time")).Return(nil)
+	s.authService.On("IssueTokens", user).Return(&dto.LoginResponse{AccessToken: "access"}, nil)
+
+	result, err := s.service.CompleteLogin(&dto.MFALoginRequest{MFAToken: "challenge", RecoveryCode: "ABCD-efgh-ijkl-mnop"}, "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "access", result.AccessToken)
+}
+
+func TestCompleteLogin_ActivatesPendingEnrollment(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, secret := s.credential(t, user, false)
+	challenge := s.challenge(user, "challenge")
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("UseTOTPStep", user.ID.String(), mock.AnythingOfType("int64")).Return(nil)
+	s.repo.On("ConsumeChallenge", challenge.ID.String(), mock.AnythingOfType("time.Time")).Return(nil)
+	s.repo.On("SaveTOTPCredential", credential).Return(nil)
+	s.repo.On("ReplaceRecoveryCodes", user.ID.String(), mock.AnythingOfType("[]*models.RecoveryCode")).Return(nil)
+	s.authService.On("IssueTokens", user).Return(&dto.LoginResponse{AccessToken: "access"}, nil)
+
+	result, err := s.service.CompleteLogin(&dto.MFALoginRequest{MFAToken: "challenge", Code: currentCode(t, secret)}, "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Len(t, result.RecoveryCodes, 10)
+	assert.NotNil(t, credential.ConfirmedAt)
+}
+
+func TestCompleteLogin_InvalidChallenge(t *testing.T) {
+	s := newMFATestSetup(t)
+
+	s.repo.On("FindActiveChallenge", utils.HashToken("expired"), mock.AnythingOfType("time.Time"), 5).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := s.service.CompleteLogin(&dto.MFALoginRequest{MFAToken: "expired", Code: "123456"}, "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrInvalidMFAToken)
+}
+
+func TestDisableTOTP_RefusedWhenPolicyRequiresMFA(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, secret := s.credential(t, user, true)
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("UseTOTPStep", user.ID.String(), mock.AnythingOfType("int64")).Return(nil)
+	s.repo.On("FindMFAPolicy", user.TenantID.String()).Return(&models.MFAPolicy{Required: true}, nil)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(0), nil)
+
+	err := s.service.DisableTOTP(user, currentCode(t, secret))
+
+	var appErr *utils.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 403, appErr.Code)
+	s.repo.AssertNotCalled(t, "DeleteMFA", mock.Anything)
+}
+
+func TestDisableTOTP_AllowedWithPasskeyUnderPolicy(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	credential, secret := s.credential(t, user, true)
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(credential, nil)
+	s.repo.On("UseTOTPStep", user.ID.String(), mock.AnythingOfType("int64")).Return(nil)
+	s.repo.On("FindMFAPolicy", user.TenantID.String()).Return(&models.MFAPolicy{Required: true}, nil)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(1), nil)
+	s.repo.On("DeleteMFA", user.ID.String()).Return(nil)
+
+	err := s.service.DisableTOTP(user, currentCode(t, secret))
+
+	require.NoError(t, err)
+	s.repo.AssertCalled(t, "DeleteMFA", user.ID.String())
+}
+
+func TestBeginLogin_PasskeyOnly(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(nil, gorm.ErrRecordNotFound)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(2), nil)
+	s.repo.On("CreateChallenge", mock.AnythingOfType("*models.MFAChallenge")).Return(nil)
+
+	challenge, err := s.service.BeginLogin(user)
+
+	require.NoError(t, err)
+	require.NotNil(t, challenge)
+	assert.False(t, challenge.EnrollmentRequired)
+	assert.Equal(t, []string{utils.MFAMethodWebAuthn}, challenge.Methods)
+}
+
+func TestEnrollChallenge_PolicyRequiresMFA(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	s.challenge(user, "challenge")
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(nil, gorm.ErrRecordNotFound)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(0), nil)
+	s.repo.On("FindMFAPolicy", user.TenantID.String()).Return(&models.MFAPolicy{Required: true}, nil)
+	s.repo.On("SaveTOTPCredential", mock.AnythingOfType("*models.TOTPCredential")).Return(nil)
+
+	enrollment, err := s.service.EnrollChallenge("challenge")
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, enrollment.Secret)
+}
+
+func TestEnrollChallenge_PasskeyOnlyRefused(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	s.challenge(user, "challenge")
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(nil, gorm.ErrRecordNotFound)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(1), nil)
+	s.repo.On("FindMFAPolicy", user.TenantID.String()).Return(&models.MFAPolicy{Required: true}, nil)
+
+	_, err := s.service.EnrollChallenge("challenge")
+
+	requireAppError(t, err, http.StatusForbidden)
+	s.repo.AssertNotCalled(t, "SaveTOTPCredential", mock.Anything)
+}
+
+func TestEnrollChallenge_PolicyNotRequired(t *testing.T) {
+	s := newMFATestSetup(t)
+	user := newMFAUser()
+	s.challenge(user, "challenge")
+
+	s.repo.On("FindTOTPCredential", user.ID.String()).Return(nil, gorm.ErrRecordNotFound)
+	s.webauthnRepo.On("CountUserCredentials", user.ID.String()).Return(int64(0), nil)
+	s.repo.On("FindMFAPolicy", user.TenantID.String()).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := s.service.EnrollChallenge("challenge")
+
+	requireAppError(t, err, http.StatusForbidden)
+	s.repo.AssertNotCalled(t, "SaveTOTPCredential", mock.Anything)
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTenantAdmin() *models.User {
+	tenantID := uuid.New()
+	roleID := uuid.New()
+	return &models.User{
+		ID:       uuid.New(),
+		TenantID: &tenantID,
+		RoleID:   roleID.String(),
+		Role:     models.Role{ID: roleID, Name: "admin"},
+	}
+}
+
+func TestCreateClient_TenantAdminOwnsClient(t *testing.T) {
+	mockClientRepo := &mocks.MockOAuthClientRepository{}
+	mockRoleRepo := &mocks.MockRoleRepository{}
+	clientService := services.NewOAuthClientService(mockClientRepo, mockRoleRepo)
+
+	requestor := newTenantAdmin()
+	otherTenant := uuid.NewString()
+	role := &models.Role{Permissions: []*models.Permission{{Code: "user:read"}}}
+
+	mockRoleRepo.On("GetRoleById", requestor.RoleID).Return(role, nil)
+	mockClientRepo.On("CreateClient", mock.AnythingOfType("*models.OAuthClient")).Return(nil)
+
+	response, err := clientService.CreateClient(requestor, &dto.OAuthClientRequest{
+		Name:       "job",
+		TenantID:   otherTenant,
+		Scopes:     []string{"user:read"},
+		GrantTypes: []string{utils.GrantTypeClientCredentials},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, *requestor.TenantID, *response.Client.TenantID)
+	assert.NotEmpty(t, response.ClientSecret)
+	assert.Equal(t, utils.HashToken(response.ClientSecret), response.Client.ClientSecretHash)
+}
+
+func TestCreateClient_CannotGrantMissingPermission(t *testing.T) {
+	mockClientRepo := &mocks.MockOAuthClientRepository{}
+	mockRoleRepo := &mocks.MockRoleRepository{}
+	clientService := services.NewOAuthClientService(mockClientRepo, mockRoleRepo)
+
+	requestor := newTenantAdmin()
+	role := &models.Role{Permissions: []*models.Permission{{Code: "user:read"}}}
+	mockRoleRepo.On("GetRoleById", requestor.RoleID).Return(role, nil)
+
+	_, err := clientService.CreateClient(requestor, &dto.OAuthClientRequest{
+		Name:   "job",
+		Scopes: []string{"user:delete"},
+	})
+
+	assert.Error(t, err)
+	mockClientRepo.AssertNotCalled(t, "CreateClient", mock.Anything)
+}
+
+func TestCreateClient_GlobalClientCredentialsNeedsTenant(t *testing.T) {
+	mockClientRepo := &mocks.MockOAuthClientRepository{}
+	clientService := services.NewOAuthClientService(mockClientRepo, &mocks.MockRoleRepository{})
+
+	requestor := &models.User{ID: uuid.New(), Role: models.Role{Name: utils.RoleSuperAdmin}}
+
+	_, err := clientService.CreateClient(requestor, &dto.OAuthClientRequest{
+		Name:       "job",
+		Scopes:     []string{"user:read"},
+		GrantTypes: []string{utils.GrantTypeClientCredentials},
+	})
+
+	requireAppError(t, err, http.StatusBadRequest)
+	mockClientRepo.AssertNotCalled(t, "CreateClient", mock.Anything)
+}
+
+func TestCreateClient_InvalidTenantID(t *testing.T) {
+	mockClientRepo := &mocks.MockOAuthClientRepository{}
+	clientService := services.NewOAuthClientService(mockClientRepo, &mocks.MockRoleRepository{})
+
+	requestor := &models.User{ID: uuid.New(), Role: models.Role{Name: utils.RoleSuperAdmin}}
+
+	_, err := clientService.CreateClient(requestor, &dto.OAuthClientRequest{
+		Name:     "job",
+		TenantID: "not-a-uuid",
+	})
+
+	requireAppError(t, err, http.StatusBadRequest)
+	mockClientRepo.AssertNotCalled(t, "CreateClient", mock.Anything)
+}
+
+func TestRotateClientSecret_KeepsPreviousSecret(t *testing.T) {
+	mockClientRepo := &mocks.MockOAuthClientRepository{}
+	clientService := services.NewOAuthClientService(mockClientRepo, &mocks.MockRoleRepository{})
+
+	requestor := newTenantAdmin()
+	client := &models.OAuthClient{ClientID: "job", TenantID: requestor.TenantID, ClientSecretHash: utils.HashToken("old")}
+
+	mockClientRepo.On("FindClientByClientId", "job").Return(client, nil)
+	mockClientRepo.On("UpdateClient", client).Return(nil)
+
+	response, err := clientService.RotateClientSecret(requestor, "job")
+
+	require.NoError(t, err)
+	assert.Equal(t, utils.HashToken(response.ClientSecret), client.ClientSecretHash)
+	assert.Equal(t, utils.HashToken("old"), client.PreviousSecretHash)
+	require.NotNil(t, client.PreviousSecretExpiresAt)
+}
+
+func TestRotateClientSecret_OtherTenant(t *testing.T) {
+	mockClientRepo := &mocks.MockOAuthClientRepository{}
+	clientService := services.NewOAuthClientService(mockClientRepo, &mocks.MockRoleRepository{})
+
+	otherTenant := uuid.New()
+	client := &models.OAuthClient{ClientID: "job", TenantID: &otherTenant}
+	mockClientRepo.On("FindClientByClientId", "job").Return(client, nil)
+
+	_, err := clientService.RotateClientSecret(newTenantAdmin(), "job")
+
+	assert.Error(t, err)
+	mockClientRepo.AssertNotCalled(t, "UpdateClient", mock.Anything)
+}
@@ -0,0 +1,549 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+type oidcTestSetup struct {
+	service     services.OIDCService
+	clientRepo  *mocks.MockOAuthClientRepository
+	codeRepo    *mocks.MockAuthorizationCodeRepository
+	userRepo    *mocks.MockUserRepository
+	refreshRepo *mocks.MockRefreshTokenRepository
+	revocation  *mocks.MockRevocationService
+	authService services.AuthService
+}
+
+func newOIDCTestSetup(t *testing.T) *oidcTestSetup {
+	viper.Set("JWT_ISSUER", "https://auth.example.com")
+	t.Cleanup(func() { viper.Set("JWT_ISSUER", "") })
+
+	setup := &oidcTestSetup{
+		clientRepo:  &mocks.MockOAuthClientRepository{},
+		codeRepo:    &mocks.MockAuthorizationCodeRepository{},
+		userRepo:    &mocks.MockUserRepository{},
+		refreshRepo: &mocks.MockRefreshTokenRepository{},
+		revocation:  &mocks.MockRevocationService{},
+	}
+
+	setup.authService = newTestAuthService(t, setup.refreshRepo, setup.userRepo, setup.revocation)
+	keyService, err := services.NewKeyService()
+	require.NoError(t, err)
+
+	setup.service = services.NewOIDCService(setup.clientRepo, setup.codeRepo, setup.userRepo, setup.authService, keyService)
+	return setup
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func newAuthorizeRequest(client *models.OAuthClient) *dto.AuthorizeRequest {
+	return &dto.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ClientID,
+		RedirectURI:         client.RedirectURIs[0],
+		Scope:               "openid email offline_access",
+		State:               "state",
+		Nonce:               "nonce",
+		CodeChallenge:       codeChallenge(testCodeVerifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+func newPublicClient() *models.OAuthClient {
+	return &models.OAuthClient{
+		ClientID:     "spa",
+		Name:         "SPA",
+		Public:       true,
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"openid", "email", "profile", "offline_access"},
+	}
+}
+
+func TestOIDC_AuthorizationCodeFlow(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newPublicClient()
+	user := &models.User{ID: uuid.New(), Email: "a@example.com"}
+	req := newAuthorizeRequest(client)
+
+	var stored *models.AuthorizationCode
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+	setup.codeRepo.On("CreateAuthorizationCode", mock.AnythingOfType("*models.AuthorizationCode")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*models.AuthorizationCode) }).
+		Return(nil)
+	setup.codeRepo.On("DeleteExpiredAuthorizationCodes", mock.Anything).Return(nil)
+
+	code, err := setup.service.Authorize(req, user)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, utils.HashToken(code), stored.CodeHash)
+
+	setup.codeRepo.On("ConsumeAuthorizationCode", stored.CodeHash, mock.Anything).Return(stored, nil)
+	setup.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	setup.refreshRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	tokens, err := setup.service.Token(&dto.TokenRequest{
+		GrantType:    utils.GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  req.RedirectURI,
+		CodeVerifier: testCodeVerifier,
+		ClientID:     client.ClientID,
+	})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, "openid email offline_access", tokens.Scope)
+
+	refreshToken := setup.refreshRepo.Calls[0].Arguments.Get(0).(*models.RefreshToken)
+	assert.Equal(t, client.ClientID, refreshToken.ClientID)
+
+	idClaims := &utils.IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(tokens.IDToken, idClaims, func(*jwt.Token) (interface{}, error) {
+		return []byte("test_secret"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com", idClaims.Issuer)
+	assert.Equal(t, user.ID.String(), idClaims.Subject)
+	assert.Equal(t, []string{client.ClientID}, []string(idClaims.Audience))
+	assert.Equal(t, "nonce", idClaims.Nonce)
+	assert.Equal(t, user.Email, idClaims.Email)
+	assert.Equal(t, utils.TokenUseID, idClaims.TokenUse)
+
+	setup.revocation.On("IsRevoked", mock.Anything).Return(false, nil)
+	_, err = setup.authService.ValidateAccessToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	_, err = setup.authService.ValidateAccessToken(tokens.IDToken)
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+}
+
+func TestOIDC_Token_WrongCodeVerifier(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newPublicClient()
+	code := &models.AuthorizationCode{
+		ClientID:      client.ClientID,
+		UserID:        uuid.New(),
+		RedirectURI:   client.RedirectURIs[0],
+		Scope:         "openid",
+		CodeChallenge: codeChallenge(testCodeVerifier),
+	}
+
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+	setup.codeRepo.On("ConsumeAuthorizationCode", utils.HashToken("code"), mock.Anything).Return(code, nil)
+
+	tokens, err := setup.service.Token(&dto.TokenRequest{
+		GrantType:    utils.GrantTypeAuthorizationCode,
+		Code:         "code",
+		RedirectURI:  client.RedirectURIs[0],
+		CodeVerifier: "wrong",
+		ClientID:     client.ClientID,
+	})
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "invalid_grant", oauthErr.Code)
+	assert.Nil(t, tokens)
+	setup.userRepo.AssertNotCalled(t, "FindUserById", mock.Anything)
+}
+
+func TestOIDC_Token_UsedCode(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newPublicClient()
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+	setup.codeRepo.On("ConsumeAuthorizationCode", utils.HashToken("code"), mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := setup.service.Token(&dto.TokenRequest{
+		GrantType:    utils.GrantTypeAuthorizationCode,
+		Code:         "code",
+		RedirectURI:  client.RedirectURIs[0],
+		CodeVerifier: testCodeVerifier,
+		ClientID:     client.ClientID,
+	})
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "invalid_grant", oauthErr.Code)
+}
+
+func TestOIDC_Token_ConfidentialClientWrongSecret(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newPublicClient()
+	client.Public = false
+	client.ClientSecretHash = utils.HashToken("secret")
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	_, err := setup.service.Token(&dto.TokenRequest{
+		GrantType:    utils.GrantTypeAuthorizationCode,
+		Code:         "code",
+		ClientID:     client.ClientID,
+		ClientSecret: "wrong",
+	})
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "invalid_client", oauthErr.Code)
+	setup.codeRepo.AssertNotCalled(t, "ConsumeAuthorizationCode", mock.Anything, mock.Anything)
+}
+
+func TestOIDC_ValidateAuthorizeRequest_UnregisteredRedirect(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newPublicClient()
+	req := newAuthorizeRequest(client)
+	req.RedirectURI = "https://evil.example.com/callback"
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	found, err := setup.service.ValidateAuthorizeRequest(req)
+
+	assert.Error(t, err)
+	assert.Nil(t, found)
+}
+
+func TestOIDC_ValidateAuthorizeRequest_RequiresPKCE(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newPublicClient()
+	req := newAuthorizeRequest(client)
+	req.CodeChallenge = ""
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	found, err := setup.service.ValidateAuthorizeRequest(req)
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "invalid_request", oauthErr.Code)
+	assert.NotNil(t, found)
+}
+
+func TestOIDC_Authorize_TenantBoundClient(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	tenantID := uuid.New()
+	client := newPublicClient()
+	client.TenantID = &tenantID
+	otherTenant := uuid.New()
+	user := &models.User{ID: uuid.New(), TenantID: &otherTenant}
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	_, err := setup.service.Authorize(newAuthorizeRequest(client), user)
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "access_denied", oauthErr.Code)
+	setup.codeRepo.AssertNotCalled(t, "CreateAuthorizationCode", mock.Anything)
+}
+
+func TestOIDC_UserInfo_RequiresOpenIDScope(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	_, err := setup.service.UserInfo(&utils.Claims{UserID: uuid.NewString(), Scope: "email"})
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "insufficient_scope", oauthErr.Code)
+}
+
+func newConfidentialClient() *models.OAuthClient {
+	tenantID := uuid.New()
+	return &models.OAuthClient{
+		ClientID:         uuid.NewString(),
+		Name:             "backend job",
+		TenantID:         &tenantID,
+		ClientSecretHash: utils.HashToken("secret"),
+		Scopes:           []string{"user:read", "invite:create"},
+		GrantTypes:       []string{utils.GrantTypeClientCredentials},
+	}
+}
+
+func TestOIDC_ClientCredentials(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newConfidentialClient()
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	tokens, err := setup.service.Token(&dto.TokenRequest{
+		GrantType:    utils.GrantTypeClientCredentials,
+		Scope:        "user:read",
+		ClientID:     client.ClientID,
+		ClientSecret: "secret",
+	})
+
+	require.NoError(t, err)
+	assert.Empty(t, tokens.RefreshToken)
+	assert.Equal(t, "user:read", tokens.Scope)
+
+	claims := &utils.Claims{}
+	_, err = jwt.ParseWithClaims(tokens.AccessToken, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte("test_secret"), nil
+	})
+	require.NoError(t, err)
+	assert.True(t, claims.IsClient())
+	assert.Equal(t, client.ClientID, claims.Subject)
+	assert.Equal(t, client.TenantID.String(), claims.TenantID)
+	assert.Equal(t, "user:read", claims.Scope)
+}
+
+func TestOIDC_ClientCredentials_ScopeNotAllowed(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newConfidentialClient()
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	_, err := setup.service.Token(&dto.TokenRequest{
+		GrantType:    utils.GrantTypeClientCredentials,
+		Scope:        "user:delete",
+		ClientID:     client.ClientID,
+		ClientSecret: "secret",
+	})
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "invalid_scope", oauthErr.Code)
+}
+
+func TestOIDC_ClientCredentials_GrantNotAllowed(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newConfidentialClient()
+	client.GrantTypes = nil
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	_, err := setup.service.Token(&dto.TokenRequest{
+		GrantType:    utils.GrantTypeClientCredentials,
+		ClientID:     client.ClientID,
+		ClientSecret: "secret",
+	})
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "unauthorized_client", oauthErr.Code)
+}
+
+func TestOIDC_ClientCredentials_PreviousSecretDuringGrace(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newConfidentialClient()
+	client.PreviousSecretHash = client.ClientSecretHash
+	client.ClientSecretHash = utils.HashToken("new-secret")
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	request := &dto.TokenRequest{
+		GrantType:    utils.GrantTypeClientCredentials,
+		ClientID:     client.ClientID,
+		ClientSecret: "secret",
+	}
+
+	validUntil := time.Now().Add(time.Hour)
+	client.PreviousSecretExpiresAt = &validUntil
+	_, err := setup.service.Token(request)
+	assert.NoError(t, err)
+
+	expiredAt := time.Now().Add(-time.Minute)
+	client.PreviousSecretExpiresAt = &expiredAt
+	_, err = setup.service.Token(request)
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "invalid_client", oauthErr.Code)
+}
+
+func TestOIDC_Introspect_ActiveAccessToken(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	gateway := newConfidentialClient()
+	gateway.TenantID = nil
+	setup.clientRepo.On("FindClientByClientId", gateway.ClientID).Return(gateway, nil)
+	setup.revocation.On("IsRevoked", mock.Anything).Return(false, nil)
+
+	job := newConfidentialClient()
+	tokens, err := setup.authService.IssueClientCredentialsToken(job, "user:read")
+	require.NoError(t, err)
+
+	response, err := setup.service.Introspect(&dto.TokenActionRequest{
+		Token:        tokens.AccessToken,
+		ClientID:     gateway.ClientID,
+		ClientSecret: "secret",
+	})
+
+	require.NoError(t, err)
+	assert.True(t, response.Active)
+	assert.Equal(t, job.ClientID, response.Sub)
+	assert.Equal(t, job.ClientID, response.ClientID)
+	assert.Equal(t, job.TenantID.String(), response.TenantID)
+	assert.Equal(t, "user:read", response.Scope)
+	assert.NotZero(t, response.Exp)
+}
+
+func TestOIDC_Introspect_RevokedTokenIsInactive(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	gateway := newConfidentialClient()
+	gateway.TenantID = nil
+	setup.clientRepo.On("FindClientByClientId", gateway.ClientID).Return(gateway, nil)
+	setup.revocation.On("IsRevoked", mock.Anything).Return(true, nil)
+	setup.refreshRepo.On("FindRefreshTokenByHash", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	tokens, err := setup.authService.IssueClientCredentialsToken(newConfidentialClient(), "")
+	require.NoError(t, err)
+
+	response, err := setup.service.Introspect(&dto.TokenActionRequest{
+		Token:        tokens.AccessToken,
+		ClientID:     gateway.ClientID,
+		ClientSecret: "secret",
+	})
+
+	require.NoError(t, err)
+	assert.False(t, response.Active)
+	assert.Empty(t, response.Sub)
+}
+
+func TestOIDC_Introspect_IDTokenIsInactive(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	gateway := newConfidentialClient()
+	gateway.TenantID = nil
+	setup.clientRepo.On("FindClientByClientId", gateway.ClientID).Return(gateway, nil)
+	setup.revocation.On("IsRevoked", mock.Anything).Return(false, nil)
+	setup.refreshRepo.On("FindRefreshTokenByHash", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &utils.IDTokenClaims{
+		TokenUse: utils.TokenUseID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://auth.example.com",
+			Subject:   uuid.NewString(),
+			Audience:  jwt.ClaimStrings{gateway.ClientID},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}).SignedString([]byte("test_secret"))
+	require.NoError(t, err)
+
+	response, err := setup.service.Introspect(&dto.TokenActionRequest{
+		Token:        idToken,
+		ClientID:     gateway.ClientID,
+		ClientSecret: "secret",
+	})
+
+	require.NoError(t, err)
+	assert.False(t, response.Active)
+}
+
+func TestOIDC_Introspect_OtherTenantIsInactive(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	gateway := newConfidentialClient()
+	setup.clientRepo.On("FindClientByClientId", gateway.ClientID).Return(gateway, nil)
+
+	otherTenant := uuid.New()
+	stored := &models.RefreshToken{
+		UserID:    uuid.New(),
+		TenantID:  &otherTenant,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	setup.refreshRepo.On("FindRefreshTokenByHash", utils.HashToken("refresh")).Return(stored, nil)
+
+	response, err := setup.service.Introspect(&dto.TokenActionRequest{
+		Token:         "refresh",
+		TokenTypeHint: utils.TokenTypeRefreshToken,
+		ClientID:      gateway.ClientID,
+		ClientSecret:  "secret",
+	})
+
+	require.NoError(t, err)
+	assert.False(t, response.Active)
+}
+
+func TestOIDC_Introspect_PublicClientRefused(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newPublicClient()
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	_, err := setup.service.Introspect(&dto.TokenActionRequest{Token: "token", ClientID: client.ClientID})
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "unauthorized_client", oauthErr.Code)
+}
+
+func TestOIDC_Revoke_OwnAccessToken(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newConfidentialClient()
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+	setup.revocation.On("IsRevoked", mock.Anything).Return(false, nil)
+	setup.revocation.On("RevokeToken", mock.Anything, uuid.Nil, mock.Anything).Return(nil)
+
+	tokens, err := setup.authService.IssueClientCredentialsToken(client, "user:read")
+	require.NoError(t, err)
+
+	err = setup.service.Revoke(&dto.TokenActionRequest{
+		Token:        tokens.AccessToken,
+		ClientID:     client.ClientID,
+		ClientSecret: "secret",
+	})
+
+	assert.NoError(t, err)
+	setup.revocation.AssertCalled(t, "RevokeToken", mock.Anything, uuid.Nil, mock.Anything)
+}
+
+func TestOIDC_Revoke_OtherClientsRefreshToken(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newConfidentialClient()
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+
+	stored := &models.RefreshToken{FamilyID: uuid.New(), ClientID: "someone-else"}
+	setup.refreshRepo.On("FindRefreshTokenByHash", utils.HashToken("refresh")).Return(stored, nil)
+
+	err := setup.service.Revoke(&dto.TokenActionRequest{
+		Token:         "refresh",
+		TokenTypeHint: utils.TokenTypeRefreshToken,
+		ClientID:      client.ClientID,
+		ClientSecret:  "secret",
+	})
+
+	var oauthErr *services.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "unauthorized_client", oauthErr.Code)
+	setup.refreshRepo.AssertNotCalled(t, "RevokeRefreshTokenFamily", mock.Anything)
+}
+
+func TestOIDC_Revoke_UnknownTokenIsIgnored(t *testing.T) {
+	setup := newOIDCTestSetup(t)
+
+	client := newConfidentialClient()
+	setup.clientRepo.On("FindClientByClientId", client.ClientID).Return(client, nil)
+	setup.refreshRepo.On("FindRefreshTokenByHash", utils.HashToken("garbage")).Return(nil, gorm.ErrRecordNotFound)
+
+	err := setup.service.Revoke(&dto.TokenActionRequest{
+		Token:        "garbage",
+		ClientID:     client.ClientID,
+		ClientSecret: "secret",
+	})
+
+	assert.NoError(t, err)
+}
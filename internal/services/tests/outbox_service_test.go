@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMailer keeps what it is asked to send and fails while err is set.
+type recordingMailer struct {
+	sent []*mailer.Message
+	err  error
+}
+
+func (r *recordingMailer) Send(msg *mailer.Message) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func newOutboxEmail(attempts int) *models.OutboxEmail {
+	return &models.OutboxEmail{
+		ID:       uuid.New(),
+		Template: mailer.TemplateInvite,
+		To:       "user@example.com",
+		Subject:  "You're invited",
+		HTMLBody: "<p>Join</p>",
+		TextBody: "Join",
+		Attempts: attempts,
+	}
+}
+
+func TestProcessDue_SendsAndMarksSent(t *testing.T) {
+	repo := &mocks.MockOutboxRepository{}
+	recorder := &recordingMailer{}
+	service := services.NewOutboxService(repo, recorder)
+	email := newOutboxEmail(0)
+
+	repo.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything).Return([]*models.OutboxEmail{email}, nil)
+	repo.On("MarkSent", email.ID.String(), mock.Anything).Return(nil)
+
+	sent, err := service.ProcessDue()
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, recorder.sent, 1)
+	assert.Equal(t, "user@example.com", recorder.sent[0].To)
+	assert.Equal(t, "Join", recorder.sent[0].Text)
+	repo.AssertNotCalled(t, "RecordFailure", mock.Anything)
+}
+
+func TestProcessDue_FailureBacksOff(t *testing.T) {
+	repo := &mocks.MockOutboxRepository{}
+	service := services.NewOutboxService(repo, &recordingMailer{err: errors.New("connection refused")})
+	email := newOutboxEmail(2)
+
+	repo.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything).Return([]*models.OutboxEmail{email}, nil)
+	repo.On("RecordFailure", email).Return(nil)
+
+	sent, err := service.ProcessDue()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Equal(t, 3, email.Attempts)
+	assert.Equal(t, "connection refused", email.LastError)
+	assert.Nil(t, email.FailedAt)
+	// third failure: 30s doubled twice
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), email.NextAttemptAt, 5*time.Second)
+	repo.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything)
+}
+
+func TestProcessDue_BackoffIsCapped(t *testing.T) {
+	viper.Set("MAIL_MAX_ATTEMPTS", 20)
+	t.Cleanup(func() { viper.Set("MAIL_MAX_ATTEMPTS", 0) })
+
+	repo := &mocks.MockOutboxRepository{}
+	service := services.NewOutboxService(repo, &recordingMailer{err: errors.New("timeout")})
+	email := newOutboxEmail(9)
+
+	repo.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything).Return([]*models.OutboxEmail{email}, nil)
+	repo.On("RecordFailure", email).Return(nil)
+
+	_, err := service.ProcessDue()
+
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), email.NextAttemptAt, 5*time.Second)
+}
+
+func TestProcessDue_GivesUpAfterMaxAttempts(t *testing.T) {
+	repo := &mocks.MockOutboxRepository{}
+	service := services.NewOutboxService(repo, &recordingMailer{err: errors.New("mailbox unavailable")})
+	email := newOutboxEmail(7)
+
+	repo.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything).Return([]*models.OutboxEmail{email}, nil)
+	repo.On("RecordFailure", email).Return(nil)
+
+	_, err := service.ProcessDue()
+
+	require.NoError(t, err)
+	assert.Equal(t, 8, email.Attempts)
+	assert.NotNil(t, email.FailedAt)
+}
+
+func TestProcessDue_ClaimError(t *testing.T) {
+	repo := &mocks.MockOutboxRepository{}
+	recorder := &recordingMailer{}
+	service := services.NewOutboxService(repo, recorder)
+
+	repo.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("db down"))
+
+	_, err := service.ProcessDue()
+
+	assert.Error(t, err)
+	assert.Empty(t, recorder.sent)
+}
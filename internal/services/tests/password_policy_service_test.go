@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/samvibes/vexop/auth-service/internal/breach"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type passwordPolicyTestSetup struct {
+	repo        *mocks.MockPasswordPolicyRepository
+	authService *mocks.MockAuthService
+	breached    *breach.Filter
+	service     services.PasswordPolicyService
+}
+
+func newPasswordPolicyTestSetup() *passwordPolicyTestSetup {
+	s := &passwordPolicyTestSetup{
+		repo:        &mocks.MockPasswordPolicyRepository{},
+		authService: &mocks.MockAuthService{},
+		breached:    breach.NewFilter(1, 0.001),
+	}
+	s.breached.Add(breach.Hash("password123"))
+	s.service = services.NewPasswordPolicyService(s.repo, s.authService, s.breached)
+	return s
+}
+
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+
+	var policyErr *services.PasswordPolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.ErrorIs(t, err, services.ErrWeakPassword)
+
+	rules := make([]string, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestValidatePassword_DefaultPolicy(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(nil, gorm.ErrRecordNotFound)
+
+	assert.NoError(t, s.service.Validate(user, "correct horse battery"))
+	assert.Equal(t, []string{utils.PasswordRuleMinLength}, violatedRules(t, s.service.Validate(user, "short")))
+	assert.Equal(t, []string{utils.PasswordRuleEmail}, violatedRules(t, s.service.Validate(user, "xMFA123456")))
+}
+
+func TestValidatePassword_ReportsEveryViolation(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(&models.PasswordPolicy{
+		MinLength:     12,
+		MaxLength:     64,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}, nil)
+
+	err := s.service.Validate(user, "lowercase")
+
+	assert.Equal(t, []string{
+		utils.PasswordRuleMinLength,
+		utils.PasswordRuleUpper,
+		utils.PasswordRuleDigit,
+		utils.PasswordRuleSymbol,
+	}, violatedRules(t, err))
+	s.repo.AssertNotCalled(t, "GetHistory", mock.Anything, mock.Anything)
+}
+
+func TestValidatePassword_RejectsBytesBcryptIgnores(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(nil, gorm.ErrRecordNotFound)
+
+	// 30 three-byte runes: short enough by count, too long for bcrypt
+	err := s.service.Validate(user, "€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€")
+
+	assert.Equal(t, []string{utils.PasswordRuleMaxLength}, violatedRules(t, err))
+}
+
+func TestValidatePassword_RejectsRecentPassword(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	user.PasswordHash = "current"
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(&models.PasswordPolicy{MinLength: 8, MaxLength: 72, HistorySize: 3}, nil)
+	s.repo.On("GetHistory", user.ID.String(), 3).Return([]*models.PasswordHistory{{PasswordHash: "current"}, {PasswordHash: "previous"}}, nil)
+	s.authService.On("CompareHashAndPassword", mock.Anything, []byte("current")).Return(false)
+	s.authService.On("CompareHashAndPassword", []byte("old password"), []byte("previous")).Return(true)
+	s.authService.On("CompareHashAndPassword", []byte("new password"), []byte("previous")).Return(false)
+
+	assert.Equal(t, []string{utils.PasswordRuleHistory}, violatedRules(t, s.service.Validate(user, "old password")))
+	assert.NoError(t, s.service.Validate(user, "new password"))
+}
+
+func TestValidatePassword_RejectsBreachedPassword(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(nil, gorm.ErrRecordNotFound)
+
+	assert.Equal(t, []string{utils.PasswordRuleBreached}, violatedRules(t, s.service.Validate(user, "password123")))
+}
+
+func TestValidatePassword_BreachedCheckOff(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(&models.PasswordPolicy{MinLength: 8, MaxLength: 72}, nil)
+
+	assert.NoError(t, s.service.Validate(user, "password123"))
+}
+
+func TestRecordPasswordTx_KeepsHistorySize(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	user.PasswordHash = "hash"
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(&models.PasswordPolicy{HistorySize: 5}, nil)
+	s.repo.On("AddHistoryTx", mock.Anything, mock.AnythingOfType("*models.PasswordHistory"), 5).Return(nil)
+
+	require.NoError(t, s.service.RecordTx(nil, user))
+
+	entry := s.repo.Calls[1].Arguments.Get(1).(*models.PasswordHistory)
+	assert.Equal(t, user.ID, entry.UserID)
+	assert.Equal(t, "hash", entry.PasswordHash)
+}
+
+func TestRecordPasswordTx_HistoryOff(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(nil, gorm.ErrRecordNotFound)
+
+	require.NoError(t, s.service.RecordTx(nil, user))
+	s.repo.AssertNotCalled(t, "AddHistoryTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCheckPasswordExpiry(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+	s.repo.On("FindPolicy", user.TenantID.String()).Return(&models.PasswordPolicy{MaxAgeDays: 90}, nil)
+
+	recent := time.Now().Add(-89 * 24 * time.Hour)
+	user.PasswordChangedAt = &recent
+	assert.NoError(t, s.service.CheckExpiry(user))
+
+	old := time.Now().Add(-91 * 24 * time.Hour)
+	user.PasswordChangedAt = &old
+	assert.ErrorIs(t, s.service.CheckExpiry(user), services.ErrPasswordExpired)
+
+	// passwords from before changes were tracked count from signup
+	user.PasswordChangedAt = nil
+	user.CreatedAt = old
+	assert.ErrorIs(t, s.service.CheckExpiry(user), services.ErrPasswordExpired)
+}
+
+func TestSavePasswordPolicy_MinAboveMax(t *testing.T) {
+	s := newPasswordPolicyTestSetup()
+	user := newMFAUser()
+
+	_, err := s.service.SavePolicy(user, &dto.PasswordPolicyRequest{MinLength: 20, MaxLength: 10})
+
+	var appErr *utils.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.Code)
+	s.repo.AssertNotCalled(t, "SavePolicy", mock.Anything)
+}
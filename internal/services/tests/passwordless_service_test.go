@@ -0,0 +1,281 @@
+package tests
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type passwordlessTestSetup struct {
+	repo           *mocks.MockPasswordlessRepository
+	userRepo       *mocks.MockUserRepository
+	mfaService     *mocks.MockMFAService
+	authService    *mocks.MockAuthService
+	mailService    *mocks.MockMailService
+	lockoutService *mocks.MockLockoutService
+	service        services.PasswordlessService
+}
+
+func newPasswordlessTestSetup() *passwordlessTestSetup {
+	s := &passwordlessTestSetup{
+		repo:           &mocks.MockPasswordlessRepository{},
+		userRepo:       &mocks.MockUserRepository{},
+		mfaService:     &mocks.MockMFAService{},
+		authService:    &mocks.MockAuthService{},
+		mailService:    &mocks.MockMailService{},
+		lockoutService: &mocks.MockLockoutService{},
+	}
+	s.lockoutService.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	s.lockoutService.On("RecordSuccess", mock.Anything).Return(nil).Maybe()
+	s.service = services.NewPasswordlessService(s.repo, s.userRepo, s.mfaService, s.authService, s.mailService, s.lockoutService, &mocks.MockTransactor{})
+	return s
+}
+
+func (s *passwordlessTestSetup) enable(user *models.User, magicLink, emailOTP bool) {
+	s.repo.On("FindLoginMethodPolicy", user.TenantID.String()).Return(&models.LoginMethodPolicy{
+		TenantID:         *user.TenantID,
+		MagicLinkEnabled: magicLink,
+		EmailOTPEnabled:  emailOTP,
+	}, nil)
+}
+
+func TestRequestMagicLink_UnknownEmail(t *testing.T) {
+	s := newPasswordlessTestSetup()
+
+	s.userRepo.On("FindUserByEmail", "nobody@example.com").Return(nil, gorm.ErrRecordNotFound)
+
+	err := s.service.RequestMagicLink("nobody@example.com")
+
+	require.NoError(t, err)
+	s.repo.AssertNotCalled(t, "CreateLoginTokenTx", mock.Anything, mock.Anything)
+	s.mailService.AssertNotCalled(t, "EnqueueTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRequestMagicLink_DisabledForTenant(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.enable(user, false, true)
+
+	err := s.service.RequestMagicLink(user.Email)
+
+	require.NoError(t, err)
+	s.repo.AssertNotCalled(t, "CreateLoginTokenTx", mock.Anything, mock.Anything)
+	s.mailService.AssertNotCalled(t, "EnqueueTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRequestMagicLink_EmailsLinkWithHashedToken(t *testing.T) {
+	viper.Set("MAGIC_LINK_URL", "https://app.example.com/login/link")
+	t.Cleanup(func() { viper.Set("MAGIC_LINK_URL", "") })
+
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.enable(user, true, false)
+	s.repo.On("CreateLoginTokenTx", mock.Anything, mock.AnythingOfType("*models.LoginToken")).Return(nil)
+	s.mailService.On("EnqueueTx", mock.Anything, user.TenantID, mailer.TemplateMagicLink, user.Email, mock.Anything).Return(nil)
+
+	err := s.service.RequestMagicLink(user.Email)
+	require.NoError(t, err)
+
+	data := s.mailService.Calls[0].Arguments.Get(4).(map[string]any)
+	assert.Equal(t, "15 minutes", data["ExpiresIn"])
+	parsed, err := url.Parse(data["Link"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", parsed.Host)
+	token := parsed.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	stored := s.repo.Calls[len(s.repo.Calls)-1].Arguments.Get(1).(*models.LoginToken)
+	assert.Equal(t, user.ID, stored.UserID)
+	assert.Equal(t, utils.LoginTokenMagicLink, stored.Kind)
+	assert.Equal(t, utils.HashToken(token), stored.TokenHash)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), stored.ExpiresAt, time.Minute)
+}
+
+func TestLoginWithMagicLink_IssuesTokens(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+	loginToken := &models.LoginToken{ID: uuid.New(), UserID: user.ID, Kind: utils.LoginTokenMagicLink}
+	tokens := &dto.LoginResponse{AccessToken: "access"}
+
+	s.repo.On("FindActiveLoginTokenByHash", utils.HashToken("link-token"), utils.LoginTokenMagicLink, mock.Anything).Return(loginToken, nil)
+	s.repo.On("ConsumeLoginToken", loginToken.ID.String(), mock.Anything).Return(nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.enable(user, true, false)
+	s.mfaService.On("BeginLogin", user).Return(nil, nil)
+	s.authService.On("IssueTokens", user).Return(tokens, nil)
+
+	response, challenge, err := s.service.LoginWithMagicLink("link-token")
+
+	require.NoError(t, err)
+	assert.Nil(t, challenge)
+	assert.Equal(t, tokens, response)
+}
+
+func TestLoginWithMagicLink_AlreadyUsed(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	loginToken := &models.LoginToken{ID: uuid.New(), UserID: uuid.New(), Kind: utils.LoginTokenMagicLink}
+
+	s.repo.On("FindActiveLoginTokenByHash", utils.HashToken("link-token"), utils.LoginTokenMagicLink, mock.Anything).Return(loginToken, nil)
+	s.repo.On("ConsumeLoginToken", loginToken.ID.String(), mock.Anything).Return(gorm.ErrRecordNotFound)
+
+	_, _, err := s.service.LoginWithMagicLink("link-token")
+
+	assert.ErrorIs(t, err, services.ErrInvalidLoginToken)
+	s.authService.AssertNotCalled(t, "IssueTokens", mock.Anything)
+}
+
+func TestLoginWithMagicLink_MFAChallenge(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+	loginToken := &models.LoginToken{ID: uuid.New(), UserID: user.ID, Kind: utils.LoginTokenMagicLink}
+	mfaChallenge := &dto.MFAChallengeResponse{MFARequired: true, MFAToken: "mfa-token"}
+
+	s.repo.On("FindActiveLoginTokenByHash", utils.HashToken("link-token"), utils.LoginTokenMagicLink, mock.Anything).Return(loginToken, nil)
+	s.repo.On("ConsumeLoginToken", loginToken.ID.String(), mock.Anything).Return(nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.enable(user, true, false)
+	s.mfaService.On("BeginLogin", user).Return(mfaChallenge, nil)
+
+	response, challenge, err := s.service.LoginWithMagicLink("link-token")
+
+	require.NoError(t, err)
+	assert.Nil(t, response)
+	assert.Equal(t, mfaChallenge, challenge)
+	s.authService.AssertNotCalled(t, "IssueTokens", mock.Anything)
+}
+
+func TestRequestEmailOTP_EmailsSixDigitCode(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.enable(user, false, true)
+	s.repo.On("CreateLoginTokenTx", mock.Anything, mock.AnythingOfType("*models.LoginToken")).Return(nil)
+	s.mailService.On("EnqueueTx", mock.Anything, user.TenantID, mailer.TemplateLoginCode, user.Email, mock.Anything).Return(nil)
+
+	err := s.service.RequestEmailOTP(user.Email)
+
+	require.NoError(t, err)
+	code := s.mailService.Calls[0].Arguments.Get(4).(map[string]any)["Code"].(string)
+	assert.Regexp(t, regexp.MustCompile(`^\d{6}$`), code)
+	stored := s.repo.Calls[len(s.repo.Calls)-1].Arguments.Get(1).(*models.LoginToken)
+	assert.Equal(t, utils.LoginTokenEmailOTP, stored.Kind)
+	assert.Equal(t, utils.HashToken(code), stored.TokenHash)
+}
+
+func TestLoginWithEmailOTP_WrongCodeCountsAttempt(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+	loginToken := &models.LoginToken{ID: uuid.New(), UserID: user.ID, Kind: utils.LoginTokenEmailOTP, TokenHash: utils.HashToken("123456")}
+
+	s.lockoutService.On("Check", user.Email, "10.0.0.1").Return(nil)
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.enable(user, false, true)
+	s.repo.On("UseLoginTokenAttempt", user.ID.String(), utils.LoginTokenEmailOTP, mock.Anything, 5).Return(loginToken, nil)
+
+	_, _, err := s.service.LoginWithEmailOTP(user.Email, "654321", "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrInvalidLoginCode)
+	s.lockoutService.AssertCalled(t, "RecordFailure", user.Email, "10.0.0.1", user)
+	s.lockoutService.AssertNotCalled(t, "RecordSuccess", mock.Anything)
+	s.repo.AssertNotCalled(t, "ConsumeLoginToken", mock.Anything, mock.Anything)
+}
+
+func TestLoginWithEmailOTP_NoAttemptsLeft(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+
+	s.lockoutService.On("Check", user.Email, "10.0.0.1").Return(nil)
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.enable(user, false, true)
+	s.repo.On("UseLoginTokenAttempt", user.ID.String(), utils.LoginTokenEmailOTP, mock.Anything, 5).Return(nil, gorm.ErrRecordNotFound)
+
+	_, _, err := s.service.LoginWithEmailOTP(user.Email, "123456", "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrInvalidLoginCode)
+	s.lockoutService.AssertCalled(t, "RecordFailure", user.Email, "10.0.0.1", user)
+	s.repo.AssertNotCalled(t, "ConsumeLoginToken", mock.Anything, mock.Anything)
+}
+
+func TestLoginWithEmailOTP_NewCodeKeepsFailureBudget(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+	loginToken := &models.LoginToken{ID: uuid.New(), UserID: user.ID, Kind: utils.LoginTokenEmailOTP, TokenHash: utils.HashToken("123456")}
+
+	s.lockoutService.On("Check", user.Email, "10.0.0.1").Return(nil).Once()
+	s.lockoutService.On("Check", user.Email, "10.0.0.1").Return(&services.LoginLockedError{RetryAfter: time.Minute})
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.enable(user, false, true)
+	s.repo.On("UseLoginTokenAttempt", user.ID.String(), utils.LoginTokenEmailOTP, mock.Anything, 5).Return(loginToken, nil)
+	s.repo.On("CreateLoginTokenTx", mock.Anything, mock.AnythingOfType("*models.LoginToken")).Return(nil)
+	s.mailService.On("EnqueueTx", mock.Anything, user.TenantID, mailer.TemplateLoginCode, user.Email, mock.Anything).Return(nil)
+
+	_, _, err := s.service.LoginWithEmailOTP(user.Email, "654321", "10.0.0.1")
+	assert.ErrorIs(t, err, services.ErrInvalidLoginCode)
+
+	require.NoError(t, s.service.RequestEmailOTP(user.Email))
+	code := s.mailService.Calls[0].Arguments.Get(4).(map[string]any)["Code"].(string)
+
+	// the account is locked out now, and the fresh code does not change that
+	_, _, err = s.service.LoginWithEmailOTP(user.Email, code, "10.0.0.1")
+
+	var locked *services.LoginLockedError
+	assert.ErrorAs(t, err, &locked)
+	s.lockoutService.AssertNotCalled(t, "RecordSuccess", mock.Anything)
+	s.lockoutService.AssertNotCalled(t, "Unlock", mock.Anything, mock.Anything)
+	s.repo.AssertNumberOfCalls(t, "UseLoginTokenAttempt", 1)
+	s.repo.AssertNotCalled(t, "ConsumeLoginToken", mock.Anything, mock.Anything)
+}
+
+func TestLoginWithEmailOTP_ValidCode(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+	loginToken := &models.LoginToken{ID: uuid.New(), UserID: user.ID, Kind: utils.LoginTokenEmailOTP, TokenHash: utils.HashToken("123456")}
+	tokens := &dto.LoginResponse{AccessToken: "access"}
+
+	s.lockoutService.On("Check", user.Email, "10.0.0.1").Return(nil)
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.enable(user, false, true)
+	s.repo.On("UseLoginTokenAttempt", user.ID.String(), utils.LoginTokenEmailOTP, mock.Anything, 5).Return(loginToken, nil)
+	s.repo.On("ConsumeLoginToken", loginToken.ID.String(), mock.Anything).Return(nil)
+	s.mfaService.On("BeginLogin", user).Return(nil, nil)
+	s.authService.On("IssueTokens", user).Return(tokens, nil)
+
+	response, _, err := s.service.LoginWithEmailOTP(user.Email, "123456", "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Equal(t, tokens, response)
+	s.lockoutService.AssertCalled(t, "RecordSuccess", user.Email)
+}
+
+func TestLoginWithEmailOTP_DisabledForTenant(t *testing.T) {
+	s := newPasswordlessTestSetup()
+	user := newMFAUser()
+
+	s.userRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	s.lockoutService.On("Check", user.Email, "10.0.0.1").Return(nil)
+	s.enable(user, true, false)
+
+	_, _, err := s.service.LoginWithEmailOTP(user.Email, "123456", "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrInvalidLoginCode)
+	s.repo.AssertNotCalled(t, "UseLoginTokenAttempt", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
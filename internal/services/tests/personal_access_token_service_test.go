@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type tokenTestSetup struct {
+	repo         *mocks.MockPersonalAccessTokenRepository
+	userRepo     *mocks.MockUserRepository
+	roleRepo     *mocks.MockRoleRepository
+	auditService *mocks.MockAuditService
+	service      services.PersonalAccessTokenService
+}
+
+func newTokenTestSetup() *tokenTestSetup {
+	s := &tokenTestSetup{
+		repo:         &mocks.MockPersonalAccessTokenRepository{},
+		userRepo:     &mocks.MockUserRepository{},
+		roleRepo:     &mocks.MockRoleRepository{},
+		auditService: &mocks.MockAuditService{},
+	}
+	s.service = services.NewPersonalAccessTokenService(s.repo, s.userRepo, s.roleRepo, s.auditService)
+	return s
+}
+
+func newTokenRole(codes ...string) *models.Role {
+	role := &models.Role{Name: "member"}
+	for _, code := range codes {
+		role.Permissions = append(role.Permissions, &models.Permission{Code: code})
+	}
+	return role
+}
+
+func newAccessToken(user *models.User) *models.PersonalAccessToken {
+	return &models.PersonalAccessToken{ID: uuid.New(), UserID: user.ID, TenantID: user.TenantID, Scopes: []string{"user:read"}}
+}
+
+func TestCreateToken_Success(t *testing.T) {
+	s := newTokenTestSetup()
+	user := newMFAUser()
+
+	s.roleRepo.On("GetRoleById", user.RoleID).Return(newTokenRole("user:read", "invite:read"), nil)
+	s.repo.On("CreateToken", mock.AnythingOfType("*models.PersonalAccessToken")).Return(nil)
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	res, err := s.service.CreateToken(user, &dto.PersonalAccessTokenRequest{
+		Name:   "ci",
+		Scopes: []string{"user:read", "invite:read", "user:read"},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(res.AccessToken, utils.PersonalAccessTokenPrefix))
+	stored := s.repo.Calls[0].Arguments.Get(0).(*models.PersonalAccessToken)
+	assert.Equal(t, utils.HashToken(res.AccessToken), stored.TokenHash)
+	assert.Equal(t, []string{"invite:read", "user:read"}, stored.Scopes)
+	assert.Equal(t, user.ID, stored.UserID)
+
+	event := s.auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditTokenCreated, event.Event)
+}
+
+func TestCreateToken_ScopeNotGranted(t *testing.T) {
+	s := newTokenTestSetup()
+	user := newMFAUser()
+
+	s.roleRepo.On("GetRoleById", user.RoleID).Return(newTokenRole("user:read"), nil)
+
+	_, err := s.service.CreateToken(user, &dto.PersonalAccessTokenRequest{Name: "ci", Scopes: []string{"user:delete"}})
+
+	requireAppError(t, err, http.StatusForbidden)
+	s.repo.AssertNotCalled(t, "CreateToken", mock.Anything)
+}
+
+func TestCreateToken_PastExpiry(t *testing.T) {
+	s := newTokenTestSetup()
+	expiresAt := time.Now().Add(-time.Hour)
+
+	_, err := s.service.CreateToken(newMFAUser(), &dto.PersonalAccessTokenRequest{
+		Name:      "ci",
+		Scopes:    []string{"user:read"},
+		ExpiresAt: &expiresAt,
+	})
+
+	requireAppError(t, err, http.StatusBadRequest)
+}
+
+func TestAuthenticateToken_Unknown(t *testing.T) {
+	s := newTokenTestSetup()
+	raw := utils.PersonalAccessTokenPrefix + "unknown"
+
+	s.repo.On("FindTokenByHash", utils.HashToken(raw)).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := s.service.Authenticate(raw)
+
+	assert.ErrorIs(t, err, services.ErrInvalidToken)
+}
+
+func TestAuthenticateToken_ExpiredOrRevoked(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	for name, token := range map[string]*models.PersonalAccessToken{
+		"expired": {ID: uuid.New(), ExpiresAt: &past},
+		"revoked": {ID: uuid.New(), RevokedAt: &past},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newTokenTestSetup()
+			s.repo.On("FindTokenByHash", mock.Anything).Return(token, nil)
+
+			_, err := s.service.Authenticate(utils.PersonalAccessTokenPrefix + "secret")
+
+			assert.ErrorIs(t, err, services.ErrInvalidToken)
+			s.repo.AssertNotCalled(t, "TouchToken", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAuthenticateToken_TouchesOnlyWhenStale(t *testing.T) {
+	s := newTokenTestSetup()
+	user := newMFAUser()
+
+	recent := time.Now().Add(-10 * time.Second)
+	fresh := newAccessToken(user)
+	fresh.LastUsedAt = &recent
+	s.repo.On("FindTokenByHash", utils.HashToken("fresh")).Return(fresh, nil)
+
+	stale := newAccessToken(user)
+	s.repo.On("FindTokenByHash", utils.HashToken("stale")).Return(stale, nil)
+	s.repo.On("TouchToken", stale.ID.String(), mock.Anything).Return(nil)
+
+	_, err := s.service.Authenticate("fresh")
+	require.NoError(t, err)
+	_, err = s.service.Authenticate("stale")
+	require.NoError(t, err)
+
+	s.repo.AssertNumberOfCalls(t, "TouchToken", 1)
+	s.repo.AssertCalled(t, "TouchToken", stale.ID.String(), mock.Anything)
+}
+
+func TestRevokeToken_OtherUsersToken(t *testing.T) {
+	s := newTokenTestSetup()
+	token := newAccessToken(newMFAUser())
+
+	s.repo.On("FindTokenById", token.ID.String()).Return(token, nil)
+
+	err := s.service.RevokeToken(newMFAUser(), token.ID.String())
+
+	requireAppError(t, err, http.StatusNotFound)
+	s.repo.AssertNotCalled(t, "RevokeToken", mock.Anything, mock.Anything)
+}
+
+func TestRevokeUserToken_SameTenant(t *testing.T) {
+	s := newTokenTestSetup()
+	admin := newMFAUser()
+	member := newMFAUser()
+	member.TenantID = admin.TenantID
+	token := newAccessToken(member)
+
+	s.repo.On("FindTokenById", token.ID.String()).Return(token, nil)
+	s.repo.On("RevokeToken", token.ID.String(), mock.Anything).Return(true, nil)
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	require.NoError(t, s.service.RevokeUserToken(admin, token.ID.String()))
+
+	event := s.auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditTokenRevoked, event.Event)
+	assert.Equal(t, admin.ID, *event.ActorID)
+	assert.Equal(t, member.ID, *event.UserID)
+}
+
+func TestRevokeToken_AuditFailureStillRevokes(t *testing.T) {
+	s := newTokenTestSetup()
+	user := newMFAUser()
+	token := newAccessToken(user)
+
+	s.repo.On("FindTokenById", token.ID.String()).Return(token, nil)
+	s.repo.On("RevokeToken", token.ID.String(), mock.Anything).Return(true, nil)
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(errors.New("db down"))
+
+	require.NoError(t, s.service.RevokeToken(user, token.ID.String()))
+	s.repo.AssertCalled(t, "RevokeToken", token.ID.String(), mock.Anything)
+}
+
+func TestRevokeUserToken_OtherTenant(t *testing.T) {
+	s := newTokenTestSetup()
+	token := newAccessToken(newMFAUser())
+
+	s.repo.On("FindTokenById", token.ID.String()).Return(token, nil)
+
+	err := s.service.RevokeUserToken(newMFAUser(), token.ID.String())
+
+	requireAppError(t, err, http.StatusNotFound)
+	s.repo.AssertNotCalled(t, "RevokeToken", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newLoadedRevocationRepo(tokens []*models.RevokedToken, users []*models.UserTokenRevocation) *mocks.MockRevocationRepository {
+	return newLoadedRevocationRepoWithSessions(tokens, users, nil)
+}
+
+func newLoadedRevocationRepoWithSessions(tokens []*models.RevokedToken, users []*models.UserTokenRevocation, sessions []*models.Session) *mocks.MockRevocationRepository {
+	repo := &mocks.MockRevocationRepository{}
+	repo.On("DeleteExpiredRevocations", mock.Anything).Return(nil)
+	repo.On("GetRevokedTokens", mock.Anything).Return(tokens, nil)
+	repo.On("GetUserTokenRevocations", mock.Anything).Return(users, nil)
+	repo.On("GetRevokedSessions", mock.Anything).Return(sessions, nil)
+	return repo
+}
+
+func TestIsRevoked_UsesCachedSnapshot(t *testing.T) {
+	userID := uuid.New()
+	repo := newLoadedRevocationRepo([]*models.RevokedToken{
+		{JTI: "revoked-jti", UserID: userID, ExpiresAt: time.Now().Add(time.Hour)},
+	}, nil)
+	revocationService := services.NewRevocationService(repo)
+
+	revoked, err := revocationService.IsRevoked(&utils.Claims{UserID: userID.String(), RegisteredClaims: jwt.RegisteredClaims{ID: "revoked-jti"}})
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = revocationService.IsRevoked(&utils.Claims{UserID: userID.String(), RegisteredClaims: jwt.RegisteredClaims{ID: "other-jti"}})
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	repo.AssertNumberOfCalls(t, "GetRevokedTokens", 1)
+}
+
+func TestRevokeToken_AppliesImmediately(t *testing.T) {
+	userID := uuid.New()
+	repo := newLoadedRevocationRepo(nil, nil)
+	repo.On("RevokeToken", mock.AnythingOfType("*models.RevokedToken")).Return(nil)
+	revocationService := services.NewRevocationService(repo)
+
+	claims := &utils.Claims{UserID: userID.String(), RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}}
+
+	revoked, _ := revocationService.IsRevoked(claims)
+	assert.False(t, revoked)
+
+	err := revocationService.RevokeToken("jti", userID, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	revoked, _ = revocationService.IsRevoked(claims)
+	assert.True(t, revoked)
+	repo.AssertNumberOfCalls(t, "GetRevokedTokens", 1)
+}
+
+func TestRevokeUserTokens_OnlyRevokesOlderTokens(t *testing.T) {
+	userID := uuid.New()
+	repo := newLoadedRevocationRepo(nil, nil)
+	repo.On("RevokeUserTokens", mock.AnythingOfType("*models.UserTokenRevocation")).Return(nil)
+	revocationService := services.NewRevocationService(repo)
+
+	older := &utils.Claims{UserID: userID.String(), RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}}
+	newer := &utils.Claims{UserID: userID.String(), RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}}
+
+	revoked, _ := revocationService.IsRevoked(older)
+	assert.False(t, revoked)
+
+	err := revocationService.RevokeUserTokens(userID)
+	assert.NoError(t, err)
+
+	revoked, _ = revocationService.IsRevoked(older)
+	assert.True(t, revoked)
+	revoked, _ = revocationService.IsRevoked(newer)
+	assert.False(t, revoked)
+}
+
+func TestRevokeUserTokens_RevokesTokensFromSameSecond(t *testing.T) {
+	userID := uuid.New()
+	repo := newLoadedRevocationRepo(nil, nil)
+	repo.On("RevokeUserTokens", mock.AnythingOfType("*models.UserTokenRevocation")).Return(nil)
+	revocationService := services.NewRevocationService(repo)
+
+	// iat is truncated to the second, like the revocation time
+	claims := &utils.Claims{UserID: userID.String(), RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now())}}
+
+	revoked, _ := revocationService.IsRevoked(claims)
+	assert.False(t, revoked)
+
+	err := revocationService.RevokeUserTokens(userID)
+	assert.NoError(t, err)
+	stored := repo.Calls[len(repo.Calls)-1].Arguments.Get(0).(*models.UserTokenRevocation)
+	assert.False(t, claims.IssuedAt.Time.After(stored.RevokedAt))
+
+	revoked, err = revocationService.IsRevoked(claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestRevokeUserTokens_OutlivesImpersonationTokens(t *testing.T) {
+	viper.Set("ACCESS_TOKEN_TTL", "5m")
+	viper.Set("IMPERSONATION_TTL", "1h")
+	t.Cleanup(func() {
+		viper.Set("ACCESS_TOKEN_TTL", "")
+		viper.Set("IMPERSONATION_TTL", "")
+	})
+
+	repo := &mocks.MockRevocationRepository{}
+	repo.On("RevokeUserTokens", mock.AnythingOfType("*models.UserTokenRevocation")).Return(nil)
+	revocationService := services.NewRevocationService(repo)
+
+	assert.NoError(t, revocationService.RevokeUserTokens(uuid.New()))
+
+	stored := repo.Calls[0].Arguments.Get(0).(*models.UserTokenRevocation)
+	assert.False(t, stored.ExpiresAt.Before(stored.RevokedAt.Add(time.Hour)))
+}
+
+func TestIsRevoked_RevokedSession(t *testing.T) {
+	userID := uuid.New()
+	revokedAt := time.Now().Add(-time.Minute)
+	loaded := &models.Session{ID: uuid.New(), RevokedAt: &revokedAt}
+	repo := newLoadedRevocationRepoWithSessions(nil, nil, []*models.Session{loaded})
+	revocationService := services.NewRevocationService(repo)
+
+	revoked, err := revocationService.IsRevoked(&utils.Claims{UserID: userID.String(), SessionID: loaded.ID.String()})
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// revocations made by this instance apply before the next reload
+	claims := &utils.Claims{UserID: userID.String(), SessionID: uuid.NewString()}
+	revoked, _ = revocationService.IsRevoked(claims)
+	assert.False(t, revoked)
+
+	revocationService.RevokeSession(claims.SessionID)
+
+	revoked, _ = revocationService.IsRevoked(claims)
+	assert.True(t, revoked)
+	revoked, _ = revocationService.IsRevoked(&utils.Claims{UserID: userID.String()})
+	assert.False(t, revoked)
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type serviceAccountTestSetup struct {
+	userRepo     *mocks.MockUserRepository
+	roleRepo     *mocks.MockRoleRepository
+	auditService *mocks.MockAuditService
+	service      services.ServiceAccountService
+}
+
+func newServiceAccountTestSetup() *serviceAccountTestSetup {
+	s := &serviceAccountTestSetup{
+		userRepo:     &mocks.MockUserRepository{},
+		roleRepo:     &mocks.MockRoleRepository{},
+		auditService: &mocks.MockAuditService{},
+	}
+	s.service = services.NewServiceAccountService(s.userRepo, s.roleRepo, s.auditService)
+	return s
+}
+
+func TestCreateServiceAccount_Success(t *testing.T) {
+	s := newServiceAccountTestSetup()
+	admin := newMFAUser()
+	role := &models.Role{ID: uuid.New(), Name: "member", TenantID: admin.TenantID}
+
+	s.roleRepo.On("GetRoleByName", admin.TenantID.String(), "member").Return(role, nil)
+	s.roleRepo.On("GetRoleById", admin.RoleID).Return(newTokenRole("user:read", "user:delete"), nil)
+	s.roleRepo.On("GetRoleById", role.ID.String()).Return(newTokenRole("user:read"), nil)
+	s.userRepo.On("CreateServiceAccount", mock.AnythingOfType("*models.User")).Return(nil)
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	account, err := s.service.CreateServiceAccount(admin, nil, &dto.ServiceAccountRequest{Name: "ci-bot", Role: "member"})
+	require.NoError(t, err)
+
+	assert.True(t, account.IsServiceAccount)
+	assert.Equal(t, admin.TenantID, account.TenantID)
+	assert.Equal(t, role.ID.String(), account.RoleID)
+	assert.Empty(t, account.PasswordHash)
+	assert.NotNil(t, account.EmailVerifiedAt)
+	assert.True(t, strings.HasPrefix(account.Email, "ci-bot@"))
+	assert.True(t, strings.HasSuffix(account.Email, "."+utils.ServiceAccountDomain))
+
+	event := s.auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditServiceAccountCreated, event.Event)
+	assert.Equal(t, admin.ID, *event.ActorID)
+	assert.Equal(t, account.ID, *event.UserID)
+}
+
+func TestCreateServiceAccount_InvalidName(t *testing.T) {
+	s := newServiceAccountTestSetup()
+
+	for _, name := range []string{"CI Bot", "bot@example.com", "-bot"} {
+		_, err := s.service.CreateServiceAccount(newMFAUser(), nil, &dto.ServiceAccountRequest{Name: name, Role: "member"})
+		requireAppError(t, err, http.StatusBadRequest)
+	}
+	s.roleRepo.AssertNotCalled(t, "GetRoleByName", mock.Anything, mock.Anything)
+}
+
+func TestCreateServiceAccount_UnknownRole(t *testing.T) {
+	s := newServiceAccountTestSetup()
+	admin := newMFAUser()
+
+	s.roleRepo.On("GetRoleByName", admin.TenantID.String(), "superadmin").Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := s.service.CreateServiceAccount(admin, nil, &dto.ServiceAccountRequest{Name: "ci-bot", Role: "superadmin"})
+
+	requireAppError(t, err, http.StatusBadRequest)
+	s.userRepo.AssertNotCalled(t, "CreateServiceAccount", mock.Anything)
+}
+
+func TestCreateServiceAccount_Exists(t *testing.T) {
+	s := newServiceAccountTestSetup()
+	admin := newMFAUser()
+
+	admin.Role.Name = utils.RoleSuperAdmin
+
+	s.roleRepo.On("GetRoleByName", admin.TenantID.String(), "member").Return(&models.Role{ID: uuid.New(), Name: "member"}, nil)
+	s.userRepo.On("CreateServiceAccount", mock.Anything).Return(&pgconn.PgError{Code: "23505"})
+
+	_, err := s.service.CreateServiceAccount(admin, nil, &dto.ServiceAccountRequest{Name: "ci-bot", Role: "member"})
+
+	requireAppError(t, err, http.StatusConflict)
+}
+
+func TestCreateServiceAccount_RoleBeyondRequestor(t *testing.T) {
+	s := newServiceAccountTestSetup()
+	admin := newMFAUser()
+	role := &models.Role{ID: uuid.New(), Name: "owner", TenantID: admin.TenantID}
+
+	s.roleRepo.On("GetRoleByName", admin.TenantID.String(), "owner").Return(role, nil)
+	s.roleRepo.On("GetRoleById", admin.RoleID).Return(newTokenRole("service-account:create", "user:read"), nil)
+	s.roleRepo.On("GetRoleById", role.ID.String()).Return(newTokenRole("user:read", "role:update"), nil)
+
+	_, err := s.service.CreateServiceAccount(admin, nil, &dto.ServiceAccountRequest{Name: "ci-bot", Role: "owner"})
+
+	requireAppError(t, err, http.StatusForbidden)
+	s.userRepo.AssertNotCalled(t, "CreateServiceAccount", mock.Anything)
+}
+
+func TestCreateServiceAccount_RoleBeyondPersonalAccessToken(t *testing.T) {
+	s := newServiceAccountTestSetup()
+	admin := newMFAUser()
+	role := &models.Role{ID: uuid.New(), Name: "member", TenantID: admin.TenantID}
+	claims := &utils.Claims{UserID: admin.ID.String(), Scope: "service-account:create", PersonalAccessTokenID: uuid.NewString()}
+
+	s.roleRepo.On("GetRoleByName", admin.TenantID.String(), "member").Return(role, nil)
+	s.roleRepo.On("GetRoleById", admin.RoleID).Return(newTokenRole("service-account:create", "user:read"), nil)
+	s.roleRepo.On("GetRoleById", role.ID.String()).Return(newTokenRole("user:read"), nil)
+
+	// the admin's role has user:read, but the token they called with does not
+	_, err := s.service.CreateServiceAccount(admin, claims, &dto.ServiceAccountRequest{Name: "ci-bot", Role: "member"})
+
+	requireAppError(t, err, http.StatusForbidden)
+	s.userRepo.AssertNotCalled(t, "CreateServiceAccount", mock.Anything)
+}
+
+func TestDeleteServiceAccount_NotInTenant(t *testing.T) {
+	s := newServiceAccountTestSetup()
+	admin := newMFAUser()
+	id := uuid.NewString()
+
+	// human users and other tenants' accounts are not found
+	s.userRepo.On("GetServiceAccountById", admin.TenantID.String(), id).Return(nil, gorm.ErrRecordNotFound)
+
+	err := s.service.DeleteServiceAccount(admin, id)
+
+	requireAppError(t, err, http.StatusNotFound)
+	s.userRepo.AssertNotCalled(t, "RemoveUserById", mock.Anything, mock.Anything)
+}
+
+func TestCreateServiceAccountToken_ScopedToAccountRole(t *testing.T) {
+	s := newTokenTestSetup()
+	admin := newMFAUser()
+	account := &models.User{ID: uuid.New(), TenantID: admin.TenantID, RoleID: uuid.NewString(), IsServiceAccount: true}
+
+	s.userRepo.On("GetServiceAccountById", admin.TenantID.String(), account.ID.String()).Return(account, nil)
+	s.roleRepo.On("GetRoleById", admin.RoleID).Return(newTokenRole("user:read", "user:delete"), nil)
+	s.roleRepo.On("GetRoleById", account.RoleID).Return(newTokenRole("user:read"), nil)
+	s.repo.On("CreateToken", mock.AnythingOfType("*models.PersonalAccessToken")).Return(nil)
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	_, err := s.service.CreateServiceAccountToken(admin, nil, account.ID.String(), &dto.PersonalAccessTokenRequest{Name: "deploy", Scopes: []string{"user:delete"}})
+	requireAppError(t, err, http.StatusForbidden)
+
+	res, err := s.service.CreateServiceAccountToken(admin, nil, account.ID.String(), &dto.PersonalAccessTokenRequest{Name: "deploy", Scopes: []string{"user:read"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, account.ID, res.Token.UserID)
+	event := s.auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, admin.ID, *event.ActorID)
+	assert.Equal(t, account.ID, *event.UserID)
+}
+
+func TestCreateServiceAccountToken_NotAServiceAccount(t *testing.T) {
+	s := newTokenTestSetup()
+	admin := newMFAUser()
+	id := uuid.NewString()
+
+	s.userRepo.On("GetServiceAccountById", admin.TenantID.String(), id).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := s.service.CreateServiceAccountToken(admin, nil, id, &dto.PersonalAccessTokenRequest{Name: "deploy", Scopes: []string{"user:read"}})
+
+	requireAppError(t, err, http.StatusNotFound)
+	s.repo.AssertNotCalled(t, "CreateToken", mock.Anything)
+}
+
+func TestCreateServiceAccountToken_LimitedToRequestor(t *testing.T) {
+	s := newTokenTestSetup()
+	admin := newMFAUser()
+	account := &models.User{ID: uuid.New(), TenantID: admin.TenantID, RoleID: uuid.NewString(), IsServiceAccount: true}
+	patClaims := &utils.Claims{UserID: admin.ID.String(), Scope: "service-account:create user:read", PersonalAccessTokenID: uuid.NewString()}
+
+	s.userRepo.On("GetServiceAccountById", admin.TenantID.String(), account.ID.String()).Return(account, nil)
+	s.roleRepo.On("GetRoleById", admin.RoleID).Return(newTokenRole("service-account:create", "user:read", "user:delete"), nil)
+	s.roleRepo.On("GetRoleById", account.RoleID).Return(newTokenRole("user:read", "user:delete", "role:update"), nil)
+
+	for name, tc := range map[string]struct {
+		claims *utils.Claims
+		scope  string
+	}{
+		"beyond role":  {nil, "role:update"},
+		"beyond token": {patClaims, "user:delete"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.service.CreateServiceAccountToken(admin, tc.claims, account.ID.String(), &dto.PersonalAccessTokenRequest{Name: "deploy", Scopes: []string{tc.scope}})
+			requireAppError(t, err, http.StatusForbidden)
+		})
+	}
+	s.repo.AssertNotCalled(t, "CreateToken", mock.Anything)
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type sessionTestSetup struct {
+	repo              *mocks.MockSessionRepository
+	userRepo          *mocks.MockUserRepository
+	revocationService *mocks.MockRevocationService
+	auditService      *mocks.MockAuditService
+	service           services.SessionService
+}
+
+func newSessionTestSetup() *sessionTestSetup {
+	s := &sessionTestSetup{
+		repo:              &mocks.MockSessionRepository{},
+		userRepo:          &mocks.MockUserRepository{},
+		revocationService: &mocks.MockRevocationService{},
+		auditService:      &mocks.MockAuditService{},
+	}
+	s.service = services.NewSessionService(s.repo, s.userRepo, s.revocationService, s.auditService)
+	return s
+}
+
+func newSession(user *models.User) *models.Session {
+	return &models.Session{ID: uuid.New(), UserID: user.ID, TenantID: user.TenantID, IP: "10.0.0.1"}
+}
+
+func requireAppError(t *testing.T, err error, code int) {
+	t.Helper()
+
+	var appErr *utils.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, code, appErr.Code)
+}
+
+func TestRevokeSession_OwnSession(t *testing.T) {
+	s := newSessionTestSetup()
+	user := newMFAUser()
+	session := newSession(user)
+
+	s.repo.On("FindActiveSession", session.ID.String(), mock.Anything).Return(session, nil)
+	s.repo.On("RevokeSession", session.ID.String(), mock.Anything).Return(true, nil)
+	s.revocationService.On("RevokeSession", session.ID.String()).Return()
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	require.NoError(t, s.service.RevokeSession(user, session.ID.String()))
+
+	s.revocationService.AssertExpectations(t)
+	event := s.auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, utils.AuditSessionRevoked, event.Event)
+	assert.Equal(t, user.ID, *event.ActorID)
+	assert.Equal(t, user.ID, *event.UserID)
+}
+
+func TestRevokeSession_OtherUsersSession(t *testing.T) {
+	s := newSessionTestSetup()
+	user := newMFAUser()
+	session := newSession(newMFAUser())
+
+	s.repo.On("FindActiveSession", session.ID.String(), mock.Anything).Return(session, nil)
+
+	err := s.service.RevokeSession(user, session.ID.String())
+
+	requireAppError(t, err, http.StatusNotFound)
+	s.repo.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+}
+
+func TestRevokeSession_AlreadyRevoked(t *testing.T) {
+	s := newSessionTestSetup()
+	user := newMFAUser()
+	session := newSession(user)
+
+	s.repo.On("FindActiveSession", session.ID.String(), mock.Anything).Return(session, nil)
+	s.repo.On("RevokeSession", session.ID.String(), mock.Anything).Return(false, nil)
+
+	require.NoError(t, s.service.RevokeSession(user, session.ID.String()))
+	s.auditService.AssertNotCalled(t, "Record", mock.Anything)
+}
+
+func TestRevokeUserSession_OtherTenant(t *testing.T) {
+	s := newSessionTestSetup()
+	admin := newMFAUser()
+	session := newSession(newMFAUser())
+
+	s.repo.On("FindActiveSession", session.ID.String(), mock.Anything).Return(session, nil)
+
+	err := s.service.RevokeUserSession(admin, session.ID.String())
+
+	requireAppError(t, err, http.StatusNotFound)
+}
+
+func TestRevokeUserSession_TenantMember(t *testing.T) {
+	s := newSessionTestSetup()
+	admin := newMFAUser()
+	member := newMFAUser()
+	member.TenantID = admin.TenantID
+	session := newSession(member)
+
+	s.repo.On("FindActiveSession", session.ID.String(), mock.Anything).Return(session, nil)
+	s.repo.On("RevokeSession", session.ID.String(), mock.Anything).Return(true, nil)
+	s.revocationService.On("RevokeSession", session.ID.String()).Return()
+	s.auditService.On("Record", mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	require.NoError(t, s.service.RevokeUserSession(admin, session.ID.String()))
+
+	event := s.auditService.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+	assert.Equal(t, admin.ID, *event.ActorID)
+	assert.Equal(t, member.ID, *event.UserID)
+}
+
+func TestRevokeSession_UnknownSession(t *testing.T) {
+	s := newSessionTestSetup()
+	user := newMFAUser()
+	session_id := uuid.NewString()
+
+	s.repo.On("FindActiveSession", session_id, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	requireAppError(t, s.service.RevokeSession(user, session_id), http.StatusNotFound)
+	requireAppError(t, s.service.RevokeSession(user, "not-a-uuid"), http.StatusNotFound)
+}
+
+func TestGetUserSessions_UnknownUser(t *testing.T) {
+	s := newSessionTestSetup()
+	admin := newMFAUser()
+	user_id := uuid.NewString()
+
+	s.userRepo.On("GetUserById", admin.TenantID.String(), user_id).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := s.service.GetUserSessions(admin, user_id)
+
+	requireAppError(t, err, http.StatusNotFound)
+	s.repo.AssertNotCalled(t, "GetActiveSessions", mock.Anything, mock.Anything)
+}
+
+func TestFlushSessions_WritesLatestActivityOnce(t *testing.T) {
+	s := newSessionTestSetup()
+	first := uuid.New()
+	second := uuid.New()
+
+	s.service.Touch(first.String(), "10.0.0.1", "curl/8.0")
+	s.service.Touch(first.String(), "10.0.0.2", "Firefox")
+	s.service.Touch(second.String(), "10.0.0.3", "Safari")
+	s.service.Touch("not-a-uuid", "10.0.0.4", "Chrome")
+	s.repo.On("TouchSessions", mock.Anything).Return(nil)
+
+	flushed, err := s.service.Flush()
+	require.NoError(t, err)
+	assert.Equal(t, 2, flushed)
+
+	touched := make(map[uuid.UUID]*models.Session)
+	for _, session := range s.repo.Calls[0].Arguments.Get(0).([]*models.Session) {
+		touched[session.ID] = session
+	}
+	require.Len(t, touched, 2)
+	assert.Equal(t, "10.0.0.2", touched[first].IP)
+	assert.Equal(t, "Firefox", touched[first].UserAgent)
+	assert.False(t, touched[first].LastSeenAt.IsZero())
+
+	// nothing new to write
+	flushed, err = s.service.Flush()
+	require.NoError(t, err)
+	assert.Zero(t, flushed)
+	s.repo.AssertNumberOfCalls(t, "TouchSessions", 1)
+}
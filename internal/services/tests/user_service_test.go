@@ -2,16 +2,23 @@ package tests
 
 import (
 	"errors"
+	"net/http"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
 	"github.com/samvibes/vexop/auth-service/internal/models"
 	"github.com/samvibes/vexop/auth-service/internal/services"
 	"github.com/samvibes/vexop/auth-service/internal/services/mocks"
 	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestFindUserByEmail_Success(t *testing.T) {
@@ -19,7 +26,7 @@ func TestFindUserByEmail_Success(t *testing.T) {
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	authService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, authService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, authService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	email := "testuser@mail.com"
 
@@ -42,7 +49,7 @@ func TestFindUserByEmail_Failure(t *testing.T) {
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	email := "testuser@mail.com"
 
@@ -62,7 +69,9 @@ func TestCreateUser_Success(t *testing.T) {
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	mockVerification := &mocks.MockEmailVerificationService{}
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, mockVerification, &mocks.MockLockoutService{}, mockPasswordPolicy)
 
 	userID := uuid.New()
 	tenantID := uuid.New()
@@ -98,6 +107,8 @@ func TestCreateUser_Success(t *testing.T) {
 	mockPermissionRepo.On("CopyPermissionsTx", mock.Anything, tenantID.String()).Return(permissionMap, nil)
 	mockRoleRepo.On("CopyRolesTx", mock.Anything, tenantID.String(), &permissionMap).Return(roles, nil)
 	mockUserRepo.On("CreateUserTx", mock.Anything, user).Return(nil)
+	mockVerification.On("SendVerificationTx", mock.Anything, user).Return(nil)
+	mockPasswordPolicy.On("RecordTx", mock.Anything, user).Return(nil)
 
 	err := userService.CreateUser(user, db)
 
@@ -106,6 +117,7 @@ func TestCreateUser_Success(t *testing.T) {
 	mockRoleRepo.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 	mockUserRepo.AssertCalled(t, "CreateUserTx", mock.Anything, user)
+	mockVerification.AssertExpectations(t)
 }
 
 func TestCreateUser_Failure(t *testing.T) {
@@ -115,7 +127,7 @@ func TestCreateUser_Failure(t *testing.T) {
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	userID := uuid.New()
 	tenantID := uuid.New()
@@ -164,7 +176,11 @@ func TestLogin_Success(t *testing.T) {
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	mockMFAService := &mocks.MockMFAService{}
+	mockVerification := &mocks.MockEmailVerificationService{}
+	mockLockout := &mocks.MockLockoutService{}
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, mockMFAService, &mocks.MockMailService{}, &mocks.MockTransactor{}, mockVerification, mockLockout, mockPasswordPolicy)
 
 	email := "testuser@mail.com"
 	password := "password"
@@ -173,18 +189,186 @@ func TestLogin_Success(t *testing.T) {
 		PasswordHash: "PasswordHash",
 	}
 
-	token := "123"
+	tokens := &dto.LoginResponse{AccessToken: "123", RefreshToken: "456"}
 
 	mockUserRepo.On("FindUserByEmail", email).Return(expectedUser, nil)
 	mockAuthService.On("CompareHashAndPassword", []byte(password), []byte(expectedUser.PasswordHash)).Return(true)
-	mockAuthService.On("GenerateJWT", mock.Anything).Return(token, nil)
+	mockAuthService.On("PasswordNeedsRehash", mock.Anything).Return(false)
+	mockVerification.On("CheckLogin", expectedUser).Return(nil)
+	mockMFAService.On("BeginLogin", expectedUser).Return(nil, nil)
+	mockAuthService.On("IssueTokens", mock.Anything).Return(tokens, nil)
+	mockLockout.On("Check", email, "10.0.0.1").Return(nil)
+	mockLockout.On("RecordSuccess", email).Return(nil)
+	mockPasswordPolicy.On("CheckExpiry", expectedUser).Return(nil)
 
-	result, err := userService.Login(email, password)
+	result, challenge, err := userService.Login(email, password, "10.0.0.1")
 
 	assert.NoError(t, err)
-	require.NotEmpty(t, result)
-	mockAuthService.AssertCalled(t, "GenerateJWT", expectedUser)
+	assert.Nil(t, challenge)
+	require.NotNil(t, result)
+	assert.Equal(t, tokens.AccessToken, result.AccessToken)
+	mockAuthService.AssertCalled(t, "IssueTokens", expectedUser)
 	mockAuthService.AssertExpectations(t)
+	mockLockout.AssertCalled(t, "RecordSuccess", email)
+}
+
+func TestLogin_MFAChallenge(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockMFAService := &mocks.MockMFAService{}
+	mockVerification := &mocks.MockEmailVerificationService{}
+	mockLockout := &mocks.MockLockoutService{}
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, mockMFAService, &mocks.MockMailService{}, &mocks.MockTransactor{}, mockVerification, mockLockout, mockPasswordPolicy)
+
+	user := &models.User{Email: "mfa@mail.com", PasswordHash: "PasswordHash"}
+	challenge := &dto.MFAChallengeResponse{MFARequired: true, MFAToken: "challenge"}
+
+	mockUserRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	mockAuthService.On("CompareHashAndPassword", []byte("password"), []byte(user.PasswordHash)).Return(true)
+	mockAuthService.On("PasswordNeedsRehash", mock.Anything).Return(false)
+	mockVerification.On("CheckLogin", user).Return(nil)
+	mockMFAService.On("BeginLogin", user).Return(challenge, nil)
+	mockLockout.On("Check", user.Email, "10.0.0.1").Return(nil)
+	mockPasswordPolicy.On("CheckExpiry", user).Return(nil)
+
+	tokens, result, err := userService.Login(user.Email, "password", "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Nil(t, tokens)
+	assert.Equal(t, challenge, result)
+	mockAuthService.AssertNotCalled(t, "IssueTokens", mock.Anything)
+	// failures are only cleared once the second factor passes
+	mockLockout.AssertNotCalled(t, "RecordSuccess", mock.Anything)
+}
+
+func TestLogin_EmailNotVerified(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockMFAService := &mocks.MockMFAService{}
+	mockVerification := &mocks.MockEmailVerificationService{}
+	mockLockout := &mocks.MockLockoutService{}
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, mockMFAService, &mocks.MockMailService{}, &mocks.MockTransactor{}, mockVerification, mockLockout, mockPasswordPolicy)
+
+	user := &models.User{Email: "unverified@mail.com", PasswordHash: "PasswordHash"}
+
+	mockUserRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	mockAuthService.On("CompareHashAndPassword", []byte("password"), []byte(user.PasswordHash)).Return(true)
+	mockAuthService.On("PasswordNeedsRehash", mock.Anything).Return(false)
+	mockVerification.On("CheckLogin", user).Return(services.ErrEmailNotVerified)
+	mockLockout.On("Check", user.Email, "10.0.0.1").Return(nil)
+	mockPasswordPolicy.On("CheckExpiry", user).Return(nil)
+
+	tokens, challenge, err := userService.Login(user.Email, "password", "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrEmailNotVerified)
+	assert.Nil(t, tokens)
+	assert.Nil(t, challenge)
+	mockMFAService.AssertNotCalled(t, "BeginLogin", mock.Anything)
+	mockAuthService.AssertNotCalled(t, "IssueTokens", mock.Anything)
+}
+
+func TestAuthenticate_UnknownEmailComparesDummyHash(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockLockout := &mocks.MockLockoutService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, mockLockout, &mocks.MockPasswordPolicyService{})
+
+	mockLockout.On("Check", "nobody@mail.com", "10.0.0.1").Return(nil)
+	mockUserRepo.On("FindUserByEmail", "nobody@mail.com").Return(nil, gorm.ErrRecordNotFound)
+	mockAuthService.On("CompareHashAndPassword", []byte("password"), mock.Anything).Return(false)
+	mockLockout.On("RecordFailure", "nobody@mail.com", "10.0.0.1", (*models.User)(nil)).Return(nil)
+
+	user, err := userService.Authenticate("nobody@mail.com", "password", "10.0.0.1")
+
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	// unknown addresses cost a hash comparison too, so timing does not tell them apart
+	mockAuthService.AssertNumberOfCalls(t, "CompareHashAndPassword", 1)
+	mockLockout.AssertExpectations(t)
+}
+
+func TestAuthenticate_RehashesOutdatedHash(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockLockout := &mocks.MockLockoutService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, mockLockout, &mocks.MockPasswordPolicyService{})
+
+	user := newMFAUser()
+	user.PasswordHash = "$2a$10$old"
+
+	mockLockout.On("Check", user.Email, "10.0.0.1").Return(nil)
+	mockUserRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	mockAuthService.On("CompareHashAndPassword", []byte("password"), []byte("$2a$10$old")).Return(true)
+	mockAuthService.On("PasswordNeedsRehash", "$2a$10$old").Return(true)
+	mockAuthService.On("HashPassword", "password").Return("$argon2id$new", nil)
+	mockUserRepo.On("RehashPassword", user.ID.String(), "$2a$10$old", "$argon2id$new").Return(nil)
+
+	result, err := userService.Authenticate(user.Email, "password", "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "$argon2id$new", result.PasswordHash)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAuthenticate_RehashFailureStillLogsIn(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockLockout := &mocks.MockLockoutService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, mockLockout, &mocks.MockPasswordPolicyService{})
+
+	user := newMFAUser()
+	user.PasswordHash = "$2a$10$old"
+
+	mockLockout.On("Check", user.Email, "10.0.0.1").Return(nil)
+	mockUserRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	mockAuthService.On("CompareHashAndPassword", []byte("password"), []byte("$2a$10$old")).Return(true)
+	mockAuthService.On("PasswordNeedsRehash", "$2a$10$old").Return(true)
+	mockAuthService.On("HashPassword", "password").Return("$argon2id$new", nil)
+	mockUserRepo.On("RehashPassword", user.ID.String(), "$2a$10$old", "$argon2id$new").Return(errors.New("db down"))
+
+	result, err := userService.Authenticate(user.Email, "password", "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "$2a$10$old", result.PasswordHash)
+}
+
+func TestAuthenticate_WrongPasswordRecordsFailure(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockLockout := &mocks.MockLockoutService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, mockLockout, &mocks.MockPasswordPolicyService{})
+
+	user := &models.User{ID: uuid.New(), Email: "user@mail.com", PasswordHash: "PasswordHash"}
+
+	mockLockout.On("Check", user.Email, "10.0.0.1").Return(nil)
+	mockUserRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	mockAuthService.On("CompareHashAndPassword", []byte("wrong"), []byte(user.PasswordHash)).Return(false)
+	mockLockout.On("RecordFailure", user.Email, "10.0.0.1", user).Return(nil)
+
+	_, err := userService.Authenticate(user.Email, "wrong", "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	mockLockout.AssertExpectations(t)
+	mockLockout.AssertNotCalled(t, "RecordSuccess", mock.Anything)
+}
+
+func TestAuthenticate_Locked(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockLockout := &mocks.MockLockoutService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, mockLockout, &mocks.MockPasswordPolicyService{})
+
+	mockLockout.On("Check", "user@mail.com", "10.0.0.1").Return(&services.LoginLockedError{RetryAfter: time.Minute})
+
+	_, err := userService.Authenticate("user@mail.com", "password", "10.0.0.1")
+
+	var locked *services.LoginLockedError
+	require.ErrorAs(t, err, &locked)
+	assert.Equal(t, time.Minute, locked.RetryAfter)
+	mockUserRepo.AssertNotCalled(t, "FindUserByEmail", mock.Anything)
+	mockAuthService.AssertNotCalled(t, "CompareHashAndPassword", mock.Anything, mock.Anything)
 }
 
 func TestRemoveUserById_Success(t *testing.T) {
@@ -192,16 +376,18 @@ func TestRemoveUserById_Success(t *testing.T) {
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	tenant_id := "tenant_id"
 	user_id := "user_id"
 	mockUserRepo.On("RemoveUserById", tenant_id, user_id).Return(nil)
+	mockAuthService.On("RevokeUserTokens", user_id).Return(nil)
 
 	err := userService.RemoveUserById(tenant_id, user_id)
 
 	assert.Nil(t, err)
 	mockUserRepo.AssertCalled(t, "RemoveUserById", tenant_id, user_id)
+	mockAuthService.AssertCalled(t, "RevokeUserTokens", user_id)
 	mockUserRepo.AssertExpectations(t)
 }
 
@@ -210,7 +396,7 @@ func TestRemoveUserById_Failure(t *testing.T) {
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	tenant_id := "tenant_id"
 	user_id := "user_id"
@@ -220,43 +406,65 @@ func TestRemoveUserById_Failure(t *testing.T) {
 
 	assert.NotNil(t, err)
 	mockUserRepo.AssertCalled(t, "RemoveUserById", tenant_id, user_id)
+	mockAuthService.AssertNotCalled(t, "RevokeUserTokens", user_id)
 	mockUserRepo.AssertExpectations(t)
 }
 
 func TestInitResetPassword_Success(t *testing.T) {
+	viper.Set("RESET_PASSWORD_URL", "https://app.example.com/reset")
+	t.Cleanup(func() { viper.Set("RESET_PASSWORD_URL", "") })
+
 	mockUserRepo := &mocks.MockUserRepository{}
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	mockMailService := &mocks.MockMailService{}
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, mockMailService, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	email := "testuser@mail.com"
 	userId := uuid.New()
+	tenantId := uuid.New()
 
 	expectedUser := &models.User{
-		ID:    userId,
-		Email: email,
+		ID:       userId,
+		TenantID: &tenantId,
+		Email:    email,
 	}
 
 	mockUserRepo.On("FindUserByEmail", email).Return(expectedUser, nil)
+	mockUserRepo.On("SetResetPasswordTokenHashTx", mock.Anything, userId.String(), mock.Anything, mock.Anything).Return(nil)
+	mockMailService.On("EnqueueTx", mock.Anything, &tenantId, mailer.TemplateResetPassword, email, mock.Anything).Return(nil)
+
+	err := userService.InitResetPassword(email)
+
+	require.NoError(t, err)
+	data := mockMailService.Calls[0].Arguments.Get(4).(map[string]any)
+	assert.Equal(t, "1 hour", data["ExpiresIn"])
+	link, err := url.Parse(data["Link"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", link.Host)
+	token := link.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	stored := mockUserRepo.Calls[1].Arguments
+	assert.Equal(t, utils.HashToken(token), stored.Get(2))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), stored.Get(3).(time.Time), time.Minute)
+	mockUserRepo.AssertExpectations(t)
+	mockMailService.AssertExpectations(t)
+}
 
-	rawToken := "rawToken"
-	hashedToken := "hashedToken"
-	utils.CreateRandomToken = func() (string, string, error) {
-		return rawToken, hashedToken, nil
-	}
-	defer func() {
-		utils.CreateRandomToken = utils.GenerateRandomToken
-	}()
+func TestInitResetPassword_UnknownEmail(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockMailService := &mocks.MockMailService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, &mocks.MockAuthService{}, &mocks.MockMFAService{}, mockMailService, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
-	mockUserRepo.On("SetResetPasswordTokenHash", userId.String(), hashedToken).Return(nil)
+	mockUserRepo.On("FindUserByEmail", "nobody@mail.com").Return(nil, gorm.ErrRecordNotFound)
 
-	token, err := userService.InitResetPassword(email)
+	err := userService.InitResetPassword("nobody@mail.com")
 
-	assert.Nil(t, err)
-	assert.Equal(t, rawToken, token)
-	mockUserRepo.AssertCalled(t, "SetResetPasswordTokenHash", userId.String(), hashedToken)
-	mockUserRepo.AssertExpectations(t)
+	assert.NoError(t, err)
+	mockUserRepo.AssertNotCalled(t, "SetResetPasswordTokenHashTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockMailService.AssertNotCalled(t, "EnqueueTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestInitResetPassword_Failure(t *testing.T) {
@@ -264,7 +472,8 @@ func TestInitResetPassword_Failure(t *testing.T) {
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	mockMailService := &mocks.MockMailService{}
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, mockMailService, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	email := "testuser@mail.com"
 	userId := uuid.New()
@@ -275,23 +484,12 @@ func TestInitResetPassword_Failure(t *testing.T) {
 	}
 
 	mockUserRepo.On("FindUserByEmail", email).Return(expectedUser, nil)
+	mockUserRepo.On("SetResetPasswordTokenHashTx", mock.Anything, userId.String(), mock.Anything, mock.Anything).Return(errors.New("error"))
 
-	rawToken := "rawToken"
-	hashedToken := "hashedToken"
-	utils.CreateRandomToken = func() (string, string, error) {
-		return rawToken, hashedToken, nil
-	}
-	defer func() {
-		utils.CreateRandomToken = utils.GenerateRandomToken
-	}()
-
-	mockUserRepo.On("SetResetPasswordTokenHash", userId.String(), hashedToken).Return(errors.New("error"))
-
-	token, err := userService.InitResetPassword(email)
+	err := userService.InitResetPassword(email)
 
 	assert.NotNil(t, err)
-	assert.Equal(t, "", token)
-	mockUserRepo.AssertCalled(t, "SetResetPasswordTokenHash", userId.String(), hashedToken)
+	mockMailService.AssertNotCalled(t, "EnqueueTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	mockUserRepo.AssertExpectations(t)
 }
 
@@ -300,90 +498,87 @@ func TestResetPassword_Success(t *testing.T) {
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	mockMailService := &mocks.MockMailService{}
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, mockMailService, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, mockPasswordPolicy)
 
 	tenantId := uuid.New()
 	userId := uuid.New()
 	token := "token"
 	newPassword := "newPassword"
-	resetPasswordTokenHash := "resetPasswordTokenHash"
+	tokenHash := utils.HashToken(token)
 
 	user := &models.User{
 		ID:                     userId,
 		TenantID:               &tenantId,
+		Email:                  "testuser@mail.com",
 		PasswordHash:           "hashedPassword",
-		ResetPasswordTokenHash: resetPasswordTokenHash,
+		ResetPasswordTokenHash: tokenHash,
 	}
 
-	mockUserRepo.On("GetUserById", tenantId.String(), userId.String()).Return(user, nil)
-	mockAuthService.On("CompareHashAndPassword", []byte(token), []byte(user.ResetPasswordTokenHash)).Return(true)
-	mockAuthService.On("HashPassword", newPassword).Return("hashedPassword", nil)
-	mockUserRepo.On("UpdateUser", user).Return(nil)
+	mockUserRepo.On("FindUserByResetTokenHash", tokenHash, mock.Anything).Return(user, nil)
+	mockAuthService.On("HashPassword", newPassword).Return("newHashedPassword", nil)
+	mockUserRepo.On("ResetPasswordTx", mock.Anything, userId.String(), tokenHash, "newHashedPassword", mock.Anything).Return(nil)
+	mockMailService.On("EnqueueTx", mock.Anything, &tenantId, mailer.TemplateSecurityAlert, user.Email, mock.Anything).Return(nil)
+	mockAuthService.On("RevokeUserTokens", userId.String()).Return(nil)
+	mockPasswordPolicy.On("Validate", user, newPassword).Return(nil)
+	mockPasswordPolicy.On("RecordTx", mock.Anything, user).Return(nil)
 
-	err := userService.ResetPassword(tenantId.String(), userId.String(), token, newPassword)
+	err := userService.ResetPassword(token, newPassword)
 
 	assert.Nil(t, err)
-	mockUserRepo.AssertCalled(t, "GetUserById", tenantId.String(), userId.String())
-	mockAuthService.AssertCalled(t, "CompareHashAndPassword", []byte(token), []byte(resetPasswordTokenHash))
-	mockAuthService.AssertCalled(t, "HashPassword", newPassword)
-	mockUserRepo.AssertCalled(t, "UpdateUser", user)
-
 	mockUserRepo.AssertExpectations(t)
 	mockAuthService.AssertExpectations(t)
+	mockMailService.AssertExpectations(t)
 }
 
-func TestResetPassword_InvalidUser_Failed(t *testing.T) {
+func TestResetPassword_InvalidToken_Failed(t *testing.T) {
 	mockUserRepo := &mocks.MockUserRepository{}
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
-	tenantId := uuid.New()
-	userId := uuid.New()
 	token := "token"
 	newPassword := "newPassword"
 
-	mockUserRepo.On("GetUserById", tenantId.String(), userId.String()).Return(nil, errors.New("user not found"))
+	mockUserRepo.On("FindUserByResetTokenHash", utils.HashToken(token), mock.Anything).Return(nil, gorm.ErrRecordNotFound)
 
-	err := userService.ResetPassword(tenantId.String(), userId.String(), token, newPassword)
+	err := userService.ResetPassword(token, newPassword)
 
-	assert.NotNil(t, err)
-	mockUserRepo.AssertCalled(t, "GetUserById", tenantId.String(), userId.String())
+	assert.ErrorIs(t, err, services.ErrInvalidResetToken)
+	mockAuthService.AssertNotCalled(t, "HashPassword", mock.Anything)
+	mockAuthService.AssertNotCalled(t, "RevokeUserTokens", mock.Anything)
 	mockUserRepo.AssertExpectations(t)
 }
 
-func TestResetPassword_InvalidToken_Failed(t *testing.T) {
+func TestResetPassword_TokenAlreadyUsed_Failed(t *testing.T) {
 	mockUserRepo := &mocks.MockUserRepository{}
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	mockMailService := &mocks.MockMailService{}
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, mockMailService, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, mockPasswordPolicy)
 
-	tenantId := uuid.New()
 	userId := uuid.New()
 	token := "token"
 	newPassword := "newPassword"
-	resetPasswordTokenHash := "resetPasswordTokenHash"
+	tokenHash := utils.HashToken(token)
 
-	user := &models.User{
-		ID:                     userId,
-		TenantID:               &tenantId,
-		PasswordHash:           "hashedPassword",
-		ResetPasswordTokenHash: resetPasswordTokenHash,
-	}
+	user := &models.User{ID: userId, ResetPasswordTokenHash: tokenHash}
 
-	mockUserRepo.On("GetUserById", tenantId.String(), userId.String()).Return(user, nil)
-	mockAuthService.On("CompareHashAndPassword", []byte(token), []byte(user.ResetPasswordTokenHash)).Return(false)
+	// a concurrent reset consumed the token between the lookup and the update
+	mockUserRepo.On("FindUserByResetTokenHash", tokenHash, mock.Anything).Return(user, nil)
+	mockAuthService.On("HashPassword", newPassword).Return("newHashedPassword", nil)
+	mockUserRepo.On("ResetPasswordTx", mock.Anything, userId.String(), tokenHash, "newHashedPassword", mock.Anything).Return(gorm.ErrRecordNotFound)
+	mockPasswordPolicy.On("Validate", user, newPassword).Return(nil)
 
-	err := userService.ResetPassword(tenantId.String(), userId.String(), token, newPassword)
+	err := userService.ResetPassword(token, newPassword)
 
-	assert.NotNil(t, err)
-	mockUserRepo.AssertCalled(t, "GetUserById", tenantId.String(), userId.String())
-	mockAuthService.AssertCalled(t, "CompareHashAndPassword", []byte(token), []byte(resetPasswordTokenHash))
-
-	mockUserRepo.AssertExpectations(t)
-	mockAuthService.AssertExpectations(t)
+	assert.ErrorIs(t, err, services.ErrInvalidResetToken)
+	mockMailService.AssertNotCalled(t, "EnqueueTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockAuthService.AssertNotCalled(t, "RevokeUserTokens", mock.Anything)
 }
 
 func TestResetPassword_UpdateUser_Failure(t *testing.T) {
@@ -391,36 +586,133 @@ func TestResetPassword_UpdateUser_Failure(t *testing.T) {
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, mockPasswordPolicy)
 
-	tenantId := uuid.New()
 	userId := uuid.New()
 	token := "token"
 	newPassword := "newPassword"
-	resetPasswordTokenHash := "resetPasswordTokenHash"
+	tokenHash := utils.HashToken(token)
 
-	user := &models.User{
-		ID:                     userId,
-		TenantID:               &tenantId,
-		PasswordHash:           "hashedPassword",
-		ResetPasswordTokenHash: resetPasswordTokenHash,
-	}
+	user := &models.User{ID: userId, ResetPasswordTokenHash: tokenHash}
 
-	mockUserRepo.On("GetUserById", tenantId.String(), userId.String()).Return(user, nil)
-	mockAuthService.On("CompareHashAndPassword", []byte(token), []byte(user.ResetPasswordTokenHash)).Return(true)
-	mockAuthService.On("HashPassword", newPassword).Return("hashedPassword", nil)
-	mockUserRepo.On("UpdateUser", user).Return(errors.New("user update failed"))
+	mockUserRepo.On("FindUserByResetTokenHash", tokenHash, mock.Anything).Return(user, nil)
+	mockAuthService.On("HashPassword", newPassword).Return("newHashedPassword", nil)
+	mockUserRepo.On("ResetPasswordTx", mock.Anything, userId.String(), tokenHash, "newHashedPassword", mock.Anything).Return(errors.New("user update failed"))
+	mockPasswordPolicy.On("Validate", user, newPassword).Return(nil)
 
-	err := userService.ResetPassword(tenantId.String(), userId.String(), token, newPassword)
+	err := userService.ResetPassword(token, newPassword)
 
 	assert.NotNil(t, err)
-	mockUserRepo.AssertCalled(t, "GetUserById", tenantId.String(), userId.String())
-	mockAuthService.AssertCalled(t, "CompareHashAndPassword", []byte(token), []byte(resetPasswordTokenHash))
-	mockAuthService.AssertCalled(t, "HashPassword", newPassword)
-	mockUserRepo.AssertCalled(t, "UpdateUser", user)
+	assert.NotErrorIs(t, err, services.ErrInvalidResetToken)
+	mockAuthService.AssertNotCalled(t, "RevokeUserTokens", mock.Anything)
+	mockUserRepo.AssertExpectations(t)
+}
 
+func TestChangePassword_Success(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockMailService := &mocks.MockMailService{}
+	mockLockout := &mocks.MockLockoutService{}
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, mockMailService, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, mockLockout, mockPasswordPolicy)
+
+	user := newMFAUser()
+	user.PasswordHash = "oldHash"
+
+	mockLockout.On("Check", user.Email, "10.0.0.1").Return(nil)
+	mockUserRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	mockAuthService.On("CompareHashAndPassword", []byte("old password"), []byte("oldHash")).Return(true)
+	mockAuthService.On("PasswordNeedsRehash", mock.Anything).Return(false)
+	mockPasswordPolicy.On("Validate", user, "new password").Return(nil)
+	mockAuthService.On("HashPassword", "new password").Return("newHash", nil)
+	mockUserRepo.On("UpdatePasswordTx", mock.Anything, user.ID.String(), "newHash", mock.Anything).Return(nil)
+	mockPasswordPolicy.On("RecordTx", mock.Anything, user).Return(nil)
+	mockMailService.On("EnqueueTx", mock.Anything, user.TenantID, mailer.TemplateSecurityAlert, user.Email, mock.Anything).Return(nil)
+	mockAuthService.On("RevokeUserTokens", user.ID.String()).Return(nil)
+
+	err := userService.ChangePassword(user.Email, "old password", "new password", "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "newHash", user.PasswordHash)
 	mockUserRepo.AssertExpectations(t)
 	mockAuthService.AssertExpectations(t)
+	mockPasswordPolicy.AssertExpectations(t)
+}
+
+func TestChangePassword_WeakPassword(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	mockLockout := &mocks.MockLockoutService{}
+	mockPasswordPolicy := &mocks.MockPasswordPolicyService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, mockLockout, mockPasswordPolicy)
+
+	user := newMFAUser()
+	user.PasswordHash = "oldHash"
+	policyErr := &services.PasswordPolicyError{Violations: []services.PasswordViolation{{Rule: utils.PasswordRuleHistory}}}
+
+	mockLockout.On("Check", user.Email, "10.0.0.1").Return(nil)
+	mockUserRepo.On("FindUserByEmail", user.Email).Return(user, nil)
+	mockAuthService.On("CompareHashAndPassword", []byte("old password"), []byte("oldHash")).Return(true)
+	mockAuthService.On("PasswordNeedsRehash", mock.Anything).Return(false)
+	mockPasswordPolicy.On("Validate", user, "old password").Return(policyErr)
+
+	err := userService.ChangePassword(user.Email, "old password", "old password", "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrWeakPassword)
+	mockAuthService.AssertNotCalled(t, "HashPassword", mock.Anything)
+	mockUserRepo.AssertNotCalled(t, "UpdatePasswordTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportUsers_Success(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockRoleRepo := &mocks.MockRoleRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
+
+	requestor := newMFAUser()
+	role := &models.Role{ID: uuid.New(), Name: "member"}
+	changedAt := time.Now().Add(-30 * 24 * time.Hour)
+	req := &dto.ImportUsersRequest{Users: []dto.ImportUserRequest{
+		{Email: "a@example.com", PasswordHash: "$scrypt$a", Role: "member", EmailVerified: true},
+		{Email: "b@example.com", PasswordHash: "$pbkdf2-sha256$b", Role: "member", PasswordChangedAt: &changedAt},
+	}}
+
+	mockAuthService.On("SupportsPasswordHash", mock.Anything).Return(true)
+	mockRoleRepo.On("GetRoleByName", requestor.TenantID.String(), "member").Return(role, nil).Once()
+	mockUserRepo.On("CreateUserTx", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
+
+	imported, err := userService.ImportUsers(requestor, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, imported)
+	first := mockUserRepo.Calls[0].Arguments.Get(1).(*models.User)
+	assert.Equal(t, "$scrypt$a", first.PasswordHash)
+	assert.Equal(t, requestor.TenantID, first.TenantID)
+	assert.Equal(t, role.ID.String(), first.RoleID)
+	assert.NotNil(t, first.EmailVerifiedAt)
+	require.NotNil(t, first.PasswordChangedAt)
+	assert.WithinDuration(t, time.Now(), *first.PasswordChangedAt, time.Minute)
+	second := mockUserRepo.Calls[1].Arguments.Get(1).(*models.User)
+	assert.Nil(t, second.EmailVerifiedAt)
+	assert.Equal(t, changedAt, *second.PasswordChangedAt)
+	mockRoleRepo.AssertExpectations(t)
+}
+
+func TestImportUsers_UnsupportedHash(t *testing.T) {
+	mockUserRepo := &mocks.MockUserRepository{}
+	mockAuthService := &mocks.MockAuthService{}
+	userService := services.NewUserService(mockUserRepo, &mocks.MockRoleRepository{}, &mocks.MockPermissionRepository{}, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
+
+	req := &dto.ImportUsersRequest{Users: []dto.ImportUserRequest{{Email: "a@example.com", PasswordHash: "plaintext", Role: "member"}}}
+	mockAuthService.On("SupportsPasswordHash", "plaintext").Return(false)
+
+	_, err := userService.ImportUsers(newMFAUser(), req)
+
+	var appErr *utils.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.Code)
+	mockUserRepo.AssertNotCalled(t, "CreateUserTx", mock.Anything, mock.Anything)
 }
 
 func TestGetUsers_Success(t *testing.T) {
@@ -428,7 +720,7 @@ func TestGetUsers_Success(t *testing.T) {
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	tenantId := uuid.New()
 	userId := uuid.New()
@@ -452,7 +744,7 @@ func TestGetUsers_Failuer(t *testing.T) {
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	tenantId := uuid.New()
 	userId := uuid.New()
@@ -471,7 +763,7 @@ func TestUpdateUserRole_Success(t *testing.T) {
 	mockPermissionRepo := &mocks.MockPermissionRepository{}
 	mockRoleRepo := &mocks.MockRoleRepository{}
 	mockAuthService := &mocks.MockAuthService{}
-	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService)
+	userService := services.NewUserService(mockUserRepo, mockRoleRepo, mockPermissionRepo, mockAuthService, &mocks.MockMFAService{}, &mocks.MockMailService{}, &mocks.MockTransactor{}, &mocks.MockEmailVerificationService{}, &mocks.MockLockoutService{}, &mocks.MockPasswordPolicyService{})
 
 	tenantId := uuid.New()
 	userId := uuid.New()
@@ -492,10 +784,12 @@ func TestUpdateUserRole_Success(t *testing.T) {
 	mockRoleRepo.On("GetRoleByName", tenantId.String(), roleName).Return(expectedRole, nil)
 	mockUserRepo.On("GetUserById", tenantId.String(), userId.String()).Return(user, nil)
 	mockUserRepo.On("UpdateUser", user).Return(nil)
+	mockAuthService.On("RevokeUserTokens", userId.String()).Return(nil)
 
 	err := userService.UpdateUserRole(tenantId.String(), userId.String(), roleName)
 
 	assert.Nil(t, err)
+	mockAuthService.AssertCalled(t, "RevokeUserTokens", userId.String())
 	mockRoleRepo.AssertCalled(t, "GetRoleByName", tenantId.String(), roleName)
 	mockUserRepo.AssertCalled(t, "GetUserById", tenantId.String(), userId.String())
 	mockUserRepo.AssertCalled(t, "UpdateUser", user)
@@ -0,0 +1,384 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/services"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const (
+	testRPID   = "auth.example.com"
+	testOrigin = "https://auth.example.com"
+)
+
+// softAuthenticator is a minimal ES256 authenticator producing "none"
+// attestations and assertions the way a browser would hand them over.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 32)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *softAuthenticator) publicKey(t *testing.T) []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.PublicKey.X.FillBytes(x)
+	a.key.PublicKey.Y.FillBytes(y)
+
+	// COSE_Key: kty EC2, alg ES256, crv P-256
+	key, err := cbor.Marshal(map[int]any{1: 2, 3: -7, -1: 1, -2: x, -3: y})
+	require.NoError(t, err)
+	return key
+}
+
+func (a *softAuthenticator) authenticatorData(t *testing.T, flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.publicKey(t)...)
+	}
+	return data
+}
+
+func clientData(t *testing.T, ceremony, challenge string) []byte {
+	data, err := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": testOrigin})
+	require.NoError(t, err)
+	return data
+}
+
+// register answers navigator.credentials.create.
+func (a *softAuthenticator) register(t *testing.T, challenge string) json.RawMessage {
+	attestation, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(t, 0x45, true), // UP, UV, AT
+	})
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64(clientData(t, "webauthn.create", challenge)),
+		"attestationObject": b64(attestation),
+	})
+}
+
+// assert answers navigator.credentials.get, bumping the sign count first.
+func (a *softAuthenticator) assert(t *testing.T, challenge string, userHandle []byte) json.RawMessage {
+	a.signCount++
+	authData := a.authenticatorData(t, 0x05, false) // UP, UV
+	clientDataJSON := clientData(t, "webauthn.get", challenge)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64(clientDataJSON),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(userHandle),
+	})
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]string) json.RawMessage {
+	credential, err := json.Marshal(map[string]any{
+		"id":       b64(a.credentialID),
+		"rawId":    b64(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return credential
+}
+
+// record is the stored form of the authenticator's credential.
+func (a *softAuthenticator) record(t *testing.T, user *models.User) *models.WebAuthnCredential {
+	return &models.WebAuthnCredential{
+		ID:              uuid.New(),
+		UserID:          user.ID,
+		CredentialID:    a.credentialID,
+		PublicKey:       a.publicKey(t),
+		AttestationType: "none",
+		SignCount:       a.signCount,
+		UserVerified:    true,
+	}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type webAuthnTestSetup struct {
+	*mfaTestSetup
+	service services.WebAuthnService
+}
+
+func newWebAuthnTestSetup(t *testing.T) *webAuthnTestSetup {
+	viper.Set("WEBAUTHN_RP_ID", testRPID)
+	viper.Set("WEBAUTHN_RP_ORIGINS", testOrigin)
+	t.Cleanup(func() {
+		viper.Set("WEBAUTHN_RP_ID", "")
+		viper.Set("WEBAUTHN_RP_ORIGINS", "")
+	})
+
+	mfa := newMFATestSetup(t)
+	service, err := services.NewWebAuthnService(mfa.webauthnRepo, mfa.userRepo, mfa.service, mfa.authService)
+	require.NoError(t, err)
+
+	return &webAuthnTestSetup{mfaTestSetup: mfa, service: service}
+}
+
+// storedSession returns the session saved by the last begin call and makes
+// the repository hand it back once.
+func (s *webAuthnTestSetup) storedSession(options *dto.WebAuthnOptions, purpose string) *models.WebAuthnSession {
+	calls := s.webauthnRepo.Calls
+	var session *models.WebAuthnSession
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].Method == "CreateSession" {
+			session = calls[i].Arguments.Get(0).(*models.WebAuthnSession)
+			break
+		}
+	}
+	s.webauthnRepo.On("ConsumeSession", utils.HashToken(options.SessionID), purpose, mock.Anything).Return(session, nil).Once()
+	return session
+}
+
+func TestWebAuthn_RegisterCredential(t *testing.T) {
+	s := newWebAuthnTestSetup(t)
+	user := newMFAUser()
+	authenticator := newSoftAuthenticator(t)
+
+	s.webauthnRepo.On("FindUserCredentials", user.ID.String()).Return([]*models.WebAuthnCredential{}, nil)
+	s.webauthnRepo.On("CreateSession", mock.AnythingOfType("*models.WebAuthnSession")).Return(nil)
+	s.webauthnRepo.On("CreateCredential", mock.AnythingOfType("*models.WebAuthnCredential")).Return(nil)
+
+	options, err := s.service.BeginRegistration(user)
+	require.NoError(t, err)
+	creation := options.Options.(*protocol.CredentialCreation)
+	session := s.storedSession(options, utils.WebAuthnPurposeRegister)
+	assert.Equal(t, user.ID, *session.UserID)
+
+	credential, err := s.service.FinishRegistration(user, &dto.WebAuthnFinishRequest{
+		SessionID:  options.SessionID,
+		Name:       "Laptop",
+		Credential: authenticator.register(t, creation.Response.Challenge.String()),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Laptop", credential.Name)
+	assert.Equal(t, user.ID, credential.UserID)
+	assert.Equal(t, authenticator.credentialID, credential.CredentialID)
+	assert.Equal(t, "none", credential.AttestationType)
+	assert.True(t, credential.UserVerified)
+}
+
+func TestWebAuthn_RegisterRejectsWrongChallenge(t *testing.T) {
+	s := newWebAuthnTestSetup(t)
+	user := newMFAUser()
+	authenticator := newSoftAuthenticator(t)
+
+	s.webauthnRepo.On("FindUserCredentials", user.ID.String()).Return([]*models.WebAuthnCredential{}, nil)
+	s.webauthnRepo.On("CreateSession", mock.AnythingOfType("*models.WebAuthnSession")).Return(nil)
+
+	options, err := s.service.BeginRegistration(user)
+	require.NoError(t, err)
+	s.storedSession(options, utils.WebAuthnPurposeRegister)
+
+	_, err = s.service.FinishRegistration(user, &dto.WebAuthnFinishRequest{
+		SessionID:  options.SessionID,
+		Credential: authenticator.register(t, b64([]byte("not the challenge"))),
+	})
+
+	var appErr *utils.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 400, appErr.Code)
+	s.webauthnRepo.AssertNotCalled(t, "CreateCredential", mock.Anything)
+}
+
+func TestWebAuthn_PasskeyLogin(t *testing.T) {
+	s := newWebAuthnTestSetup(t)
+	user := newMFAUser()
+	authenticator := newSoftAuthenticator(t)
+	record := authenticator.record(t, user)
+	tokens := &dto.LoginResponse{AccessToken: "access"}
+
+	s.webauthnRepo.On("CreateSession", mock.AnythingOfType("*models.WebAuthnSession")).Return(nil)
+	s.webauthnRepo.On("FindCredentialByCredentialId", authenticator.credentialID).Return(record, nil)
+	s.webauthnRepo.On("FindUserCredentials", user.ID.String()).Return([]*models.WebAuthnCredential{record}, nil)
+	s.webauthnRepo.On("UpdateCredential", record).Return(nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.authService.On("IssueTokens", user).Return(tokens, nil)
+
+	options, err := s.service.BeginLogin()
+	require.NoError(t, err)
+	assertion := options.Options.(*protocol.CredentialAssertion)
+	assert.Equal(t, protocol.VerificationRequired, assertion.Response.UserVerification)
+	s.storedSession(options, utils.WebAuthnPurposeLogin)
+
+	response, err := s.service.FinishLogin(&dto.WebAuthnFinishRequest{
+		SessionID:  options.SessionID,
+		Credential: authenticator.assert(t, assertion.Response.Challenge.String(), user.ID[:]),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, tokens, response)
+	assert.Equal(t, uint32(1), record.SignCount)
+	assert.NotNil(t, record.LastUsedAt)
+}
+
+func TestWebAuthn_PasskeyLoginRejectsSignCountRegression(t *testing.T) {
+	s := newWebAuthnTestSetup(t)
+	user := newMFAUser()
+	authenticator := newSoftAuthenticator(t)
+	record := authenticator.record(t, user)
+	// the stored count is ahead of the authenticator, as it would be if the
+	// key had been copied and used elsewhere
+	record.SignCount = 10
+
+	s.webauthnRepo.On("CreateSession", mock.AnythingOfType("*models.WebAuthnSession")).Return(nil)
+	s.webauthnRepo.On("FindCredentialByCredentialId", authenticator.credentialID).Return(record, nil)
+	s.webauthnRepo.On("FindUserCredentials", user.ID.String()).Return([]*models.WebAuthnCredential{record}, nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+
+	options, err := s.service.BeginLogin()
+	require.NoError(t, err)
+	assertion := options.Options.(*protocol.CredentialAssertion)
+	s.storedSession(options, utils.WebAuthnPurposeLogin)
+
+	_, err = s.service.FinishLogin(&dto.WebAuthnFinishRequest{
+		SessionID:  options.SessionID,
+		Credential: authenticator.assert(t, assertion.Response.Challenge.String(), user.ID[:]),
+	})
+
+	assert.ErrorIs(t, err, services.ErrInvalidPasskey)
+	s.webauthnRepo.AssertNotCalled(t, "UpdateCredential", mock.Anything)
+	s.authService.AssertNotCalled(t, "IssueTokens", mock.Anything)
+}
+
+func TestWebAuthn_PasskeyLoginSessionUsedOnce(t *testing.T) {
+	s := newWebAuthnTestSetup(t)
+	authenticator := newSoftAuthenticator(t)
+
+	s.webauthnRepo.On("ConsumeSession", utils.HashToken("used"), utils.WebAuthnPurposeLogin, mock.Anything).
+		Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := s.service.FinishLogin(&dto.WebAuthnFinishRequest{
+		SessionID:  "used",
+		Credential: authenticator.assert(t, b64([]byte("challenge")), []byte("user")),
+	})
+
+	assert.ErrorIs(t, err, services.ErrInvalidWebAuthnSession)
+}
+
+func TestWebAuthn_SecondFactor(t *testing.T) {
+	s := newWebAuthnTestSetup(t)
+	user := newMFAUser()
+	authenticator := newSoftAuthenticator(t)
+	record := authenticator.record(t, user)
+	challenge := &models.MFAChallenge{ID: uuid.New(), UserID: user.ID}
+	tokens := &dto.LoginResponse{AccessToken: "access"}
+
+	s.repo.On("FindActiveChallenge", utils.HashToken("mfa-token"), mock.Anything, mock.Anything).Return(challenge, nil)
+	s.repo.On("ConsumeChallenge", challenge.ID.String(), mock.Anything).Return(nil)
+	s.webauthnRepo.On("CreateSession", mock.AnythingOfType("*models.WebAuthnSession")).Return(nil)
+	s.webauthnRepo.On("FindUserCredentials", user.ID.String()).Return([]*models.WebAuthnCredential{record}, nil)
+	s.webauthnRepo.On("FindCredentialByCredentialId", authenticator.credentialID).Return(record, nil)
+	s.webauthnRepo.On("UpdateCredential", record).Return(nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+	s.authService.On("IssueTokens", user).Return(tokens, nil)
+
+	options, err := s.service.BeginMFA("mfa-token")
+	require.NoError(t, err)
+	assertion := options.Options.(*protocol.CredentialAssertion)
+	require.Len(t, assertion.Response.AllowedCredentials, 1)
+	s.storedSession(options, utils.WebAuthnPurposeMFA)
+
+	response, err := s.service.FinishMFA(&dto.WebAuthnMFARequest{
+		MFAToken:   "mfa-token",
+		SessionID:  options.SessionID,
+		Credential: authenticator.assert(t, assertion.Response.Challenge.String(), user.ID[:]),
+	}, "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.Equal(t, tokens, response)
+	s.repo.AssertCalled(t, "ConsumeChallenge", challenge.ID.String(), mock.Anything)
+}
+
+func TestWebAuthn_SecondFactorBadSignatureCountsAttempt(t *testing.T) {
+	s := newWebAuthnTestSetup(t)
+	user := newMFAUser()
+	authenticator := newSoftAuthenticator(t)
+	record := authenticator.record(t, user)
+	challenge := &models.MFAChallenge{ID: uuid.New(), UserID: user.ID}
+
+	s.repo.On("FindActiveChallenge", utils.HashToken("mfa-token"), mock.Anything, mock.Anything).Return(challenge, nil)
+	s.repo.On("IncrementChallengeAttempts", challenge.ID.String()).Return(nil)
+	s.webauthnRepo.On("CreateSession", mock.AnythingOfType("*models.WebAuthnSession")).Return(nil)
+	s.webauthnRepo.On("FindUserCredentials", user.ID.String()).Return([]*models.WebAuthnCredential{record}, nil)
+	s.userRepo.On("FindUserById", user.ID.String()).Return(user, nil)
+
+	options, err := s.service.BeginMFA("mfa-token")
+	require.NoError(t, err)
+	s.storedSession(options, utils.WebAuthnPurposeMFA)
+
+	// signed by a different key than the one registered
+	impostor := newSoftAuthenticator(t)
+	impostor.credentialID = authenticator.credentialID
+	assertion := options.Options.(*protocol.CredentialAssertion)
+
+	_, err = s.service.FinishMFA(&dto.WebAuthnMFARequest{
+		MFAToken:   "mfa-token",
+		SessionID:  options.SessionID,
+		Credential: impostor.assert(t, assertion.Response.Challenge.String(), user.ID[:]),
+	}, "10.0.0.1")
+
+	assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+	s.repo.AssertCalled(t, "IncrementChallengeAttempts", challenge.ID.String())
+	s.repo.AssertNotCalled(t, "ConsumeChallenge", mock.Anything, mock.Anything)
+	s.lockoutService.AssertCalled(t, "RecordFailure", user.Email, "10.0.0.1", user)
+}
+
+func TestWebAuthn_Disabled(t *testing.T) {
+	mfa := newMFATestSetup(t)
+	service, err := services.NewWebAuthnService(mfa.webauthnRepo, mfa.userRepo, mfa.service, mfa.authService)
+	require.NoError(t, err)
+
+	_, err = service.BeginLogin()
+
+	assert.ErrorIs(t, err, services.ErrWebAuthnDisabled)
+}
@@ -3,32 +3,52 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"time"
 
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/mailer"
 	"github.com/samvibes/vexop/auth-service/internal/models"
 	"github.com/samvibes/vexop/auth-service/internal/repository"
 	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
 
 type UserService interface {
 	FindUserByEmail(email string) (*models.User, error)
 	CreateUser(user *models.User, db *gorm.DB) error
-	Login(email, password string) (string, error)
+	Authenticate(email, password, ip string) (*models.User, error)
+	Login(email, password, ip string) (*dto.LoginResponse, *dto.MFAChallengeResponse, error)
 	RemoveUserById(tenant_id, user_id string) error
 	RemoveUserByEmail(tenant_id string, email string) error
-	InitResetPassword(email string) (string, error)
-	ResetPassword(tenant_id, user_id, token, password string) error
+	InitResetPassword(email string) error
+	ResetPassword(token, password string) error
+	ValidatePassword(user *models.User, password string) error
+	ChangePassword(email, currentPassword, newPassword, ip string) error
+	CheckPasswordExpiry(user *models.User) error
+	CheckEmailVerification(user *models.User) error
 	GetUsers(tenant_id string, page, limit int) ([]*models.User, error)
 	GetUserById(tenant_id, user_id string) (*models.User, error)
 	UpdateUserRole(tenant_id, user_id, role_name string) error
+	ImportUsers(requestor *models.User, req *dto.ImportUsersRequest) (int, error)
 }
 
+const defaultResetPasswordTTL = time.Hour
+
 type UserServiceImpl struct {
 	userRepo        repository.UserRepository
 	roleRepo        repository.RoleRepository
 	permissionsRepo repository.PermissionRepository
 	authService     AuthService
+	mfaService      MFAService
+	mailService     MailService
+	transactor      repository.Transactor
+	verification    EmailVerificationService
+	lockoutService  LockoutService
+	passwordPolicy  PasswordPolicyService
 }
 
 func NewUserService(
@@ -36,8 +56,25 @@ func NewUserService(
 	role repository.RoleRepository,
 	permission repository.PermissionRepository,
 	authService AuthService,
+	mfaService MFAService,
+	mailService MailService,
+	transactor repository.Transactor,
+	verification EmailVerificationService,
+	lockoutService LockoutService,
+	passwordPolicy PasswordPolicyService,
 ) UserService {
-	return &UserServiceImpl{userRepo: repo, roleRepo: role, permissionsRepo: permission, authService: authService}
+	return &UserServiceImpl{
+		userRepo:        repo,
+		roleRepo:        role,
+		permissionsRepo: permission,
+		authService:     authService,
+		mfaService:      mfaService,
+		mailService:     mailService,
+		transactor:      transactor,
+		verification:    verification,
+		lockoutService:  lockoutService,
+		passwordPolicy:  passwordPolicy,
+	}
 }
 
 func (u *UserServiceImpl) FindUserByEmail(email string) (*models.User, error) {
@@ -66,6 +103,10 @@ func (u *UserServiceImpl) CreateUser(user *models.User, db *gorm.DB) error {
 
 		user.Role = *defaultRole
 		user.RoleID = defaultRole.ID.String()
+		if user.PasswordChangedAt == nil {
+			now := time.Now()
+			user.PasswordChangedAt = &now
+		}
 		err = u.userRepo.CreateUserTx(tx, user)
 		if utils.UniqueViolation(err) {
 			return utils.NewAppError(http.StatusBadRequest, "user already exists")
@@ -73,27 +114,105 @@ func (u *UserServiceImpl) CreateUser(user *models.User, db *gorm.DB) error {
 		if err != nil {
 			return err
 		}
+		if err := u.passwordPolicy.RecordTx(tx, user); err != nil {
+			return err
+		}
 
-		return nil
+		return u.verification.SendVerificationTx(tx, user)
 	})
 
 	return err
 }
 
-func (u *UserServiceImpl) Login(email, password string) (string, error) {
+// Authenticate checks a user's email and password. Unknown emails and wrong
+// passwords fail the same way so callers cannot tell them apart. Repeated
+// failures from the same account or IP address are throttled with a
+// *LoginLockedError. The account's failures are not cleared here since a
+// second factor may still be due; that is left to whoever completes the
+// login.
+func (u *UserServiceImpl) Authenticate(email, password, ip string) (*models.User, error) {
+	if err := u.lockoutService.Check(email, ip); err != nil {
+		return nil, err
+	}
+
 	// check if user exists
 	user, err := u.userRepo.FindUserByEmail(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// match password; the empty hash of unknown emails takes as long to
+	// reject as a wrong password
+	passwordHash := ""
+	if user != nil {
+		passwordHash = user.PasswordHash
+	}
+	if !u.authService.CompareHashAndPassword([]byte(password), []byte(passwordHash)) || user == nil {
+		if err := u.lockoutService.RecordFailure(email, ip, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// hashes from an outdated scheme or cost are upgraded while the
+	// password is at hand
+	if u.authService.PasswordNeedsRehash(user.PasswordHash) {
+		u.rehashPassword(user, password)
+	}
+
+	return user, nil
+}
+
+// rehashPassword stores a hash of password made the current way. Failures
+// are only logged, the old hash keeps working.
+func (u *UserServiceImpl) rehashPassword(user *models.User, password string) {
+	newHash, err := u.authService.HashPassword(password)
+	if err != nil {
+		log.Printf("failed to rehash password of user %s: %v", user.ID, err)
+		return
+	}
+	if err := u.userRepo.RehashPassword(user.ID.String(), user.PasswordHash, newHash); err != nil {
+		log.Printf("failed to rehash password of user %s: %v", user.ID, err)
+		return
+	}
+	user.PasswordHash = newHash
+}
+
+// Login checks the password and issues tokens. When the user has MFA enabled
+// or their tenant requires it, an MFA challenge is returned instead. Expired
+// passwords fail with ErrPasswordExpired until changed with ChangePassword.
+func (u *UserServiceImpl) Login(email, password, ip string) (*dto.LoginResponse, *dto.MFAChallengeResponse, error) {
+	user, err := u.Authenticate(email, password, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := u.CheckPasswordExpiry(user); err != nil {
+		return nil, nil, err
+	}
+
+	if err := u.CheckEmailVerification(user); err != nil {
+		return nil, nil, err
+	}
+
+	challenge, err := u.mfaService.BeginLogin(user)
 	if err != nil {
-		return "", err
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
 	}
 
-	// match password
-	if !u.authService.CompareHashAndPassword([]byte(password), []byte(user.PasswordHash)) {
-		return "", errors.New("incorrect password")
+	if err := u.lockoutService.RecordSuccess(user.Email); err != nil {
+		return nil, nil, err
 	}
 
-	// generate jwt token
-	return u.authService.GenerateJWT(user)
+	// generate access and refresh tokens
+	tokens, err := u.authService.IssueTokens(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, nil, nil
 }
 
 func (u *UserServiceImpl) RemoveUserById(tenant_id, user_id string) error {
@@ -105,11 +224,14 @@ func (u *UserServiceImpl) RemoveUserById(tenant_id, user_id string) error {
 
 		return err
 	}
-	return nil
+
+	// tokens issued to a removed user must stop working right away
+	return u.authService.RevokeUserTokens(user_id)
 }
 
 func (u *UserServiceImpl) RemoveUserByEmail(tenant_id string, email string) error {
-	if err := u.userRepo.RemoveUserByEmail(tenant_id, email); err != nil {
+	user, err := u.userRepo.FindUserByEmailAndTenant(email, tenant_id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			appError := utils.NewAppError(http.StatusNotFound, "user not found")
 			return appError
@@ -117,44 +239,64 @@ func (u *UserServiceImpl) RemoveUserByEmail(tenant_id string, email string) erro
 
 		return err
 	}
-	return nil
+
+	if err := u.userRepo.RemoveUserByEmail(tenant_id, email); err != nil {
+		return err
+	}
+
+	return u.authService.RevokeUserTokens(user.ID.String())
 }
 
-func (u *UserServiceImpl) InitResetPassword(email string) (string, error) {
+// InitResetPassword emails the user a link to RESET_PASSWORD_URL carrying a
+// single-use reset token that expires after RESET_PASSWORD_TTL. Unknown
+// addresses are ignored without an error so callers do not reveal which
+// accounts exist.
+func (u *UserServiceImpl) InitResetPassword(email string) error {
 	user, err := u.userRepo.FindUserByEmail(email)
 	if err != nil {
-		return "", err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
 	}
 
-	// generate token to send back
-	token, hashToken, err := utils.CreateRandomToken()
+	token, hashToken, err := utils.GenerateOpaqueToken()
 	if err != nil {
-		return "", fmt.Errorf("error while generating token %s", err.Error())
+		return fmt.Errorf("error while generating token %s", err.Error())
 	}
 
-	err = u.userRepo.SetResetPasswordTokenHash(user.ID.String(), hashToken)
+	link, err := emailLink(viper.GetString("RESET_PASSWORD_URL"), url.Values{"token": {token}})
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return token, nil
+	ttl := utils.GetDuration("RESET_PASSWORD_TTL", defaultResetPasswordTTL)
+	return u.transactor.Transaction(func(tx *gorm.DB) error {
+		if err := u.userRepo.SetResetPasswordTokenHashTx(tx, user.ID.String(), hashToken, time.Now().Add(ttl)); err != nil {
+			return err
+		}
+		return u.mailService.EnqueueTx(tx, user.TenantID, mailer.TemplateResetPassword, user.Email, map[string]any{
+			"Link":      link,
+			"ExpiresIn": utils.HumanizeDuration(ttl),
+		})
+	})
 }
 
-func (u *UserServiceImpl) ResetPassword(tenant_id, user_id, token, password string) error {
-	user, err := u.userRepo.GetUserById(tenant_id, user_id)
+// ResetPassword sets a new password using a token from InitResetPassword,
+// tells the user by email, and logs them out of every session.
+func (u *UserServiceImpl) ResetPassword(token, password string) error {
+	tokenHash := utils.HashToken(token)
+
+	user, err := u.userRepo.FindUserByResetTokenHash(tokenHash, time.Now())
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			appErr := utils.NewAppError(http.StatusNotFound, "user not found")
-			return appErr
+			return ErrInvalidResetToken
 		}
-
 		return err
 	}
 
-	// check token against ResetPasswordTokenHash
-	if !u.authService.CompareHashAndPassword([]byte(token), []byte(user.ResetPasswordTokenHash)) {
-		appErr := utils.NewAppError(http.StatusBadRequest, "incorrect password reset token")
-		return appErr
+	if err := u.passwordPolicy.Validate(user, password); err != nil {
+		return err
 	}
 
 	newPasswordHash, err := u.authService.HashPassword(password)
@@ -162,15 +304,81 @@ func (u *UserServiceImpl) ResetPassword(tenant_id, user_id, token, password stri
 		return err
 	}
 
-	user.PasswordHash = newPasswordHash
-	user.ResetPasswordTokenHash = ""
+	err = u.transactor.Transaction(func(tx *gorm.DB) error {
+		if err := u.userRepo.ResetPasswordTx(tx, user.ID.String(), tokenHash, newPasswordHash, time.Now()); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		user.PasswordHash = newPasswordHash
+		if err := u.passwordPolicy.RecordTx(tx, user); err != nil {
+			return err
+		}
+		return u.mailService.EnqueueTx(tx, user.TenantID, mailer.TemplateSecurityAlert, user.Email, map[string]any{
+			"Event": "The password for your account was reset.",
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return u.authService.RevokeUserTokens(user.ID.String())
+}
+
+// ValidatePassword checks password against the password policy of user's
+// tenant, as a new password for them.
+func (u *UserServiceImpl) ValidatePassword(user *models.User, password string) error {
+	return u.passwordPolicy.Validate(user, password)
+}
+
+// CheckPasswordExpiry returns ErrPasswordExpired when user's password is
+// older than their tenant's password policy allows.
+func (u *UserServiceImpl) CheckPasswordExpiry(user *models.User) error {
+	return u.passwordPolicy.CheckExpiry(user)
+}
+
+// CheckEmailVerification returns ErrEmailNotVerified when user's tenant
+// blocks sign-in until their email address is verified.
+func (u *UserServiceImpl) CheckEmailVerification(user *models.User) error {
+	return u.verification.CheckLogin(user)
+}
+
+// ChangePassword replaces a password the user knows, including one that has
+// expired. It is throttled like a login, tells the user by email and logs
+// them out of every session.
+func (u *UserServiceImpl) ChangePassword(email, currentPassword, newPassword, ip string) error {
+	user, err := u.Authenticate(email, currentPassword, ip)
+	if err != nil {
+		return err
+	}
 
-	err = u.userRepo.UpdateUser(user)
+	if err := u.passwordPolicy.Validate(user, newPassword); err != nil {
+		return err
+	}
+
+	newPasswordHash, err := u.authService.HashPassword(newPassword)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = u.transactor.Transaction(func(tx *gorm.DB) error {
+		if err := u.userRepo.UpdatePasswordTx(tx, user.ID.String(), newPasswordHash, time.Now()); err != nil {
+			return err
+		}
+		user.PasswordHash = newPasswordHash
+		if err := u.passwordPolicy.RecordTx(tx, user); err != nil {
+			return err
+		}
+		return u.mailService.EnqueueTx(tx, user.TenantID, mailer.TemplateSecurityAlert, user.Email, map[string]any{
+			"Event": "The password for your account was changed.",
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return u.authService.RevokeUserTokens(user.ID.String())
 }
 
 func (u *UserServiceImpl) GetUsers(tenant_id string, page, limit int) ([]*models.User, error) {
@@ -203,5 +411,83 @@ func (u *UserServiceImpl) UpdateUserRole(tenant_id, user_id, role_name string) e
 	user.Role = *role
 	user.RoleID = role.ID.String()
 
-	return u.userRepo.UpdateUser(user)
+	if err := u.userRepo.UpdateUser(user); err != nil {
+		return err
+	}
+
+	// existing tokens were issued under the old role
+	return u.authService.RevokeUserTokens(user_id)
+}
+
+// ImportUsers creates users in the requestor's tenant with password hashes
+// exported from another system. Hashes of any supported scheme are taken
+// as they are and upgraded as each user logs in. Either every user is
+// created or none is.
+func (u *UserServiceImpl) ImportUsers(requestor *models.User, req *dto.ImportUsersRequest) (int, error) {
+	if requestor.TenantID == nil {
+		return 0, ErrUnauthorized
+	}
+	tenant_id := requestor.TenantID.String()
+
+	roles := make(map[string]*models.Role)
+	users := make([]*models.User, 0, len(req.Users))
+	now := time.Now()
+	for i, entry := range req.Users {
+		if !u.authService.SupportsPasswordHash(entry.PasswordHash) {
+			return 0, utils.NewAppError(http.StatusBadRequest, fmt.Sprintf("users[%d]: unsupported password hash", i))
+		}
+
+		role, ok := roles[entry.Role]
+		if !ok {
+			var err error
+			role, err = u.roleRepo.GetRoleByName(tenant_id, entry.Role)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return 0, utils.NewAppError(http.StatusBadRequest, fmt.Sprintf("users[%d]: invalid role name", i))
+				}
+				return 0, err
+			}
+			roles[entry.Role] = role
+		}
+
+		// the password policy's expiry counts from PasswordChangedAt
+		passwordChangedAt := now
+		if entry.PasswordChangedAt != nil {
+			if entry.PasswordChangedAt.After(now) {
+				return 0, utils.NewAppError(http.StatusBadRequest, fmt.Sprintf("users[%d]: password_changed_at is in the future", i))
+			}
+			passwordChangedAt = *entry.PasswordChangedAt
+		}
+
+		user := &models.User{
+			TenantID:          requestor.TenantID,
+			Email:             entry.Email,
+			PasswordHash:      entry.PasswordHash,
+			PasswordChangedAt: &passwordChangedAt,
+			RoleID:            role.ID.String(),
+			Role:              *role,
+		}
+		if entry.EmailVerified {
+			user.EmailVerifiedAt = &now
+		}
+		users = append(users, user)
+	}
+
+	err := u.transactor.Transaction(func(tx *gorm.DB) error {
+		for _, user := range users {
+			err := u.userRepo.CreateUserTx(tx, user)
+			if utils.UniqueViolation(err) {
+				return utils.NewAppError(http.StatusConflict, "user already exists: "+user.Email)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(users), nil
 }
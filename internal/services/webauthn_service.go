@@ -0,0 +1,473 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/samvibes/vexop/auth-service/internal/dto"
+	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/samvibes/vexop/auth-service/internal/repository"
+	"github.com/samvibes/vexop/auth-service/internal/utils"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultWebAuthnSessionTTL = 5 * time.Minute
+	defaultPasskeyName        = "Passkey"
+)
+
+// WebAuthnService runs the WebAuthn registration and assertion ceremonies.
+// Passkeys sign users in on their own, or complete an MFA challenge from
+// password login. It is configured with WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and
+// a comma-separated WEBAUTHN_RP_ORIGINS; without an RP ID every method returns
+// ErrWebAuthnDisabled.
+type WebAuthnService interface {
+	BeginRegistration(user *models.User) (*dto.WebAuthnOptions, error)
+	FinishRegistration(user *models.User, req *dto.WebAuthnFinishRequest) (*models.WebAuthnCredential, error)
+	GetCredentials(user *models.User) ([]*models.WebAuthnCredential, error)
+	DeleteCredential(user *models.User, id string) error
+	BeginLogin() (*dto.WebAuthnOptions, error)
+	FinishLogin(req *dto.WebAuthnFinishRequest) (*dto.LoginResponse, error)
+	BeginMFA(mfa_token string) (*dto.WebAuthnOptions, error)
+	FinishMFA(req *dto.WebAuthnMFARequest, ip string) (*dto.LoginResponse, error)
+}
+
+type WebAuthnServiceImpl struct {
+	webauthn    *webauthn.WebAuthn
+	repo        repository.WebAuthnRepository
+	userRepo    repository.UserRepository
+	mfaService  MFAService
+	authService AuthService
+}
+
+func NewWebAuthnService(
+	repo repository.WebAuthnRepository,
+	userRepo repository.UserRepository,
+	mfaService MFAService,
+	authService AuthService,
+) (WebAuthnService, error) {
+	service := &WebAuthnServiceImpl{repo: repo, userRepo: userRepo, mfaService: mfaService, authService: authService}
+
+	rpID := viper.GetString("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		return service, nil
+	}
+
+	rpName := viper.GetString("WEBAUTHN_RP_NAME")
+	if rpName == "" {
+		rpName = rpID
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(viper.GetString("WEBAUTHN_RP_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{"https://" + rpID}
+	}
+
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: rpName,
+		RPOrigins:     origins,
+	})
+	if err != nil {
+		return nil, err
+	}
+	service.webauthn = w
+
+	return service, nil
+}
+
+// BeginRegistration returns creation options for a new credential. Existing
+// credentials are excluded so the same authenticator is not registered twice.
+func (s *WebAuthnServiceImpl) BeginRegistration(user *models.User) (*dto.WebAuthnOptions, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	webAuthnUser, err := s.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(webAuthnUser.credentials))
+	for _, credential := range webAuthnUser.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := s.webauthn.BeginRegistration(webAuthnUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startSession(utils.WebAuthnPurposeRegister, &user.ID, session, creation)
+}
+
+func (s *WebAuthnServiceImpl) FinishRegistration(user *models.User, req *dto.WebAuthnFinishRequest) (*models.WebAuthnCredential, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	stored, session, err := s.consumeSession(req.SessionID, utils.WebAuthnPurposeRegister)
+	if err != nil {
+		return nil, err
+	}
+	if stored.UserID == nil || *stored.UserID != user.ID {
+		return nil, ErrInvalidWebAuthnSession
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, utils.NewAppError(http.StatusBadRequest, "invalid passkey registration")
+	}
+
+	webAuthnUser, err := s.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webauthn.CreateCredential(webAuthnUser, *session, parsed)
+	if err != nil {
+		return nil, utils.NewAppError(http.StatusBadRequest, "invalid passkey registration")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	record := &models.WebAuthnCredential{
+		ID:              uuid.New(),
+		UserID:          user.ID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := s.repo.CreateCredential(record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (s *WebAuthnServiceImpl) GetCredentials(user *models.User) ([]*models.WebAuthnCredential, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	return s.repo.FindUserCredentials(user.ID.String())
+}
+
+// DeleteCredential removes one of the user's credentials. Removing the last
+// second factor is refused while the tenant's policy requires MFA.
+func (s *WebAuthnServiceImpl) DeleteCredential(user *models.User, id string) error {
+	if s.webauthn == nil {
+		return ErrWebAuthnDisabled
+	}
+
+	required, err := s.mfaService.RequiresMFA(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		methods, err := s.mfaService.EnrolledMethods(user.ID.String())
+		if err != nil {
+			return err
+		}
+		count, err := s.repo.CountUserCredentials(user.ID.String())
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(methods, utils.MFAMethodTOTP) && count <= 1 {
+			return utils.NewAppError(http.StatusForbidden, "mfa is required by your organization")
+		}
+	}
+
+	if err := s.repo.DeleteCredential(user.ID.String(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(http.StatusNotFound, "passkey not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// BeginLogin starts a passkey login without a username: the authenticator
+// picks the credential and tells us whose it is. User verification is
+// required, so the passkey alone satisfies MFA.
+func (s *WebAuthnServiceImpl) BeginLogin() (*dto.WebAuthnOptions, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	assertion, session, err := s.webauthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startSession(utils.WebAuthnPurposeLogin, nil, session, assertion)
+}
+
+func (s *WebAuthnServiceImpl) FinishLogin(req *dto.WebAuthnFinishRequest) (*dto.LoginResponse, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	_, session, err := s.consumeSession(req.SessionID, utils.WebAuthnPurposeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	found, credential, err := s.webauthn.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		record, err := s.repo.FindCredentialByCredentialId(rawID)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(record.UserID[:], userHandle) {
+			return nil, ErrInvalidPasskey
+		}
+
+		user, err := s.userRepo.FindUserById(record.UserID.String())
+		if err != nil {
+			return nil, err
+		}
+		return s.loadUser(user)
+	}, *session, parsed)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	if err := s.recordUse(credential); err != nil {
+		return nil, err
+	}
+
+	return s.authService.IssueTokens(found.(*webAuthnUser).user)
+}
+
+// BeginMFA starts an assertion with one of the credentials of the user an
+// MFA challenge belongs to.
+func (s *WebAuthnServiceImpl) BeginMFA(mfa_token string) (*dto.WebAuthnOptions, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	user, err := s.mfaService.ChallengeUser(mfa_token)
+	if err != nil {
+		return nil, err
+	}
+
+	webAuthnUser, err := s.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+	if len(webAuthnUser.credentials) == 0 {
+		return nil, utils.NewAppError(http.StatusBadRequest, "no passkey is registered")
+	}
+
+	assertion, session, err := s.webauthn.BeginLogin(webAuthnUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startSession(utils.WebAuthnPurposeMFA, &user.ID, session, assertion)
+}
+
+// FinishMFA completes an MFA challenge with the assertion from BeginMFA. A
+// failed assertion counts as a failed attempt on the challenge, and as a
+// failed login from ip.
+func (s *WebAuthnServiceImpl) FinishMFA(req *dto.WebAuthnMFARequest, ip string) (*dto.LoginResponse, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	return s.mfaService.CompleteChallenge(req.MFAToken, ip, func(user *models.User) error {
+		stored, session, err := s.consumeSession(req.SessionID, utils.WebAuthnPurposeMFA)
+		if err != nil {
+			return err
+		}
+		if stored.UserID == nil || *stored.UserID != user.ID {
+			return ErrInvalidWebAuthnSession
+		}
+
+		parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+		if err != nil {
+			return ErrInvalidMFACode
+		}
+
+		webAuthnUser, err := s.loadUser(user)
+		if err != nil {
+			return err
+		}
+
+		credential, err := s.webauthn.ValidateLogin(webAuthnUser, *session, parsed)
+		if err != nil {
+			return ErrInvalidMFACode
+		}
+
+		if err := s.recordUse(credential); err != nil {
+			if errors.Is(err, ErrInvalidPasskey) {
+				return ErrInvalidMFACode
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// recordUse stores the sign count and flags from a verified assertion. A
+// sign count that did not go up means the authenticator may have been
+// cloned, and the assertion is rejected.
+func (s *WebAuthnServiceImpl) recordUse(credential *webauthn.Credential) error {
+	if credential.Authenticator.CloneWarning {
+		return ErrInvalidPasskey
+	}
+
+	record, err := s.repo.FindCredentialByCredentialId(credential.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	record.SignCount = credential.Authenticator.SignCount
+	record.UserVerified = credential.Flags.UserVerified
+	record.BackupState = credential.Flags.BackupState
+	record.LastUsedAt = &now
+
+	return s.repo.UpdateCredential(record)
+}
+
+func (s *WebAuthnServiceImpl) startSession(purpose string, user_id *uuid.UUID, session *webauthn.SessionData, options any) (*dto.WebAuthnOptions, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := utils.GetDuration("WEBAUTHN_SESSION_TTL", defaultWebAuthnSessionTTL)
+	err = s.repo.CreateSession(&models.WebAuthnSession{
+		ID:        uuid.New(),
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+		UserID:    user_id,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.WebAuthnOptions{SessionID: token, Options: options, ExpiresIn: int64(ttl.Seconds())}, nil
+}
+
+func (s *WebAuthnServiceImpl) consumeSession(session_id, purpose string) (*models.WebAuthnSession, *webauthn.SessionData, error) {
+	stored, err := s.repo.ConsumeSession(utils.HashToken(session_id), purpose, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidWebAuthnSession
+		}
+		return nil, nil, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(stored.Data), &session); err != nil {
+		return nil, nil, err
+	}
+
+	return stored, &session, nil
+}
+
+func (s *WebAuthnServiceImpl) loadUser(user *models.User) (*webAuthnUser, error) {
+	records, err := s.repo.FindUserCredentials(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(records))
+	for _, record := range records {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(record.Transports))
+		for _, transport := range record.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              record.CredentialID,
+			PublicKey:       record.PublicKey,
+			AttestationType: record.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   record.UserVerified,
+				BackupEligible: record.BackupEligible,
+				BackupState:    record.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    record.AAGUID,
+				SignCount: record.SignCount,
+			},
+		})
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// webAuthnUser adapts a user and their stored credentials to webauthn.User.
+// The user handle is the raw user ID.
+type webAuthnUser struct {
+	user        *models.User
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
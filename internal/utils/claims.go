@@ -0,0 +1,66 @@
+package utils
+
+import "github.com/golang-jwt/jwt/v5"
+
+// Claims are the claims carried by access tokens issued by this service. Which
+// of the optional claims are present is controlled per tenant and audience by
+// models.TokenClaimConfig.
+type Claims struct {
+	UserID      string   `json:"id"`
+	TenantID    string   `json:"tenant_id,omitempty"`
+	Email       string   `json:"email,omitempty"`
+	Role        string   `json:"role,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	// SessionID is the models.Session the token was issued in. Tokens issued
+	// without a refresh token belong to no session.
+	SessionID string `json:"sid,omitempty"`
+	// PersonalAccessTokenID is set, instead of a JWT being parsed, for
+	// requests made with a personal access token. Scope holds its scopes.
+	PersonalAccessTokenID string `json:"-"`
+	// Act is set on impersonation tokens and names who is acting as the
+	// user, as in RFC 8693.
+	Act *ActorClaim `json:"act,omitempty"`
+	// EmailVerified is set on user tokens. Tokens of unverified users are
+	// never trusted statelessly because their tenant policy may restrict them.
+	EmailVerified *bool `json:"email_verified,omitempty"`
+	// TokenUse is TokenUseAccess. Only access tokens carrying it are accepted.
+	TokenUse string `json:"token_use,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IsClient reports whether the token was issued to an OAuth client acting as
+// itself rather than on behalf of a user.
+func (c *Claims) IsClient() bool {
+	return c.UserID == "" && c.ClientID != ""
+}
+
+// IsPersonalAccessToken reports whether the request was made with a personal
+// access token rather than an access token.
+func (c *Claims) IsPersonalAccessToken() bool {
+	return c.PersonalAccessTokenID != ""
+}
+
+// IsImpersonation reports whether the token was issued to someone acting as
+// the user.
+func (c *Claims) IsImpersonation() bool {
+	return c.Act != nil
+}
+
+// ActorClaim identifies the impersonator in the act claim.
+type ActorClaim struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
+// IDTokenClaims are the claims of OpenID Connect ID tokens.
+type IDTokenClaims struct {
+	Nonce         string           `json:"nonce,omitempty"`
+	AuthTime      *jwt.NumericDate `json:"auth_time,omitempty"`
+	Email         string           `json:"email,omitempty"`
+	EmailVerified *bool            `json:"email_verified,omitempty"`
+	TenantID      string           `json:"tenant_id,omitempty"`
+	TokenUse      string           `json:"token_use,omitempty"`
+	jwt.RegisteredClaims
+}
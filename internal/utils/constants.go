@@ -4,6 +4,18 @@ import "net/http"
 
 const UserContextKey = "currentUser"
 
+const ClaimsContextKey = "currentClaims"
+
+const SessionContextKey = "currentSession"
+
+// ImpersonatorContextKey holds the claims.Act of requests made with an
+// impersonation token.
+const ImpersonatorContextKey = "currentImpersonator"
+
+// PersonalAccessTokenPrefix starts every personal access token, telling them
+// apart from JWTs and making leaked ones easy to scan for.
+const PersonalAccessTokenPrefix = "vxp_"
+
 type Action string
 
 var (
@@ -16,6 +28,19 @@ var (
 
 var RoleSuperAdmin = "superadmin"
 
+// RoleClient is the role name given to OAuth clients acting as themselves.
+var RoleClient = "client"
+
+// ServiceAccountDomain is the reserved domain the emails of service accounts
+// are under, as <name>@<tenant id>.ServiceAccountDomain.
+const ServiceAccountDomain = "service-account.invalid"
+
+const (
+	KeyStateNext    = "next"
+	KeyStateActive  = "active"
+	KeyStateRetired = "retired"
+)
+
 type Role string
 
 var (
@@ -27,11 +52,19 @@ var (
 type Resource string
 
 var (
-	ResourceUser      Resource = "user"
-	ResourceFile      Resource = "file"
-	ResourceWorkspace Resource = "workspace"
-	ResourceInvite    Resource = "invite"
-	ResourceRole      Resource = "role"
+	ResourceUser           Resource = "user"
+	ResourceFile           Resource = "file"
+	ResourceWorkspace      Resource = "workspace"
+	ResourceInvite         Resource = "invite"
+	ResourceRole           Resource = "role"
+	ResourceClient         Resource = "client"
+	ResourcePolicy         Resource = "policy"
+	ResourceEmailTemplate  Resource = "email-template"
+	ResourceAuditEvent     Resource = "audit-event"
+	ResourceSession        Resource = "session"
+	ResourceAccessToken    Resource = "access-token"
+	ResourceServiceAccount Resource = "service-account"
+	ResourceImpersonation  Resource = "impersonation"
 )
 
 var MethodToAction = map[string]string{
@@ -41,3 +74,85 @@ var MethodToAction = map[string]string{
 	http.MethodPatch:  string(ActionUpdate),
 	http.MethodDelete: string(ActionDelete),
 }
+
+// Second factors offered at login
+const (
+	MFAMethodTOTP         = "totp"
+	MFAMethodRecoveryCode = "recovery_code"
+	MFAMethodWebAuthn     = "webauthn"
+)
+
+// Kinds of passwordless login token
+const (
+	LoginTokenMagicLink = "magic_link"
+	LoginTokenEmailOTP  = "email_otp"
+)
+
+// Email verification policy modes
+const (
+	EmailVerificationOff      = "off"
+	EmailVerificationBlock    = "block"
+	EmailVerificationRestrict = "restrict"
+)
+
+// Password policy rules
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleMaxLength = "max_length"
+	PasswordRuleUpper     = "uppercase"
+	PasswordRuleLower     = "lowercase"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+	PasswordRuleEmail     = "email"
+	PasswordRuleHistory   = "history"
+	PasswordRuleBreached  = "breached"
+)
+
+// Audit events
+const (
+	AuditLoginLocked           = "login.locked"
+	AuditLoginIPLocked         = "login.ip_locked"
+	AuditAccountUnlocked       = "account.unlocked"
+	AuditSessionRevoked        = "session.revoked"
+	AuditTokenCreated          = "access_token.created"
+	AuditTokenRevoked          = "access_token.revoked"
+	AuditServiceAccountCreated = "service_account.created"
+	AuditServiceAccountDeleted = "service_account.deleted"
+	AuditImpersonationStarted  = "impersonation.started"
+	AuditImpersonationStopped  = "impersonation.stopped"
+)
+
+// Purposes of a WebAuthn ceremony
+const (
+	WebAuthnPurposeRegister = "register"
+	WebAuthnPurposeLogin    = "login"
+	WebAuthnPurposeMFA      = "mfa"
+)
+
+// OpenID Connect scopes
+const (
+	ScopeOpenID        = "openid"
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopeOfflineAccess = "offline_access"
+)
+
+// OAuth token type hints
+const (
+	TokenTypeAccessToken  = "access_token"
+	TokenTypeRefreshToken = "refresh_token"
+)
+
+// Values of the token_use claim, which keeps ID tokens from passing as access
+// tokens since both are signed with the same keys
+const (
+	TokenUseAccess = "access"
+	TokenUseID     = "id"
+)
+
+// OAuth grant types
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
@@ -2,19 +2,23 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jinzhu/inflection"
 	"github.com/samvibes/vexop/auth-service/internal/models"
+	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var CreateRandomToken = GenerateRandomToken
-
 func GetCurrentUser(c *gin.Context) *models.User {
 	userVar, exists := c.Get(UserContextKey)
 	if !exists {
@@ -24,6 +28,26 @@ func GetCurrentUser(c *gin.Context) *models.User {
 	return &user
 }
 
+func GetCurrentClaims(c *gin.Context) *Claims {
+	claimsVar, exists := c.Get(ClaimsContextKey)
+	if !exists {
+		return nil
+	}
+	claims, _ := claimsVar.(*Claims)
+	return claims
+}
+
+// GetImpersonator returns who is acting as the current user, or nil unless
+// the request was made with an impersonation token.
+func GetImpersonator(c *gin.Context) *ActorClaim {
+	actorVar, exists := c.Get(ImpersonatorContextKey)
+	if !exists {
+		return nil
+	}
+	actor, _ := actorVar.(*ActorClaim)
+	return actor
+}
+
 var irregularPlurals = map[string]string{
 	"people":    "person",
 	"data":      "data",
@@ -80,6 +104,59 @@ func GenerateRandomToken() (rawToken string, hashedToken string, err error) {
 	return
 }
 
+// GenerateOpaqueToken returns a random token and its sha256 hash. Unlike
+// GenerateRandomToken the hash is deterministic, so it can be used for lookups.
+func GenerateOpaqueToken() (rawToken string, hashedToken string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	rawToken = base64.RawURLEncoding.EncodeToString(b)
+	hashedToken = HashToken(rawToken)
+
+	return rawToken, hashedToken, nil
+}
+
+// GenerateNumericCode returns a random code of the given number of decimal
+// digits, such as a one-time code sent by email.
+func GenerateNumericCode(digits int) (string, error) {
+	max := big.NewInt(1)
+	for range digits {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetDuration reads a duration such as "15m" from config, falling back when
+// the key is unset or invalid.
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	if d := viper.GetDuration(key); d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// GetInt reads a positive integer from config, falling back when the key is
+// unset or not positive.
+func GetInt(key string, fallback int) int {
+	if v := viper.GetInt(key); v > 0 {
+		return v
+	}
+	return fallback
+}
+
 func GetPageAndLimit(c *gin.Context) (page, limit int) {
 	pageStr := c.Query("page")
 	limitStr := c.Query("limit")
@@ -106,3 +183,19 @@ func Keys(m map[uuid.UUID]uuid.UUID) []uuid.UUID {
 	}
 	return keys
 }
+
+// HumanizeDuration spells out a duration for emails, such as "15 minutes" or
+// "7 days".
+func HumanizeDuration(d time.Duration) string {
+	unit, n := "minute", int64(d/time.Minute)
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		unit, n = "day", int64(d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		unit, n = "hour", int64(d/time.Hour)
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
@@ -1,13 +1,31 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/samvibes/vexop/auth-service/app"
 	"github.com/samvibes/vexop/auth-service/internal/routes"
+	"github.com/spf13/viper"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := app.RunCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	container := app.InitApp()
 
+	// Envoy ext_authz listener, off unless configured
+	if addr := viper.GetString("EXT_AUTHZ_GRPC_ADDR"); addr != "" {
+		go func() {
+			log.Fatal(container.ExtAuthzServer.ListenAndServe(addr))
+		}()
+	}
+
 	router := routes.InitRoutes(container)
 
 	router.Run(":9000")
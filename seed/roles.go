@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/google/uuid"
 	"github.com/samvibes/vexop/auth-service/internal/models"
 	"github.com/samvibes/vexop/auth-service/internal/utils"
 	"gorm.io/gorm"
@@ -20,11 +21,19 @@ import (
 
 // All resource permissions
 var adminRole = map[utils.Resource][]utils.Action{
-	utils.ResourceFile:      {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
-	utils.ResourceWorkspace: {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
-	utils.ResourceUser:      {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
-	utils.ResourceInvite:    {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
-	utils.ResourceRole:      {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
+	utils.ResourceFile:           {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
+	utils.ResourceWorkspace:      {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
+	utils.ResourceUser:           {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
+	utils.ResourceInvite:         {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
+	utils.ResourceRole:           {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
+	utils.ResourceClient:         {utils.ActionRead, utils.ActionCreate, utils.ActionUpdate, utils.ActionDelete},
+	utils.ResourcePolicy:         {utils.ActionRead, utils.ActionUpdate},
+	utils.ResourceEmailTemplate:  {utils.ActionRead, utils.ActionUpdate, utils.ActionDelete},
+	utils.ResourceAuditEvent:     {utils.ActionRead},
+	utils.ResourceSession:        {utils.ActionRead, utils.ActionDelete},
+	utils.ResourceAccessToken:    {utils.ActionRead, utils.ActionDelete},
+	utils.ResourceServiceAccount: {utils.ActionRead, utils.ActionCreate, utils.ActionDelete},
+	utils.ResourceImpersonation:  {utils.ActionCreate},
 }
 
 var memberRole = map[utils.Resource][]utils.Action{
@@ -91,6 +100,73 @@ func SeedRoles(db *gorm.DB) error {
 	return nil
 }
 
+// GrantAdminPermissions gives every admin role, the template one and each
+// tenant's copy, the permissions of adminRole it is missing. SeedRoles leaves
+// existing roles alone, so permissions added to adminRole after a database was
+// seeded only reach its admins this way. Tenant roles get the tenant's own
+// copy of each permission. Running it again changes nothing.
+func GrantAdminPermissions(db *gorm.DB) error {
+	var roles []*models.Role
+	if err := db.Preload("Permissions").Where("name = ?", string(utils.Admin)).Find(&roles).Error; err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		granted := make(map[string]bool, len(role.Permissions))
+		for _, perm := range role.Permissions {
+			granted[perm.Code] = true
+		}
+
+		var missing []*models.Permission
+		for resource, actions := range adminRole {
+			for _, action := range actions {
+				if granted[fmt.Sprintf("%s:%s", resource, action)] {
+					continue
+				}
+				perm, err := findOrCreatePermission(db, role.TenantID, resource, action)
+				if err != nil {
+					return err
+				}
+				missing = append(missing, perm)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		if err := db.Model(role).Association("Permissions").Append(missing); err != nil {
+			return err
+		}
+		log.Printf("granted %d permissions to admin role %s\n", len(missing), role.ID)
+	}
+
+	return nil
+}
+
+// findOrCreatePermission returns the permission of tenant_id, or the global
+// one when tenant_id is nil, creating it if needed.
+func findOrCreatePermission(db *gorm.DB, tenant_id *uuid.UUID, resource utils.Resource, action utils.Action) (*models.Permission, error) {
+	code := fmt.Sprintf("%s:%s", resource, action)
+	query := db.Where("code = ?", code)
+	if tenant_id == nil {
+		query = query.Where("tenant_id IS NULL")
+	} else {
+		query = query.Where("tenant_id = ?", *tenant_id)
+	}
+
+	var perm models.Permission
+	err := query.Attrs(models.Permission{
+		TenantID: tenant_id,
+		Action:   string(action),
+		Resource: string(resource),
+		Code:     code,
+	}).FirstOrCreate(&perm).Error
+	if err != nil {
+		return nil, err
+	}
+	return &perm, nil
+}
+
 func CreatePermissions(db *gorm.DB, resource utils.Resource, actions []utils.Action) ([]*models.Permission, error) {
 	var permissions []*models.Permission
 	tx := db.Begin()
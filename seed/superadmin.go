@@ -11,7 +11,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func SeedSuperAdmin(db *gorm.DB) {
+func SeedSuperAdmin(db *gorm.DB, authService services.AuthService) {
 	email := viper.GetString("SUPERADMIN_EMAIL")
 	password := viper.GetString("SUPERADMIN_PASSWORD")
 
@@ -27,8 +27,6 @@ func SeedSuperAdmin(db *gorm.DB) {
 		return
 	}
 
-	authService := services.NewAuthService()
-
 	hashed, err := authService.HashPassword(password)
 	if err != nil {
 		log.Println("Superadmin password hash error. Skipping seed")